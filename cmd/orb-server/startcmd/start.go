@@ -1271,8 +1271,15 @@ func startOrbServices(parameters *orbParameters) error {
 			},
 			apStore, apSigVerifier, coreCASClient, authTokenManager,
 		),
-		auth.NewHandlerWrapper(policyhandler.New(policyStore), authTokenManager),
+		auth.NewHandlerWrapper(policyhandler.New(policyStore,
+			policyhandler.WithOnPolicyChanged(func(string) {
+				if e := witnessPolicy.Refresh(); e != nil {
+					logger.Warn("Error refreshing witness policy cache", log.WithError(e))
+				}
+			}),
+		), authTokenManager),
 		auth.NewHandlerWrapper(policyhandler.NewRetriever(policyStore), authTokenManager),
+		auth.NewHandlerWrapper(policyhandler.NewCacheFlusher(witnessPolicy, policyStore), authTokenManager),
 		auth.NewHandlerWrapper(logmonitorhandler.NewUpdateHandler(logMonitorStore), authTokenManager),
 		auth.NewHandlerWrapper(logmonitorhandler.NewRetriever(logMonitorStore), authTokenManager),
 		auth.NewHandlerWrapper(vcthandler.New(configStore, logMonitorStore), authTokenManager),
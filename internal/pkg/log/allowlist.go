@@ -0,0 +1,54 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package log
+
+import (
+	"go.uber.org/zap/zapcore"
+)
+
+// allowlistCore wraps a Zap core and drops all fields whose keys aren't in the allowlist.
+type allowlistCore struct {
+	zapcore.Core
+	allowlist map[string]struct{}
+}
+
+func newAllowlistCore(core zapcore.Core, allowlist map[string]struct{}) *allowlistCore {
+	return &allowlistCore{
+		Core:      core,
+		allowlist: allowlist,
+	}
+}
+
+func (c *allowlistCore) With(fields []zapcore.Field) zapcore.Core {
+	return newAllowlistCore(c.Core.With(c.filter(fields)), c.allowlist)
+}
+
+// Check adds this core (rather than the wrapped core) to the checked entry so that Write is
+// invoked on this core and the fields are filtered.
+func (c *allowlistCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return ce.AddCore(entry, c)
+	}
+
+	return ce
+}
+
+func (c *allowlistCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(entry, c.filter(fields))
+}
+
+func (c *allowlistCore) filter(fields []zapcore.Field) []zapcore.Field {
+	filtered := make([]zapcore.Field, 0, len(fields))
+
+	for _, field := range fields {
+		if _, ok := c.allowlist[field.Key]; ok {
+			filtered = append(filtered, field)
+		}
+	}
+
+	return filtered
+}
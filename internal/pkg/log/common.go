@@ -6,7 +6,13 @@ SPDX-License-Identifier: Apache-2.0
 
 package log
 
-import "go.uber.org/zap"
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"go.uber.org/zap"
+)
 
 // InvalidParameterValue outputs an 'invalid parameter' log to the given logger.
 func InvalidParameterValue(log *StructuredLog, param string, err error) {
@@ -37,3 +43,80 @@ func WriteResponseBodyError(log *StructuredLog, err error) {
 func WroteResponse(log *StructuredLog, data []byte) {
 	log.WithOptions(zap.AddCallerSkip(1)).Debug("Wrote response", WithResponse(data))
 }
+
+// RecoverAndLog recovers from a panic and logs it at error level, along with the stack trace and the given fields.
+// The panic is suppressed. This function must be deferred directly, for example:
+//
+//	defer log.RecoverAndLog(logger, log.WithTopic(topic))
+func RecoverAndLog(log *StructuredLog, fields ...zap.Field) {
+	if r := recover(); r != nil {
+		logPanic(log, r, fields...)
+	}
+}
+
+// RecoverLogAndRepanic recovers from a panic, logs it (in the same way as RecoverAndLog) and then panics
+// again with the same value. This function must be deferred directly.
+func RecoverLogAndRepanic(log *StructuredLog, fields ...zap.Field) {
+	if r := recover(); r != nil {
+		logPanic(log, r, fields...)
+
+		panic(r)
+	}
+}
+
+func logPanic(log *StructuredLog, r interface{}, fields ...zap.Field) {
+	callerSkip := panicCallerSkip()
+
+	log.WithOptions(zap.AddCallerSkip(callerSkip)).Error("Recovered from panic",
+		append(fields[:len(fields):len(fields)], WithError(fmt.Errorf("%v", r)),
+			zap.StackSkip(stacktraceKey, callerSkip))...)
+}
+
+const (
+	maxPanicFrames = 64
+
+	// defaultPanicCallerSkip skips logPanic, the recover function and the runtime's panic handler.
+	defaultPanicCallerSkip = 3
+)
+
+// panicCallerSkip returns the number of frames, relative to logPanic, to skip so that the logged caller is the
+// function that panicked. This is the first frame after the runtime's panic handler that isn't in the runtime,
+// since a runtime error (e.g. a nil map write or an index out of range) is raised by a runtime function that's
+// called by the function that panicked.
+//
+//go:noinline
+func panicCallerSkip() int {
+	pcs := make([]uintptr, maxPanicFrames)
+
+	// Skip runtime.Callers and panicCallerSkip so that the first PC is logPanic (the reference frame for
+	// zap.AddCallerSkip).
+	n := runtime.Callers(2, pcs)
+
+	panicking := false
+
+	for i, pc := range pcs[:n] {
+		// The PC is a return address, so subtract one to get the PC of the call instruction.
+		fn := runtime.FuncForPC(pc - 1)
+		if fn == nil {
+			continue
+		}
+
+		name := fn.Name()
+
+		if name == "runtime.gopanic" {
+			panicking = true
+
+			continue
+		}
+
+		if panicking && !isRuntimeFunc(name) {
+			return i
+		}
+	}
+
+	return defaultPanicCallerSkip
+}
+
+func isRuntimeFunc(name string) bool {
+	return strings.HasPrefix(name, "runtime.") || strings.HasPrefix(name, "internal/runtime/")
+}
@@ -8,6 +8,7 @@ package log
 
 import (
 	"errors"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -117,3 +118,115 @@ func TestCommonLogs(t *testing.T) {
 		require.Contains(t, stdOut.Buffer.String(), "log/common_test.go")
 	})
 }
+
+func TestRecoverAndLog(t *testing.T) {
+	const module = "test_module"
+
+	t.Run("Panic logged and suppressed", func(t *testing.T) {
+		stdErr := newMockWriter()
+
+		logger := NewStructured(module,
+			WithStdErr(stdErr),
+			WithFields(WithServiceName("myservice")),
+		)
+
+		require.NotPanics(t, func() {
+			defer RecoverAndLog(logger, WithTopic("topic1"))
+
+			panic("injected panic")
+		})
+
+		require.Contains(t, stdErr.Buffer.String(), `Recovered from panic`)
+		require.Contains(t, stdErr.Buffer.String(), `"service": "myservice"`)
+		require.Contains(t, stdErr.Buffer.String(), `"topic": "topic1"`)
+		require.Contains(t, stdErr.Buffer.String(), `"error": "injected panic"`)
+		require.Contains(t, stdErr.Buffer.String(), `"stacktrace": "`)
+		require.Contains(t, stdErr.Buffer.String(), "TestRecoverAndLog")
+		require.Contains(t, stdErr.Buffer.String(), "log/common_test.go")
+	})
+
+	t.Run("Runtime panic -> caller is the function that panicked", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			panic func()
+			err   string
+		}{
+			{
+				name: "nil map write",
+				panic: func() {
+					var m map[string]int
+
+					m["key"] = 1
+				},
+				err: "assignment to entry in nil map",
+			},
+			{
+				name: "index out of range",
+				panic: func() {
+					var values []int
+
+					i := 3
+
+					_ = values[i]
+				},
+				err: "index out of range",
+			},
+			{
+				name: "nil pointer dereference",
+				panic: func() {
+					var p *struct{ value int }
+
+					_ = p.value
+				},
+				err: "nil pointer dereference",
+			},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				stdErr := newMockWriter()
+
+				logger := NewStructured(module, WithStdErr(stdErr), WithEncoding(JSON))
+
+				require.NotPanics(t, func() {
+					defer RecoverAndLog(logger)
+
+					test.panic()
+				})
+
+				l := unmarshalLogData(t, stdErr.Bytes())
+				require.Equal(t, "Recovered from panic", l.Msg)
+				require.Contains(t, l.Error, test.err)
+				require.True(t, strings.HasPrefix(l.Caller, "log/common_test.go:"),
+					"unexpected caller: %s", l.Caller)
+			})
+		}
+	})
+
+	t.Run("No panic", func(t *testing.T) {
+		stdErr := newMockWriter()
+
+		logger := NewStructured(module, WithStdErr(stdErr))
+
+		func() {
+			defer RecoverAndLog(logger)
+		}()
+
+		require.Empty(t, stdErr.Buffer.String())
+	})
+
+	t.Run("Repanic", func(t *testing.T) {
+		stdErr := newMockWriter()
+
+		logger := NewStructured(module, WithStdErr(stdErr))
+
+		require.PanicsWithValue(t, "injected panic", func() {
+			defer RecoverLogAndRepanic(logger)
+
+			panic("injected panic")
+		})
+
+		require.Contains(t, stdErr.Buffer.String(), `Recovered from panic`)
+		require.Contains(t, stdErr.Buffer.String(), `"error": "injected panic"`)
+	})
+}
@@ -0,0 +1,137 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package log
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// dedupCore wraps a Zap core and collapses immediately-repeated identical logs (same level, message and fields).
+// The first log is written immediately and the repeats are suppressed. The last repeat is then written, with the
+// number of suppressed repeats under the FieldRepeated key, when a different log is written, when the flush
+// interval elapses, or when the core is synced. A dedupCore must wrap a single output core (rather than a Tee)
+// so that the output's level check is applied in Check.
+type dedupCore struct {
+	zapcore.Core
+
+	flushInterval time.Duration
+
+	mutex    sync.Mutex
+	last     *loggedEntry
+	repeated int
+	timer    *time.Timer
+}
+
+type loggedEntry struct {
+	entry  zapcore.Entry
+	fields []zapcore.Field
+}
+
+func newDedupCore(core zapcore.Core, flushInterval time.Duration) *dedupCore {
+	return &dedupCore{
+		Core:          core,
+		flushInterval: flushInterval,
+	}
+}
+
+// With returns a new core (with its own dedup state) since logs with different context fields aren't identical.
+func (c *dedupCore) With(fields []zapcore.Field) zapcore.Core {
+	return newDedupCore(c.Core.With(fields), c.flushInterval)
+}
+
+// Check adds this core (rather than the wrapped core) to the checked entry so that Write is
+// invoked on this core and repeated logs are suppressed.
+func (c *dedupCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return ce.AddCore(entry, c)
+	}
+
+	return ce
+}
+
+func (c *dedupCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.last != nil && c.last.matches(entry, fields) {
+		c.last.entry = entry
+		c.repeated++
+
+		if c.timer == nil {
+			c.timer = time.AfterFunc(c.flushInterval, c.flushPending)
+		}
+
+		return nil
+	}
+
+	if err := c.writeRepeated(); err != nil {
+		return err
+	}
+
+	c.last = &loggedEntry{entry: entry, fields: append([]zapcore.Field(nil), fields...)}
+
+	return c.Core.Write(entry, fields)
+}
+
+func (c *dedupCore) Sync() error {
+	c.mutex.Lock()
+	err := c.writeRepeated()
+	c.mutex.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	return c.Core.Sync()
+}
+
+// flushPending is invoked when the flush interval elapses.
+func (c *dedupCore) flushPending() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// There's nowhere to report the error since we're not being called by a logger.
+	_ = c.writeRepeated() //nolint:errcheck
+}
+
+// writeRepeated writes the last log along with the number of times it was repeated (if it was repeated).
+// The caller must hold the lock.
+func (c *dedupCore) writeRepeated() error {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+
+	if c.repeated == 0 {
+		return nil
+	}
+
+	repeated := c.repeated
+
+	c.repeated = 0
+
+	return c.Core.Write(c.last.entry, append(c.last.fields, zap.Int(FieldRepeated, repeated)))
+}
+
+func (e *loggedEntry) matches(entry zapcore.Entry, fields []zapcore.Field) bool {
+	if e.entry.Level != entry.Level || e.entry.LoggerName != entry.LoggerName || e.entry.Message != entry.Message ||
+		len(e.fields) != len(fields) {
+		return false
+	}
+
+	for i, field := range fields {
+		if !e.fields[i].Equals(field) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,192 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package log
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithDedupConsecutive(t *testing.T) {
+	const module = "sample-module-dedup"
+
+	t.Run("Repeated logs collapsed", func(t *testing.T) {
+		stdOut := newMockWriter()
+
+		logger := NewStructured(module, WithStdOut(stdOut), WithEncoding(JSON), WithDedupConsecutive(time.Hour))
+
+		for i := 0; i < 5; i++ {
+			logger.Info("No expired data", WithTopic("topic1"))
+		}
+
+		logger.Info("Expired data deleted", WithTopic("topic1"))
+
+		lines := unmarshalLogLines(t, stdOut.Bytes())
+		require.Len(t, lines, 3)
+
+		require.Equal(t, "No expired data", lines[0].Msg)
+		require.Zero(t, lines[0].Repeated)
+
+		require.Equal(t, "No expired data", lines[1].Msg)
+		require.Equal(t, "topic1", lines[1].Topic)
+		require.Equal(t, 4, lines[1].Repeated)
+
+		require.Equal(t, "Expired data deleted", lines[2].Msg)
+		require.Zero(t, lines[2].Repeated)
+	})
+
+	t.Run("Different fields or level -> not collapsed", func(t *testing.T) {
+		stdOut := newMockWriter()
+
+		logger := NewStructured(module, WithStdOut(stdOut), WithEncoding(JSON), WithDedupConsecutive(time.Hour))
+
+		logger.Info("Sample log", WithTopic("topic1"))
+		logger.Info("Sample log", WithTopic("topic2"))
+		logger.Warn("Sample log", WithTopic("topic2"))
+
+		lines := unmarshalLogLines(t, stdOut.Bytes())
+		require.Len(t, lines, 3)
+
+		for _, l := range lines {
+			require.Zero(t, l.Repeated)
+		}
+	})
+
+	t.Run("Flush interval elapsed", func(t *testing.T) {
+		stdOut := &lockedWriter{}
+
+		logger := NewStructured(module, WithStdOut(stdOut), WithEncoding(JSON),
+			WithDedupConsecutive(10*time.Millisecond))
+
+		for i := 0; i < 3; i++ {
+			logger.Info("Policy served from cache")
+		}
+
+		require.Eventually(t, func() bool {
+			return len(unmarshalLogLines(t, stdOut.Bytes())) == 2
+		}, time.Second, 5*time.Millisecond)
+
+		lines := unmarshalLogLines(t, stdOut.Bytes())
+		require.Equal(t, "Policy served from cache", lines[1].Msg)
+		require.Equal(t, 2, lines[1].Repeated)
+
+		// Further repeats are counted from the last flush.
+		logger.Info("Policy served from cache")
+
+		require.Eventually(t, func() bool {
+			return len(unmarshalLogLines(t, stdOut.Bytes())) == 3
+		}, time.Second, 5*time.Millisecond)
+
+		lines = unmarshalLogLines(t, stdOut.Bytes())
+		require.Equal(t, 1, lines[2].Repeated)
+	})
+
+	t.Run("Level routing preserved", func(t *testing.T) {
+		stdOut := newMockWriter()
+		stdErr := newMockWriter()
+
+		logger := NewStructured(module, WithStdOut(stdOut), WithStdErr(stdErr), WithEncoding(JSON),
+			WithDedupConsecutive(time.Hour))
+
+		logger.Info("Sample info log")
+		logger.Info("Sample info log")
+		logger.Error("Sample error log")
+		logger.Error("Sample error log")
+		logger.Info("Sample info log")
+
+		requireLevel := func(t *testing.T, lines []*logData, level, msg string) {
+			t.Helper()
+
+			for _, l := range lines {
+				require.Equal(t, level, l.Level)
+				require.Equal(t, msg, l.Msg)
+			}
+		}
+
+		// Repeats are collapsed per output, so the error logs don't interrupt the info logs on standard out.
+		outLines := unmarshalLogLines(t, stdOut.Bytes())
+		require.Len(t, outLines, 1)
+		requireLevel(t, outLines, "info", "Sample info log")
+
+		errLines := unmarshalLogLines(t, stdErr.Bytes())
+		require.Len(t, errLines, 1)
+		requireLevel(t, errLines, "error", "Sample error log")
+
+		require.NoError(t, logger.Sync())
+
+		outLines = unmarshalLogLines(t, stdOut.Bytes())
+		require.Len(t, outLines, 2)
+		requireLevel(t, outLines, "info", "Sample info log")
+		require.Equal(t, 2, outLines[1].Repeated)
+
+		errLines = unmarshalLogLines(t, stdErr.Bytes())
+		require.Len(t, errLines, 2)
+		requireLevel(t, errLines, "error", "Sample error log")
+		require.Equal(t, 1, errLines[1].Repeated)
+	})
+
+	t.Run("Sync", func(t *testing.T) {
+		stdOut := newMockWriter()
+
+		logger := NewStructured(module, WithStdOut(stdOut), WithStdErr(newMockWriter()), WithEncoding(JSON),
+			WithDedupConsecutive())
+
+		logger.Info("Sample log")
+		logger.Info("Sample log")
+
+		require.Len(t, unmarshalLogLines(t, stdOut.Bytes()), 1)
+
+		require.NoError(t, logger.Sync())
+
+		lines := unmarshalLogLines(t, stdOut.Bytes())
+		require.Len(t, lines, 2)
+		require.Equal(t, 1, lines[1].Repeated)
+	})
+}
+
+func unmarshalLogLines(t *testing.T, b []byte) []*logData {
+	t.Helper()
+
+	var lines []*logData
+
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		if line != "" {
+			lines = append(lines, unmarshalLogData(t, []byte(line)))
+		}
+	}
+
+	return lines
+}
+
+// lockedWriter is a mock writer that may be written to (e.g. by the dedup flush timer) while it's being read.
+type lockedWriter struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.buf.Write(p)
+}
+
+func (w *lockedWriter) Sync() error {
+	return nil
+}
+
+func (w *lockedWriter) Bytes() []byte {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return append([]byte(nil), w.buf.Bytes()...)
+}
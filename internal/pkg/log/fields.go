@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"go.uber.org/zap"
@@ -121,6 +122,7 @@ const (
 	FieldAuthToken              = "auth-token"
 	FieldAuthTokens             = "auth-tokens" //nolint:gosec
 	FieldAddress                = "address"
+	FieldHost                   = "host"
 	FieldAttributedTo           = "attributed-to"
 	FieldAnchorLink             = "anchor-link"
 	FieldAnchorLinkset          = "anchor-linkset"
@@ -146,6 +148,14 @@ const (
 	FieldSource                 = "source"
 	FieldAge                    = "age"
 	FieldMinAge                 = "min-age"
+	FieldErrorCode              = "error-code"
+	FieldTagName                = "tag-name"
+	FieldResult                 = "result"
+	FieldServiceVersion         = "service-version"
+	FieldBuild                  = "build"
+	FieldEvaluationDuration     = "evaluation-duration"
+	FieldDuration               = "duration"
+	FieldRepeated               = "repeated"
 )
 
 // WithError sets the error field.
@@ -740,6 +750,21 @@ func WithAddress(value string) zap.Field {
 	return zap.String(FieldAddress, value)
 }
 
+// WithHost sets the host field to the host (and port) of the given URL. Any user info in the URL is omitted.
+func WithHost(value *url.URL) zap.Field {
+	return zap.String(FieldHost, ExtractHost(value))
+}
+
+// ExtractHost returns the host (and port, if specified) of the given URL, excluding any user info.
+// An empty string is returned if the URL is nil or has no host (e.g. an opaque URL such as urn:uuid:1234).
+func ExtractHost(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+
+	return u.Host
+}
+
 // WithAttributedTo sets the attributed-to field.
 func WithAttributedTo(value string) zap.Field {
 	return zap.String(FieldAttributedTo, value)
@@ -875,6 +900,63 @@ func WithMinAge(value time.Duration) zap.Field {
 	return zap.Duration(FieldMinAge, value)
 }
 
+// WithPercent sets a field with the given key to the given percentage, formatted with two decimal
+// places and a percent sign (e.g. 66.67%).
+func WithPercent(key string, value float64) zap.Field {
+	return zap.String(key, strconv.FormatFloat(value, 'f', 2, 64)+"%")
+}
+
+// WithRate sets a field with the given key to the given per-second rate, formatted with two decimal
+// places and a per-second unit (e.g. 12.50/s).
+func WithRate(key string, perSecond float64) zap.Field {
+	return zap.String(key, strconv.FormatFloat(perSecond, 'f', 2, 64)+"/s")
+}
+
+// WithErrorCode sets the error-code field. The error code is a stable category for an error
+// (e.g. policy-parse, broker-unreachable, store-write) which may be used for log-based alerting.
+func WithErrorCode(value string) zap.Field {
+	return zap.String(FieldErrorCode, value)
+}
+
+// Values for the result field.
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// WithResult sets the result field, which indicates the outcome of an operation (ResultSuccess or ResultFailure).
+// This allows success/failure ratios to be computed from the logs.
+func WithResult(value string) zap.Field {
+	return zap.String(FieldResult, value)
+}
+
+// WithTagName sets the tag-name field.
+func WithTagName(value string) zap.Field {
+	return zap.String(FieldTagName, value)
+}
+
+// WithEvaluationDuration sets the evaluation-duration field.
+func WithEvaluationDuration(value time.Duration) zap.Field {
+	return zap.Duration(FieldEvaluationDuration, value)
+}
+
+// WithDuration sets the duration field.
+func WithDuration(value time.Duration) zap.Field {
+	return zap.Duration(FieldDuration, value)
+}
+
+// WithServiceVersion sets the service-version field. This field is typically set as a default field
+// (see WithFields) when the logger is created so that every log record carries the Orb version.
+func WithServiceVersion(value string) zap.Field {
+	return zap.String(FieldServiceVersion, value)
+}
+
+// WithBuildInfo sets the build field (e.g. the build commit). This field is typically set as a default field
+// (see WithFields) when the logger is created so that every log record carries the build information.
+func WithBuildInfo(value string) zap.Field {
+	return zap.String(FieldBuild, value)
+}
+
 type jsonMarshaller struct {
 	key string
 	obj interface{}
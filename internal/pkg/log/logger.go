@@ -12,6 +12,7 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -26,10 +27,11 @@ const (
 	stacktraceKey = "stacktrace"
 )
 
-// DefaultEncoding sets the default logger encoding.
-// It may be overridden at build time using the -ldflags option.
+// defaultEncoding is the default logger encoding. It should be accessed using SetDefaultEncoding and
+// GetDefaultEncoding, although it may still be overridden at build time using the -ldflags option
+// (-X github.com/trustbloc/orb/internal/pkg/log.defaultEncoding=json).
 //nolint:gochecknoglobals
-var DefaultEncoding = Console
+var defaultEncoding = Console
 
 // Level defines a log level for logging messages.
 type Level int
@@ -85,15 +87,38 @@ const (
 
 	minLogLevel  = DEBUG
 	defaultLevel = INFO
+
+	// DefaultRoutingLevel is the level at and above which logs are written to standard error
+	// when the WithLevelRouting option is used without specifying a level.
+	DefaultRoutingLevel = WARNING
+
+	// DefaultDedupFlushInterval is the interval after which the number of suppressed repeats of a log is written
+	// when the WithDedupConsecutive option is used without specifying an interval.
+	DefaultDedupFlushInterval = 10 * time.Second
+
+	defaultStdErrLevel = ERROR
 )
 
 var levels = newModuleLevels() //nolint:gochecknoglobals
 
+var defaultEncodingMutex sync.RWMutex //nolint:gochecknoglobals
+
 type options struct {
+	encoding    Encoding
+	stdOut      zapcore.WriteSyncer
+	stdErr      zapcore.WriteSyncer
+	stdErrLevel Level
+	fields      []zap.Field
+	stack       bool
+	outputs     []output
+	allowlist   map[string]struct{}
+	dedup       bool
+	dedupFlush  time.Duration
+}
+
+type output struct {
 	encoding Encoding
-	stdOut   zapcore.WriteSyncer
-	stdErr   zapcore.WriteSyncer
-	fields   []zap.Field
+	writer   zapcore.WriteSyncer
 }
 
 // Encoding defines the log encoding.
@@ -124,6 +149,27 @@ func WithStdErr(stdErr zapcore.WriteSyncer) Option {
 	}
 }
 
+// WithLevelRouting routes logs at or above the given level to the standard error writer and all
+// other logs to the standard output writer. If no level is provided then DefaultRoutingLevel (WARN) is used.
+// If this option is not specified then logs of type ERROR, PANIC, and FATAL are written to standard error.
+func WithLevelRouting(stdErrLevel ...Level) Option {
+	return func(o *options) {
+		o.stdErrLevel = DefaultRoutingLevel
+
+		if len(stdErrLevel) > 0 {
+			o.stdErrLevel = stdErrLevel[0]
+		}
+	}
+}
+
+// WithStack adds the stack trace of the current goroutine (under the 'stacktrace' key) to every log
+// of type ERROR, PANIC, and FATAL. This option is off by default since capturing the stack is expensive.
+func WithStack() Option {
+	return func(o *options) {
+		o.stack = true
+	}
+}
+
 // WithFields sets the fields that will be output with every log.
 func WithFields(fields ...zap.Field) Option {
 	return func(o *options) {
@@ -138,6 +184,51 @@ func WithEncoding(encoding Encoding) Option {
 	}
 }
 
+// WithAdditionalOutput writes every log, using the given encoding, to the given writer in addition to the
+// standard output and standard error writers. For example, logs may be written as JSON to a collector and,
+// at the same time, in console format to a local file. This option may be specified multiple times in order
+// to add multiple outputs. The module's log level applies to all outputs, although level routing
+// (see WithLevelRouting) only applies to the standard writers.
+func WithAdditionalOutput(encoding Encoding, writer zapcore.WriteSyncer) Option {
+	return func(o *options) {
+		o.outputs = append(o.outputs, output{encoding: encoding, writer: writer})
+	}
+}
+
+// WithFieldAllowlist outputs only the fields with the given keys (e.g. FieldServiceName, FieldTopic).
+// All other fields, including those added with WithFields or With, are silently omitted from every output.
+// This option is stricter than redaction and may be used to minimize the amount of sensitive data that
+// is logged. The message, level, time, module and caller are always output.
+func WithFieldAllowlist(keys ...string) Option {
+	return func(o *options) {
+		o.allowlist = make(map[string]struct{}, len(keys))
+
+		for _, key := range keys {
+			o.allowlist[key] = struct{}{}
+		}
+	}
+}
+
+// WithDedupConsecutive collapses immediately-repeated identical logs (same level, message and fields), e.g.
+// from a tight polling loop, into a single log. The first log is written immediately and the repeats are
+// suppressed. The last repeat is then written once, with the number of suppressed repeats under the 'repeated'
+// key (FieldRepeated), when a different log is written or when the given flush interval elapses. If no interval
+// is provided then DefaultDedupFlushInterval (10s) is used. Unlike sampling, no log is lost without being counted.
+// Repeats are collapsed separately for each output (standard out, standard error and any additional outputs),
+// i.e. only the logs that are written to an output are compared.
+// Note that, if WithFieldAllowlist is also specified, FieldRepeated must be in the allowlist for the count
+// to be output.
+func WithDedupConsecutive(flushInterval ...time.Duration) Option {
+	return func(o *options) {
+		o.dedup = true
+		o.dedupFlush = DefaultDedupFlushInterval
+
+		if len(flushInterval) > 0 && flushInterval[0] > 0 {
+			o.dedupFlush = flushInterval[0]
+		}
+	}
+}
+
 // Log uses the Zap SugaredLogger to log messages.
 type Log struct {
 	*zap.SugaredLogger
@@ -149,7 +240,7 @@ func New(module string, opts ...Option) *Log {
 	options := getOptions(opts)
 
 	return &Log{
-		SugaredLogger: newZap(module, options.encoding, options.stdOut, options.stdErr).With(options.fields...).Sugar(),
+		SugaredLogger: newZap(module, options).With(options.fields...).Sugar(),
 		module:        module,
 	}
 }
@@ -170,11 +261,20 @@ func NewStructured(module string, opts ...Option) *StructuredLog {
 	options := getOptions(opts)
 
 	return &StructuredLog{
-		Logger: newZap(module, options.encoding, options.stdOut, options.stdErr).With(options.fields...),
+		Logger: newZap(module, options).With(options.fields...),
 		module: module,
 	}
 }
 
+// With returns a child logger that includes the given fields in every log in addition to
+// the fields of the parent logger. The parent logger is not affected.
+func (l *StructuredLog) With(fields ...zap.Field) *StructuredLog {
+	return &StructuredLog{
+		Logger: l.Logger.With(fields...),
+		module: l.module,
+	}
+}
+
 // IsEnabled returns true if given log level is enabled.
 func (l *StructuredLog) IsEnabled(level Level) bool {
 	return levels.isEnabled(l.module, level)
@@ -190,6 +290,23 @@ func SetDefaultLevel(level Level) {
 	levels.SetDefault(level)
 }
 
+// SetDefaultEncoding sets the encoding (console or json) used by all subsequently created loggers
+// that don't explicitly specify an encoding using the WithEncoding option.
+func SetDefaultEncoding(encoding Encoding) {
+	defaultEncodingMutex.Lock()
+	defer defaultEncodingMutex.Unlock()
+
+	defaultEncoding = encoding
+}
+
+// GetDefaultEncoding returns the default encoding.
+func GetDefaultEncoding() Encoding {
+	defaultEncodingMutex.RLock()
+	defer defaultEncodingMutex.RUnlock()
+
+	return defaultEncoding
+}
+
 // GetLevel returns the log level for the given module.
 func GetLevel(module string) Level {
 	return levels.Get(module)
@@ -348,23 +465,55 @@ func (l *moduleLevels) isEnabled(module string, level Level) bool {
 	return level >= l.Get(module)
 }
 
-func newZap(module string, encoding Encoding, stdOut, stdErr zapcore.WriteSyncer) *zap.Logger {
-	encoder := newZapEncoder(encoding)
+func newZap(module string, o *options) *zap.Logger {
+	encoder := newZapEncoder(o.encoding)
 
-	core := zapcore.NewTee(
-		zapcore.NewCore(encoder, zapcore.Lock(stdErr),
+	stdErrLevel := zapcore.Level(o.stdErrLevel)
+
+	cores := []zapcore.Core{
+		zapcore.NewCore(encoder, zapcore.Lock(o.stdErr),
 			zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
-				return lvl >= zapcore.ErrorLevel && levels.isEnabled(module, Level(lvl))
+				return lvl >= stdErrLevel && levels.isEnabled(module, Level(lvl))
 			}),
 		),
-		zapcore.NewCore(encoder, zapcore.Lock(stdOut),
+		zapcore.NewCore(encoder, zapcore.Lock(o.stdOut),
 			zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
-				return lvl < zapcore.ErrorLevel && levels.isEnabled(module, Level(lvl))
+				return lvl < stdErrLevel && levels.isEnabled(module, Level(lvl))
 			}),
 		),
-	)
+	}
+
+	for _, out := range o.outputs {
+		cores = append(cores,
+			zapcore.NewCore(newZapEncoder(out.encoding), zapcore.Lock(out.writer),
+				zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
+					return levels.isEnabled(module, Level(lvl))
+				}),
+			),
+		)
+	}
+
+	if o.allowlist != nil {
+		for i, c := range cores {
+			cores[i] = newAllowlistCore(c, o.allowlist)
+		}
+	}
+
+	if o.dedup {
+		for i, c := range cores {
+			cores[i] = newDedupCore(c, o.dedupFlush)
+		}
+	}
+
+	core := zapcore.NewTee(cores...)
+
+	zapOpts := []zap.Option{zap.AddCaller()}
+
+	if o.stack {
+		zapOpts = append(zapOpts, zap.AddStacktrace(zapcore.ErrorLevel))
+	}
 
-	return zap.New(core, zap.AddCaller()).Named(module)
+	return zap.New(core, zapOpts...).Named(module)
 }
 
 func newZapEncoder(encoding Encoding) zapcore.Encoder {
@@ -403,9 +552,10 @@ func newZapEncoder(encoding Encoding) zapcore.Encoder {
 
 func getOptions(opts []Option) *options {
 	options := &options{
-		encoding: DefaultEncoding,
-		stdOut:   os.Stdout,
-		stdErr:   os.Stderr,
+		encoding:    GetDefaultEncoding(),
+		stdOut:      os.Stdout,
+		stdErr:      os.Stderr,
+		stdErrLevel: defaultStdErrLevel,
 	}
 
 	for _, opt := range opts {
@@ -8,6 +8,9 @@ package log
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -114,6 +117,245 @@ func TestDefaultLogger(t *testing.T) {
 	})
 }
 
+func TestStructuredLog_With(t *testing.T) {
+	const module = "sample-module-with"
+
+	stdOut := newMockWriter()
+
+	logger := NewStructured(module, WithStdOut(stdOut), WithEncoding(JSON),
+		WithFields(WithServiceName("service1")))
+
+	childLogger := logger.With(WithMessageID("msg1"))
+	require.True(t, childLogger.IsEnabled(INFO))
+
+	childLogger.Info("Child log 1")
+
+	l := unmarshalLogData(t, stdOut.Bytes())
+	require.Equal(t, "Child log 1", l.Msg)
+	require.Equal(t, "service1", l.Service)
+	require.Equal(t, "msg1", l.MessageID)
+	require.Equal(t, module, l.Logger)
+
+	stdOut.Reset()
+
+	childLogger.Warn("Child log 2")
+
+	l = unmarshalLogData(t, stdOut.Bytes())
+	require.Equal(t, "Child log 2", l.Msg)
+	require.Equal(t, "msg1", l.MessageID)
+
+	stdOut.Reset()
+
+	logger.Info("Parent log")
+
+	l = unmarshalLogData(t, stdOut.Bytes())
+	require.Equal(t, "Parent log", l.Msg)
+	require.Equal(t, "service1", l.Service)
+	require.Empty(t, l.MessageID)
+}
+
+func TestWithStack(t *testing.T) {
+	const module = "sample-module-stack"
+
+	t.Run("Stack disabled", func(t *testing.T) {
+		stdErr := newMockWriter()
+
+		logger := NewStructured(module, WithStdErr(stdErr), WithEncoding(JSON))
+
+		logger.Error("Sample error log")
+
+		require.NotContains(t, stdErr.String(), stacktraceKey)
+	})
+
+	t.Run("Stack enabled", func(t *testing.T) {
+		stdOut := newMockWriter()
+		stdErr := newMockWriter()
+
+		logger := NewStructured(module, WithStdOut(stdOut), WithStdErr(stdErr), WithEncoding(JSON), WithStack())
+
+		logger.Warn("Sample warn log")
+		logger.Error("Sample error log")
+
+		require.NotContains(t, stdOut.String(), stacktraceKey)
+
+		logData := make(map[string]interface{})
+		require.NoError(t, json.Unmarshal(stdErr.Bytes(), &logData))
+
+		stack, ok := logData[stacktraceKey].(string)
+		require.True(t, ok)
+		require.Contains(t, stack, "TestWithStack")
+	})
+}
+
+func TestLevelRouting(t *testing.T) {
+	const module = "sample-module-routing"
+
+	t.Run("Default routing level (WARN)", func(t *testing.T) {
+		stdOut := newMockWriter()
+		stdErr := newMockWriter()
+
+		logger := NewStructured(module, WithStdOut(stdOut), WithStdErr(stdErr), WithLevelRouting())
+
+		logger.Info("Sample info log")
+		logger.Warn("Sample warn log")
+		logger.Error("Sample error log")
+
+		require.Contains(t, stdOut.String(), "Sample info log")
+		require.NotContains(t, stdOut.String(), "Sample warn log")
+		require.NotContains(t, stdOut.String(), "Sample error log")
+
+		require.NotContains(t, stdErr.String(), "Sample info log")
+		require.Contains(t, stdErr.String(), "Sample warn log")
+		require.Contains(t, stdErr.String(), "Sample error log")
+	})
+
+	t.Run("Custom routing level", func(t *testing.T) {
+		stdOut := newMockWriter()
+		stdErr := newMockWriter()
+
+		logger := NewStructured(module, WithStdOut(stdOut), WithStdErr(stdErr), WithLevelRouting(INFO))
+
+		logger.Info("Sample info log")
+		logger.Error("Sample error log")
+
+		require.Empty(t, stdOut.String())
+		require.Contains(t, stdErr.String(), "Sample info log")
+		require.Contains(t, stdErr.String(), "Sample error log")
+	})
+}
+
+func TestWithAdditionalOutput(t *testing.T) {
+	const module = "sample-module-outputs"
+
+	stdOut := newMockWriter()
+	stdErr := newMockWriter()
+	jsonOut := newMockWriter()
+	consoleOut := newMockWriter()
+
+	logger := NewStructured(module, WithStdOut(stdOut), WithStdErr(stdErr), WithEncoding(JSON),
+		WithAdditionalOutput(JSON, jsonOut), WithAdditionalOutput(Console, consoleOut),
+		WithFields(WithServiceName("service1")))
+
+	logger.Info("Sample info log", WithMessageID("msg1"))
+
+	l := unmarshalLogData(t, jsonOut.Bytes())
+	require.Equal(t, "Sample info log", l.Msg)
+	require.Equal(t, "service1", l.Service)
+	require.Equal(t, "msg1", l.MessageID)
+	require.Equal(t, module, l.Logger)
+
+	require.False(t, json.Valid(consoleOut.Bytes()))
+	require.Contains(t, consoleOut.String(), "INFO")
+	require.Contains(t, consoleOut.String(), fmt.Sprintf("[%s]", module))
+	require.Contains(t, consoleOut.String(), "Sample info log")
+	require.Contains(t, consoleOut.String(), `"service": "service1"`)
+
+	// The standard writers are unaffected.
+	require.Equal(t, jsonOut.String(), stdOut.String())
+	require.Empty(t, stdErr.String())
+
+	t.Run("Level filtering applies to all outputs", func(t *testing.T) {
+		SetLevel(module, WARNING)
+		defer SetLevel(module, INFO)
+
+		stdOut.Reset()
+		jsonOut.Reset()
+		consoleOut.Reset()
+
+		logger.Info("Filtered info log")
+
+		require.Empty(t, stdOut.String())
+		require.Empty(t, jsonOut.String())
+		require.Empty(t, consoleOut.String())
+
+		logger.Error("Sample error log")
+
+		require.Contains(t, stdErr.String(), "Sample error log")
+		require.Contains(t, jsonOut.String(), "Sample error log")
+		require.Contains(t, consoleOut.String(), "Sample error log")
+	})
+}
+
+func TestWithFieldAllowlist(t *testing.T) {
+	const module = "sample-module-allowlist"
+
+	stdOut := newMockWriter()
+	stdErr := newMockWriter()
+	additionalOut := newMockWriter()
+
+	logger := NewStructured(module, WithStdOut(stdOut), WithStdErr(stdErr), WithEncoding(JSON),
+		WithAdditionalOutput(JSON, additionalOut),
+		WithFieldAllowlist(FieldServiceName, FieldTopic),
+		WithFields(WithServiceName("service1"), WithActorID("https://example.com/actor")),
+	).With(WithTopic("topic1"), WithData([]byte("sensitive data")))
+
+	logger.Info("Sample info log", WithMessageID("msg1"), WithTopic("topic2"))
+
+	for _, out := range []*mockWriter{stdOut, additionalOut} {
+		l := unmarshalLogData(t, out.Bytes())
+		require.Equal(t, "Sample info log", l.Msg)
+		require.Equal(t, module, l.Logger)
+		require.Equal(t, "service1", l.Service)
+		require.Equal(t, "topic2", l.Topic)
+		require.Empty(t, l.ActorID)
+		require.Empty(t, l.Data)
+		require.Empty(t, l.MessageID)
+		require.NotContains(t, out.String(), "sensitive data")
+	}
+
+	logger.Error("Sample error log", WithError(errors.New("some error")))
+
+	l := unmarshalLogData(t, stdErr.Bytes())
+	require.Equal(t, "Sample error log", l.Msg)
+	require.Equal(t, "service1", l.Service)
+	require.Empty(t, l.Error)
+
+	t.Run("Empty allowlist -> all fields omitted", func(t *testing.T) {
+		stdOut.Reset()
+
+		NewStructured(module, WithStdOut(stdOut), WithEncoding(JSON), WithFieldAllowlist()).
+			Info("Sample info log", WithServiceName("service1"))
+
+		l := unmarshalLogData(t, stdOut.Bytes())
+		require.Equal(t, "Sample info log", l.Msg)
+		require.Empty(t, l.Service)
+	})
+}
+
+func TestSetDefaultEncoding(t *testing.T) {
+	const module = "sample-module-encoding"
+
+	encoding := GetDefaultEncoding()
+	defer SetDefaultEncoding(encoding)
+
+	SetDefaultEncoding(JSON)
+	require.Equal(t, JSON, GetDefaultEncoding())
+
+	t.Run("Default encoding", func(t *testing.T) {
+		stdOut := newMockWriter()
+
+		logger := NewStructured(module, WithStdOut(stdOut))
+
+		logger.Info("Sample info log")
+
+		l := unmarshalLogData(t, stdOut.Bytes())
+		require.Equal(t, "Sample info log", l.Msg)
+		require.Equal(t, "info", l.Level)
+	})
+
+	t.Run("Encoding overridden", func(t *testing.T) {
+		stdOut := newMockWriter()
+
+		logger := NewStructured(module, WithStdOut(stdOut), WithEncoding(Console))
+
+		logger.Info("Sample info log")
+
+		require.Contains(t, stdOut.String(), "INFO")
+		require.Contains(t, stdOut.String(), "[sample-module-encoding]")
+		require.NotContains(t, stdOut.String(), `"msg"`)
+	})
+}
+
 // TestAllLevels tests logging level behaviour
 // logging levels can be set per modules, if not set then it will default to 'INFO'.
 func TestAllLevels(t *testing.T) {
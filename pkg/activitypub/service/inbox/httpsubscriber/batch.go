@@ -0,0 +1,191 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package httpsubscriber
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+
+	"github.com/trustbloc/orb/internal/pkg/log"
+)
+
+// BatchContentType is the content type of a request that contains a batch of messages.
+//
+// The body of a batch request is a JSON array of BatchMessage. Each message is published and the handler
+// waits for all of the messages to be acknowledged. If all messages are acked then a 200 (OK) is returned.
+// Otherwise a 207 (Multi-Status) is returned with a JSON array of BatchResult (in the same order as the
+// request) which holds the HTTP status of each message, i.e. 200 (acked), 500 (nacked), 504 (not acked within
+// AckTimeout, which applies to the batch as a whole) or 503 (service unavailable). A 400 (Bad Request) is returned
+// if the batch is empty, contains a null message or can't be unmarshalled.
+const BatchContentType = "application/vnd.orb.message-batch+json"
+
+// BatchMessage is a single message within a batch request.
+type BatchMessage struct {
+	UUID     string            `json:"uuid"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Payload  []byte            `json:"payload"`
+}
+
+// BatchResult contains the result of a single message within a batch request.
+type BatchResult struct {
+	UUID   string `json:"uuid"`
+	Status int    `json:"status"`
+}
+
+func isBatchRequest(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+
+	return mediaType == BatchContentType
+}
+
+func (s *Subscriber) handleBatch(w http.ResponseWriter, r *http.Request, actorIRI *url.URL,
+	reqLogger *log.StructuredLog) {
+	messages, err := s.unmarshalBatch(r)
+	if err != nil {
+		reqLogger.Warn("Error reading message batch", log.WithError(err), log.WithResult(log.ResultFailure))
+
+		s.writeInvalidMessage(w, err)
+
+		return
+	}
+
+	if len(messages) == 0 {
+		reqLogger.Warn("Message batch is empty", log.WithResult(log.ResultFailure))
+
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	if err := s.StateError(); err != nil {
+		reqLogger.Info("Message batch wasn't sent", log.WithTotal(len(messages)), log.WithError(err),
+			log.WithResult(log.ResultFailure))
+
+		w.WriteHeader(http.StatusServiceUnavailable)
+
+		return
+	}
+
+	reqLogger.Debug("Handling message batch", log.WithTotal(len(messages)), log.WithActorIRI(actorIRI),
+		log.WithHost(actorIRI))
+
+	results := make([]*BatchResult, len(messages))
+
+	var published []int
+
+	for i, msg := range messages {
+		results[i] = &BatchResult{UUID: msg.UUID}
+
+		if !s.checkMetadataSize(msg, reqLogger) {
+			results[i].Status = http.StatusRequestEntityTooLarge
+
+			continue
+		}
+
+		if actorIRI != nil {
+			msg.Metadata[s.ActorIRIMetadataKey] = actorIRI.String()
+		}
+
+		if err := s.publish(msg); err != nil {
+			reqLogger.Info("Message wasn't sent", log.WithMessageID(msg.UUID), log.WithError(err),
+				log.WithResult(log.ResultFailure))
+
+			results[i].Status = http.StatusServiceUnavailable
+
+			continue
+		}
+
+		published = append(published, i)
+	}
+
+	allAcked := len(published) == len(messages)
+
+	// A single ack deadline applies to the whole batch so that the handler doesn't wait for up to
+	// AckTimeout for each message in turn.
+	ackDeadline, release := s.ackDeadline()
+	defer release()
+
+	for _, i := range published {
+		results[i].Status = s.waitForAck(messages[i], r, ackDeadline, nil)
+
+		if results[i].Status != http.StatusOK {
+			allAcked = false
+		}
+	}
+
+	if allAcked {
+		w.WriteHeader(http.StatusOK)
+
+		return
+	}
+
+	s.writeBatchResults(w, results)
+}
+
+func (s *Subscriber) unmarshalBatch(r *http.Request) ([]*message.Message, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var batch []*BatchMessage
+
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return nil, err
+	}
+
+	messages := make([]*message.Message, len(batch))
+
+	for i, bm := range batch {
+		if bm == nil {
+			return nil, errors.New("batch contains a null message")
+		}
+
+		uuid := bm.UUID
+		if uuid == "" {
+			uuid = watermill.NewUUID()
+		}
+
+		msg := message.NewMessage(uuid, bm.Payload)
+
+		for k, v := range bm.Metadata {
+			msg.Metadata[k] = v
+		}
+
+		messages[i] = msg
+	}
+
+	return messages, nil
+}
+
+func (s *Subscriber) writeBatchResults(w http.ResponseWriter, results []*BatchResult) {
+	respBytes, err := json.Marshal(results)
+	if err != nil {
+		s.logger.Error("Error marshalling batch results", log.WithError(err))
+
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusMultiStatus)
+
+	if _, err := w.Write(respBytes); err != nil {
+		log.WriteResponseBodyError(s.logger, err)
+	}
+}
@@ -0,0 +1,257 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package httpsubscriber
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	apmocks "github.com/trustbloc/orb/pkg/activitypub/mocks"
+	"github.com/trustbloc/orb/pkg/activitypub/service/mocks"
+	"github.com/trustbloc/orb/pkg/internal/testutil"
+)
+
+func TestSubscriber_HandleBatch(t *testing.T) {
+	sigVerifier := &mocks.SignatureVerifier{}
+	sigVerifier.VerifyRequestReturns(true, testutil.MustParseURL(serviceURL), nil)
+
+	tm := &apmocks.AuthTokenMgr{}
+	tm.RequiredAuthTokensReturns([]string{"admin"}, nil)
+
+	batch := []*BatchMessage{
+		{UUID: "msg1", Payload: []byte("payload1"), Metadata: map[string]string{"key": "value1"}},
+		{UUID: "msg2", Payload: []byte("payload2")},
+		{UUID: "msg3", Payload: []byte("payload3")},
+	}
+
+	batchBytes, err := json.Marshal(batch)
+	require.NoError(t, err)
+
+	newBatchRequest := func(body []byte) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+		req.Header.Set("Content-Type", BatchContentType)
+
+		return req
+	}
+
+	t.Run("All acked", func(t *testing.T) {
+		s := New(&Config{ServiceEndpoint: endpoint}, sigVerifier, tm)
+		require.NotNil(t, s)
+
+		defer s.Stop()
+
+		msgChan, err := s.Subscribe(context.Background(), "")
+		require.NoError(t, err)
+
+		received := make(chan string, len(batch))
+
+		go func() {
+			for msg := range msgChan {
+				require.Equal(t, serviceURL, msg.Metadata[ActorIRIKey])
+
+				received <- msg.UUID + ":" + string(msg.Payload) + ":" + msg.Metadata["key"]
+
+				msg.Ack()
+			}
+		}()
+
+		rw := httptest.NewRecorder()
+
+		s.handleMessage(rw, newBatchRequest(batchBytes))
+
+		result := rw.Result()
+		require.Equal(t, http.StatusOK, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+
+		require.Equal(t, "msg1:payload1:value1", <-received)
+		require.Equal(t, "msg2:payload2:", <-received)
+		require.Equal(t, "msg3:payload3:", <-received)
+	})
+
+	t.Run("Partial failure", func(t *testing.T) {
+		s := New(&Config{ServiceEndpoint: endpoint}, sigVerifier, tm)
+		require.NotNil(t, s)
+
+		defer s.Stop()
+
+		msgChan, err := s.Subscribe(context.Background(), "")
+		require.NoError(t, err)
+
+		go func() {
+			for msg := range msgChan {
+				if msg.UUID == "msg2" {
+					msg.Nack()
+				} else {
+					msg.Ack()
+				}
+			}
+		}()
+
+		rw := httptest.NewRecorder()
+
+		s.handleMessage(rw, newBatchRequest(batchBytes))
+
+		result := rw.Result()
+		require.Equal(t, http.StatusMultiStatus, result.StatusCode)
+
+		respBytes, err := ioutil.ReadAll(result.Body)
+		require.NoError(t, err)
+		require.NoError(t, result.Body.Close())
+
+		var results []*BatchResult
+		require.NoError(t, json.Unmarshal(respBytes, &results))
+		require.Len(t, results, 3)
+		require.Equal(t, &BatchResult{UUID: "msg1", Status: http.StatusOK}, results[0])
+		require.Equal(t, &BatchResult{UUID: "msg2", Status: http.StatusInternalServerError}, results[1])
+		require.Equal(t, &BatchResult{UUID: "msg3", Status: http.StatusOK}, results[2])
+	})
+
+	t.Run("Invalid batch", func(t *testing.T) {
+		s := New(&Config{ServiceEndpoint: endpoint}, sigVerifier, tm)
+		require.NotNil(t, s)
+
+		defer s.Stop()
+
+		rw := httptest.NewRecorder()
+
+		s.handleMessage(rw, newBatchRequest([]byte("{")))
+
+		result := rw.Result()
+		require.Equal(t, http.StatusBadRequest, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+	})
+
+	t.Run("Null message", func(t *testing.T) {
+		s := New(&Config{ServiceEndpoint: endpoint}, sigVerifier, tm)
+		require.NotNil(t, s)
+
+		defer s.Stop()
+
+		for _, body := range []string{`[null]`, `[{"uuid":"msg1"},null]`} {
+			rw := httptest.NewRecorder()
+
+			s.handleMessage(rw, newBatchRequest([]byte(body)))
+
+			result := rw.Result()
+			require.Equal(t, http.StatusBadRequest, result.StatusCode, body)
+			require.NoError(t, result.Body.Close())
+		}
+	})
+
+	t.Run("Empty batch", func(t *testing.T) {
+		s := New(&Config{ServiceEndpoint: endpoint}, sigVerifier, tm)
+		require.NotNil(t, s)
+
+		defer s.Stop()
+
+		rw := httptest.NewRecorder()
+
+		s.handleMessage(rw, newBatchRequest([]byte("[]")))
+
+		result := rw.Result()
+		require.Equal(t, http.StatusBadRequest, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+	})
+
+	t.Run("Ack timeout -> applies to the whole batch", func(t *testing.T) {
+		const ackTimeout = 200 * time.Millisecond
+
+		s := New(&Config{ServiceEndpoint: endpoint, AckTimeout: ackTimeout}, sigVerifier, tm)
+		require.NotNil(t, s)
+
+		defer s.Stop()
+
+		msgChan, err := s.Subscribe(context.Background(), "")
+		require.NoError(t, err)
+
+		go func() {
+			for range msgChan {
+				// Never ack or nack the message.
+			}
+		}()
+
+		rw := httptest.NewRecorder()
+
+		start := time.Now()
+
+		s.handleMessage(rw, newBatchRequest(batchBytes))
+
+		// The handler doesn't wait for up to the ack timeout for each message in turn.
+		require.Less(t, time.Since(start), time.Duration(len(batch))*ackTimeout)
+
+		result := rw.Result()
+		require.Equal(t, http.StatusMultiStatus, result.StatusCode)
+
+		respBytes, err := ioutil.ReadAll(result.Body)
+		require.NoError(t, err)
+		require.NoError(t, result.Body.Close())
+
+		var results []*BatchResult
+		require.NoError(t, json.Unmarshal(respBytes, &results))
+		require.Len(t, results, 3)
+
+		for _, r := range results {
+			require.Equal(t, http.StatusGatewayTimeout, r.Status)
+		}
+	})
+
+	t.Run("Service stopped", func(t *testing.T) {
+		s := New(&Config{ServiceEndpoint: endpoint}, sigVerifier, tm)
+		require.NotNil(t, s)
+
+		s.Stop()
+
+		rw := httptest.NewRecorder()
+
+		s.handleMessage(rw, newBatchRequest(batchBytes))
+
+		result := rw.Result()
+		require.Equal(t, http.StatusServiceUnavailable, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+	})
+	t.Run("Metadata too large", func(t *testing.T) {
+		s := New(&Config{ServiceEndpoint: endpoint, MaxMetadataSize: 8}, sigVerifier, tm)
+		require.NotNil(t, s)
+
+		defer s.Stop()
+
+		msgChan, err := s.Subscribe(context.Background(), "")
+		require.NoError(t, err)
+
+		go func() {
+			for msg := range msgChan {
+				msg.Ack()
+			}
+		}()
+
+		rw := httptest.NewRecorder()
+
+		s.handleMessage(rw, newBatchRequest(batchBytes))
+
+		result := rw.Result()
+		require.Equal(t, http.StatusMultiStatus, result.StatusCode)
+
+		respBytes, err := ioutil.ReadAll(result.Body)
+		require.NoError(t, err)
+		require.NoError(t, result.Body.Close())
+
+		var results []*BatchResult
+		require.NoError(t, json.Unmarshal(respBytes, &results))
+		require.Len(t, results, 3)
+		require.Equal(t, &BatchResult{UUID: "msg1", Status: http.StatusRequestEntityTooLarge}, results[0])
+		require.Equal(t, &BatchResult{UUID: "msg2", Status: http.StatusOK}, results[1])
+		require.Equal(t, &BatchResult{UUID: "msg3", Status: http.StatusOK}, results[2])
+	})
+}
@@ -8,8 +8,12 @@ package httpsubscriber
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/url"
+	"sort"
+	"sync"
+	"time"
 
 	wmhttp "github.com/ThreeDotsLabs/watermill-http/pkg/http"
 	"github.com/ThreeDotsLabs/watermill/message"
@@ -24,7 +28,12 @@ const (
 	// ActorIRIKey is the metadata key for the actor IRI.
 	ActorIRIKey = "actor-iri"
 
-	defaultBufferSize = 100
+	defaultBufferSize      = 100
+	defaultDrainTimeout    = 5 * time.Second
+	defaultAsyncAckTimeout = time.Minute
+
+	invalidMessageResponse = "invalid message format"
+	maxErrorResponseLength = 256
 
 	loggerModule = "activitypub_service"
 )
@@ -33,6 +42,65 @@ const (
 type Config struct {
 	ServiceEndpoint string
 	BufferSize      int
+
+	// ActorIRIMetadataKey is the message metadata key under which the verified actor IRI is stored.
+	// If not set then ActorIRIKey is used.
+	ActorIRIMetadataKey string
+
+	// RequireActor indicates that a request must establish an authenticated actor (via HTTP signature).
+	// If true then a request that is authorized without an actor (e.g. no authorization tokens are
+	// required or a bearer token was provided) is rejected with a 401 (Unauthorized).
+	RequireActor bool
+
+	// AckTimeout is the maximum time to wait for a message to be acknowledged. If the timeout is exceeded
+	// then a 504 (Gateway Timeout) is returned. If not set then the handler waits until the message is
+	// acknowledged or the request context is done.
+	AckTimeout time.Duration
+
+	// RequestTimeout is a server-side deadline for handling a request, which guarantees that the handler releases
+	// its resources even if the client never times out. If the request context already has an earlier deadline
+	// then that deadline applies. If not set then only the request context's deadline (if any) applies.
+	RequestTimeout time.Duration
+
+	// DrainTimeout is the maximum time to wait, when the subscriber is stopped, for buffered messages
+	// to be delivered to the subscriber before the message channel is closed. The request for a message
+	// that's delivered while draining continues to wait for the message to be acknowledged. Messages that
+	// can't be delivered within the timeout are dropped and a 503 (Service Unavailable) is returned for
+	// them. Default is 5s.
+	DrainTimeout time.Duration
+
+	// DebugErrors indicates that the parser error should be included in the response body when a
+	// message can't be unmarshalled. By default, only a generic error description is returned.
+	DebugErrors bool
+
+	// MaxMetadataSize is the maximum total size (in bytes) of the message metadata keys and values. A message
+	// whose metadata exceeds this size is rejected with a 413 (Request Entity Too Large) unless TruncateMetadata
+	// is set. If zero then the metadata size isn't limited.
+	MaxMetadataSize int
+
+	// TruncateMetadata indicates that, rather than rejecting a message whose metadata exceeds MaxMetadataSize,
+	// metadata entries (in key order) that don't fit within the limit are dropped.
+	TruncateMetadata bool
+
+	// AsyncAck indicates that a 202 (Accepted) should be returned as soon as a message is published rather
+	// than waiting for the message to be acknowledged. The outcome of the message is logged but isn't reported
+	// to the sender. The outcome is waited for until AckTimeout (or 1m if AckTimeout isn't set) or until the
+	// subscriber is stopped. Batch requests always wait for the messages to be acknowledged.
+	AsyncAck bool
+
+	// OverflowBufferSize is the size of a secondary buffer that absorbs short spikes in which messages arrive
+	// faster than the buffer (see BufferSize) can accept them. If the overflow buffer is also full then the new
+	// message is handled according to OverflowPolicy, so sustained overload still applies backpressure. If zero
+	// (the default) then there's no overflow buffer and a request blocks while the buffer is full.
+	OverflowBufferSize int
+
+	// OverflowPolicy determines how a new message is handled when both the buffer and the overflow buffer are
+	// full (reject-new or drop-oldest). Default is reject-new.
+	OverflowPolicy OverflowPolicy
+
+	// AccessLog indicates that an access-log line (containing the HTTP method, request URL, status, duration,
+	// authenticated actor and sender) should be logged at info level for each request.
+	AccessLog bool
 }
 
 type signatureVerifier interface {
@@ -49,21 +117,51 @@ type Subscriber struct {
 	*Config
 
 	pubChan          chan *message.Message
+	overflow         *overflowBuffer
 	msgChan          chan *message.Message
+	undelivered      sync.Map
+	publishMutex     sync.RWMutex
 	stopped          chan struct{}
 	done             chan struct{}
 	unmarshalMessage wmhttp.UnmarshalMessageFunc
 	verifier         signatureVerifier
-	tokenVerifier    *auth.TokenVerifier
+	tokenManager     authTokenManager
+	tokenVerifiers   map[string]*auth.TokenVerifier
+	tokenMutex       sync.RWMutex
 	logger           *log.StructuredLog
 }
 
 // New returns a new HTTP subscriber.
 func New(cfg *Config, sigVerifier signatureVerifier, tm authTokenManager) *Subscriber {
+	// Include stack traces in error logs since these are unexpected (e.g. internal server errors).
+	logger := log.NewStructured(loggerModule, log.WithFields(log.WithServiceName(cfg.ServiceEndpoint)), log.WithStack())
+
+	return newSubscriber(cfg, sigVerifier, tm, logger)
+}
+
+func newSubscriber(cfg *Config, sigVerifier signatureVerifier, tm authTokenManager,
+	logger *log.StructuredLog,
+) *Subscriber {
 	if cfg.BufferSize == 0 {
 		cfg.BufferSize = defaultBufferSize
 	}
 
+	if cfg.DrainTimeout == 0 {
+		cfg.DrainTimeout = defaultDrainTimeout
+	}
+
+	if cfg.ActorIRIMetadataKey == "" {
+		cfg.ActorIRIMetadataKey = ActorIRIKey
+	}
+
+	switch cfg.OverflowPolicy {
+	case "":
+		cfg.OverflowPolicy = OverflowPolicyRejectNew
+	case OverflowPolicyRejectNew, OverflowPolicyDropOldest:
+	default:
+		panic(fmt.Sprintf("unsupported overflow policy: %s", cfg.OverflowPolicy))
+	}
+
 	s := &Subscriber{
 		Config:           cfg,
 		unmarshalMessage: wmhttp.DefaultUnmarshalMessageFunc,
@@ -72,8 +170,18 @@ func New(cfg *Config, sigVerifier signatureVerifier, tm authTokenManager) *Subsc
 		msgChan:          make(chan *message.Message, cfg.BufferSize),
 		stopped:          make(chan struct{}),
 		done:             make(chan struct{}),
-		tokenVerifier:    auth.NewTokenVerifier(tm, cfg.ServiceEndpoint, http.MethodPost),
-		logger:           log.NewStructured(loggerModule, log.WithFields(log.WithServiceName(cfg.ServiceEndpoint))),
+		tokenManager:     tm,
+		logger:           logger,
+	}
+
+	if cfg.OverflowBufferSize > 0 {
+		s.overflow = newOverflowBuffer(s.pubChan, cfg.OverflowBufferSize, cfg.OverflowPolicy)
+	}
+
+	// Create the token verifier for the subscriber's method up front so that a configuration error is
+	// detected on startup. Token verifiers for other methods are created on demand.
+	s.tokenVerifiers = map[string]*auth.TokenVerifier{
+		s.Method(): auth.NewTokenVerifier(tm, cfg.ServiceEndpoint, s.Method()),
 	}
 
 	s.Lifecycle = lifecycle.New("httpsubscriber-"+cfg.ServiceEndpoint,
@@ -120,13 +228,41 @@ func (s *Subscriber) Handler() common.HTTPRequestHandler {
 func (s *Subscriber) handleMessage(w http.ResponseWriter, r *http.Request) {
 	var actorIRI *url.URL
 
-	if !s.tokenVerifier.Verify(r) {
-		s.logger.Debug("Request was not verified using authorization bearer tokens. Verifying request via HTTP signature",
-			log.WithSenderURL(r.URL))
+	if s.RequestTimeout > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), s.RequestTimeout)
+		defer cancel()
+
+		r = r.WithContext(ctx)
+	}
+
+	if s.AccessLog {
+		start := time.Now()
+
+		aw := &accessLogResponseWriter{ResponseWriter: w}
+		w = aw
+
+		defer func() {
+			s.logAccess(r, aw.statusCode(), actorIRI, time.Since(start))
+		}()
+	}
+
+	reqLogger := s.logger.With(log.WithSenderURL(r.URL))
+
+	tokenVerifier, err := s.tokenVerifier(r.Method)
+	if err != nil {
+		reqLogger.Error("Error resolving authorization tokens", log.WithError(err), log.WithResult(log.ResultFailure))
+
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	if !tokenVerifier.Verify(r) {
+		reqLogger.Debug("Request was not verified using authorization bearer tokens. Verifying request via HTTP signature")
 
 		verified, actor, err := s.verifier.VerifyRequest(r)
 		if err != nil {
-			s.logger.Error("Error verifying HTTP signature", log.WithError(err), log.WithSenderURL(r.URL))
+			reqLogger.Error("Error verifying HTTP signature", log.WithError(err), log.WithResult(log.ResultFailure))
 
 			w.WriteHeader(http.StatusInternalServerError)
 
@@ -134,7 +270,7 @@ func (s *Subscriber) handleMessage(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if !verified {
-			s.logger.Info("Invalid HTTP signature", log.WithSenderURL(r.URL))
+			reqLogger.Info("Invalid HTTP signature", log.WithResult(log.ResultFailure))
 
 			w.WriteHeader(http.StatusUnauthorized)
 
@@ -143,27 +279,50 @@ func (s *Subscriber) handleMessage(w http.ResponseWriter, r *http.Request) {
 
 		actorIRI = actor
 	} else {
-		s.logger.Debug("Request was verified with a bearer token or no authorization was required.", log.WithSenderURL(r.URL))
+		reqLogger.Debug("Request was verified with a bearer token or no authorization was required.")
+	}
+
+	if actorIRI == nil && s.RequireActor {
+		reqLogger.Info("Request was rejected since no authenticated actor was established",
+			log.WithResult(log.ResultFailure))
+
+		w.WriteHeader(http.StatusUnauthorized)
+
+		return
+	}
+
+	if isBatchRequest(r) {
+		s.handleBatch(w, r, actorIRI, reqLogger)
+
+		return
 	}
 
 	msg, err := s.unmarshalMessage("", r)
 	if err != nil {
-		s.logger.Warn("Error reading message", log.WithError(err), log.WithSenderURL(r.URL))
+		reqLogger.Warn("Error reading message", log.WithError(err), log.WithResult(log.ResultFailure))
 
-		w.WriteHeader(http.StatusBadRequest)
+		s.writeInvalidMessage(w, err)
+
+		return
+	}
+
+	if !s.checkMetadataSize(msg, reqLogger) {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
 
 		return
 	}
 
 	if actorIRI != nil {
-		msg.Metadata[ActorIRIKey] = actorIRI.String()
+		msg.Metadata[s.ActorIRIMetadataKey] = actorIRI.String()
 	}
 
-	s.logger.Debug("Handling message", log.WithMessageID(msg.UUID), log.WithActorIRI(actorIRI), log.WithSenderURL(r.URL))
+	reqLogger.Debug("Handling message", log.WithMessageID(msg.UUID), log.WithActorIRI(actorIRI),
+		log.WithHost(actorIRI))
 
 	err = s.publish(msg)
 	if err != nil {
-		s.logger.Info("Message wasn't sent", log.WithMessageID(msg.UUID), log.WithError(err), log.WithSenderURL(r.URL))
+		reqLogger.Info("Message wasn't sent", log.WithMessageID(msg.UUID), log.WithError(err),
+			log.WithResult(log.ResultFailure))
 
 		w.WriteHeader(http.StatusServiceUnavailable)
 
@@ -173,12 +332,159 @@ func (s *Subscriber) handleMessage(w http.ResponseWriter, r *http.Request) {
 	s.respond(msg, w, r)
 }
 
+func (s *Subscriber) logAccess(r *http.Request, status int, actorIRI *url.URL, duration time.Duration) {
+	s.logger.Info("Handled HTTP request", log.WithHTTPMethod(r.Method), log.WithRequestURL(r.URL),
+		log.WithHTTPStatus(status), log.WithDuration(duration), log.WithActorIRI(actorIRI),
+		log.WithSenderURL(r.URL))
+}
+
+// accessLogResponseWriter records the status code that's written to the response.
+type accessLogResponseWriter struct {
+	http.ResponseWriter
+
+	status int
+}
+
+func (w *accessLogResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessLogResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+// statusCode returns the status code that was written. If no status code was written then 200 (OK) is
+// returned since that's the status that's sent by the HTTP server.
+func (w *accessLogResponseWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+
+	return w.status
+}
+
+// tokenVerifier returns the token verifier for the given HTTP method, since the authorization tokens that
+// are required for a request may differ per method.
+func (s *Subscriber) tokenVerifier(method string) (*auth.TokenVerifier, error) {
+	s.tokenMutex.RLock()
+	v, ok := s.tokenVerifiers[method]
+	s.tokenMutex.RUnlock()
+
+	if ok {
+		return v, nil
+	}
+
+	// Resolve the tokens first since NewTokenVerifier panics on error.
+	if _, err := s.tokenManager.RequiredAuthTokens(s.ServiceEndpoint, method); err != nil {
+		return nil, fmt.Errorf("resolve authorization tokens for method [%s]: %w", method, err)
+	}
+
+	s.tokenMutex.Lock()
+	defer s.tokenMutex.Unlock()
+
+	v, ok = s.tokenVerifiers[method]
+	if !ok {
+		v = auth.NewTokenVerifier(s.tokenManager, s.ServiceEndpoint, method)
+
+		s.tokenVerifiers[method] = v
+	}
+
+	return v, nil
+}
+
+// checkMetadataSize returns false if the size of the message metadata exceeds the maximum size and the message
+// should be rejected. If TruncateMetadata is set then entries are dropped until the metadata is within the limit.
+func (s *Subscriber) checkMetadataSize(msg *message.Message, reqLogger *log.StructuredLog) bool {
+	if s.MaxMetadataSize <= 0 {
+		return true
+	}
+
+	size := 0
+
+	for k, v := range msg.Metadata {
+		size += len(k) + len(v)
+	}
+
+	if size <= s.MaxMetadataSize {
+		return true
+	}
+
+	if !s.TruncateMetadata {
+		reqLogger.Warn("Message was rejected since the metadata exceeds the maximum size",
+			log.WithMessageID(msg.UUID), log.WithSize(size), log.WithMaxSize(s.MaxMetadataSize),
+			log.WithResult(log.ResultFailure))
+
+		return false
+	}
+
+	keys := make([]string, 0, len(msg.Metadata))
+
+	for k := range msg.Metadata {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	size = 0
+
+	for _, k := range keys {
+		entrySize := len(k) + len(msg.Metadata[k])
+
+		if size+entrySize > s.MaxMetadataSize {
+			reqLogger.Warn("Metadata entry was dropped since the metadata exceeds the maximum size",
+				log.WithMessageID(msg.UUID), log.WithProperty(k), log.WithMaxSize(s.MaxMetadataSize))
+
+			delete(msg.Metadata, k)
+
+			continue
+		}
+
+		size += entrySize
+	}
+
+	return true
+}
+
 func (s *Subscriber) publish(msg *message.Message) error {
-	if s.State() != lifecycle.StateStarted {
-		return lifecycle.ErrNotStarted
+	// The read lock is held while the state is checked and the message is posted to the publish buffer so that
+	// stop can't drop the undelivered messages in between, which would leave this message waiting forever.
+	s.publishMutex.RLock()
+	defer s.publishMutex.RUnlock()
+
+	if err := s.StateError(); err != nil {
+		return err
 	}
 
-	s.pubChan <- msg
+	if s.overflow == nil {
+		select {
+		case s.pubChan <- msg:
+		case <-s.stopped:
+			return lifecycle.ErrStopping
+		}
+	} else {
+		dropped, err := s.overflow.push(msg)
+		if err != nil {
+			return err
+		}
+
+		if dropped != nil {
+			s.logger.Warn("Overflow buffer is full. The oldest buffered message was dropped.",
+				log.WithMessageID(dropped.UUID), log.WithMaxSize(s.OverflowBufferSize))
+
+			// The dropped message was never delivered, so a 503 (Service Unavailable) is returned to its sender.
+			s.undelivered.Store(dropped, struct{}{})
+
+			dropped.Nack()
+		}
+	}
 
 	s.logger.Debug("Message was posted to publisher", log.WithMessageID(msg.UUID))
 
@@ -186,47 +492,221 @@ func (s *Subscriber) publish(msg *message.Message) error {
 }
 
 func (s *Subscriber) publisher() {
+	// The done channel is closed even if the publisher panics so that stop doesn't block.
+	defer close(s.done)
+	defer log.RecoverAndLog(s.logger)
+
 	s.logger.Info("Starting publisher.")
 
 	for {
 		select {
 		case msg := <-s.pubChan:
-			s.msgChan <- msg
+			// Make room in the publish buffer for any messages that are waiting in the overflow buffer.
+			s.overflow.refill()
+
+			select {
+			case s.msgChan <- msg:
+				s.logger.Debug("Message was delivered to subscriber", log.WithMessageID(msg.UUID))
 
-			s.logger.Debug("Message was delivered to subscriber", log.WithMessageID(msg.UUID))
+			case <-s.stopped:
+				s.stopPublisher(msg)
+
+				return
+			}
 
 		case <-s.stopped:
-			s.logger.Info("Stopping publisher.")
+			s.stopPublisher()
+
+			return
+		}
+	}
+}
+
+func (s *Subscriber) stopPublisher(pending ...*message.Message) {
+	s.logger.Info("Stopping publisher.")
+
+	s.drain(pending...)
+}
+
+// drain delivers the given pending messages and any messages remaining in the publish buffer to the
+// subscriber so that they aren't dropped on shutdown. Messages that can't be delivered within the
+// drain timeout are dropped (see dropUndelivered).
+func (s *Subscriber) drain(pending ...*message.Message) {
+	timer := time.NewTimer(s.DrainTimeout)
+	defer timer.Stop()
+
+	for {
+		var msg *message.Message
+
+		if len(pending) > 0 {
+			msg, pending = pending[0], pending[1:]
+		} else {
+			select {
+			case msg = <-s.pubChan:
+			default:
+				if msg = s.overflow.pop(); msg == nil {
+					return
+				}
+			}
+		}
 
-			close(s.done)
+		select {
+		case s.msgChan <- msg:
+			s.logger.Debug("Buffered message was delivered to subscriber", log.WithMessageID(msg.UUID))
+
+		case <-timer.C:
+			s.logger.Warn("Timed out draining buffered messages. Remaining messages were dropped.",
+				log.WithTotal(len(pending)+len(s.pubChan)+s.overflow.len()+1), log.WithTimeout(s.DrainTimeout))
+
+			s.dropUndelivered(append(pending, msg)...)
 
 			return
 		}
 	}
 }
 
+// dropUndelivered drops the given messages and any messages remaining in the publish and overflow buffers.
+// A dropped message is nacked (so that the request that's waiting for it is released) and recorded as
+// undelivered so that a 503 (Service Unavailable) is returned to the sender, who may retry later. Since the
+// message was never delivered to the subscriber, it isn't processed twice.
+func (s *Subscriber) dropUndelivered(pending ...*message.Message) {
+	drop := func(msg *message.Message) {
+		s.undelivered.Store(msg, struct{}{})
+
+		msg.Nack()
+	}
+
+	for _, msg := range pending {
+		drop(msg)
+	}
+
+	for {
+		select {
+		case msg := <-s.pubChan:
+			drop(msg)
+		default:
+			msg := s.overflow.pop()
+			if msg == nil {
+				return
+			}
+
+			drop(msg)
+		}
+	}
+}
+
 func (s *Subscriber) respond(msg *message.Message, w http.ResponseWriter, r *http.Request) {
+	if s.AsyncAck {
+		s.logger.Debug("Message was accepted for asynchronous processing", log.WithMessageID(msg.UUID))
+
+		w.WriteHeader(http.StatusAccepted)
+
+		go s.waitForAsyncAck(msg, r)
+
+		return
+	}
+
+	ackDeadline, release := s.ackDeadline()
+	defer release()
+
+	w.WriteHeader(s.waitForAck(msg, r, ackDeadline, nil))
+}
+
+// waitForAsyncAck waits for an asynchronously accepted message to be acknowledged in order to log the outcome.
+// The request context is cancelled once the response is written, so a context that's not tied to the request
+// is used. The wait is bounded by a timeout and ends when the subscriber is stopped so that it doesn't leak.
+func (s *Subscriber) waitForAsyncAck(msg *message.Message, r *http.Request) {
+	ctx := context.Background()
+
+	if s.AckTimeout <= 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, defaultAsyncAckTimeout)
+		defer cancel()
+	}
+
+	ackDeadline, release := s.ackDeadline()
+	defer release()
+
+	s.waitForAck(msg, r.WithContext(ctx), ackDeadline, s.stopped)
+}
+
+// ackDeadline returns a channel that's closed once AckTimeout elapses (or nil if AckTimeout isn't set) along
+// with a function that releases the timer. The channel may be shared by multiple waits, e.g. for a batch.
+func (s *Subscriber) ackDeadline() (<-chan struct{}, func()) {
+	if s.AckTimeout <= 0 {
+		return nil, func() {}
+	}
+
+	deadline := make(chan struct{})
+
+	timer := time.AfterFunc(s.AckTimeout, func() { close(deadline) })
+
+	return deadline, func() { timer.Stop() }
+}
+
+// waitForAck waits for the given message to be acknowledged and returns the corresponding HTTP status code.
+// A 504 (Gateway Timeout) is returned if the given ack deadline is closed first. If the given stopped channel
+// isn't nil then the wait also ends when the channel is closed.
+func (s *Subscriber) waitForAck(msg *message.Message, r *http.Request, ackDeadline <-chan struct{},
+	stopped <-chan struct{},
+) int {
 	select {
 	case <-msg.Acked():
-		s.logger.Debug("Ack received for message", log.WithMessageID(msg.UUID))
+		s.logger.Debug("Ack received for message", log.WithMessageID(msg.UUID), log.WithResult(log.ResultSuccess))
 
-		w.WriteHeader(http.StatusOK)
+		return http.StatusOK
 
 	case <-msg.Nacked():
-		s.logger.Warn("Nack received for message", log.WithMessageID(msg.UUID))
+		if _, ok := s.undelivered.LoadAndDelete(msg); ok {
+			s.logger.Info("Message was not handled since service was stopped", log.WithMessageID(msg.UUID),
+				log.WithResult(log.ResultFailure))
 
-		w.WriteHeader(http.StatusInternalServerError)
+			return http.StatusServiceUnavailable
+		}
+
+		s.logger.Warn("Nack received for message", log.WithMessageID(msg.UUID), log.WithResult(log.ResultFailure))
+
+		return http.StatusInternalServerError
 
 	case <-r.Context().Done():
 		s.logger.Info("Timed out waiting for ack or nack for message",
-			log.WithMessageID(msg.UUID), log.WithError(r.Context().Err()))
+			log.WithMessageID(msg.UUID), log.WithError(r.Context().Err()), log.WithResult(log.ResultFailure))
 
-		w.WriteHeader(http.StatusInternalServerError)
+		return http.StatusInternalServerError
 
-	case <-s.stopped:
-		s.logger.Info("Message was not handled since service was stopped", log.WithMessageID(msg.UUID))
+	case <-ackDeadline:
+		s.logger.Warn("Timed out waiting for ack or nack for message",
+			log.WithMessageID(msg.UUID), log.WithTimeout(s.AckTimeout), log.WithResult(log.ResultFailure))
 
-		w.WriteHeader(http.StatusServiceUnavailable)
+		return http.StatusGatewayTimeout
+
+	case <-stopped:
+		s.logger.Info("Stopped waiting for ack or nack for message since service was stopped",
+			log.WithMessageID(msg.UUID), log.WithResult(log.ResultFailure))
+
+		return http.StatusServiceUnavailable
+	}
+}
+
+// writeInvalidMessage writes a 400 (Bad Request) with a generic error description. If DebugErrors is set
+// then the (truncated) parser error is also included. The request content is never echoed back.
+func (s *Subscriber) writeInvalidMessage(w http.ResponseWriter, err error) {
+	body := invalidMessageResponse
+
+	if s.DebugErrors {
+		body = fmt.Sprintf("%s: %s", invalidMessageResponse, err)
+
+		if len(body) > maxErrorResponseLength {
+			body = body[:maxErrorResponseLength]
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusBadRequest)
+
+	if _, err := w.Write([]byte(body)); err != nil {
+		log.WriteResponseBodyError(s.logger, err)
 	}
 }
 
@@ -239,6 +719,12 @@ func (s *Subscriber) stop() {
 	// while we're trying to publish a message to it (which would result in a panic).
 	<-s.done
 
+	// Drop any messages that were posted to the publish buffer after the publisher stopped. The write lock
+	// waits for in-flight publishes to complete and any subsequent publish fails since the state is 'stopping'.
+	s.publishMutex.Lock()
+	s.dropUndelivered()
+	s.publishMutex.Unlock()
+
 	close(s.msgChan)
 
 	s.logger.Info("... HTTP subscriber stopped.")
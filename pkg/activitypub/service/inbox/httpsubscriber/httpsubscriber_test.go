@@ -10,15 +10,20 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	wmhttp "github.com/ThreeDotsLabs/watermill-http/pkg/http"
+	"github.com/ThreeDotsLabs/watermill/message"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
 
+	"github.com/trustbloc/orb/internal/pkg/log"
 	apmocks "github.com/trustbloc/orb/pkg/activitypub/mocks"
 	"github.com/trustbloc/orb/pkg/activitypub/service/mocks"
 	"github.com/trustbloc/orb/pkg/internal/testutil"
@@ -145,33 +150,265 @@ func TestSubscriber_HandleRequestTimeout(t *testing.T) {
 	require.NoError(t, result.Body.Close())
 }
 
-func TestSubscriber_UnmarshalError(t *testing.T) {
+func TestSubscriber_HandleAckTimeout(t *testing.T) {
 	sigVerifier := &mocks.SignatureVerifier{}
 	sigVerifier.VerifyRequestReturns(true, testutil.MustParseURL(serviceURL), nil)
 
 	tm := &apmocks.AuthTokenMgr{}
 	tm.RequiredAuthTokensReturns([]string{"admin"}, nil)
 
-	s := New(&Config{ServiceEndpoint: endpoint}, sigVerifier, tm)
+	s := New(&Config{ServiceEndpoint: endpoint, AckTimeout: 10 * time.Millisecond}, sigVerifier, tm)
 	require.NotNil(t, s)
 
 	defer s.Stop()
 
 	msgChan, err := s.Subscribe(context.Background(), "")
 	require.NoError(t, err)
-	require.NotNil(t, msgChan)
+
+	go func() {
+		for range msgChan {
+			// Never ack or nack the message.
+		}
+	}()
 
 	rw := httptest.NewRecorder()
-	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(nil))
+	req := httptest.NewRequest(http.MethodPost, endpoint, bytes.NewReader([]byte("data")))
+
+	s.handleMessage(rw, req)
+
+	result := rw.Result()
+	require.Equal(t, http.StatusGatewayTimeout, result.StatusCode)
+	require.NoError(t, result.Body.Close())
+}
+
+func TestSubscriber_HandleServerRequestTimeout(t *testing.T) {
+	sigVerifier := &mocks.SignatureVerifier{}
+	sigVerifier.VerifyRequestReturns(true, testutil.MustParseURL(serviceURL), nil)
+
+	tm := &apmocks.AuthTokenMgr{}
+	tm.RequiredAuthTokensReturns([]string{"admin"}, nil)
+
+	newSubscriber := func(t *testing.T, requestTimeout time.Duration) *Subscriber {
+		t.Helper()
+
+		s := New(&Config{ServiceEndpoint: endpoint, RequestTimeout: requestTimeout}, sigVerifier, tm)
+		require.NotNil(t, s)
+
+		msgChan, err := s.Subscribe(context.Background(), "")
+		require.NoError(t, err)
+
+		go func() {
+			for range msgChan {
+				// Never ack or nack the message.
+			}
+		}()
+
+		return s
+	}
+
+	t.Run("No client deadline -> server timeout applies", func(t *testing.T) {
+		s := newSubscriber(t, 50*time.Millisecond)
+		defer s.Stop()
+
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, endpoint, bytes.NewReader([]byte("data")))
+
+		_, hasDeadline := req.Context().Deadline()
+		require.False(t, hasDeadline)
+
+		done := make(chan struct{})
+
+		go func() {
+			defer close(done)
+
+			s.handleMessage(rw, req)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			require.FailNow(t, "timed out waiting for handler to return")
+		}
+
+		result := rw.Result()
+		require.Equal(t, http.StatusInternalServerError, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+	})
+
+	t.Run("Client deadline earlier than server timeout -> client deadline applies", func(t *testing.T) {
+		s := newSubscriber(t, time.Minute)
+		defer s.Stop()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, endpoint, bytes.NewReader([]byte("data"))).WithContext(ctx)
+
+		start := time.Now()
+
+		s.handleMessage(rw, req)
+
+		require.Less(t, time.Since(start), time.Second)
+
+		result := rw.Result()
+		require.Equal(t, http.StatusInternalServerError, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+	})
+}
+
+func TestSubscriber_AsyncAck(t *testing.T) {
+	sigVerifier := &mocks.SignatureVerifier{}
+	sigVerifier.VerifyRequestReturns(true, testutil.MustParseURL(serviceURL), nil)
+
+	tm := &apmocks.AuthTokenMgr{}
+	tm.RequiredAuthTokensReturns([]string{"admin"}, nil)
+
+	s := New(&Config{ServiceEndpoint: endpoint, AsyncAck: true}, sigVerifier, tm)
+	require.NotNil(t, s)
+
+	defer s.Stop()
+
+	msgChan, err := s.Subscribe(context.Background(), "")
 	require.NoError(t, err)
 
-	req.Header.Add(wmhttp.HeaderMetadata, "{invalid")
+	received := make(chan *message.Message, 1)
 
-	s.handleMessage(rw, req)
+	go func() {
+		for msg := range msgChan {
+			// Don't ack the message until the response has been written.
+			received <- msg
+		}
+	}()
+
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, endpoint, bytes.NewReader([]byte("data")))
+
+	done := make(chan struct{})
+
+	go func() {
+		s.handleMessage(rw, req)
+
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expecting handler to return without waiting for an ack")
+	}
 
 	result := rw.Result()
-	require.Equal(t, http.StatusBadRequest, result.StatusCode)
+	require.Equal(t, http.StatusAccepted, result.StatusCode)
 	require.NoError(t, result.Body.Close())
+
+	select {
+	case msg := <-received:
+		msg.Ack()
+	case <-time.After(time.Second):
+		t.Fatal("expecting message to be delivered to subscriber")
+	}
+}
+
+func TestSubscriber_AsyncAckWait(t *testing.T) {
+	// waitAsync waits in the background for the given message (which is never acked or nacked) and
+	// returns a channel that's closed once the wait ends.
+	waitAsync := func(s *Subscriber, msg *message.Message) <-chan struct{} {
+		done := make(chan struct{})
+
+		go func() {
+			defer close(done)
+
+			s.waitForAsyncAck(msg, httptest.NewRequest(http.MethodPost, endpoint, nil))
+		}()
+
+		return done
+	}
+
+	t.Run("Ack timeout -> wait ends", func(t *testing.T) {
+		s := New(&Config{ServiceEndpoint: endpoint, AsyncAck: true, AckTimeout: 10 * time.Millisecond},
+			&mocks.SignatureVerifier{}, &apmocks.AuthTokenMgr{})
+		defer s.Stop()
+
+		select {
+		case <-waitAsync(s, message.NewMessage("msg1", nil)):
+		case <-time.After(time.Second):
+			require.FailNow(t, "expecting wait to end after the ack timeout")
+		}
+	})
+
+	t.Run("Subscriber stopped -> wait ends", func(t *testing.T) {
+		s := New(&Config{ServiceEndpoint: endpoint, AsyncAck: true}, &mocks.SignatureVerifier{},
+			&apmocks.AuthTokenMgr{})
+
+		done := waitAsync(s, message.NewMessage("msg1", nil))
+
+		select {
+		case <-done:
+			require.FailNow(t, "unexpected end of wait")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		s.Stop()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			require.FailNow(t, "expecting wait to end when the subscriber is stopped")
+		}
+	})
+}
+
+func TestSubscriber_UnmarshalError(t *testing.T) {
+	sigVerifier := &mocks.SignatureVerifier{}
+	sigVerifier.VerifyRequestReturns(true, testutil.MustParseURL(serviceURL), nil)
+
+	tm := &apmocks.AuthTokenMgr{}
+	tm.RequiredAuthTokensReturns([]string{"admin"}, nil)
+
+	handle := func(t *testing.T, cfg *Config) (int, string) {
+		t.Helper()
+
+		s := New(cfg, sigVerifier, tm)
+		require.NotNil(t, s)
+
+		defer s.Stop()
+
+		msgChan, err := s.Subscribe(context.Background(), "")
+		require.NoError(t, err)
+		require.NotNil(t, msgChan)
+
+		rw := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader([]byte("secret payload")))
+		require.NoError(t, err)
+
+		req.Header.Add(wmhttp.HeaderMetadata, "{invalid")
+
+		s.handleMessage(rw, req)
+
+		result := rw.Result()
+
+		respBytes, err := ioutil.ReadAll(result.Body)
+		require.NoError(t, err)
+		require.NoError(t, result.Body.Close())
+
+		return result.StatusCode, string(respBytes)
+	}
+
+	t.Run("default response", func(t *testing.T) {
+		status, body := handle(t, &Config{ServiceEndpoint: endpoint})
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Equal(t, invalidMessageResponse, body)
+	})
+
+	t.Run("debug response", func(t *testing.T) {
+		status, body := handle(t, &Config{ServiceEndpoint: endpoint, DebugErrors: true})
+		require.Equal(t, http.StatusBadRequest, status)
+		require.True(t, strings.HasPrefix(body, invalidMessageResponse+": "))
+		require.Contains(t, body, "could not unmarshal metadata from request")
+		require.NotContains(t, body, "secret payload")
+		require.LessOrEqual(t, len(body), maxErrorResponseLength)
+	})
 }
 
 func TestSubscriber_Close(t *testing.T) {
@@ -222,26 +459,291 @@ func TestSubscriber_Close(t *testing.T) {
 		s := New(&Config{ServiceEndpoint: endpoint}, sigVerifier, tm)
 		require.NotNil(t, s)
 
-		_, err := s.Subscribe(context.Background(), "")
+		msgChan, err := s.Subscribe(context.Background(), "")
 		require.NoError(t, err)
 
-		rw := httptest.NewRecorder()
-		req := httptest.NewRequest(http.MethodPost, endpoint, nil)
-
 		go func() {
 			time.Sleep(10 * time.Millisecond)
 
 			s.Stop()
+
+			// The message was delivered before the subscriber was stopped, so the handler waits for it
+			// to be acknowledged rather than rejecting it (which would cause the sender to retry).
+			for msg := range msgChan {
+				msg.Ack()
+			}
 		}()
 
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, endpoint, nil)
+
 		s.handleMessage(rw, req)
 
 		result := rw.Result()
-		require.Equal(t, http.StatusServiceUnavailable, result.StatusCode)
+		require.Equal(t, http.StatusOK, result.StatusCode)
 		require.NoError(t, result.Body.Close())
 	})
 }
 
+func TestSubscriber_Drain(t *testing.T) {
+	const numMessages = 5
+
+	s := New(&Config{ServiceEndpoint: endpoint, BufferSize: 2}, &mocks.SignatureVerifier{}, &apmocks.AuthTokenMgr{})
+	require.NotNil(t, s)
+
+	msgChan, err := s.Subscribe(context.Background(), "")
+	require.NoError(t, err)
+
+	// Fill the message channel and the publish buffer (nobody is reading from the message channel).
+	for i := 0; i < numMessages; i++ {
+		require.NoError(t, s.publish(message.NewMessage(fmt.Sprintf("msg%d", i), nil)))
+	}
+
+	go s.Stop()
+
+	require.Eventually(t, func() bool {
+		return s.State() != lifecycle.StateStarted
+	}, time.Second, time.Millisecond)
+
+	var received []string
+
+	for msg := range msgChan {
+		received = append(received, msg.UUID)
+	}
+
+	require.Len(t, received, numMessages)
+}
+
+func TestSubscriber_DrainTimeout(t *testing.T) {
+	s := New(&Config{ServiceEndpoint: endpoint, BufferSize: 1, DrainTimeout: 10 * time.Millisecond},
+		&mocks.SignatureVerifier{}, &apmocks.AuthTokenMgr{})
+	require.NotNil(t, s)
+
+	msgChan, err := s.Subscribe(context.Background(), "")
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, s.publish(message.NewMessage(fmt.Sprintf("msg%d", i), nil)))
+	}
+
+	require.Eventually(t, func() bool {
+		return len(s.pubChan) == 1
+	}, time.Second, time.Millisecond)
+
+	// Nobody reads from the message channel while stopping, so the drain times out.
+	s.Stop()
+
+	var received []string
+
+	for msg := range msgChan {
+		received = append(received, msg.UUID)
+	}
+
+	require.Len(t, received, 1)
+}
+
+func TestSubscriber_DrainResponse(t *testing.T) {
+	const numMessages = 3
+
+	sigVerifier := &mocks.SignatureVerifier{}
+	sigVerifier.VerifyRequestReturns(true, testutil.MustParseURL(serviceURL), nil)
+
+	// handle posts messages (while nobody is reading from the message channel) and returns the HTTP status
+	// of each request once the subscriber is stopped.
+	handle := func(t *testing.T, s *Subscriber) <-chan int {
+		t.Helper()
+
+		statusChan := make(chan int, numMessages)
+
+		for i := 0; i < numMessages; i++ {
+			go func() {
+				rw := httptest.NewRecorder()
+
+				s.handleMessage(rw, httptest.NewRequest(http.MethodPost, endpoint, nil))
+
+				result := rw.Result()
+				statusChan <- result.StatusCode
+				require.NoError(t, result.Body.Close())
+			}()
+		}
+
+		// Wait for the message channel and the publish buffer to fill up.
+		require.Eventually(t, func() bool {
+			return len(s.msgChan) == 1 && len(s.pubChan) == 1
+		}, time.Second, time.Millisecond)
+
+		return statusChan
+	}
+
+	t.Run("Drained -> waits for ack", func(t *testing.T) {
+		s := New(&Config{ServiceEndpoint: endpoint, BufferSize: 1}, sigVerifier, &apmocks.AuthTokenMgr{})
+
+		msgChan, err := s.Subscribe(context.Background(), "")
+		require.NoError(t, err)
+
+		statusChan := handle(t, s)
+
+		go s.Stop()
+
+		require.Eventually(t, func() bool {
+			return s.State() != lifecycle.StateStarted
+		}, time.Second, time.Millisecond)
+
+		// No response is sent while the drained messages are waiting to be acknowledged.
+		select {
+		case status := <-statusChan:
+			require.FailNow(t, "unexpected response", status)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		var received int
+
+		for msg := range msgChan {
+			msg.Ack()
+
+			received++
+		}
+
+		require.Equal(t, numMessages, received)
+
+		for i := 0; i < numMessages; i++ {
+			require.Equal(t, http.StatusOK, <-statusChan)
+		}
+	})
+
+	t.Run("Drain timeout -> dropped messages rejected", func(t *testing.T) {
+		s := New(&Config{ServiceEndpoint: endpoint, BufferSize: 1, DrainTimeout: 10 * time.Millisecond},
+			sigVerifier, &apmocks.AuthTokenMgr{})
+
+		msgChan, err := s.Subscribe(context.Background(), "")
+		require.NoError(t, err)
+
+		statusChan := handle(t, s)
+
+		// Nobody reads from the message channel while stopping, so the drain times out.
+		s.Stop()
+
+		var received int
+
+		for msg := range msgChan {
+			msg.Ack()
+
+			received++
+		}
+
+		statuses := make(map[int]int)
+
+		for i := 0; i < numMessages; i++ {
+			statuses[<-statusChan]++
+		}
+
+		// Only the messages that were delivered are acknowledged. A 503 is returned for the dropped
+		// messages, which were never delivered, so they aren't processed twice when the sender retries.
+		require.Equal(t, received, statuses[http.StatusOK])
+		require.Equal(t, numMessages-received, statuses[http.StatusServiceUnavailable])
+		require.Positive(t, statuses[http.StatusServiceUnavailable])
+	})
+
+	t.Run("In-flight publish while stopping -> message dropped", func(t *testing.T) {
+		s := New(&Config{ServiceEndpoint: endpoint, BufferSize: 1}, sigVerifier, &apmocks.AuthTokenMgr{})
+
+		msgChan, err := s.Subscribe(context.Background(), "")
+		require.NoError(t, err)
+
+		// Simulate a publish that has checked the state but hasn't yet posted its message.
+		s.publishMutex.RLock()
+
+		stopped := make(chan struct{})
+
+		go func() {
+			s.Stop()
+
+			close(stopped)
+		}()
+
+		require.Eventually(t, func() bool {
+			return s.State() != lifecycle.StateStarted
+		}, time.Second, time.Millisecond)
+
+		// The publisher has stopped, so the message is posted to the publish buffer but is never delivered.
+		<-s.done
+
+		msg := message.NewMessage("msg1", nil)
+
+		s.pubChan <- msg
+
+		select {
+		case <-stopped:
+			require.FailNow(t, "expecting stop to wait for the in-flight publish")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		s.publishMutex.RUnlock()
+
+		<-stopped
+
+		for range msgChan {
+			require.FailNow(t, "unexpected message")
+		}
+
+		require.Equal(t, http.StatusServiceUnavailable,
+			s.waitForAck(msg, httptest.NewRequest(http.MethodPost, endpoint, nil), nil, nil))
+	})
+
+	t.Run("Publish while stopping -> every request is answered", func(t *testing.T) {
+		const (
+			numIterations = 50
+			numRequests   = 20
+		)
+
+		for i := 0; i < numIterations; i++ {
+			s := New(&Config{ServiceEndpoint: endpoint, BufferSize: numRequests}, sigVerifier, &apmocks.AuthTokenMgr{})
+
+			msgChan, err := s.Subscribe(context.Background(), "")
+			require.NoError(t, err)
+
+			go func() {
+				for msg := range msgChan {
+					msg.Ack()
+				}
+			}()
+
+			statusChan := make(chan int, numRequests)
+
+			var wg sync.WaitGroup
+
+			wg.Add(1)
+
+			for j := 0; j < numRequests; j++ {
+				go func() {
+					wg.Wait()
+
+					rw := httptest.NewRecorder()
+
+					// The request has no deadline, so the handler would block forever if the
+					// message were neither delivered nor dropped.
+					s.handleMessage(rw, httptest.NewRequest(http.MethodPost, endpoint, nil))
+
+					statusChan <- rw.Result().StatusCode
+				}()
+			}
+
+			// Release the requests and stop the subscriber at the same time.
+			wg.Done()
+			s.Stop()
+
+			for j := 0; j < numRequests; j++ {
+				select {
+				case status := <-statusChan:
+					require.Contains(t, []int{http.StatusOK, http.StatusServiceUnavailable}, status)
+				case <-time.After(5 * time.Second):
+					require.FailNow(t, "timed out waiting for response")
+				}
+			}
+		}
+	})
+}
+
 func TestSubscriber_InvalidHTTPSignature(t *testing.T) {
 	sigVerifier := &mocks.SignatureVerifier{}
 	sigVerifier.VerifyRequestReturns(false, nil, nil)
@@ -336,3 +838,310 @@ func TestSubscriber_NoHTTPSignatureRequired(t *testing.T) {
 	require.Equal(t, http.StatusOK, result.StatusCode)
 	require.NoError(t, result.Body.Close())
 }
+
+func TestSubscriber_MethodAuthTokens(t *testing.T) {
+	const (
+		postToken = "post-token"
+		getToken  = "get-token"
+	)
+
+	sigVerifier := &mocks.SignatureVerifier{}
+	sigVerifier.VerifyRequestReturns(false, nil, nil)
+
+	tm := &apmocks.AuthTokenMgr{}
+	tm.RequiredAuthTokensStub = func(_, method string) ([]string, error) {
+		switch method {
+		case http.MethodPost:
+			return []string{postToken}, nil
+		case http.MethodGet:
+			return []string{getToken}, nil
+		default:
+			return nil, fmt.Errorf("injected token manager error")
+		}
+	}
+
+	s := New(&Config{ServiceEndpoint: endpoint}, sigVerifier, tm)
+	require.NotNil(t, s)
+
+	defer s.Stop()
+
+	msgChan, err := s.Subscribe(context.Background(), "")
+	require.NoError(t, err)
+	require.NotNil(t, msgChan)
+
+	go func() {
+		for msg := range msgChan {
+			msg.Ack()
+		}
+	}()
+
+	handle := func(method, token string) int {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(method, endpoint, nil)
+
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		s.handleMessage(rw, req)
+
+		result := rw.Result()
+		require.NoError(t, result.Body.Close())
+
+		return result.StatusCode
+	}
+
+	require.Equal(t, http.StatusOK, handle(http.MethodPost, postToken))
+	require.Equal(t, http.StatusUnauthorized, handle(http.MethodPost, getToken))
+	require.Equal(t, http.StatusOK, handle(http.MethodGet, getToken))
+	require.Equal(t, http.StatusUnauthorized, handle(http.MethodGet, postToken))
+	require.Equal(t, http.StatusUnauthorized, handle(http.MethodGet, ""))
+	require.Equal(t, http.StatusInternalServerError, handle(http.MethodPut, postToken))
+
+	// The token verifier for each method is cached.
+	numCalls := tm.RequiredAuthTokensCallCount()
+
+	require.Equal(t, http.StatusOK, handle(http.MethodGet, getToken))
+	require.Equal(t, numCalls, tm.RequiredAuthTokensCallCount())
+}
+
+func TestSubscriber_RequireActor(t *testing.T) {
+	t.Run("Actor not required", func(t *testing.T) {
+		sigVerifier := &mocks.SignatureVerifier{}
+		sigVerifier.VerifyRequestReturns(false, nil, nil)
+
+		s := New(&Config{ServiceEndpoint: endpoint}, sigVerifier, &apmocks.AuthTokenMgr{})
+		require.NotNil(t, s)
+
+		defer s.Stop()
+
+		md := handleAndGetMetadata(t, s)
+		require.Empty(t, md.Get(ActorIRIKey))
+		require.Zero(t, sigVerifier.VerifyRequestCallCount())
+	})
+
+	t.Run("Actor required -> no actor established", func(t *testing.T) {
+		sigVerifier := &mocks.SignatureVerifier{}
+		sigVerifier.VerifyRequestReturns(false, nil, nil)
+
+		s := New(&Config{ServiceEndpoint: endpoint, RequireActor: true}, sigVerifier, &apmocks.AuthTokenMgr{})
+		require.NotNil(t, s)
+
+		defer s.Stop()
+
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, endpoint, nil)
+
+		s.handleMessage(rw, req)
+
+		result := rw.Result()
+		require.Equal(t, http.StatusUnauthorized, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+	})
+
+	t.Run("Actor required -> actor established via HTTP signature", func(t *testing.T) {
+		sigVerifier := &mocks.SignatureVerifier{}
+		sigVerifier.VerifyRequestReturns(true, testutil.MustParseURL(serviceURL), nil)
+
+		tm := &apmocks.AuthTokenMgr{}
+		tm.RequiredAuthTokensReturns([]string{"admin"}, nil)
+
+		s := New(&Config{ServiceEndpoint: endpoint, RequireActor: true}, sigVerifier, tm)
+		require.NotNil(t, s)
+
+		defer s.Stop()
+
+		md := handleAndGetMetadata(t, s)
+		require.Equal(t, serviceURL, md.Get(ActorIRIKey))
+	})
+}
+
+func TestSubscriber_ActorIRIMetadataKey(t *testing.T) {
+	sigVerifier := &mocks.SignatureVerifier{}
+	sigVerifier.VerifyRequestReturns(true, testutil.MustParseURL(serviceURL), nil)
+
+	tm := &apmocks.AuthTokenMgr{}
+	tm.RequiredAuthTokensReturns([]string{"admin"}, nil)
+
+	t.Run("Default key", func(t *testing.T) {
+		s := New(&Config{ServiceEndpoint: endpoint}, sigVerifier, tm)
+		require.NotNil(t, s)
+		require.Equal(t, ActorIRIKey, s.ActorIRIMetadataKey)
+
+		defer s.Stop()
+
+		md := handleAndGetMetadata(t, s)
+		require.Equal(t, serviceURL, md.Get(ActorIRIKey))
+	})
+
+	t.Run("Custom key", func(t *testing.T) {
+		const customKey = "custom-actor-iri"
+
+		s := New(&Config{ServiceEndpoint: endpoint, ActorIRIMetadataKey: customKey}, sigVerifier, tm)
+		require.NotNil(t, s)
+
+		defer s.Stop()
+
+		md := handleAndGetMetadata(t, s)
+		require.Equal(t, serviceURL, md.Get(customKey))
+		require.Empty(t, md.Get(ActorIRIKey))
+	})
+}
+
+func TestSubscriber_MaxMetadataSize(t *testing.T) {
+	sigVerifier := &mocks.SignatureVerifier{}
+	sigVerifier.VerifyRequestReturns(true, testutil.MustParseURL(serviceURL), nil)
+
+	tm := &apmocks.AuthTokenMgr{}
+	tm.RequiredAuthTokensReturns([]string{"admin"}, nil)
+
+	newRequest := func(metadata string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, endpoint, nil)
+		req.Header.Set(wmhttp.HeaderMetadata, metadata)
+
+		return req
+	}
+
+	oversizedMetadata := `{"a":"12345","b":"` + strings.Repeat("x", 1000) + `","c":"123"}`
+
+	t.Run("Rejected", func(t *testing.T) {
+		s := New(&Config{ServiceEndpoint: endpoint, MaxMetadataSize: 100}, sigVerifier, tm)
+		require.NotNil(t, s)
+
+		defer s.Stop()
+
+		rw := httptest.NewRecorder()
+
+		s.handleMessage(rw, newRequest(oversizedMetadata))
+
+		result := rw.Result()
+		require.Equal(t, http.StatusRequestEntityTooLarge, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		s := New(&Config{ServiceEndpoint: endpoint, MaxMetadataSize: 100, TruncateMetadata: true}, sigVerifier, tm)
+		require.NotNil(t, s)
+
+		defer s.Stop()
+
+		md := handleRequestAndGetMetadata(t, s, newRequest(oversizedMetadata))
+		require.Equal(t, "12345", md.Get("a"))
+		require.Empty(t, md.Get("b"))
+		require.Equal(t, "123", md.Get("c"))
+		require.Equal(t, serviceURL, md.Get(ActorIRIKey))
+	})
+
+	t.Run("Within limit", func(t *testing.T) {
+		s := New(&Config{ServiceEndpoint: endpoint, MaxMetadataSize: 2000}, sigVerifier, tm)
+		require.NotNil(t, s)
+
+		defer s.Stop()
+
+		md := handleRequestAndGetMetadata(t, s, newRequest(oversizedMetadata))
+		require.Len(t, md.Get("b"), 1000)
+	})
+}
+
+func TestSubscriber_AccessLog(t *testing.T) {
+	sigVerifier := &mocks.SignatureVerifier{}
+	sigVerifier.VerifyRequestReturns(true, testutil.MustParseURL(serviceURL), nil)
+
+	tm := &apmocks.AuthTokenMgr{}
+	tm.RequiredAuthTokensReturns([]string{"admin"}, nil)
+
+	newLogger := func() (*log.StructuredLog, *bytes.Buffer) {
+		stdOut := &bytes.Buffer{}
+
+		return log.NewStructured(loggerModule, log.WithStdOut(zapcore.AddSync(stdOut)),
+			log.WithEncoding(log.JSON)), stdOut
+	}
+
+	t.Run("Enabled", func(t *testing.T) {
+		logger, stdOut := newLogger()
+
+		s := newSubscriber(&Config{ServiceEndpoint: endpoint, AccessLog: true}, sigVerifier, tm, logger)
+		require.NotNil(t, s)
+
+		defer s.Stop()
+
+		handleAndGetMetadata(t, s)
+
+		require.Equal(t, 1, strings.Count(stdOut.String(), "Handled HTTP request"))
+		require.Contains(t, stdOut.String(), `"http-status":200`)
+		require.Contains(t, stdOut.String(), `"http-method":"POST"`)
+		require.Contains(t, stdOut.String(), `"duration":`)
+		require.Contains(t, stdOut.String(), `"actor-id":"`+serviceURL+`"`)
+	})
+
+	t.Run("Error status", func(t *testing.T) {
+		sigVerifier := &mocks.SignatureVerifier{}
+		sigVerifier.VerifyRequestReturns(false, nil, nil)
+
+		logger, stdOut := newLogger()
+
+		s := newSubscriber(&Config{ServiceEndpoint: endpoint, AccessLog: true}, sigVerifier, tm, logger)
+		require.NotNil(t, s)
+
+		defer s.Stop()
+
+		rw := httptest.NewRecorder()
+
+		s.handleMessage(rw, httptest.NewRequest(http.MethodPost, endpoint, nil))
+
+		result := rw.Result()
+		require.Equal(t, http.StatusUnauthorized, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+
+		require.Equal(t, 1, strings.Count(stdOut.String(), "Handled HTTP request"))
+		require.Contains(t, stdOut.String(), `"http-status":401`)
+		require.Contains(t, stdOut.String(), `"duration":`)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		logger, stdOut := newLogger()
+
+		s := newSubscriber(&Config{ServiceEndpoint: endpoint}, sigVerifier, tm, logger)
+		require.NotNil(t, s)
+
+		defer s.Stop()
+
+		handleAndGetMetadata(t, s)
+
+		require.NotContains(t, stdOut.String(), "Handled HTTP request")
+	})
+}
+
+func handleAndGetMetadata(t *testing.T, s *Subscriber) message.Metadata {
+	t.Helper()
+
+	return handleRequestAndGetMetadata(t, s, httptest.NewRequest(http.MethodPost, endpoint, nil))
+}
+
+func handleRequestAndGetMetadata(t *testing.T, s *Subscriber, req *http.Request) message.Metadata {
+	t.Helper()
+
+	msgChan, err := s.Subscribe(context.Background(), "")
+	require.NoError(t, err)
+	require.NotNil(t, msgChan)
+
+	mdChan := make(chan message.Metadata, 1)
+
+	go func() {
+		msg := <-msgChan
+
+		mdChan <- msg.Metadata
+
+		msg.Ack()
+	}()
+
+	rw := httptest.NewRecorder()
+
+	s.handleMessage(rw, req)
+
+	result := rw.Result()
+	require.Equal(t, http.StatusOK, result.StatusCode)
+	require.NoError(t, result.Body.Close())
+
+	return <-mdChan
+}
@@ -0,0 +1,132 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package httpsubscriber
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+// ErrBufferFull is returned when a message can't be buffered since both the buffer and the overflow buffer are full.
+var ErrBufferFull = errors.New("message buffer is full")
+
+// OverflowPolicy determines how a new message is handled when both the buffer and the overflow buffer are full.
+type OverflowPolicy string
+
+const (
+	// OverflowPolicyRejectNew rejects the new message with a 503 (Service Unavailable). This is the default.
+	OverflowPolicyRejectNew OverflowPolicy = "reject-new"
+	// OverflowPolicyDropOldest drops the oldest message in the overflow buffer in order to make room for the
+	// new message. The dropped message is nacked and a 503 (Service Unavailable) is returned to its sender.
+	OverflowPolicyDropOldest OverflowPolicy = "drop-oldest"
+)
+
+// overflowBuffer is a bounded, secondary buffer that absorbs short spikes in which messages arrive faster than
+// the publish buffer can accept them. Messages are moved from the overflow buffer to the publish buffer (oldest
+// first) as room becomes available.
+type overflowBuffer struct {
+	mutex    sync.Mutex
+	pubChan  chan *message.Message
+	messages []*message.Message
+	capacity int
+	policy   OverflowPolicy
+}
+
+func newOverflowBuffer(pubChan chan *message.Message, capacity int, policy OverflowPolicy) *overflowBuffer {
+	return &overflowBuffer{
+		pubChan:  pubChan,
+		capacity: capacity,
+		policy:   policy,
+	}
+}
+
+// push posts the given message to the publish buffer if there's room and no messages are waiting in the overflow
+// buffer (so that messages are delivered in order). Otherwise the message is added to the overflow buffer. If the
+// overflow buffer is full then, depending on the policy, either ErrBufferFull is returned or the oldest message
+// in the overflow buffer is dropped and returned.
+func (b *overflowBuffer) push(msg *message.Message) (*message.Message, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if len(b.messages) == 0 {
+		select {
+		case b.pubChan <- msg:
+			return nil, nil
+		default:
+		}
+	}
+
+	if len(b.messages) < b.capacity {
+		b.messages = append(b.messages, msg)
+
+		return nil, nil
+	}
+
+	if b.policy != OverflowPolicyDropOldest {
+		return nil, ErrBufferFull
+	}
+
+	dropped := b.messages[0]
+
+	b.messages = append(b.messages[1:], msg)
+
+	return dropped, nil
+}
+
+// refill moves messages from the overflow buffer, oldest first, to the publish buffer while there's room.
+func (b *overflowBuffer) refill() {
+	if b == nil {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for len(b.messages) > 0 {
+		select {
+		case b.pubChan <- b.messages[0]:
+			b.messages[0] = nil
+			b.messages = b.messages[1:]
+		default:
+			return
+		}
+	}
+}
+
+// pop removes and returns the oldest message in the overflow buffer. Nil is returned if the buffer is empty.
+func (b *overflowBuffer) pop() *message.Message {
+	if b == nil {
+		return nil
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if len(b.messages) == 0 {
+		return nil
+	}
+
+	msg := b.messages[0]
+
+	b.messages[0] = nil
+	b.messages = b.messages[1:]
+
+	return msg
+}
+
+func (b *overflowBuffer) len() int {
+	if b == nil {
+		return 0
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return len(b.messages)
+}
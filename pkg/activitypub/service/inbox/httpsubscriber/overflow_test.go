@@ -0,0 +1,232 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package httpsubscriber
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/require"
+
+	apmocks "github.com/trustbloc/orb/pkg/activitypub/mocks"
+	"github.com/trustbloc/orb/pkg/activitypub/service/mocks"
+	"github.com/trustbloc/orb/pkg/internal/testutil"
+)
+
+func TestSubscriber_OverflowBuffer(t *testing.T) {
+	const (
+		bufferSize         = 1
+		overflowBufferSize = 3
+	)
+
+	newSubscriber := func(t *testing.T, policy OverflowPolicy) (*Subscriber, <-chan *message.Message) {
+		t.Helper()
+
+		sigVerifier := &mocks.SignatureVerifier{}
+		sigVerifier.VerifyRequestReturns(true, testutil.MustParseURL(serviceURL), nil)
+
+		s := New(&Config{
+			ServiceEndpoint:    endpoint,
+			BufferSize:         bufferSize,
+			OverflowBufferSize: overflowBufferSize,
+			OverflowPolicy:     policy,
+		}, sigVerifier, &apmocks.AuthTokenMgr{})
+
+		msgChan, err := s.Subscribe(context.Background(), "")
+		require.NoError(t, err)
+
+		return s, msgChan
+	}
+
+	// fill fills the message channel, the publisher and the publish buffer (nobody is reading from the
+	// message channel) so that subsequent messages go to the overflow buffer.
+	fill := func(t *testing.T, s *Subscriber) []*message.Message {
+		t.Helper()
+
+		var messages []*message.Message
+
+		for i := 0; i < 2*bufferSize+1; i++ {
+			msg := message.NewMessage(fmt.Sprintf("msg%d", i), nil)
+
+			require.NoError(t, publishWithTimeout(s, msg))
+
+			messages = append(messages, msg)
+
+			// Wait for the publisher to take the message so that the publish buffer doesn't overflow.
+			require.Eventually(t, func() bool {
+				return len(s.pubChan) == 0 || i == 2*bufferSize
+			}, time.Second, time.Millisecond)
+		}
+
+		require.Zero(t, s.overflow.len())
+
+		return messages
+	}
+
+	receive := func(t *testing.T, msgChan <-chan *message.Message, n int) []string {
+		t.Helper()
+
+		var received []string
+
+		for i := 0; i < n; i++ {
+			select {
+			case msg := <-msgChan:
+				received = append(received, msg.UUID)
+			case <-time.After(time.Second):
+				require.FailNow(t, "timed out waiting for message")
+			}
+		}
+
+		return received
+	}
+
+	t.Run("Spike absorbed", func(t *testing.T) {
+		s, msgChan := newSubscriber(t, "")
+		defer s.Stop()
+
+		require.Equal(t, OverflowPolicyRejectNew, s.OverflowPolicy)
+
+		fill(t, s)
+
+		for i := 0; i < overflowBufferSize; i++ {
+			require.NoError(t, publishWithTimeout(s, message.NewMessage(fmt.Sprintf("overflow%d", i), nil)))
+		}
+
+		require.Equal(t, overflowBufferSize, s.overflow.len())
+
+		// All messages are delivered in order once the subscriber catches up.
+		require.Equal(t, []string{"msg0", "msg1", "msg2", "overflow0", "overflow1", "overflow2"},
+			receive(t, msgChan, 2*bufferSize+1+overflowBufferSize))
+
+		require.Zero(t, s.overflow.len())
+
+		// There's room again.
+		require.NoError(t, publishWithTimeout(s, message.NewMessage("msg3", nil)))
+		require.Equal(t, []string{"msg3"}, receive(t, msgChan, 1))
+	})
+
+	t.Run("Sustained overload -> reject new", func(t *testing.T) {
+		s, msgChan := newSubscriber(t, OverflowPolicyRejectNew)
+		defer s.Stop()
+
+		fill(t, s)
+
+		for i := 0; i < overflowBufferSize; i++ {
+			require.NoError(t, publishWithTimeout(s, message.NewMessage(fmt.Sprintf("overflow%d", i), nil)))
+		}
+
+		err := publishWithTimeout(s, message.NewMessage("rejected", nil))
+		require.ErrorIs(t, err, ErrBufferFull)
+
+		rw := httptest.NewRecorder()
+
+		s.handleMessage(rw, httptest.NewRequest(http.MethodPost, endpoint, nil))
+
+		result := rw.Result()
+		require.Equal(t, http.StatusServiceUnavailable, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+
+		require.Equal(t, []string{"msg0", "msg1", "msg2", "overflow0", "overflow1", "overflow2"},
+			receive(t, msgChan, 2*bufferSize+1+overflowBufferSize))
+	})
+
+	t.Run("Sustained overload -> drop oldest", func(t *testing.T) {
+		s, msgChan := newSubscriber(t, OverflowPolicyDropOldest)
+		defer s.Stop()
+
+		fill(t, s)
+
+		var overflowed []*message.Message
+
+		for i := 0; i < overflowBufferSize+2; i++ {
+			msg := message.NewMessage(fmt.Sprintf("overflow%d", i), nil)
+
+			require.NoError(t, publishWithTimeout(s, msg))
+
+			overflowed = append(overflowed, msg)
+		}
+
+		require.Equal(t, overflowBufferSize, s.overflow.len())
+
+		// The two oldest overflowed messages were dropped and nacked. A 503 is returned for them since they
+		// were never delivered.
+		for _, msg := range overflowed[:2] {
+			select {
+			case <-msg.Nacked():
+			default:
+				require.FailNow(t, "expecting dropped message to be nacked", msg.UUID)
+			}
+
+			require.Equal(t, http.StatusServiceUnavailable,
+				s.waitForAck(msg, httptest.NewRequest(http.MethodPost, endpoint, nil), nil, nil))
+		}
+
+		require.Equal(t, []string{"msg0", "msg1", "msg2", "overflow2", "overflow3", "overflow4"},
+			receive(t, msgChan, 2*bufferSize+1+overflowBufferSize))
+	})
+
+	t.Run("Overflowed messages drained on stop", func(t *testing.T) {
+		s, msgChan := newSubscriber(t, "")
+
+		fill(t, s)
+
+		require.NoError(t, publishWithTimeout(s, message.NewMessage("overflow0", nil)))
+
+		go s.Stop()
+
+		var received []string
+
+		for msg := range msgChan {
+			received = append(received, msg.UUID)
+		}
+
+		require.Equal(t, []string{"msg0", "msg1", "msg2", "overflow0"}, received)
+	})
+
+	t.Run("No overflow buffer -> backpressure", func(t *testing.T) {
+		s := New(&Config{ServiceEndpoint: endpoint, BufferSize: bufferSize, DrainTimeout: 10 * time.Millisecond},
+			&mocks.SignatureVerifier{}, &apmocks.AuthTokenMgr{})
+		defer s.Stop()
+
+		require.Nil(t, s.overflow)
+
+		fill(t, s)
+
+		require.ErrorIs(t, publishWithTimeout(s, message.NewMessage("blocked", nil)), errPublishTimedOut)
+	})
+
+	t.Run("Unsupported policy", func(t *testing.T) {
+		require.PanicsWithValue(t, "unsupported overflow policy: drop-newest", func() {
+			New(&Config{ServiceEndpoint: endpoint, OverflowPolicy: "drop-newest"},
+				&mocks.SignatureVerifier{}, &apmocks.AuthTokenMgr{})
+		})
+	})
+}
+
+var errPublishTimedOut = errors.New("publish timed out")
+
+// publishWithTimeout publishes the given message and returns errPublishTimedOut if publish blocks.
+func publishWithTimeout(s *Subscriber, msg *message.Message) error {
+	errChan := make(chan error, 1)
+
+	go func() {
+		errChan <- s.publish(msg)
+	}()
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-time.After(100 * time.Millisecond):
+		return errPublishTimedOut
+	}
+}
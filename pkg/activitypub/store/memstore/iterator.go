@@ -58,6 +58,13 @@ func (it *ActivityIterator) Next() (*vocab.ActivityType, error) {
 	return it.results[it.current], nil
 }
 
+// Reset rewinds the iterator to the beginning so that the same results may be iterated again without
+// re-querying the store. Note that this is only supported by the in-memory iterator and is not part of
+// the spi.ActivityIterator interface.
+func (it *ActivityIterator) Reset() {
+	it.current = -1
+}
+
 // ReferenceIterator is used to iterator over references.
 type ReferenceIterator struct {
 	*iterator
@@ -54,6 +54,38 @@ func TestActivityIterator(t *testing.T) {
 	require.NoError(t, it.Close())
 }
 
+func TestActivityIterator_Reset(t *testing.T) {
+	activities := newMockActivities(vocab.TypeCreate, 3)
+
+	it := NewActivityIterator(activities, len(activities))
+	require.NotNil(t, it)
+
+	for i := 0; i < 2; i++ {
+		for _, expected := range activities {
+			a, err := it.Next()
+			require.NoError(t, err)
+			require.Equal(t, expected.ID().String(), a.ID().String())
+		}
+
+		a, err := it.Next()
+		require.True(t, errors.Is(err, spi.ErrNotFound))
+		require.Nil(t, a)
+
+		it.Reset()
+	}
+
+	// Reset part way through.
+	a, err := it.Next()
+	require.NoError(t, err)
+	require.Equal(t, activities[0].ID().String(), a.ID().String())
+
+	it.Reset()
+
+	a, err = it.Next()
+	require.NoError(t, err)
+	require.Equal(t, activities[0].ID().String(), a.ID().String())
+}
+
 func TestReferenceIterator(t *testing.T) {
 	ref1 := testutil.MustParseURL("https://ref_1")
 	ref2 := testutil.MustParseURL("https://ref_2")
@@ -7,10 +7,12 @@ SPDX-License-Identifier: Apache-2.0
 package memstore
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/trustbloc/orb/internal/pkg/log"
 	"github.com/trustbloc/orb/pkg/activitypub/store/spi"
@@ -20,16 +22,39 @@ import (
 
 const loggerModule = "activitypub_memstore"
 
+// ErrCapacityExceeded is returned from AddReference when the maximum number of references
+// of a given type has been reached for an object.
+var ErrCapacityExceeded = errors.New("reference capacity exceeded")
+
 // Store implements an in-memory ActivityPub store.
 type Store struct {
 	activityStore   *activityStore
 	referenceStores map[spi.ReferenceType]*referenceStore
 	logger          *log.StructuredLog
+
+	// addsInProgress is read-locked for the duration of each add so that a query with read-your-writes
+	// consistency may wait for all adds that are in progress by acquiring (and immediately releasing) the
+	// write lock.
+	addsInProgress sync.RWMutex
+}
+
+// Option is an option for the in-memory store.
+type Option func(s *Store)
+
+// WithMaxReferences sets the maximum number of references of the given type that may be stored
+// for a single object. If the maximum is reached then AddReference returns ErrCapacityExceeded.
+// By default, the number of references is unlimited.
+func WithMaxReferences(refType spi.ReferenceType, maxRefs int) Option {
+	return func(s *Store) {
+		if rs, ok := s.referenceStores[refType]; ok {
+			rs.maxRefs = maxRefs
+		}
+	}
 }
 
 // New returns a new in-memory ActivityPub store.
-func New(serviceName string) *Store {
-	return &Store{
+func New(serviceName string, opts ...Option) *Store {
+	s := &Store{
 		activityStore: newActivitiesStore(),
 		logger:        log.NewStructured(loggerModule, log.WithFields(log.WithServiceName(serviceName))),
 		referenceStores: map[spi.ReferenceType]*referenceStore{
@@ -46,10 +71,19 @@ func New(serviceName string) *Store {
 			spi.AnchorLinkset: newReferenceStore(),
 		},
 	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
 // AddActivity adds the given activity to the activity store.
 func (s *Store) AddActivity(activity *vocab.ActivityType) error {
+	s.addsInProgress.RLock()
+	defer s.addsInProgress.RUnlock()
+
 	s.logger.Debug("Storing activity", log.WithActivityType(activity.Type().String()), log.WithActivityID(activity.ID()))
 
 	return s.activityStore.add(activity)
@@ -63,11 +97,28 @@ func (s *Store) GetActivity(activityID *url.URL) (*vocab.ActivityType, error) {
 	return s.activityStore.get(activityID.String())
 }
 
+// DeleteActivity deletes the activity for the given ID from the activity store
+// or returns ErrNotFound error if it wasn't found.
+func (s *Store) DeleteActivity(activityID *url.URL) error {
+	s.logger.Debug("Deleting activity", log.WithActivityID(activityID))
+
+	return s.activityStore.delete(activityID.String())
+}
+
+// Stats returns the number of stored activities per activity type.
+func (s *Store) Stats() map[vocab.Type]int {
+	return s.activityStore.stats()
+}
+
 // QueryActivities queries the given activity store using the provided criteria
-// and returns a results iterator.
+// and returns a results iterator. By default, the query is best-effort, i.e. activities that are being added
+// concurrently may not be included in the results. The spi.WithConsistency(spi.ConsistencyReadYourWrites)
+// option may be specified in order to wait for adds that are in progress.
 func (s *Store) QueryActivities(query *spi.Criteria, opts ...spi.QueryOpt) (spi.ActivityIterator, error) {
 	s.logger.Debug("Querying activities", log.WithQuery(query))
 
+	s.syncWithAddsInProgress(opts...)
+
 	if query.ReferenceType != "" && query.ObjectIRI != nil {
 		return s.queryActivitiesByRef(query.ReferenceType, query, opts...)
 	}
@@ -78,6 +129,9 @@ func (s *Store) QueryActivities(query *spi.Criteria, opts ...spi.QueryOpt) (spi.
 // AddReference adds the reference of the given type to the given object.
 func (s *Store) AddReference(referenceType spi.ReferenceType, objectIRI *url.URL, referenceIRI *url.URL,
 	refMetaDataOpts ...spi.RefMetadataOpt) error {
+	s.addsInProgress.RLock()
+	defer s.addsInProgress.RUnlock()
+
 	s.logger.Debug("Adding reference to object", log.WithReferenceType(string(referenceType)),
 		log.WithObjectIRI(objectIRI), log.WithReferenceIRI(referenceIRI))
 
@@ -89,7 +143,12 @@ func (s *Store) AddReference(referenceType spi.ReferenceType, objectIRI *url.URL
 		return fmt.Errorf("nil reference IRI")
 	}
 
-	return s.referenceStores[referenceType].add(objectIRI, referenceIRI)
+	err := s.referenceStores[referenceType].add(objectIRI, referenceIRI)
+	if err != nil {
+		return fmt.Errorf("add reference of type %s to object %s: %w", referenceType, objectIRI, err)
+	}
+
+	return nil
 }
 
 // DeleteReference deletes the reference of the given type from the given actor.
@@ -108,14 +167,160 @@ func (s *Store) DeleteReference(referenceType spi.ReferenceType, objectIRI, refe
 	return s.referenceStores[referenceType].delete(objectIRI, referenceIRI)
 }
 
+// HasReference returns true if the given object has a reference of the given type to the given reference IRI.
+func (s *Store) HasReference(refType spi.ReferenceType, objectIRI, referenceIRI *url.URL) (bool, error) {
+	s.logger.Debug("Checking for reference", log.WithReferenceType(string(refType)),
+		log.WithObjectIRI(objectIRI), log.WithReferenceIRI(referenceIRI))
+
+	if objectIRI == nil {
+		return false, fmt.Errorf("nil object IRI")
+	}
+
+	if referenceIRI == nil {
+		return false, fmt.Errorf("nil reference IRI")
+	}
+
+	rs, ok := s.referenceStores[refType]
+	if !ok {
+		return false, fmt.Errorf("unsupported reference type: %s", refType)
+	}
+
+	return rs.has(objectIRI, referenceIRI), nil
+}
+
+// DeleteReferencesForUndo deletes the reference(s) that were added for the activity that's undone by the given
+// 'Undo' activity. If the undone activity is in the store then the stored activity is used, otherwise the
+// activity embedded in the 'Undo' is used. The following activities are supported:
+//   - Follow: the Following reference to the followed actor is deleted from the actor of the 'Follow'
+//   - Like: the Like reference is deleted from each of the liked anchor events
+func (s *Store) DeleteReferencesForUndo(undo *vocab.ActivityType) error {
+	if undo == nil || !undo.Type().Is(vocab.TypeUndo) {
+		return fmt.Errorf("expecting an 'Undo' activity")
+	}
+
+	activity := undo.Object().Activity()
+	if activity == nil || activity.ID() == nil {
+		return fmt.Errorf("no activity specified in 'object' field of the 'Undo' activity")
+	}
+
+	if a, err := s.activityStore.get(activity.ID().String()); err == nil {
+		activity = a
+	}
+
+	s.logger.Debug("Deleting references for undone activity", log.WithActivityID(undo.ID()),
+		log.WithActivityType(activity.Type().String()))
+
+	switch {
+	case activity.Type().Is(vocab.TypeFollow):
+		return s.DeleteReference(spi.Following, activity.Actor(), activity.Object().IRI())
+
+	case activity.Type().Is(vocab.TypeLike):
+		anchorEvent := activity.Object().AnchorEvent()
+		if anchorEvent == nil || len(anchorEvent.URL()) == 0 {
+			return fmt.Errorf("no anchor reference in the 'Like' activity")
+		}
+
+		for _, u := range anchorEvent.URL() {
+			if err := s.DeleteReference(spi.Like, u, activity.ID().URL()); err != nil {
+				return err
+			}
+		}
+
+		return nil
+
+	default:
+		return fmt.Errorf("unsupported activity type in 'Undo': %s", activity.Type())
+	}
+}
+
 // QueryReferences returns the list of references of the given type according to the given query.
+// As with QueryActivities, the query is best-effort unless read-your-writes consistency is requested.
 func (s *Store) QueryReferences(refType spi.ReferenceType,
 	query *spi.Criteria, opts ...spi.QueryOpt) (spi.ReferenceIterator, error) {
 	s.logger.Debug("Querying references", log.WithReferenceType(string(refType)), log.WithQuery(query))
 
+	s.syncWithAddsInProgress(opts...)
+
 	return s.referenceStores[refType].query(query, opts...)
 }
 
+// ExportCollection returns the references of the given type for the given object as an ActivityPub
+// OrderedCollection (e.g. for debugging or migration). The items are in the order in which they were stored.
+// The returned collection has no ID since the in-memory store doesn't know the IRI of the collection.
+func (s *Store) ExportCollection(refType spi.ReferenceType, objectIRI *url.URL) (*vocab.OrderedCollectionType, error) {
+	s.logger.Debug("Exporting references as collection", log.WithReferenceType(string(refType)),
+		log.WithObjectIRI(objectIRI))
+
+	if objectIRI == nil {
+		return nil, fmt.Errorf("nil object IRI")
+	}
+
+	rs, ok := s.referenceStores[refType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported reference type: %s", refType)
+	}
+
+	refs := rs.get(objectIRI)
+
+	items := make([]*vocab.ObjectProperty, len(refs))
+
+	for i, ref := range refs {
+		items[i] = vocab.NewObjectProperty(vocab.WithIRI(ref))
+	}
+
+	return vocab.NewOrderedCollection(items), nil
+}
+
+// ExportedActivity is an activity that was exported from the store along with its sequence number, which
+// determines the order of the activity relative to the other exported activities.
+type ExportedActivity struct {
+	Sequence uint64              `json:"sequence"`
+	Activity *vocab.ActivityType `json:"activity"`
+}
+
+// ExportActivities returns all activities in the order in which they were added (e.g. for migration).
+// The activities may be restored, in the same order, using ImportActivities.
+func (s *Store) ExportActivities() []*ExportedActivity {
+	s.logger.Debug("Exporting activities")
+
+	return s.activityStore.export()
+}
+
+// ImportActivities replaces all activities in the store with the given exported activities. The activities
+// are ordered by their sequence numbers (regardless of the order of the given slice) so that queries return
+// the activities in the same order as the store from which they were exported. References are not affected.
+func (s *Store) ImportActivities(activities []*ExportedActivity) error {
+	s.logger.Debug("Importing activities", log.WithTotal(len(activities)))
+
+	s.addsInProgress.RLock()
+	defer s.addsInProgress.RUnlock()
+
+	return s.activityStore.restore(activities)
+}
+
+// Clear removes all activities and references from the store. The store may continue to be used after
+// it's cleared. Configured options (e.g. maximum references) are retained.
+func (s *Store) Clear() {
+	s.logger.Debug("Clearing all activities and references")
+
+	s.activityStore.clear()
+
+	for _, rs := range s.referenceStores {
+		rs.clear()
+	}
+}
+
+// syncWithAddsInProgress waits for all adds that are in progress to complete if the query options
+// specify read-your-writes consistency.
+func (s *Store) syncWithAddsInProgress(opts ...spi.QueryOpt) {
+	if storeutil.GetQueryOptions(opts...).Consistency != spi.ConsistencyReadYourWrites {
+		return
+	}
+
+	s.addsInProgress.Lock()
+	s.addsInProgress.Unlock() //nolint:staticcheck
+}
+
 func (s *Store) queryActivitiesByRef(refType spi.ReferenceType, query *spi.Criteria,
 	opts ...spi.QueryOpt) (spi.ActivityIterator, error) {
 	it, err := s.QueryReferences(refType, query, opts...)
@@ -141,7 +346,8 @@ func (s *Store) queryActivitiesByRef(refType spi.ReferenceType, query *spi.Crite
 
 	ait := s.activityStore.query(
 		spi.NewCriteria(spi.WithActivityIRIs(refs...)),
-		spi.WithSortOrder(options.SortOrder))
+		spi.WithSortOrder(options.SortOrder),
+		spi.WithSortField(options.SortField))
 
 	// Set 'totalItems' to the 'totalItems' returned in the original reference query, which may be based on paging.
 	ait.totalItems = totalItems
@@ -152,12 +358,27 @@ func (s *Store) queryActivitiesByRef(refType spi.ReferenceType, query *spi.Crite
 type activityStore struct {
 	mutex        sync.RWMutex
 	activities   []*vocab.ActivityType
+	seqs         []uint64 // The sequence number of each entry in activities.
 	activityByID map[string]*vocab.ActivityType
+	countByType  map[vocab.Type]int
+
+	// byType indexes the activities by type (in insertion order) so that a query by type doesn't have to
+	// scan all activities. The sequence number of each entry is used to restore the insertion order when
+	// the results for multiple types are combined.
+	byType  map[vocab.Type][]*indexedActivity
+	nextSeq uint64
+}
+
+type indexedActivity struct {
+	seq      uint64
+	activity *vocab.ActivityType
 }
 
 func newActivitiesStore() *activityStore {
 	return &activityStore{
 		activityByID: make(map[string]*vocab.ActivityType),
+		countByType:  make(map[vocab.Type]int),
+		byType:       make(map[vocab.Type][]*indexedActivity),
 	}
 }
 
@@ -165,12 +386,197 @@ func (s *activityStore) add(activity *vocab.ActivityType) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	s.addWithSeq(activity, s.nextSeq)
+
+	return nil
+}
+
+// addWithSeq adds the given activity with the given sequence number. The caller must hold the lock.
+func (s *activityStore) addWithSeq(activity *vocab.ActivityType, seq uint64) {
 	s.activities = append(s.activities, activity)
+	s.seqs = append(s.seqs, seq)
 	s.activityByID[activity.ID().String()] = activity
 
+	entry := &indexedActivity{seq: seq, activity: activity}
+
+	if seq >= s.nextSeq {
+		s.nextSeq = seq + 1
+	}
+
+	for _, t := range activity.Type().Types() {
+		s.countByType[t]++
+
+		s.byType[t] = append(s.byType[t], entry)
+	}
+}
+
+func (s *activityStore) export() []*ExportedActivity {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	exported := make([]*ExportedActivity, len(s.activities))
+
+	for i, activity := range s.activities {
+		exported[i] = &ExportedActivity{Sequence: s.seqs[i], Activity: activity}
+	}
+
+	return exported
+}
+
+func (s *activityStore) restore(activities []*ExportedActivity) error {
+	for _, a := range activities {
+		if a == nil || a.Activity == nil || a.Activity.ID() == nil {
+			return fmt.Errorf("invalid exported activity: activity and activity ID are required")
+		}
+	}
+
+	sorted := make([]*ExportedActivity, len(activities))
+	copy(sorted, activities)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Sequence < sorted[j].Sequence
+	})
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.clearActivities()
+
+	for _, a := range sorted {
+		s.addWithSeq(a.Activity, a.Sequence)
+	}
+
+	return nil
+}
+
+func (s *activityStore) clear() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.clearActivities()
+}
+
+// clearActivities removes all activities. The caller must hold the lock.
+func (s *activityStore) clearActivities() {
+	s.activities = nil
+	s.seqs = nil
+	s.nextSeq = 0
+	s.activityByID = make(map[string]*vocab.ActivityType)
+	s.countByType = make(map[vocab.Type]int)
+	s.byType = make(map[vocab.Type][]*indexedActivity)
+}
+
+func (s *activityStore) delete(activityID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	a, ok := s.activityByID[activityID]
+	if !ok {
+		return spi.ErrNotFound
+	}
+
+	delete(s.activityByID, activityID)
+
+	for i, activity := range s.activities {
+		if activity == a {
+			s.activities = append(s.activities[0:i], s.activities[i+1:]...)
+			s.seqs = append(s.seqs[0:i], s.seqs[i+1:]...)
+
+			break
+		}
+	}
+
+	for _, t := range a.Type().Types() {
+		s.countByType[t]--
+
+		if s.countByType[t] <= 0 {
+			delete(s.countByType, t)
+		}
+
+		s.removeFromTypeIndex(t, a)
+	}
+
 	return nil
 }
 
+// removeFromTypeIndex removes the first (i.e. the oldest) index entry of the given type for the given activity,
+// which corresponds to the entry that's removed from the activities slice. The caller must hold the lock.
+func (s *activityStore) removeFromTypeIndex(t vocab.Type, a *vocab.ActivityType) {
+	entries := s.byType[t]
+
+	for i, entry := range entries {
+		if entry.activity == a {
+			entries = append(entries[0:i], entries[i+1:]...)
+
+			break
+		}
+	}
+
+	if len(entries) == 0 {
+		delete(s.byType, t)
+
+		return
+	}
+
+	s.byType[t] = entries
+}
+
+// activitiesOfType returns the activities that have any of the given types, in insertion order.
+// The caller must hold the lock.
+func (s *activityStore) activitiesOfType(types ...vocab.Type) []*vocab.ActivityType {
+	if len(types) == 1 {
+		entries := s.byType[types[0]]
+
+		activities := make([]*vocab.ActivityType, len(entries))
+
+		for i, entry := range entries {
+			activities[i] = entry.activity
+		}
+
+		return activities
+	}
+
+	// An activity with multiple types may be indexed under more than one of the given types.
+	entriesBySeq := make(map[uint64]*indexedActivity)
+
+	for _, t := range types {
+		for _, entry := range s.byType[t] {
+			entriesBySeq[entry.seq] = entry
+		}
+	}
+
+	entries := make([]*indexedActivity, 0, len(entriesBySeq))
+
+	for _, entry := range entriesBySeq {
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].seq < entries[j].seq
+	})
+
+	activities := make([]*vocab.ActivityType, len(entries))
+
+	for i, entry := range entries {
+		activities[i] = entry.activity
+	}
+
+	return activities
+}
+
+func (s *activityStore) stats() map[vocab.Type]int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	counts := make(map[vocab.Type]int, len(s.countByType))
+
+	for t, count := range s.countByType {
+		counts[t] = count
+	}
+
+	return counts
+}
+
 func (s *activityStore) get(activityID string) (*vocab.ActivityType, error) {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
@@ -187,11 +593,20 @@ func (s *activityStore) query(query *spi.Criteria, opts ...spi.QueryOpt) *Activi
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	return NewActivityIterator(activityQueryResults(s.activities).filter(query, opts...))
+	activities := s.activities
+
+	// Use the type index to narrow down the activities to filter. (If activity IRIs are specified then
+	// the types are ignored by the filter.)
+	if len(query.ActivityIRIs) == 0 && len(query.Types) > 0 {
+		activities = s.activitiesOfType(query.Types...)
+	}
+
+	return NewActivityIterator(activityQueryResults(activities).filter(query, opts...))
 }
 
 type referenceStore struct {
 	irisByObject map[string][]*url.URL
+	maxRefs      int
 	mutex        sync.RWMutex
 }
 
@@ -207,11 +622,22 @@ func (s *referenceStore) add(actor fmt.Stringer, iri *url.URL) error {
 
 	actorID := actor.String()
 
+	if s.maxRefs > 0 && len(s.irisByObject[actorID]) >= s.maxRefs {
+		return fmt.Errorf("%w: maximum of %d references", ErrCapacityExceeded, s.maxRefs)
+	}
+
 	s.irisByObject[actorID] = append(s.irisByObject[actorID], iri)
 
 	return nil
 }
 
+func (s *referenceStore) clear() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.irisByObject = make(map[string][]*url.URL)
+}
+
 func (s *referenceStore) delete(actor, iri fmt.Stringer) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
@@ -229,6 +655,32 @@ func (s *referenceStore) delete(actor, iri fmt.Stringer) error {
 	return nil
 }
 
+func (s *referenceStore) has(object, iri fmt.Stringer) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, ref := range s.irisByObject[object.String()] {
+		if ref.String() == iri.String() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// get returns a copy of the references for the given object.
+func (s *referenceStore) get(object fmt.Stringer) []*url.URL {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	refs := s.irisByObject[object.String()]
+
+	result := make([]*url.URL, len(refs))
+	copy(result, refs)
+
+	return result
+}
+
 func (s *referenceStore) query(query *spi.Criteria, opts ...spi.QueryOpt) (spi.ReferenceIterator, error) {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
@@ -279,6 +731,8 @@ func (r activityQueryResults) filter(query *spi.Criteria, opts ...spi.QueryOpt)
 
 	options := storeutil.GetQueryOptions(opts...)
 
+	sortByField(results, options.SortField)
+
 	if options.SortOrder == spi.SortDescending {
 		reverseSort(results)
 	}
@@ -365,6 +819,28 @@ func startIndex(totalItems int, options *spi.QueryOptions) int {
 	return (getFirstPageNum(totalItems, options.PageSize) - options.PageNumber) * options.PageSize
 }
 
+func sortByField(results []*vocab.ActivityType, field spi.SortField) {
+	switch field {
+	case spi.SortByPublished:
+		sort.SliceStable(results, func(i, j int) bool {
+			return publishedTime(results[i]).Before(publishedTime(results[j]))
+		})
+	case spi.SortByID:
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].ID().String() < results[j].ID().String()
+		})
+	case spi.SortByInsertionOrder:
+	}
+}
+
+func publishedTime(a *vocab.ActivityType) time.Time {
+	if published := a.Published(); published != nil {
+		return *published
+	}
+
+	return time.Time{}
+}
+
 func reverseSort(results interface{}) {
 	sort.SliceStable(results, func(i, j int) bool { return i > j }) //nolint:gocritic
 }
@@ -7,14 +7,19 @@ SPDX-License-Identifier: Apache-2.0
 package memstore
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math/rand"
 	"net/url"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/trustbloc/orb/pkg/activitypub/store/spi"
+	"github.com/trustbloc/orb/pkg/activitypub/store/storeutil"
 	"github.com/trustbloc/orb/pkg/activitypub/vocab"
 	"github.com/trustbloc/orb/pkg/internal/testutil"
 )
@@ -78,6 +83,185 @@ func TestStore_Activity(t *testing.T) {
 	})
 }
 
+func TestStore_DeleteActivity(t *testing.T) {
+	s := New("service1")
+	require.NotNil(t, s)
+
+	activityID1 := testutil.MustParseURL("https://example.com/activities/activity1")
+	activityID2 := testutil.MustParseURL("https://example.com/activities/activity2")
+
+	require.True(t, errors.Is(s.DeleteActivity(activityID1), spi.ErrNotFound))
+
+	require.NoError(t, s.AddActivity(newMockActivity(vocab.TypeCreate, activityID1)))
+	require.NoError(t, s.AddActivity(newMockActivity(vocab.TypeAnnounce, activityID2)))
+
+	require.NoError(t, s.DeleteActivity(activityID1))
+
+	_, err := s.GetActivity(activityID1)
+	require.True(t, errors.Is(err, spi.ErrNotFound))
+
+	it, err := s.QueryActivities(spi.NewCriteria())
+	require.NoError(t, err)
+
+	checkQueryResults(t, it, activityID2)
+}
+
+func TestStore_Clear(t *testing.T) {
+	s := New("service1", WithMaxReferences(spi.Follower, 1))
+	require.NotNil(t, s)
+
+	actor1 := testutil.MustParseURL("https://actor1")
+	activityID1 := testutil.MustParseURL("https://example.com/activities/activity1")
+	activityID2 := testutil.MustParseURL("https://example.com/activities/activity2")
+
+	require.NoError(t, s.AddActivity(newMockActivity(vocab.TypeCreate, activityID1)))
+	require.NoError(t, s.AddReference(spi.Follower, actor1, testutil.MustParseURL("https://follower_1")))
+	require.NoError(t, s.AddReference(spi.Inbox, actor1, activityID1))
+
+	s.Clear()
+
+	_, err := s.GetActivity(activityID1)
+	require.True(t, errors.Is(err, spi.ErrNotFound))
+
+	it, err := s.QueryActivities(spi.NewCriteria())
+	require.NoError(t, err)
+
+	checkQueryResults(t, it)
+
+	refIt, err := s.QueryReferences(spi.Follower, spi.NewCriteria(spi.WithObjectIRI(actor1)))
+	require.NoError(t, err)
+
+	total, err := refIt.TotalItems()
+	require.NoError(t, err)
+	require.Zero(t, total)
+
+	require.Empty(t, s.Stats())
+
+	t.Run("Store is reusable", func(t *testing.T) {
+		require.NoError(t, s.AddActivity(newMockActivity(vocab.TypeAnnounce, activityID2)))
+		require.NoError(t, s.AddReference(spi.Follower, actor1, testutil.MustParseURL("https://follower_2")))
+
+		// The maximum references option is retained.
+		require.True(t, errors.Is(s.AddReference(spi.Follower, actor1, testutil.MustParseURL("https://follower_3")),
+			ErrCapacityExceeded))
+
+		it, err := s.QueryActivities(spi.NewCriteria())
+		require.NoError(t, err)
+
+		checkQueryResults(t, it, activityID2)
+	})
+}
+
+func TestStore_MaxReferences(t *testing.T) {
+	s := New("service1", WithMaxReferences(spi.Follower, 2))
+	require.NotNil(t, s)
+
+	actor1 := testutil.MustParseURL("https://actor1")
+	actor2 := testutil.MustParseURL("https://actor2")
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, s.AddReference(spi.Follower, actor1,
+			testutil.MustParseURL(fmt.Sprintf("https://follower_%d", i))))
+	}
+
+	err := s.AddReference(spi.Follower, actor1, testutil.MustParseURL("https://follower_2"))
+	require.Error(t, err)
+	require.True(t, errors.Is(err, ErrCapacityExceeded))
+
+	// The limit applies per object.
+	require.NoError(t, s.AddReference(spi.Follower, actor2, testutil.MustParseURL("https://follower_0")))
+
+	// Other reference types are unlimited.
+	for i := 0; i < 10; i++ {
+		require.NoError(t, s.AddReference(spi.Following, actor1,
+			testutil.MustParseURL(fmt.Sprintf("https://following_%d", i))))
+	}
+
+	// A reference may be added after one is deleted.
+	require.NoError(t, s.DeleteReference(spi.Follower, actor1, testutil.MustParseURL("https://follower_0")))
+	require.NoError(t, s.AddReference(spi.Follower, actor1, testutil.MustParseURL("https://follower_2")))
+
+	it, err := s.QueryReferences(spi.Follower, spi.NewCriteria(spi.WithObjectIRI(actor1)))
+	require.NoError(t, err)
+
+	total, err := it.TotalItems()
+	require.NoError(t, err)
+	require.Equal(t, 2, total)
+}
+
+func TestStore_Stats(t *testing.T) {
+	t.Run("Mixed types", func(t *testing.T) {
+		s := New("service1")
+		require.NotNil(t, s)
+
+		require.Empty(t, s.Stats())
+
+		for _, a := range newMockActivities(vocab.TypeCreate, 3) {
+			require.NoError(t, s.AddActivity(a))
+		}
+
+		announceActivities := newMockActivities(vocab.TypeAnnounce, 2)
+
+		for _, a := range announceActivities {
+			require.NoError(t, s.AddActivity(a))
+		}
+
+		require.NoError(t, s.AddActivity(vocab.NewFollowActivity(vocab.NewObjectProperty(),
+			vocab.WithID(testutil.MustParseURL("https://example.com/activities/follow1")))))
+
+		require.Equal(t, map[vocab.Type]int{
+			vocab.TypeCreate:   3,
+			vocab.TypeAnnounce: 2,
+			vocab.TypeFollow:   1,
+		}, s.Stats())
+
+		for _, a := range announceActivities {
+			require.NoError(t, s.DeleteActivity(a.ID().URL()))
+		}
+
+		require.Equal(t, map[vocab.Type]int{
+			vocab.TypeCreate: 3,
+			vocab.TypeFollow: 1,
+		}, s.Stats())
+	})
+
+	t.Run("Concurrent add and delete", func(t *testing.T) {
+		s := New("service1")
+		require.NotNil(t, s)
+
+		const n = 100
+
+		createActivities := newMockActivities(vocab.TypeCreate, n)
+		announceActivities := newMockActivities(vocab.TypeAnnounce, n)
+
+		for _, a := range announceActivities {
+			require.NoError(t, s.AddActivity(a))
+		}
+
+		var wg sync.WaitGroup
+
+		wg.Add(2 * n)
+
+		for i := 0; i < n; i++ {
+			go func(a *vocab.ActivityType) {
+				defer wg.Done()
+
+				require.NoError(t, s.AddActivity(a))
+			}(createActivities[i])
+
+			go func(a *vocab.ActivityType) {
+				defer wg.Done()
+
+				require.NoError(t, s.DeleteActivity(a.ID().URL()))
+			}(announceActivities[i])
+		}
+
+		wg.Wait()
+
+		require.Equal(t, map[vocab.Type]int{vocab.TypeCreate: n}, s.Stats())
+	})
+}
+
 func TestStore_Reference(t *testing.T) {
 	s := New("service1")
 	require.NotNil(t, s)
@@ -160,6 +344,133 @@ func TestStore_ReferenceError(t *testing.T) {
 	})
 }
 
+func TestStore_ExportCollection(t *testing.T) {
+	s := New("service1")
+	require.NotNil(t, s)
+
+	actor1 := testutil.MustParseURL("https://actor1")
+	actor2 := testutil.MustParseURL("https://actor2")
+
+	followers := []*url.URL{
+		testutil.MustParseURL("https://follower_3"),
+		testutil.MustParseURL("https://follower_1"),
+		testutil.MustParseURL("https://follower_2"),
+	}
+
+	for _, follower := range followers {
+		require.NoError(t, s.AddReference(spi.Follower, actor1, follower))
+	}
+
+	require.NoError(t, s.AddReference(spi.Follower, actor2, testutil.MustParseURL("https://follower_4")))
+	require.NoError(t, s.AddReference(spi.Following, actor1, testutil.MustParseURL("https://following_1")))
+
+	t.Run("Success", func(t *testing.T) {
+		coll, err := s.ExportCollection(spi.Follower, actor1)
+		require.NoError(t, err)
+		require.NotNil(t, coll)
+		require.True(t, coll.Type().Is(vocab.TypeOrderedCollection))
+		require.Equal(t, len(followers), coll.TotalItems())
+
+		items := coll.Items()
+		require.Len(t, items, len(followers))
+
+		for i, item := range items {
+			require.Equal(t, followers[i].String(), item.IRI().String())
+		}
+
+		collBytes, err := json.Marshal(coll)
+		require.NoError(t, err)
+
+		coll2 := &vocab.OrderedCollectionType{}
+		require.NoError(t, json.Unmarshal(collBytes, coll2))
+		require.Equal(t, len(followers), coll2.TotalItems())
+		require.Len(t, coll2.Items(), len(followers))
+	})
+
+	t.Run("No references", func(t *testing.T) {
+		coll, err := s.ExportCollection(spi.Witness, actor1)
+		require.NoError(t, err)
+		require.Zero(t, coll.TotalItems())
+		require.Empty(t, coll.Items())
+	})
+
+	t.Run("Nil object IRI -> error", func(t *testing.T) {
+		_, err := s.ExportCollection(spi.Follower, nil)
+		require.EqualError(t, err, "nil object IRI")
+	})
+
+	t.Run("Unsupported reference type -> error", func(t *testing.T) {
+		_, err := s.ExportCollection("unknown", actor1)
+		require.EqualError(t, err, "unsupported reference type: unknown")
+	})
+}
+
+func TestStore_HasReference(t *testing.T) {
+	s := New("service1")
+	require.NotNil(t, s)
+
+	actor1 := testutil.MustParseURL("https://actor1")
+	actor2 := testutil.MustParseURL("https://actor2")
+	follower1 := testutil.MustParseURL("https://follower_1")
+	follower2 := testutil.MustParseURL("https://follower_2")
+
+	require.NoError(t, s.AddReference(spi.Follower, actor1, follower1))
+	require.NoError(t, s.AddReference(spi.Following, actor2, follower2))
+
+	t.Run("Present", func(t *testing.T) {
+		exists, err := s.HasReference(spi.Follower, actor1, follower1)
+		require.NoError(t, err)
+		require.True(t, exists)
+
+		// A different instance of the same URI.
+		exists, err = s.HasReference(spi.Follower, testutil.MustParseURL("https://actor1"),
+			testutil.MustParseURL("https://follower_1"))
+		require.NoError(t, err)
+		require.True(t, exists)
+	})
+
+	t.Run("Absent", func(t *testing.T) {
+		exists, err := s.HasReference(spi.Follower, actor1, follower2)
+		require.NoError(t, err)
+		require.False(t, exists)
+
+		exists, err = s.HasReference(spi.Follower, actor2, follower2)
+		require.NoError(t, err)
+		require.False(t, exists)
+
+		exists, err = s.HasReference(spi.Following, actor1, follower1)
+		require.NoError(t, err)
+		require.False(t, exists)
+	})
+
+	t.Run("Deleted", func(t *testing.T) {
+		require.NoError(t, s.AddReference(spi.Like, actor1, follower1))
+
+		exists, err := s.HasReference(spi.Like, actor1, follower1)
+		require.NoError(t, err)
+		require.True(t, exists)
+
+		require.NoError(t, s.DeleteReference(spi.Like, actor1, follower1))
+
+		exists, err = s.HasReference(spi.Like, actor1, follower1)
+		require.NoError(t, err)
+		require.False(t, exists)
+	})
+
+	t.Run("Nil IRI -> error", func(t *testing.T) {
+		_, err := s.HasReference(spi.Follower, nil, follower1)
+		require.EqualError(t, err, "nil object IRI")
+
+		_, err = s.HasReference(spi.Follower, actor1, nil)
+		require.EqualError(t, err, "nil reference IRI")
+	})
+
+	t.Run("Unsupported reference type -> error", func(t *testing.T) {
+		_, err := s.HasReference("unknown", actor1, follower1)
+		require.EqualError(t, err, "unsupported reference type: unknown")
+	})
+}
+
 func checkQueryResults(t *testing.T, it spi.ActivityIterator, expectedTypes ...*url.URL) {
 	t.Helper()
 
@@ -196,6 +507,350 @@ func checkRefQueryResults(t *testing.T, it spi.ReferenceIterator, expectedIRIs .
 	require.Nil(t, iri)
 }
 
+func TestStore_ReadYourWritesConsistency(t *testing.T) {
+	actor1 := testutil.MustParseURL("https://actor1")
+	actor2 := testutil.MustParseURL("https://actor2")
+	activityID1 := testutil.MustParseURL("https://example.com/activities/activity1")
+
+	consistent := spi.WithConsistency(spi.ConsistencyReadYourWrites)
+
+	t.Run("Add then query", func(t *testing.T) {
+		s := New("service1")
+
+		require.NoError(t, s.AddActivity(newMockActivity(vocab.TypeCreate, activityID1)))
+		require.NoError(t, s.AddReference(spi.Inbox, actor1, activityID1))
+
+		it, err := s.QueryActivities(spi.NewCriteria(spi.WithType(vocab.TypeCreate)), consistent)
+		require.NoError(t, err)
+
+		totalItems, err := it.TotalItems()
+		require.NoError(t, err)
+		require.Equal(t, 1, totalItems)
+
+		it, err = s.QueryActivities(spi.NewCriteria(spi.WithReferenceType(spi.Inbox), spi.WithObjectIRI(actor1)),
+			consistent)
+		require.NoError(t, err)
+
+		totalItems, err = it.TotalItems()
+		require.NoError(t, err)
+		require.Equal(t, 1, totalItems)
+
+		refIt, err := s.QueryReferences(spi.Inbox, spi.NewCriteria(spi.WithObjectIRI(actor1)), consistent)
+		require.NoError(t, err)
+
+		checkRefQueryResults(t, refIt, activityID1)
+	})
+
+	t.Run("Query waits for adds in progress", func(t *testing.T) {
+		s := New("service1")
+
+		// Simulate an add that's in progress.
+		s.addsInProgress.RLock()
+
+		queried := make(chan spi.ReferenceIterator)
+
+		go func() {
+			it, err := s.QueryReferences(spi.Follower, spi.NewCriteria(spi.WithObjectIRI(actor1)), consistent)
+			require.NoError(t, err)
+
+			queried <- it
+		}()
+
+		// A best-effort query doesn't wait.
+		it, err := s.QueryReferences(spi.Follower, spi.NewCriteria(spi.WithObjectIRI(actor1)))
+		require.NoError(t, err)
+
+		checkRefQueryResults(t, it)
+
+		select {
+		case <-queried:
+			t.Fatal("expecting query to wait for the add in progress")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		require.NoError(t, s.referenceStores[spi.Follower].add(actor1, actor2))
+
+		s.addsInProgress.RUnlock()
+
+		select {
+		case it := <-queried:
+			checkRefQueryResults(t, it, actor2)
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for query")
+		}
+	})
+
+	t.Run("Concurrent adds", func(t *testing.T) {
+		s := New("service1")
+
+		const n = 50
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+
+				require.NoError(t, s.AddReference(spi.Follower, actor1,
+					testutil.MustParseURL(fmt.Sprintf("https://actor_%d", i))))
+
+				it, err := s.QueryReferences(spi.Follower, spi.NewCriteria(spi.WithObjectIRI(actor1)), consistent)
+				require.NoError(t, err)
+
+				refs, err := storeutil.ReadReferences(it, -1)
+				require.NoError(t, err)
+				require.True(t, containsIRI(refs, testutil.MustParseURL(fmt.Sprintf("https://actor_%d", i))))
+			}(i)
+		}
+
+		wg.Wait()
+	})
+}
+
+func TestStore_QueryByTypeIndex(t *testing.T) {
+	types := []vocab.Type{
+		vocab.TypeCreate, vocab.TypeAnnounce, vocab.TypeLike, vocab.TypeFollow, vocab.TypeOffer,
+	}
+
+	s := New("service1")
+
+	var ids []*url.URL
+
+	for i := 0; i < 100; i++ {
+		id := testutil.MustParseURL(fmt.Sprintf("https://example.com/activities/activity%d", i))
+
+		ids = append(ids, id)
+
+		if i%7 == 0 {
+			// An activity with multiple types.
+			a := &vocab.ActivityType{}
+			require.NoError(t, json.Unmarshal([]byte(fmt.Sprintf(`{"id":"%s","type":["%s","%s"]}`,
+				id, types[i%len(types)], types[(i+1)%len(types)])), a))
+
+			require.NoError(t, s.AddActivity(a))
+
+			continue
+		}
+
+		require.NoError(t, s.AddActivity(newMockActivity(types[i%len(types)], id)))
+	}
+
+	// Re-add an activity and delete some activities so that the index is updated.
+	a, err := s.GetActivity(ids[3])
+	require.NoError(t, err)
+	require.NoError(t, s.AddActivity(a))
+
+	for i := 0; i < len(ids); i += 9 {
+		require.NoError(t, s.DeleteActivity(ids[i]))
+	}
+
+	queries := [][]vocab.Type{
+		{vocab.TypeCreate},
+		{vocab.TypeLike},
+		{vocab.TypeCreate, vocab.TypeAnnounce},
+		{vocab.TypeAnnounce, vocab.TypeCreate, vocab.TypeAnnounce},
+		{vocab.TypeLike, vocab.TypeFollow, vocab.TypeOffer},
+		types,
+		{vocab.TypeUndo},
+	}
+
+	for _, queryTypes := range queries {
+		for _, opts := range [][]spi.QueryOpt{
+			nil,
+			{spi.WithSortOrder(spi.SortDescending)},
+			{spi.WithPageSize(5), spi.WithPageNum(1)},
+			{spi.WithSortField(spi.SortByID)},
+		} {
+			query := spi.NewCriteria(spi.WithType(queryTypes...))
+
+			expected, expectedTotal := activityQueryResults(s.activityStore.activities).filter(query, opts...)
+
+			it := s.activityStore.query(query, opts...)
+
+			totalItems, err := it.TotalItems()
+			require.NoError(t, err)
+			require.Equalf(t, expectedTotal, totalItems, "types: %s", queryTypes)
+
+			var actual []*vocab.ActivityType
+
+			for {
+				a, err := it.Next()
+				if errors.Is(err, spi.ErrNotFound) {
+					break
+				}
+
+				require.NoError(t, err)
+
+				actual = append(actual, a)
+			}
+
+			require.Equalf(t, expected, actual, "types: %s", queryTypes)
+		}
+	}
+
+	s.Clear()
+
+	it, err := s.QueryActivities(spi.NewCriteria(spi.WithType(vocab.TypeCreate)))
+	require.NoError(t, err)
+
+	totalItems, err := it.TotalItems()
+	require.NoError(t, err)
+	require.Zero(t, totalItems)
+}
+
+func BenchmarkStore_QueryActivitiesByType(b *testing.B) {
+	types := []vocab.Type{
+		vocab.TypeCreate, vocab.TypeAnnounce, vocab.TypeLike, vocab.TypeFollow, vocab.TypeOffer,
+		vocab.TypeAccept, vocab.TypeReject, vocab.TypeInvite, vocab.TypeUndo,
+	}
+
+	s := New("service1")
+
+	for i := 0; i < 10000; i++ {
+		require.NoError(b, s.AddActivity(newMockActivity(types[i%len(types)],
+			testutil.MustParseURL(fmt.Sprintf("https://example.com/activities/activity%d", i)))))
+	}
+
+	query := spi.NewCriteria(spi.WithType(vocab.TypeLike, vocab.TypeUndo))
+
+	b.Run("scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			activityQueryResults(s.activityStore.activities).filter(query)
+		}
+	})
+
+	b.Run("type index", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, err := s.QueryActivities(query)
+			require.NoError(b, err)
+		}
+	})
+}
+
+func TestStore_ExportImportActivities(t *testing.T) {
+	s := New("service1")
+
+	published := time.Now().Truncate(time.Second)
+
+	for i := 0; i < 20; i++ {
+		id := testutil.MustParseURL(fmt.Sprintf("https://example.com/activities/activity%d", i))
+
+		// Published times aren't in the same order as the activities were added.
+		publishedTime := published.Add(time.Duration((i*7)%20) * time.Minute)
+
+		var a *vocab.ActivityType
+
+		if i%3 == 0 {
+			a = vocab.NewAnnounceActivity(vocab.NewObjectProperty(vocab.WithIRI(id)), vocab.WithID(id),
+				vocab.WithPublishedTime(&publishedTime))
+		} else {
+			a = vocab.NewCreateActivity(vocab.NewObjectProperty(vocab.WithIRI(id)), vocab.WithID(id),
+				vocab.WithPublishedTime(&publishedTime))
+		}
+
+		require.NoError(t, s.AddActivity(a))
+	}
+
+	require.NoError(t, s.DeleteActivity(testutil.MustParseURL("https://example.com/activities/activity5")))
+
+	exported := s.ExportActivities()
+	require.Len(t, exported, 19)
+
+	// Serialize each exported activity and shuffle the serialized order.
+	var serialized [][]byte
+
+	for _, a := range exported {
+		b, err := json.Marshal(a)
+		require.NoError(t, err)
+
+		serialized = append(serialized, b)
+	}
+
+	rand.New(rand.NewSource(1)).Shuffle(len(serialized), func(i, j int) { //nolint:gosec
+		serialized[i], serialized[j] = serialized[j], serialized[i]
+	})
+
+	var imported []*ExportedActivity
+
+	for _, b := range serialized {
+		a := &ExportedActivity{}
+		require.NoError(t, json.Unmarshal(b, a))
+
+		imported = append(imported, a)
+	}
+
+	s2 := New("service2")
+
+	// Existing activities are replaced.
+	require.NoError(t, s2.AddActivity(newMockActivity(vocab.TypeCreate,
+		testutil.MustParseURL("https://example.com/activities/other"))))
+
+	require.NoError(t, s2.ImportActivities(imported))
+
+	queryIDs := func(t *testing.T, store *Store, query *spi.Criteria, opts ...spi.QueryOpt) []string {
+		t.Helper()
+
+		it, err := store.QueryActivities(query, opts...)
+		require.NoError(t, err)
+
+		activities, err := storeutil.ReadActivities(it, -1)
+		require.NoError(t, err)
+
+		ids := make([]string, len(activities))
+
+		for i, a := range activities {
+			ids[i] = a.ID().String()
+		}
+
+		return ids
+	}
+
+	for _, tc := range []struct {
+		query *spi.Criteria
+		opts  []spi.QueryOpt
+	}{
+		{query: spi.NewCriteria()},
+		{query: spi.NewCriteria(), opts: []spi.QueryOpt{spi.WithSortOrder(spi.SortDescending)}},
+		{query: spi.NewCriteria(spi.WithType(vocab.TypeAnnounce))},
+		{query: spi.NewCriteria(spi.WithType(vocab.TypeCreate, vocab.TypeAnnounce))},
+		{query: spi.NewCriteria(), opts: []spi.QueryOpt{spi.WithSortField(spi.SortByPublished)}},
+		{query: spi.NewCriteria(), opts: []spi.QueryOpt{spi.WithPageSize(4), spi.WithPageNum(2)}},
+	} {
+		expected := queryIDs(t, s, tc.query, tc.opts...)
+		require.NotEmpty(t, expected)
+		require.Equal(t, expected, queryIDs(t, s2, tc.query, tc.opts...))
+	}
+
+	require.Equal(t, s.Stats(), s2.Stats())
+
+	exported2 := s2.ExportActivities()
+	require.Len(t, exported2, len(exported))
+
+	for i, a := range exported {
+		require.Equal(t, a.Sequence, exported2[i].Sequence)
+		require.Equal(t, a.Activity.ID().String(), exported2[i].Activity.ID().String())
+	}
+
+	// Activities added after the import are ordered after the imported activities.
+	id := testutil.MustParseURL("https://example.com/activities/new")
+
+	require.NoError(t, s2.AddActivity(newMockActivity(vocab.TypeCreate, id)))
+
+	ids := queryIDs(t, s2, spi.NewCriteria())
+	require.Len(t, ids, 20)
+	require.Equal(t, id.String(), ids[19])
+
+	t.Run("invalid exported activity", func(t *testing.T) {
+		require.EqualError(t, s2.ImportActivities([]*ExportedActivity{{Sequence: 1}}),
+			"invalid exported activity: activity and activity ID are required")
+
+		// The store isn't modified.
+		require.Len(t, s2.ExportActivities(), 20)
+	})
+}
+
 func TestActivityQueryResults(t *testing.T) {
 	createActivities := newMockActivities(vocab.TypeCreate, 7)
 	announceActivities := newMockActivities(vocab.TypeAnnounce, 3)
@@ -260,6 +915,88 @@ func TestActivityQueryResults(t *testing.T) {
 	require.True(t, filtered[2] == results[9])
 }
 
+func TestActivityQueryResults_SortField(t *testing.T) {
+	now := time.Now()
+
+	newActivity := func(id string, published time.Time) *vocab.ActivityType {
+		return vocab.NewCreateActivity(vocab.NewObjectProperty(),
+			vocab.WithID(testutil.MustParseURL(id)), vocab.WithPublishedTime(&published))
+	}
+
+	// Inserted out of chronological order.
+	a1 := newActivity("https://example.com/activities/c", now.Add(2*time.Minute))
+	a2 := newActivity("https://example.com/activities/a", now)
+	a3 := newActivity("https://example.com/activities/b", now.Add(time.Minute))
+
+	s := New("service1")
+
+	require.NoError(t, s.AddActivity(a1))
+	require.NoError(t, s.AddActivity(a2))
+	require.NoError(t, s.AddActivity(a3))
+
+	t.Run("Default (insertion order)", func(t *testing.T) {
+		it, err := s.QueryActivities(spi.NewCriteria())
+		require.NoError(t, err)
+
+		checkOrderedQueryResults(t, it, a1, a2, a3)
+	})
+
+	t.Run("By published time - ascending", func(t *testing.T) {
+		it, err := s.QueryActivities(spi.NewCriteria(), spi.WithSortField(spi.SortByPublished))
+		require.NoError(t, err)
+
+		checkOrderedQueryResults(t, it, a2, a3, a1)
+	})
+
+	t.Run("By published time - descending", func(t *testing.T) {
+		it, err := s.QueryActivities(spi.NewCriteria(), spi.WithSortField(spi.SortByPublished),
+			spi.WithSortOrder(spi.SortDescending))
+		require.NoError(t, err)
+
+		checkOrderedQueryResults(t, it, a1, a3, a2)
+	})
+
+	t.Run("By published time - with paging", func(t *testing.T) {
+		it, err := s.QueryActivities(spi.NewCriteria(), spi.WithSortField(spi.SortByPublished),
+			spi.WithPageSize(2), spi.WithPageNum(1))
+		require.NoError(t, err)
+
+		checkOrderedQueryResults(t, it, a1)
+	})
+
+	t.Run("By ID", func(t *testing.T) {
+		it, err := s.QueryActivities(spi.NewCriteria(), spi.WithSortField(spi.SortByID))
+		require.NoError(t, err)
+
+		checkOrderedQueryResults(t, it, a2, a3, a1)
+	})
+
+	t.Run("No published time", func(t *testing.T) {
+		a4 := vocab.NewCreateActivity(vocab.NewObjectProperty(),
+			vocab.WithID(testutil.MustParseURL("https://example.com/activities/d")))
+
+		results := activityQueryResults{a1, a2, a4, a3}
+
+		filtered, totalItems := results.filter(spi.NewCriteria(), spi.WithSortField(spi.SortByPublished))
+		require.Equal(t, 4, totalItems)
+		require.Equal(t, []*vocab.ActivityType{a4, a2, a3, a1}, filtered)
+	})
+}
+
+func checkOrderedQueryResults(t *testing.T, it spi.ActivityIterator, expected ...*vocab.ActivityType) {
+	t.Helper()
+
+	for _, e := range expected {
+		a, err := it.Next()
+		require.NoError(t, err)
+		require.Equal(t, e.ID().String(), a.ID().String())
+	}
+
+	a, err := it.Next()
+	require.True(t, errors.Is(err, spi.ErrNotFound))
+	require.Nil(t, a)
+}
+
 func TestReferenceQueryResults(t *testing.T) {
 	results := refQueryResults(testutil.NewMockURLs(10, func(i int) string {
 		return fmt.Sprintf("https://ref_%d", i)
@@ -350,3 +1087,125 @@ func newMockActivity(t vocab.Type, id *url.URL) *vocab.ActivityType {
 
 	return vocab.NewCreateActivity(vocab.NewObjectProperty(), vocab.WithID(id))
 }
+
+func TestStore_DeleteReferencesForUndo(t *testing.T) {
+	var (
+		service1 = testutil.MustParseURL("https://example.com/services/service1")
+		service2 = testutil.MustParseURL("https://example.com/services/service2")
+		service3 = testutil.MustParseURL("https://example.com/services/service3")
+		anchor1  = testutil.MustParseURL("hl:uEiCsFp-ft8tI1DFGbXs78tw-HS561mMPa3Z6GsGAHElrNQ")
+	)
+
+	newUndo := func(activity *vocab.ActivityType) *vocab.ActivityType {
+		return vocab.NewUndoActivity(
+			vocab.NewObjectProperty(vocab.WithActivity(activity)),
+			vocab.WithID(testutil.MustParseURL("https://example.com/activities/undo")),
+			vocab.WithActor(activity.Actor()),
+		)
+	}
+
+	getRefs := func(t *testing.T, s *Store, refType spi.ReferenceType, objectIRI *url.URL) []*url.URL {
+		t.Helper()
+
+		it, err := s.QueryReferences(refType, spi.NewCriteria(spi.WithObjectIRI(objectIRI)))
+		require.NoError(t, err)
+
+		refs, err := storeutil.ReadReferences(it, -1)
+		require.NoError(t, err)
+
+		return refs
+	}
+
+	t.Run("Undo Follow", func(t *testing.T) {
+		s := New("service1")
+
+		follow := vocab.NewFollowActivity(
+			vocab.NewObjectProperty(vocab.WithIRI(service2)),
+			vocab.WithID(testutil.MustParseURL("https://example.com/activities/follow")),
+			vocab.WithActor(service1),
+		)
+
+		require.NoError(t, s.AddReference(spi.Following, service1, service2))
+		require.NoError(t, s.AddReference(spi.Following, service1, service3))
+
+		require.NoError(t, s.DeleteReferencesForUndo(newUndo(follow)))
+
+		refs := getRefs(t, s, spi.Following, service1)
+		require.Len(t, refs, 1)
+		require.Equal(t, service3.String(), refs[0].String())
+	})
+
+	t.Run("Undo Like", func(t *testing.T) {
+		s := New("service1")
+
+		likeID := testutil.MustParseURL("https://example.com/activities/like")
+
+		like := vocab.NewLikeActivity(
+			vocab.NewObjectProperty(
+				vocab.WithAnchorEvent(vocab.NewAnchorEvent(nil, vocab.WithURL(anchor1))),
+			),
+			vocab.WithID(likeID),
+			vocab.WithActor(service2),
+		)
+
+		require.NoError(t, s.AddReference(spi.Like, anchor1, likeID))
+		require.Len(t, getRefs(t, s, spi.Like, anchor1), 1)
+
+		require.NoError(t, s.DeleteReferencesForUndo(newUndo(like)))
+		require.Empty(t, getRefs(t, s, spi.Like, anchor1))
+	})
+
+	t.Run("Undo Like -> activity resolved from store", func(t *testing.T) {
+		s := New("service1")
+
+		likeID := testutil.MustParseURL("https://example.com/activities/like")
+
+		like := vocab.NewLikeActivity(
+			vocab.NewObjectProperty(
+				vocab.WithAnchorEvent(vocab.NewAnchorEvent(nil, vocab.WithURL(anchor1))),
+			),
+			vocab.WithID(likeID),
+			vocab.WithActor(service2),
+		)
+
+		require.NoError(t, s.AddActivity(like))
+		require.NoError(t, s.AddReference(spi.Like, anchor1, likeID))
+
+		// The 'Undo' only references the 'Like' by ID.
+		undo := vocab.NewUndoActivity(
+			vocab.NewObjectProperty(vocab.WithActivity(vocab.NewLikeActivity(nil, vocab.WithID(likeID)))),
+			vocab.WithActor(service2),
+		)
+
+		require.NoError(t, s.DeleteReferencesForUndo(undo))
+		require.Empty(t, getRefs(t, s, spi.Like, anchor1))
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		s := New("service1")
+
+		follow := vocab.NewFollowActivity(nil,
+			vocab.WithID(testutil.MustParseURL("https://example.com/activities/follow")),
+			vocab.WithActor(service1),
+		)
+
+		err := s.DeleteReferencesForUndo(follow)
+		require.EqualError(t, err, "expecting an 'Undo' activity")
+
+		err = s.DeleteReferencesForUndo(vocab.NewUndoActivity(vocab.NewObjectProperty()))
+		require.EqualError(t, err, "no activity specified in 'object' field of the 'Undo' activity")
+
+		err = s.DeleteReferencesForUndo(newUndo(vocab.NewLikeActivity(vocab.NewObjectProperty(),
+			vocab.WithID(testutil.MustParseURL("https://example.com/activities/like")),
+			vocab.WithActor(service2),
+		)))
+		require.EqualError(t, err, "no anchor reference in the 'Like' activity")
+
+		err = s.DeleteReferencesForUndo(newUndo(vocab.NewAnnounceActivity(vocab.NewObjectProperty(),
+			vocab.WithID(testutil.MustParseURL("https://example.com/activities/announce")),
+			vocab.WithActor(service2),
+		)))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported activity type in 'Undo'")
+	})
+}
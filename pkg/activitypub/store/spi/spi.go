@@ -79,11 +79,40 @@ const (
 	SortDescending
 )
 
+// SortField specifies the field by which query results are sorted.
+type SortField string
+
+const (
+	// SortByInsertionOrder indicates that the query results are sorted in the order in which they were added
+	// to the store. This is the default.
+	SortByInsertionOrder SortField = ""
+	// SortByPublished indicates that activity query results are sorted by the time that the activity
+	// was published (created). Activities without a published time are sorted before all others.
+	SortByPublished SortField = "published"
+	// SortByID indicates that activity query results are sorted by activity ID.
+	SortByID SortField = "id"
+)
+
+// Consistency specifies the read consistency of a query.
+type Consistency int
+
+const (
+	// ConsistencyBestEffort indicates that the query returns the data that's visible in the store at the time
+	// the query is executed. Writes that are in progress at that time may not be included in the results.
+	// This is the default since it's the fastest.
+	ConsistencyBestEffort Consistency = iota
+	// ConsistencyReadYourWrites indicates that the query waits for writes that are in progress at the time
+	// the query is executed so that they're included in the results.
+	ConsistencyReadYourWrites
+)
+
 // QueryOptions holds options for a query.
 type QueryOptions struct {
-	PageNumber int
-	PageSize   int
-	SortOrder  SortOrder
+	PageNumber  int
+	PageSize    int
+	SortOrder   SortOrder
+	SortField   SortField
+	Consistency Consistency
 }
 
 // QueryOpt sets a query option.
@@ -110,6 +139,22 @@ func WithSortOrder(sortOrder SortOrder) QueryOpt {
 	}
 }
 
+// WithSortField sets the field by which activity query results are sorted. (Default is insertion order.)
+// Note that stores which don't support the given field return results in insertion order.
+func WithSortField(sortField SortField) QueryOpt {
+	return func(options *QueryOptions) {
+		options.SortField = sortField
+	}
+}
+
+// WithConsistency sets the read consistency of the query. (Default is ConsistencyBestEffort.)
+// Not all store implementations support this option.
+func WithConsistency(consistency Consistency) QueryOpt {
+	return func(options *QueryOptions) {
+		options.Consistency = consistency
+	}
+}
+
 // RefMetadata holds additional metadata to be stored in a reference entry.
 type RefMetadata struct {
 	ActivityType vocab.Type
@@ -563,6 +563,18 @@ func TestWitnessProofHandler(t *testing.T) {
 		err = aeStore.Put(al)
 		require.NoError(t, err)
 
+		witnessStore := &mocks.WitnessStore{}
+		witnessStore.GetReturns(
+			[]*proofapi.WitnessProof{
+				{
+					Witness: &proofapi.Witness{
+						Type: proofapi.WitnessTypeSystem,
+						URI:  vocab.NewURLProperty(witness1IRI),
+					},
+					Proof: []byte(witnessProofJSONWebSignature),
+				},
+			}, nil)
+
 		witnessPolicy, err := policy.New(configStore, defaultPolicyCacheExpiry)
 		require.NoError(t, err)
 
@@ -574,7 +586,7 @@ func TestWitnessProofHandler(t *testing.T) {
 			AnchorLinkStore: aeStore,
 			StatusStore:     mockStatusStore,
 
-			WitnessStore:  &mocks.WitnessStore{},
+			WitnessStore:  witnessStore,
 			WitnessPolicy: witnessPolicy,
 			Metrics:       &orbmocks.MetricsProvider{},
 			DocLoader:     testutil.GetLoader(t),
@@ -602,6 +614,18 @@ func TestWitnessProofHandler(t *testing.T) {
 		err = aeStore.Put(al)
 		require.NoError(t, err)
 
+		witnessStore := &mocks.WitnessStore{}
+		witnessStore.GetReturns(
+			[]*proofapi.WitnessProof{
+				{
+					Witness: &proofapi.Witness{
+						Type: proofapi.WitnessTypeSystem,
+						URI:  vocab.NewURLProperty(witness1IRI),
+					},
+					Proof: []byte(witnessProofJSONWebSignature),
+				},
+			}, nil)
+
 		witnessPolicy, err := policy.New(configStore, defaultPolicyCacheExpiry)
 		require.NoError(t, err)
 
@@ -612,7 +636,7 @@ func TestWitnessProofHandler(t *testing.T) {
 			AnchorLinkStore: aeStore,
 			StatusStore:     mockStatusStore,
 
-			WitnessStore:  &mocks.WitnessStore{},
+			WitnessStore:  witnessStore,
 			WitnessPolicy: witnessPolicy,
 			Metrics:       &orbmocks.MetricsProvider{},
 			DocLoader:     testutil.GetLoader(t),
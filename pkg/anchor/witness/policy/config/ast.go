@@ -0,0 +1,174 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AST is the machine-readable form of a parsed witness policy, e.g.
+//
+//	{"operator":"AND","rules":[{"type":"OutOf","args":["1","batch"]},{"type":"MinPercent","args":["50","system"]}]}
+//
+// which is equivalent to the policy "OutOf(1,batch) AND MinPercent(50,system)".
+type AST struct {
+	// Operator is the operator (AND or OR) that combines the batch and system rules.
+	Operator string `json:"operator"`
+	// Rules contains the policy rules.
+	Rules []*Rule `json:"rules,omitempty"`
+}
+
+// Rule is a single policy rule, e.g. OutOf(1,batch) is {"type":"OutOf","args":["1","batch"]}.
+type Rule struct {
+	Type string   `json:"type"`
+	Args []string `json:"args,omitempty"`
+}
+
+func (r *Rule) String() string {
+	if len(r.Args) == 0 {
+		return r.Type
+	}
+
+	return fmt.Sprintf("%s(%s)", r.Type, strings.Join(r.Args, ","))
+}
+
+// AST returns the machine-readable form of the parsed policy. Rules that are equal to the defaults
+// (i.e. 100% of batch and system witnesses) are omitted.
+func (wp *WitnessPolicyConfig) AST() *AST {
+	var rules []*Rule
+
+	rules = append(rules, roleRules(RoleBatch, wp.MinNumberBatch, wp.MinPercentBatch, wp.WithinBatch)...)
+	rules = append(rules, roleRules(RoleSystem, wp.MinNumberSystem, wp.MinPercentSystem, wp.WithinSystem)...)
+
+	if wp.LogRequired {
+		rules = append(rules, &Rule{Type: LogRequired})
+	}
+
+	if len(wp.RequiredWitnesses) > 0 {
+		rules = append(rules, &Rule{Type: RequireFrom, Args: append([]string(nil), wp.RequiredWitnesses...)})
+	}
+
+	if wp.MinTypes > 0 {
+		rules = append(rules, &Rule{Type: Spread, Args: []string{strconv.Itoa(wp.MinTypes)}})
+	}
+
+	return &AST{
+		Operator: wp.Operator,
+		Rules:    rules,
+	}
+}
+
+// MarshalAST serializes the parsed policy to JSON (see AST).
+func (wp *WitnessPolicyConfig) MarshalAST() ([]byte, error) {
+	return json.Marshal(wp.AST())
+}
+
+// Policy returns the policy string for the parsed policy. The returned policy is equivalent to (but not
+// necessarily the same as) the policy string that was parsed.
+func (wp *WitnessPolicyConfig) Policy() string {
+	return wp.AST().Policy()
+}
+
+// Policy returns the policy string for the AST. The operator is placed between the batch and system rules
+// (if any) and the remaining rules are appended.
+func (a *AST) Policy() string {
+	var batchRules, systemRules, otherRules []string
+
+	for _, r := range a.Rules {
+		switch r.role() {
+		case RoleBatch:
+			batchRules = append(batchRules, r.String())
+		case RoleSystem:
+			systemRules = append(systemRules, r.String())
+		default:
+			otherRules = append(otherRules, r.String())
+		}
+	}
+
+	tokens := batchRules
+
+	// AND is the default operator so it's only required if rules for both roles are specified.
+	if a.Operator == OR || (a.Operator != "" && len(batchRules) > 0 && len(systemRules) > 0) {
+		tokens = append(tokens, a.Operator)
+	}
+
+	tokens = append(tokens, systemRules...)
+	tokens = append(tokens, otherRules...)
+
+	return strings.Join(tokens, " ")
+}
+
+// ParseAST parses a policy from its machine-readable form (see AST).
+func ParseAST(data []byte) (*WitnessPolicyConfig, error) {
+	ast := &AST{}
+
+	if err := json.Unmarshal(data, ast); err != nil {
+		return nil, fmt.Errorf("unmarshal policy AST: %w", err)
+	}
+
+	switch ast.Operator {
+	case "", AND, OR:
+	default:
+		return nil, fmt.Errorf("operator not supported: %s", ast.Operator)
+	}
+
+	for _, r := range ast.Rules {
+		switch r.Type {
+		case OutOf, OutOfWithin, MinPercent, RequireFrom, Spread, LogRequired:
+		default:
+			return nil, fmt.Errorf("rule not supported: %s", r.Type)
+		}
+
+		for _, arg := range r.Args {
+			if arg == "" || strings.ContainsAny(arg, " ,()") {
+				return nil, fmt.Errorf("invalid argument '%s' for %s rule", arg, r.Type)
+			}
+		}
+	}
+
+	return Parse(ast.Policy())
+}
+
+// role returns the role (batch or system) that the rule applies to or an empty string if the rule
+// doesn't apply to a role.
+func (r *Rule) role() string {
+	switch r.Type {
+	case OutOf, OutOfWithin, MinPercent:
+		const roleArgIdx = 1
+
+		if len(r.Args) > roleArgIdx {
+			return r.Args[roleArgIdx]
+		}
+	}
+
+	return ""
+}
+
+// roleRules returns the rules that produce the given minimum number/percent for the given role.
+func roleRules(role string, minNumber, minPercent int, within time.Duration) []*Rule {
+	var rules []*Rule
+
+	switch {
+	case minNumber > 0 && within > 0:
+		rules = append(rules, &Rule{Type: OutOfWithin, Args: []string{strconv.Itoa(minNumber), role, within.String()}})
+	case minNumber > 0:
+		rules = append(rules, &Rule{Type: OutOf, Args: []string{strconv.Itoa(minNumber), role}})
+	case minPercent == 0:
+		// OutOf(0,role) sets both the minimum number and the minimum percent to zero.
+		return []*Rule{{Type: OutOf, Args: []string{"0", role}}}
+	}
+
+	if minPercent != maxPercent {
+		rules = append(rules, &Rule{Type: MinPercent, Args: []string{strconv.Itoa(minPercent), role}})
+	}
+
+	return rules
+}
@@ -0,0 +1,100 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAST_RoundTrip(t *testing.T) {
+	policies := []string{
+		"",
+		"OutOf(2,system)",
+		"OutOf(1,batch) AND OutOf(2,system)",
+		"MinPercent(50,batch) OR OutOfWithin(1,system,1h0m0s)",
+		"OutOf(0,batch) AND MinPercent(30,system) LogRequired",
+		"OutOf(2,batch) MinPercent(0,batch) AND OutOf(0,system)",
+		"OutOf(1,system) RequireFrom(https://w1.com/services/orb,https://w2.com/services/orb)",
+		"OutOf(1,batch) OR OutOf(1,system) Spread(2)",
+		"OR",
+	}
+
+	for _, policy := range policies {
+		t.Run(policy, func(t *testing.T) {
+			cfg, err := Parse(policy)
+			require.NoError(t, err)
+
+			astBytes, err := cfg.MarshalAST()
+			require.NoError(t, err)
+
+			cfg2, err := ParseAST(astBytes)
+			require.NoError(t, err)
+
+			require.Equal(t, policy, cfg2.Policy())
+			require.Equal(t, cfg.String(), cfg2.String())
+		})
+	}
+}
+
+func TestAST_Marshal(t *testing.T) {
+	cfg, err := Parse("OutOf(1,batch) OR MinPercent(50,system) LogRequired")
+	require.NoError(t, err)
+
+	astBytes, err := cfg.MarshalAST()
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"operator":"OR",
+		"rules":[
+			{"type":"OutOf","args":["1","batch"]},
+			{"type":"MinPercent","args":["50","system"]},
+			{"type":"LogRequired"}
+		]
+	}`, string(astBytes))
+}
+
+func TestParseAST(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		cfg, err := ParseAST([]byte(`{"rules":[{"type":"MinPercent","args":["20","system"]},{"type":"OutOf","args":["3","batch"]}]}`))
+		require.NoError(t, err)
+		require.Equal(t, AND, cfg.Operator)
+		require.Equal(t, 20, cfg.MinPercentSystem)
+		require.Equal(t, 3, cfg.MinNumberBatch)
+		require.Equal(t, "OutOf(3,batch) AND MinPercent(20,system)", cfg.Policy())
+	})
+
+	t.Run("Invalid JSON", func(t *testing.T) {
+		_, err := ParseAST([]byte(`{`))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unmarshal policy AST")
+	})
+
+	t.Run("Invalid operator", func(t *testing.T) {
+		_, err := ParseAST([]byte(`{"operator":"XOR"}`))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "operator not supported: XOR")
+	})
+
+	t.Run("Invalid rule", func(t *testing.T) {
+		_, err := ParseAST([]byte(`{"rules":[{"type":"AtLeast","args":["1","batch"]}]}`))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "rule not supported: AtLeast")
+	})
+
+	t.Run("Invalid argument", func(t *testing.T) {
+		_, err := ParseAST([]byte(`{"rules":[{"type":"OutOf","args":["1","batch) OR OutOf(0"]}]}`))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid argument")
+	})
+
+	t.Run("Invalid rule arguments", func(t *testing.T) {
+		_, err := ParseAST([]byte(`{"rules":[{"type":"OutOf","args":["x","batch"]}]}`))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "first argument for OutOf policy must be an integer")
+	})
+}
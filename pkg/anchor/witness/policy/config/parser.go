@@ -8,8 +8,12 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/trustbloc/orb/pkg/anchor/witness/proof"
 )
 
 // WitnessPolicyConfig parses witness policy.
@@ -24,13 +28,32 @@ type WitnessPolicyConfig struct {
 	Operator    string
 
 	LogRequired bool
+
+	// WithinSystem and WithinBatch are the trailing time windows (set by the OutOfWithin rule) in which
+	// a witness proof must have been created in order to be counted. Zero means that proofs are counted
+	// regardless of when they were created.
+	WithinSystem time.Duration
+	WithinBatch  time.Duration
+
+	// RequiredWitnesses (set by the RequireFrom rule) is a set of witness URIs, at least one of which must
+	// have provided a proof (regardless of witness type) in order for the policy to be satisfied. This
+	// requirement applies in addition to the other rules, regardless of the operator.
+	RequiredWitnesses []string
+
+	// MinTypes (set by the Spread rule) is the minimum number of distinct witness types (batch and system) from
+	// which proofs must be collected in order for the policy to be satisfied. This requirement applies in addition
+	// to the other rules, regardless of the operator, so that witnessing isn't concentrated in one type.
+	MinTypes int
 }
 
 // Gate values.
 const (
 	OutOf       = "OutOf"
+	OutOfWithin = "OutOfWithin"
 	MinPercent  = "MinPercent"
 	LogRequired = "LogRequired"
+	RequireFrom = "RequireFrom"
+	Spread      = "Spread"
 
 	AND = "AND"
 	OR  = "OR"
@@ -42,7 +65,12 @@ const (
 	RoleSystem = "system"
 )
 
-const maxPercent = 100
+const (
+	maxPercent = 100
+
+	// numWitnessTypes is the number of witness types (batch and system).
+	numWitnessTypes = 2
+)
 
 type operatorFnc func(a, b bool) bool
 
@@ -74,6 +102,11 @@ func Parse(policy string) (*WitnessPolicyConfig, error) {
 
 func (wp *WitnessPolicyConfig) processToken(token string) error {
 	switch t := token; {
+	case strings.HasPrefix(t, OutOfWithin):
+		err := wp.processOutOfWithin(token)
+		if err != nil {
+			return err
+		}
 	case strings.HasPrefix(t, OutOf):
 		err := wp.processOutOf(token)
 		if err != nil {
@@ -84,6 +117,16 @@ func (wp *WitnessPolicyConfig) processToken(token string) error {
 		if err != nil {
 			return err
 		}
+	case strings.HasPrefix(t, RequireFrom):
+		err := wp.processRequireFrom(token)
+		if err != nil {
+			return err
+		}
+	case strings.HasPrefix(t, Spread):
+		err := wp.processSpread(token)
+		if err != nil {
+			return err
+		}
 	case t == LogRequired:
 		wp.LogRequired = true
 	case t == AND:
@@ -141,6 +184,41 @@ func (wp *WitnessPolicyConfig) processOutOf(token string) error {
 	return nil
 }
 
+// processOutOfWithin processes the OutOfWithin rule (e.g. OutOfWithin(2,system,1h) means that proofs
+// from at least 2 system witnesses, created within the last hour, are required).
+func (wp *WitnessPolicyConfig) processOutOfWithin(token string) error {
+	insideBrackets := token[len(OutOfWithin)+1 : len(token)-1]
+
+	args := strings.Split(insideBrackets, ",")
+
+	const outOfWithinArgsNo = 3
+	if len(args) != outOfWithinArgsNo {
+		return fmt.Errorf("expected 3 but got %d arguments for OutOfWithin policy", len(args))
+	}
+
+	within, err := time.ParseDuration(args[2])
+	if err != nil {
+		return fmt.Errorf("third argument for OutOfWithin policy must be a duration: %w", err)
+	}
+
+	if within <= 0 {
+		return fmt.Errorf("third argument[%s] for OutOfWithin policy rule must be a positive duration", args[2])
+	}
+
+	err = wp.processOutOf(fmt.Sprintf("%s(%s,%s)", OutOf, args[0], args[1]))
+	if err != nil {
+		return err
+	}
+
+	if args[1] == RoleSystem {
+		wp.WithinSystem = within
+	} else {
+		wp.WithinBatch = within
+	}
+
+	return nil
+}
+
 // processMinPercent will process minimum percent rule.
 // e.g. MinPercent(0.2,system) rule means that proofs from at least 20% of system witnesses are required.
 func (wp *WitnessPolicyConfig) processMinPercent(token string) error {
@@ -176,9 +254,82 @@ func (wp *WitnessPolicyConfig) processMinPercent(token string) error {
 	return nil
 }
 
+// processRequireFrom processes the RequireFrom rule (e.g. RequireFrom(https://w1.com/services/orb,https://w2.com/services/orb)
+// means that a proof from at least one of the given witnesses is required).
+func (wp *WitnessPolicyConfig) processRequireFrom(token string) error {
+	insideBrackets := token[len(RequireFrom)+1 : len(token)-1]
+
+	if insideBrackets == "" {
+		return fmt.Errorf("expected at least one argument for RequireFrom policy")
+	}
+
+	for _, arg := range strings.Split(insideBrackets, ",") {
+		u, err := url.Parse(arg)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("argument '%s' for RequireFrom policy must be a witness URI", arg)
+		}
+
+		wp.RequiredWitnesses = append(wp.RequiredWitnesses, arg)
+	}
+
+	return nil
+}
+
+// processSpread processes the Spread rule (e.g. Spread(2) means that proofs from at least 2 distinct
+// witness types are required).
+func (wp *WitnessPolicyConfig) processSpread(token string) error {
+	insideBrackets := token[len(Spread)+1 : len(token)-1]
+
+	if insideBrackets == "" || strings.Contains(insideBrackets, ",") {
+		return fmt.Errorf("expected 1 argument for Spread policy")
+	}
+
+	minTypes, err := strconv.Atoi(insideBrackets)
+	if err != nil {
+		return fmt.Errorf("argument for Spread policy must be an integer: %w", err)
+	}
+
+	if minTypes < 0 || minTypes > numWitnessTypes {
+		return fmt.Errorf("argument[%d] for Spread policy rule must be an integer between 0 and %d",
+			minTypes, numWitnessTypes)
+	}
+
+	wp.MinTypes = minTypes
+
+	return nil
+}
+
+// IsRequiredWitness returns true if the given witness URI is one of the witnesses in the RequireFrom rule.
+func (wp *WitnessPolicyConfig) IsRequiredWitness(uri string) bool {
+	for _, w := range wp.RequiredWitnesses {
+		if w == uri {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ReferencedTypes returns the distinct witness types for which the policy requires proofs, i.e. the types
+// that have a non-zero OutOf or MinPercent requirement.
+func (wp *WitnessPolicyConfig) ReferencedTypes() []proof.WitnessType {
+	var types []proof.WitnessType
+
+	if wp.MinNumberBatch > 0 || wp.MinPercentBatch > 0 {
+		types = append(types, proof.WitnessTypeBatch)
+	}
+
+	if wp.MinNumberSystem > 0 || wp.MinPercentSystem > 0 {
+		types = append(types, proof.WitnessTypeSystem)
+	}
+
+	return types
+}
+
 func (wp *WitnessPolicyConfig) String() string {
-	return fmt.Sprintf("minBatch:%d, minSystem:%d, percentBatch:%d, percentSystem:%d, operator: %s, log:%t",
-		wp.MinNumberBatch, wp.MinNumberSystem, wp.MinPercentBatch, wp.MinPercentSystem, wp.Operator, wp.LogRequired)
+	return fmt.Sprintf("minBatch:%d, minSystem:%d, percentBatch:%d, percentSystem:%d, operator: %s, log:%t, withinBatch:%s, withinSystem:%s, requireFrom:%s, spread:%d", //nolint:lll
+		wp.MinNumberBatch, wp.MinNumberSystem, wp.MinPercentBatch, wp.MinPercentSystem, wp.Operator, wp.LogRequired,
+		wp.WithinBatch, wp.WithinSystem, wp.RequiredWitnesses, wp.MinTypes)
 }
 
 func and(a, b bool) bool {
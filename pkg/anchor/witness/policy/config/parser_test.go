@@ -8,8 +8,11 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/anchor/witness/proof"
 )
 
 func TestParse(t *testing.T) {
@@ -102,6 +105,133 @@ func TestParse_OutOf(t *testing.T) {
 	})
 }
 
+func TestParse_OutOfWithin(t *testing.T) {
+	t.Run("success - OutOfWithin policy for system", func(t *testing.T) {
+		wp, err := Parse("OutOfWithin(2,system,1h) AND OutOf(1,batch)")
+		require.NoError(t, err)
+		require.NotNil(t, wp)
+
+		require.Equal(t, 1, wp.MinNumberBatch)
+		require.Equal(t, 2, wp.MinNumberSystem)
+		require.Equal(t, time.Hour, wp.WithinSystem)
+		require.Zero(t, wp.WithinBatch)
+	})
+
+	t.Run("success - OutOfWithin policy for batch", func(t *testing.T) {
+		wp, err := Parse("OutOfWithin(3,batch,30m)")
+		require.NoError(t, err)
+		require.NotNil(t, wp)
+
+		require.Equal(t, 3, wp.MinNumberBatch)
+		require.Equal(t, 30*time.Minute, wp.WithinBatch)
+		require.Zero(t, wp.WithinSystem)
+	})
+
+	t.Run("error - invalid number of arguments", func(t *testing.T) {
+		wp, err := Parse("OutOfWithin(2,system)")
+		require.Error(t, err)
+		require.Nil(t, wp)
+		require.Contains(t, err.Error(), "expected 3 but got 2 arguments for OutOfWithin policy")
+	})
+
+	t.Run("error - invalid duration", func(t *testing.T) {
+		wp, err := Parse("OutOfWithin(2,system,abc)")
+		require.Error(t, err)
+		require.Nil(t, wp)
+		require.Contains(t, err.Error(), "third argument for OutOfWithin policy must be a duration")
+	})
+
+	t.Run("error - non-positive duration", func(t *testing.T) {
+		wp, err := Parse("OutOfWithin(2,system,0s)")
+		require.Error(t, err)
+		require.Nil(t, wp)
+		require.Contains(t, err.Error(), "must be a positive duration")
+	})
+
+	t.Run("error - invalid role", func(t *testing.T) {
+		wp, err := Parse("OutOfWithin(2,other,1h)")
+		require.Error(t, err)
+		require.Nil(t, wp)
+		require.Contains(t, err.Error(), "role 'other' not supported for OutOf policy")
+	})
+}
+
+func TestParse_RequireFrom(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		wp, err := Parse("OutOf(1,system) RequireFrom(https://w1.com/services/orb,https://w2.com/services/orb)")
+		require.NoError(t, err)
+		require.NotNil(t, wp)
+
+		require.Equal(t, 1, wp.MinNumberSystem)
+		require.Equal(t, []string{"https://w1.com/services/orb", "https://w2.com/services/orb"}, wp.RequiredWitnesses)
+		require.True(t, wp.IsRequiredWitness("https://w2.com/services/orb"))
+		require.False(t, wp.IsRequiredWitness("https://w3.com/services/orb"))
+		require.Contains(t, wp.String(), "requireFrom:[https://w1.com/services/orb https://w2.com/services/orb]")
+	})
+
+	t.Run("error - no arguments", func(t *testing.T) {
+		wp, err := Parse("RequireFrom()")
+		require.Error(t, err)
+		require.Nil(t, wp)
+		require.Contains(t, err.Error(), "expected at least one argument for RequireFrom policy")
+	})
+
+	t.Run("error - invalid URI", func(t *testing.T) {
+		wp, err := Parse("RequireFrom(https://w1.com/services/orb,w2)")
+		require.Error(t, err)
+		require.Nil(t, wp)
+		require.Contains(t, err.Error(), "argument 'w2' for RequireFrom policy must be a witness URI")
+	})
+}
+
+func TestParse_Spread(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		wp, err := Parse("OutOf(1,batch) OR OutOf(1,system) Spread(2)")
+		require.NoError(t, err)
+		require.NotNil(t, wp)
+
+		require.Equal(t, 1, wp.MinNumberBatch)
+		require.Equal(t, 1, wp.MinNumberSystem)
+		require.Equal(t, OR, wp.Operator)
+		require.Equal(t, 2, wp.MinTypes)
+		require.Contains(t, wp.String(), "spread:2")
+	})
+
+	t.Run("default", func(t *testing.T) {
+		wp, err := Parse("OutOf(1,system)")
+		require.NoError(t, err)
+		require.Zero(t, wp.MinTypes)
+	})
+
+	t.Run("error - no arguments", func(t *testing.T) {
+		wp, err := Parse("Spread()")
+		require.Error(t, err)
+		require.Nil(t, wp)
+		require.Contains(t, err.Error(), "expected 1 argument for Spread policy")
+	})
+
+	t.Run("error - too many arguments", func(t *testing.T) {
+		wp, err := Parse("Spread(1,2)")
+		require.Error(t, err)
+		require.Nil(t, wp)
+		require.Contains(t, err.Error(), "expected 1 argument for Spread policy")
+	})
+
+	t.Run("error - not an integer", func(t *testing.T) {
+		wp, err := Parse("Spread(two)")
+		require.Error(t, err)
+		require.Nil(t, wp)
+		require.Contains(t, err.Error(), "argument for Spread policy must be an integer")
+	})
+
+	t.Run("error - out of range", func(t *testing.T) {
+		wp, err := Parse("Spread(3)")
+		require.Error(t, err)
+		require.Nil(t, wp)
+		require.Contains(t, err.Error(), "argument[3] for Spread policy rule must be an integer between 0 and 2")
+	})
+}
+
 func TestParse_MinPercent(t *testing.T) {
 	t.Run("success - MinPercent policy for batch", func(t *testing.T) {
 		wp, err := Parse("MinPercent(70,batch)")
@@ -170,3 +300,35 @@ func TestParse_LogRequired(t *testing.T) {
 		require.Equal(t, and(true, false), wp.OperatorFnc(true, false))
 	})
 }
+
+func TestWitnessPolicyConfig_ReferencedTypes(t *testing.T) {
+	t.Run("batch only", func(t *testing.T) {
+		wp, err := Parse("OutOf(2,batch) AND OutOf(0,system)")
+		require.NoError(t, err)
+		require.Equal(t, []proof.WitnessType{proof.WitnessTypeBatch}, wp.ReferencedTypes())
+	})
+
+	t.Run("system only", func(t *testing.T) {
+		wp, err := Parse("MinPercent(0,batch) AND MinPercent(50,system)")
+		require.NoError(t, err)
+		require.Equal(t, []proof.WitnessType{proof.WitnessTypeSystem}, wp.ReferencedTypes())
+	})
+
+	t.Run("batch and system", func(t *testing.T) {
+		wp, err := Parse("OutOf(1,batch) OR OutOfWithin(1,system,1h)")
+		require.NoError(t, err)
+		require.Equal(t, []proof.WitnessType{proof.WitnessTypeBatch, proof.WitnessTypeSystem}, wp.ReferencedTypes())
+	})
+
+	t.Run("default policy", func(t *testing.T) {
+		wp, err := Parse("")
+		require.NoError(t, err)
+		require.Equal(t, []proof.WitnessType{proof.WitnessTypeBatch, proof.WitnessTypeSystem}, wp.ReferencedTypes())
+	})
+
+	t.Run("no requirements", func(t *testing.T) {
+		wp, err := Parse("OutOf(0,batch) AND OutOf(0,system)")
+		require.NoError(t, err)
+		require.Empty(t, wp.ReferencedTypes())
+	})
+}
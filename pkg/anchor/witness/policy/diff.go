@@ -0,0 +1,23 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policy
+
+import (
+	"github.com/trustbloc/orb/pkg/anchor/witness/proof"
+	"github.com/trustbloc/orb/pkg/anchor/witness/witnessset"
+)
+
+// WitnessDiff compares two witness selections (e.g. before and after a change to the witness policy or to the
+// set of available witnesses) and returns the witnesses that were added to and removed from the previous selection.
+// Witnesses are matched by URI, so a witness that appears in both selections (even with a different type) is
+// neither added nor removed. Witnesses that have no URI are ignored.
+func WitnessDiff(previous, current []*proof.Witness) (added, removed []*proof.Witness) {
+	previous = validWitnesses(previous)
+	current = validWitnesses(current)
+
+	return witnessset.Difference(current, previous), witnessset.Difference(previous, current)
+}
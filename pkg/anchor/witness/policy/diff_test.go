@@ -0,0 +1,73 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/activitypub/vocab"
+	"github.com/trustbloc/orb/pkg/anchor/witness/proof"
+	"github.com/trustbloc/orb/pkg/internal/testutil"
+)
+
+func TestWitnessDiff(t *testing.T) {
+	newWitness := func(witnessType proof.WitnessType, uri string) *proof.Witness {
+		return &proof.Witness{
+			Type: witnessType,
+			URI:  vocab.NewURLProperty(testutil.MustParseURL(uri)),
+		}
+	}
+
+	witness1 := newWitness(proof.WitnessTypeBatch, "https://witness1.com/service")
+	witness2 := newWitness(proof.WitnessTypeBatch, "https://witness2.com/service")
+	witness3 := newWitness(proof.WitnessTypeSystem, "https://witness3.com/service")
+	witness4 := newWitness(proof.WitnessTypeSystem, "https://witness4.com/service")
+
+	t.Run("Full overlap", func(t *testing.T) {
+		added, removed := WitnessDiff(
+			[]*proof.Witness{witness1, witness2, witness3},
+			[]*proof.Witness{witness3, witness1, witness2},
+		)
+		require.Empty(t, added)
+		require.Empty(t, removed)
+	})
+
+	t.Run("Disjoint", func(t *testing.T) {
+		added, removed := WitnessDiff(
+			[]*proof.Witness{witness1, witness2},
+			[]*proof.Witness{witness3, witness4},
+		)
+		require.Equal(t, []*proof.Witness{witness3, witness4}, added)
+		require.Equal(t, []*proof.Witness{witness1, witness2}, removed)
+	})
+
+	t.Run("Partial overlap", func(t *testing.T) {
+		added, removed := WitnessDiff(
+			[]*proof.Witness{witness1, witness2, witness3},
+			[]*proof.Witness{witness2, witness3, witness4},
+		)
+		require.Equal(t, []*proof.Witness{witness4}, added)
+		require.Equal(t, []*proof.Witness{witness1}, removed)
+	})
+
+	t.Run("Witness type changed -> same witness", func(t *testing.T) {
+		added, removed := WitnessDiff(
+			[]*proof.Witness{witness1},
+			[]*proof.Witness{newWitness(proof.WitnessTypeSystem, "https://witness1.com/service")},
+		)
+		require.Empty(t, added)
+		require.Empty(t, removed)
+	})
+
+	t.Run("No previous selection", func(t *testing.T) {
+		added, removed := WitnessDiff(nil, []*proof.Witness{witness1, witness1, {}})
+		require.Equal(t, []*proof.Witness{witness1}, added)
+		require.Empty(t, removed)
+	})
+}
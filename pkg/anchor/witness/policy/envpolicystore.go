@@ -0,0 +1,41 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policy
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/trustbloc/orb/pkg/anchor/witness/policy/config"
+)
+
+// EnvPolicyStore is a read-only witness policy store that retrieves the witness policy
+// from an environment variable. It may be passed to New in place of the database-backed store.
+type EnvPolicyStore struct {
+	envKey string
+}
+
+// NewEnvPolicyStore returns a witness policy store that reads the policy from the given environment variable.
+func NewEnvPolicyStore(envKey string) *EnvPolicyStore {
+	return &EnvPolicyStore{envKey: envKey}
+}
+
+// GetPolicy returns the witness policy from the environment variable. An empty string is returned
+// if the variable is not set, in which case the default policy applies. An error is returned
+// if the policy is invalid.
+func (s *EnvPolicyStore) GetPolicy() (string, error) {
+	policy := os.Getenv(s.envKey)
+	if policy == "" {
+		return "", nil
+	}
+
+	if _, err := config.Parse(policy); err != nil {
+		return "", fmt.Errorf("invalid witness policy in environment variable [%s]: %w", s.envKey, err)
+	}
+
+	return policy, nil
+}
@@ -0,0 +1,69 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const policyEnvKey = "ORB_TEST_WITNESS_POLICY"
+
+func TestEnvPolicyStore_GetPolicy(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		t.Setenv(policyEnvKey, "MinPercent(50,batch) AND OutOf(1,system)")
+
+		s := NewEnvPolicyStore(policyEnvKey)
+
+		policy, err := s.GetPolicy()
+		require.NoError(t, err)
+		require.Equal(t, "MinPercent(50,batch) AND OutOf(1,system)", policy)
+
+		wp, err := New(s, defaultPolicyCacheExpiry)
+		require.NoError(t, err)
+
+		cfg, err := wp.getWitnessPolicyConfig()
+		require.NoError(t, err)
+		require.Equal(t, 50, cfg.MinPercentBatch)
+		require.Equal(t, 1, cfg.MinNumberSystem)
+	})
+
+	t.Run("success - not set (default policy)", func(t *testing.T) {
+		t.Setenv(policyEnvKey, "")
+
+		s := NewEnvPolicyStore(policyEnvKey)
+
+		policy, err := s.GetPolicy()
+		require.NoError(t, err)
+		require.Empty(t, policy)
+
+		wp, err := New(s, defaultPolicyCacheExpiry)
+		require.NoError(t, err)
+
+		cfg, err := wp.getWitnessPolicyConfig()
+		require.NoError(t, err)
+		require.Equal(t, 100, cfg.MinPercentBatch)
+		require.Equal(t, 100, cfg.MinPercentSystem)
+	})
+
+	t.Run("error - invalid policy", func(t *testing.T) {
+		t.Setenv(policyEnvKey, "InvalidRule(1,batch)")
+
+		s := NewEnvPolicyStore(policyEnvKey)
+
+		policy, err := s.GetPolicy()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid witness policy in environment variable")
+		require.Contains(t, err.Error(), "rule not supported")
+		require.Empty(t, policy)
+
+		wp, err := New(s, defaultPolicyCacheExpiry)
+		require.Error(t, err)
+		require.Nil(t, wp)
+	})
+}
@@ -0,0 +1,88 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/bluele/gcache"
+
+	"github.com/trustbloc/orb/internal/pkg/log"
+	"github.com/trustbloc/orb/pkg/anchor/witness/policy/config"
+	"github.com/trustbloc/orb/pkg/anchor/witness/proof"
+)
+
+const defaultEvaluationCacheSize = 1000
+
+// evaluationCache memoizes witness policy evaluation results.
+type evaluationCache struct {
+	cache gcache.Cache
+}
+
+func newEvaluationCache(ttl time.Duration) *evaluationCache {
+	return &evaluationCache{
+		cache: gcache.New(defaultEvaluationCacheSize).LRU().Expiration(ttl).Build(),
+	}
+}
+
+func (c *evaluationCache) get(key string) (bool, bool) {
+	value, err := c.cache.Get(key)
+	if err != nil {
+		return false, false
+	}
+
+	return value.(bool), true //nolint:forcetypeassert
+}
+
+func (c *evaluationCache) put(key string, evaluated bool) {
+	if err := c.cache.Set(key, evaluated); err != nil {
+		logger.Debug("Error caching witness policy evaluation result", log.WithError(err))
+	}
+}
+
+func (c *evaluationCache) purge() {
+	c.cache.Purge()
+}
+
+// cacheable returns false if the evaluation result may change for the same set of witness proofs, in which
+// case the result must not be cached. This is the case if the policy contains a time-window rule (the result
+// depends on the current time) or if a proof verifier is configured (the verifier may reject a proof that it
+// previously accepted, e.g. after a key has been revoked).
+func (wp *WitnessPolicy) cacheable(cfg *config.WitnessPolicyConfig) bool {
+	if _, ok := wp.proofVerifier.(*noopProofVerifier); !ok {
+		return false
+	}
+
+	return cfg.WithinBatch == 0 && cfg.WithinSystem == 0
+}
+
+// evaluationKey returns a hash of the policy and the (sorted) identities of the given witness proofs.
+func evaluationKey(cfg *config.WitnessPolicyConfig, witnesses []*proof.WitnessProof) string {
+	ids := make([]string, len(witnesses))
+
+	for i, w := range witnesses {
+		ids[i] = string(w.Type) + "|" + w.URI.String() + "|" + strconv.FormatBool(w.HasLog) + "|" +
+			strconv.FormatBool(w.Proof != nil) + "|" + string(w.Proof)
+	}
+
+	sort.Strings(ids)
+
+	h := sha256.New()
+
+	h.Write([]byte(cfg.String()))
+
+	for _, id := range ids {
+		h.Write([]byte{0})
+		h.Write([]byte(id))
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
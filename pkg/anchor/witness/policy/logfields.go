@@ -12,6 +12,7 @@ import (
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
+	"github.com/trustbloc/orb/internal/pkg/log"
 	"github.com/trustbloc/orb/pkg/anchor/witness/policy/config"
 	"github.com/trustbloc/orb/pkg/anchor/witness/proof"
 )
@@ -19,13 +20,21 @@ import (
 const (
 	fieldWitnessPolicyConfig = "policy-config"
 	fieldEvaluatedTo         = "evaluated-to"
+	fieldShadowPolicyConfig  = "shadow-policy-config"
+	fieldShadowEvaluatedTo   = "shadow-evaluated-to"
 	fieldBatchCondition      = "batch-condition"
 	fieldSystemCondition     = "system-condition"
+	fieldRequiredCondition   = "required-condition"
+	fieldSpreadCondition     = "spread-condition"
+	fieldWitness             = "witness"
 	fieldWitnesses           = "witnesses"
 	fieldBatchWitnesses      = "batch-witnesses"
 	fieldSystemWitnesses     = "system-witnesses"
 	fieldEligibleWitnesses   = "eligible-witnesses"
 	fieldPreferredWitnesses  = "preferred-witnesses"
+	fieldBatchPercent        = "batch-percent"
+	fieldSystemPercent       = "system-percent"
+	fieldSelectionRules      = "selection-rules"
 )
 
 func withPolicyConfigField(value *config.WitnessPolicyConfig) zap.Field {
@@ -36,6 +45,14 @@ func withEvaluatedField(value bool) zap.Field {
 	return zap.Bool(fieldEvaluatedTo, value)
 }
 
+func withShadowPolicyConfigField(value *config.WitnessPolicyConfig) zap.Field {
+	return zap.Object(fieldShadowPolicyConfig, newConfigMarshaller(value))
+}
+
+func withShadowEvaluatedField(value bool) zap.Field {
+	return zap.Bool(fieldShadowEvaluatedTo, value)
+}
+
 func withBatchConditionField(value bool) zap.Field {
 	return zap.Bool(fieldBatchCondition, value)
 }
@@ -44,6 +61,34 @@ func withSystemConditionField(value bool) zap.Field {
 	return zap.Bool(fieldSystemCondition, value)
 }
 
+func withRequiredConditionField(value bool) zap.Field {
+	return zap.Bool(fieldRequiredCondition, value)
+}
+
+func withSpreadConditionField(value bool) zap.Field {
+	return zap.Bool(fieldSpreadCondition, value)
+}
+
+func withBatchPercentField(collected, total int) zap.Field {
+	return log.WithPercent(fieldBatchPercent, percentOf(collected, total))
+}
+
+func withSystemPercentField(collected, total int) zap.Field {
+	return log.WithPercent(fieldSystemPercent, percentOf(collected, total))
+}
+
+func percentOf(collected, total int) float64 {
+	if total == 0 {
+		return maxPercent
+	}
+
+	return float64(collected) / float64(total) * maxPercent
+}
+
+func withWitnessField(value *proof.Witness) zap.Field {
+	return zap.Object(fieldWitness, newWitnessMarshaller(value))
+}
+
 func withWitnessesField(value []*proof.Witness) zap.Field {
 	return zap.Array(fieldWitnesses, newWitnessArrayMarshaller(value))
 }
@@ -64,6 +109,10 @@ func withPreferredWitnessesField(value []*proof.Witness) zap.Field {
 	return zap.Array(fieldPreferredWitnesses, newWitnessArrayMarshaller(value))
 }
 
+func withSelectionRulesField(value map[string][]string) zap.Field {
+	return zap.Object(fieldSelectionRules, newSelectionRulesMarshaller(value))
+}
+
 func withWitnessProofsField(value []*proof.WitnessProof) zap.Field {
 	return zap.Array(fieldWitnesses, newWitnessProofArrayMarshaller(value))
 }
@@ -183,3 +232,28 @@ func (m *witnessProofArrayMarshaller) MarshalLogArray(e zapcore.ArrayEncoder) er
 
 	return nil
 }
+
+type selectionRulesMarshaller struct {
+	rules map[string][]string
+}
+
+func newSelectionRulesMarshaller(rules map[string][]string) *selectionRulesMarshaller {
+	return &selectionRulesMarshaller{rules: rules}
+}
+
+func (m *selectionRulesMarshaller) MarshalLogObject(e zapcore.ObjectEncoder) error {
+	for uri, rules := range m.rules {
+		err := e.AddArray(uri, zapcore.ArrayMarshalerFunc(func(ae zapcore.ArrayEncoder) error {
+			for _, rule := range rules {
+				ae.AppendString(rule)
+			}
+
+			return nil
+		}))
+		if err != nil {
+			return fmt.Errorf("marshal selection rules: %w", err)
+		}
+	}
+
+	return nil
+}
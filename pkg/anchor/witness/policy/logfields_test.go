@@ -40,6 +40,26 @@ func TestConfigMarshaller(t *testing.T) {
 	require.Equal(t, cfg.LogRequired, encoder.Fields["logRequired"])
 }
 
+func TestPercentFields(t *testing.T) {
+	require.Equal(t, "66.67%", withBatchPercentField(2, 3).String)
+	require.Equal(t, "0.00%", withSystemPercentField(0, 4).String)
+	require.Equal(t, "100.00%", withSystemPercentField(0, 0).String)
+	require.Equal(t, fieldBatchPercent, withBatchPercentField(1, 1).Key)
+	require.Equal(t, fieldSystemPercent, withSystemPercentField(1, 1).Key)
+}
+
+func TestSelectionRulesMarshaller(t *testing.T) {
+	encoder := zapcore.NewMapObjectEncoder()
+
+	require.NoError(t, newSelectionRulesMarshaller(map[string][]string{
+		"https://batch.com": {"OutOf(1,batch)"},
+		"https://both.com":  {"OutOf(1,batch)", "OutOf(1,system)"},
+	}).MarshalLogObject(encoder))
+
+	require.Equal(t, []interface{}{"OutOf(1,batch)"}, encoder.Fields["https://batch.com"])
+	require.Equal(t, []interface{}{"OutOf(1,batch)", "OutOf(1,system)"}, encoder.Fields["https://both.com"])
+}
+
 func TestWitnessMarshaller(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		w := &proof.Witness{
@@ -0,0 +1,139 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+
+	orberrors "github.com/trustbloc/orb/pkg/errors"
+)
+
+/*
+Namespaced witness policies are stored under keys of the form "witness-policy:<namespace>", for example
+"witness-policy:default". The prefix ensures that namespaced keys never collide with the legacy (non-namespaced)
+key, WitnessPolicyKey. For backward compatibility, if no policy is stored for the default namespace then the
+policy stored under the legacy key is used.
+*/
+
+const (
+	// DefaultNamespace is the namespace of the witness policy that was stored before namespaces were introduced.
+	DefaultNamespace = "default"
+
+	namespaceKeySeparator = ":"
+)
+
+// NamespacedPolicyKey returns the store key of the witness policy for the given namespace.
+// If the namespace is empty then the key for DefaultNamespace is returned.
+func NamespacedPolicyKey(namespace string) string {
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+
+	return WitnessPolicyKey + namespaceKeySeparator + namespace
+}
+
+// ParseNamespacedPolicyKey returns the namespace from the given store key. The legacy key, WitnessPolicyKey,
+// is parsed as DefaultNamespace.
+func ParseNamespacedPolicyKey(key string) (string, error) {
+	if key == WitnessPolicyKey {
+		return DefaultNamespace, nil
+	}
+
+	prefix := WitnessPolicyKey + namespaceKeySeparator
+
+	if !strings.HasPrefix(key, prefix) {
+		return "", fmt.Errorf("invalid namespaced witness policy key [%s]", key)
+	}
+
+	namespace := key[len(prefix):]
+	if namespace == "" {
+		return "", fmt.Errorf("namespace not specified in witness policy key [%s]", key)
+	}
+
+	return namespace, nil
+}
+
+// NamespacedPolicyStore stores witness policies by namespace.
+type NamespacedPolicyStore struct {
+	store storage.Store
+}
+
+// NewNamespacedPolicyStore returns a new namespaced witness policy store.
+func NewNamespacedPolicyStore(store storage.Store) *NamespacedPolicyStore {
+	return &NamespacedPolicyStore{store: store}
+}
+
+// PutPolicy stores the witness policy for the given namespace.
+func (s *NamespacedPolicyStore) PutPolicy(namespace, policy string) error {
+	valueBytes, err := json.Marshal(&namespacedPolicy{Policy: policy})
+	if err != nil {
+		return fmt.Errorf("marshal witness policy: %w", err)
+	}
+
+	err = s.store.Put(NamespacedPolicyKey(namespace), valueBytes)
+	if err != nil {
+		return orberrors.NewTransientf("store witness policy for namespace [%s]: %w", namespace, err)
+	}
+
+	return nil
+}
+
+// GetPolicy returns the witness policy for the given namespace. If the namespace is the default namespace and
+// no policy is stored under the namespaced key then the policy stored under the legacy key is returned.
+func (s *NamespacedPolicyStore) GetPolicy(namespace string) (string, error) {
+	policyBytes, err := s.store.Get(NamespacedPolicyKey(namespace))
+	if err != nil {
+		if !errors.Is(err, storage.ErrDataNotFound) || !isDefaultNamespace(namespace) {
+			return "", err
+		}
+
+		policyBytes, err = s.store.Get(WitnessPolicyKey)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	p := &namespacedPolicy{}
+
+	err = json.Unmarshal(policyBytes, p)
+	if err != nil {
+		return "", fmt.Errorf("unmarshal witness policy: %w", err)
+	}
+
+	return p.Policy, nil
+}
+
+// ForNamespace returns a policy retriever for the given namespace which may be passed to New.
+func (s *NamespacedPolicyStore) ForNamespace(namespace string) *NamespaceRetriever {
+	return &NamespaceRetriever{store: s, namespace: namespace}
+}
+
+// NamespaceRetriever retrieves the witness policy for a single namespace.
+type NamespaceRetriever struct {
+	store     *NamespacedPolicyStore
+	namespace string
+}
+
+// GetPolicy returns the witness policy for the namespace.
+func (r *NamespaceRetriever) GetPolicy() (string, error) {
+	return r.store.GetPolicy(r.namespace)
+}
+
+func isDefaultNamespace(namespace string) bool {
+	return namespace == "" || namespace == DefaultNamespace
+}
+
+// namespacedPolicy is the stored value. It has the same format as the value stored by config.Store
+// so that a legacy policy may be read.
+type namespacedPolicy struct {
+	Policy string `json:"Policy"`
+}
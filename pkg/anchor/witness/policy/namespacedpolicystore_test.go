@@ -0,0 +1,149 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policy
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/anchor/witness/policy/config"
+	"github.com/trustbloc/orb/pkg/store/mocks"
+)
+
+func TestNamespacedPolicyKey(t *testing.T) {
+	require.Equal(t, "witness-policy:default", NamespacedPolicyKey(""))
+	require.Equal(t, "witness-policy:default", NamespacedPolicyKey(DefaultNamespace))
+	require.Equal(t, "witness-policy:ns1", NamespacedPolicyKey("ns1"))
+	require.NotEqual(t, WitnessPolicyKey, NamespacedPolicyKey(""))
+}
+
+func TestParseNamespacedPolicyKey(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		ns, err := ParseNamespacedPolicyKey(NamespacedPolicyKey("ns1"))
+		require.NoError(t, err)
+		require.Equal(t, "ns1", ns)
+
+		ns, err = ParseNamespacedPolicyKey("witness-policy:ns:with:colons")
+		require.NoError(t, err)
+		require.Equal(t, "ns:with:colons", ns)
+	})
+
+	t.Run("legacy key", func(t *testing.T) {
+		ns, err := ParseNamespacedPolicyKey(WitnessPolicyKey)
+		require.NoError(t, err)
+		require.Equal(t, DefaultNamespace, ns)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, err := ParseNamespacedPolicyKey("some-key")
+		require.EqualError(t, err, "invalid namespaced witness policy key [some-key]")
+
+		_, err = ParseNamespacedPolicyKey("witness-policy:")
+		require.EqualError(t, err, "namespace not specified in witness policy key [witness-policy:]")
+	})
+}
+
+func TestNamespacedPolicyStore(t *testing.T) {
+	const (
+		legacyPolicy = "OutOf(1,system)"
+		ns1Policy    = "OutOf(2,system)"
+	)
+
+	newStore := func(t *testing.T) storage.Store {
+		t.Helper()
+
+		s, err := mem.NewProvider().OpenStore("config")
+		require.NoError(t, err)
+
+		return s
+	}
+
+	t.Run("legacy fallback", func(t *testing.T) {
+		s := newStore(t)
+
+		// Store the policy using the legacy store.
+		require.NoError(t, config.NewPolicyStore(s).PutPolicy(legacyPolicy))
+
+		nps := NewNamespacedPolicyStore(s)
+
+		policy, err := nps.GetPolicy(DefaultNamespace)
+		require.NoError(t, err)
+		require.Equal(t, legacyPolicy, policy)
+
+		policy, err = nps.GetPolicy("")
+		require.NoError(t, err)
+		require.Equal(t, legacyPolicy, policy)
+
+		// No fallback for other namespaces.
+		_, err = nps.GetPolicy("ns1")
+		require.ErrorIs(t, err, storage.ErrDataNotFound)
+
+		// The namespaced policy takes precedence over the legacy policy.
+		require.NoError(t, nps.PutPolicy(DefaultNamespace, ns1Policy))
+
+		policy, err = nps.GetPolicy(DefaultNamespace)
+		require.NoError(t, err)
+		require.Equal(t, ns1Policy, policy)
+	})
+
+	t.Run("namespaces", func(t *testing.T) {
+		nps := NewNamespacedPolicyStore(newStore(t))
+
+		_, err := nps.GetPolicy(DefaultNamespace)
+		require.ErrorIs(t, err, storage.ErrDataNotFound)
+
+		require.NoError(t, nps.PutPolicy("ns1", ns1Policy))
+		require.NoError(t, nps.PutPolicy("ns2", legacyPolicy))
+
+		policy, err := nps.GetPolicy("ns1")
+		require.NoError(t, err)
+		require.Equal(t, ns1Policy, policy)
+
+		policy, err = nps.GetPolicy("ns2")
+		require.NoError(t, err)
+		require.Equal(t, legacyPolicy, policy)
+	})
+
+	t.Run("retriever", func(t *testing.T) {
+		nps := NewNamespacedPolicyStore(newStore(t))
+
+		require.NoError(t, nps.PutPolicy("ns1", ns1Policy))
+
+		wp, err := New(nps.ForNamespace("ns1"), time.Minute)
+		require.NoError(t, err)
+
+		cfg, err := wp.getWitnessPolicyConfig()
+		require.NoError(t, err)
+		require.Equal(t, 2, cfg.MinNumberSystem)
+	})
+
+	t.Run("store errors", func(t *testing.T) {
+		errExpected := errors.New("injected store error")
+
+		s := &mocks.Store{}
+		s.PutReturns(errExpected)
+		s.GetReturns(nil, errExpected)
+
+		nps := NewNamespacedPolicyStore(s)
+
+		require.ErrorIs(t, nps.PutPolicy("ns1", ns1Policy), errExpected)
+
+		_, err := nps.GetPolicy("ns1")
+		require.ErrorIs(t, err, errExpected)
+
+		s.GetReturns([]byte("{"), nil)
+
+		_, err = nps.GetPolicy("ns1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unmarshal witness policy")
+	})
+}
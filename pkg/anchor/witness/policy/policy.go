@@ -10,6 +10,10 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"math/rand"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/bluele/gcache"
@@ -19,15 +23,154 @@ import (
 	"github.com/trustbloc/orb/pkg/anchor/witness/policy/config"
 	"github.com/trustbloc/orb/pkg/anchor/witness/policy/selector/random"
 	"github.com/trustbloc/orb/pkg/anchor/witness/proof"
+	"github.com/trustbloc/orb/pkg/anchor/witness/witnessset"
 )
 
 // WitnessPolicy evaluates witness policy.
 type WitnessPolicy struct {
 	retriever   policyRetriever
-	cache       gCache
+	cache       Cache
+	cacheKey    string
 	cacheExpiry time.Duration
+	cacheJitter time.Duration
 
 	selector selector
+
+	mutex          sync.RWMutex
+	degraded       bool
+	lastLoadedTime time.Time
+	lastPolicy     string
+
+	evaluationCache *evaluationCache
+	proofVerifier   ProofVerifier
+	preferred       []*url.URL
+	fewestOR        bool
+
+	shadowPolicy   string
+	shadowConfig   *config.WitnessPolicyConfig
+	shadowObserver ShadowPolicyObserver
+
+	cacheRefreshHandler CacheRefreshHandler
+
+	metrics metricsProvider
+	clock   Clock
+}
+
+// Option is a witness policy evaluator option.
+type Option func(wp *WitnessPolicy)
+
+// WithCacheExpiryJitter adds a random duration, between zero and the given jitter, to the policy cache expiry
+// each time the policy is cached. This prevents all instances in a cluster from reloading the policy from the
+// shared policy store at the same time.
+func WithCacheExpiryJitter(jitter time.Duration) Option {
+	return func(wp *WitnessPolicy) {
+		wp.cacheJitter = jitter
+	}
+}
+
+// WithProofVerifier sets the verifier that is invoked for each witness proof before the proof is counted
+// toward the witness policy. Proofs that fail verification are not counted. By default proofs aren't verified.
+func WithProofVerifier(verifier ProofVerifier) Option {
+	return func(wp *WitnessPolicy) {
+		wp.proofVerifier = verifier
+	}
+}
+
+// WithCache sets the cache in which the witness policy is cached. By default, an in-memory cache is used.
+// A cache that's shared across the cluster may be provided so that all instances see the same policy (for
+// example, right after the policy is refreshed). If the cache doesn't contain the policy then the policy is
+// loaded from the policy store and added to the cache.
+func WithCache(cache Cache) Option {
+	return func(wp *WitnessPolicy) {
+		wp.cache = cache
+	}
+}
+
+// WithCacheKey sets the key under which the witness policy is cached. By default, WitnessPolicyKey is used.
+// A distinct key must be provided for each logical policy (e.g. NamespacedPolicyKey(namespace)) if multiple
+// evaluators share the same cache, otherwise the evaluators would overwrite each other's policy.
+func WithCacheKey(key string) Option {
+	return func(wp *WitnessPolicy) {
+		wp.cacheKey = key
+	}
+}
+
+// WithEvaluationCache enables memoization of evaluation results so that repeated calls to Evaluate with the
+// same set of witness proofs (e.g. during retries) return the cached result, for up to the given TTL, rather
+// than re-evaluating the policy. Cached results are invalidated when the policy is reloaded. Results are not
+// cached if the policy contains a time-window rule or if a proof verifier is configured (see WithProofVerifier).
+func WithEvaluationCache(ttl time.Duration) Option {
+	return func(wp *WitnessPolicy) {
+		wp.evaluationCache = newEvaluationCache(ttl)
+	}
+}
+
+// WithPreferredWitnesses sets the witnesses that should be chosen, in the given order, before any other
+// eligible witness when witnesses are selected. Only as many preferred witnesses as required to satisfy the
+// policy are chosen and preferred witnesses that are not eligible (e.g. excluded) are ignored. This allows
+// operators to steer traffic to their own (or faster) witnesses.
+func WithPreferredWitnesses(uris ...*url.URL) Option {
+	return func(wp *WitnessPolicy) {
+		wp.preferred = uris
+	}
+}
+
+// WithFewestWitnessesForOR changes how witnesses are selected for a policy whose batch and system rules are
+// combined with the OR operator. By default, witnesses are selected for both branches (an error is returned if
+// either branch can't be satisfied) and then one of the branches is chosen. With this option, a branch that can't
+// be satisfied is ignored and, if both branches can be satisfied, the branch that requires the fewest witnesses
+// is chosen in order to reduce network fan-out. If both branches require the same number of witnesses then the
+// batch branch is chosen.
+func WithFewestWitnessesForOR() Option {
+	return func(wp *WitnessPolicy) {
+		wp.fewestOR = true
+	}
+}
+
+// WithShadowPolicy sets a candidate witness policy that's evaluated alongside the enforced policy for
+// observability only. If the result of the shadow policy differs from the result of the enforced policy
+// then a warning is logged (and the ShadowPolicyObserver, if any, is notified) but the result of the
+// enforced policy is always returned. This allows a stricter policy to be trialled before it's enforced.
+func WithShadowPolicy(policy string) Option {
+	return func(wp *WitnessPolicy) {
+		wp.shadowPolicy = policy
+	}
+}
+
+// WithShadowPolicyObserver sets an observer that's notified when the result of the shadow policy
+// (see WithShadowPolicy) differs from the result of the enforced policy.
+func WithShadowPolicyObserver(observer ShadowPolicyObserver) Option {
+	return func(wp *WitnessPolicy) {
+		wp.shadowObserver = observer
+	}
+}
+
+// WithCacheRefreshHandler sets a handler that's invoked each time the witness policy is reloaded from the
+// policy store, either because the cached policy expired or because Refresh was called. The handler is passed
+// the previously loaded policy and the newly loaded policy or, if the policy couldn't be loaded, the error
+// (in which case the new policy is empty). The handler isn't invoked for the initial load. By default, no
+// handler is set.
+func WithCacheRefreshHandler(handler CacheRefreshHandler) Option {
+	return func(wp *WitnessPolicy) {
+		wp.cacheRefreshHandler = handler
+	}
+}
+
+// WithClock sets the clock used to expire the cached policy and to determine the age of witness proofs.
+// By default, the system clock is used. A fake clock may be provided in tests in order to advance time
+// deterministically.
+func WithClock(clock Clock) Option {
+	return func(wp *WitnessPolicy) {
+		wp.clock = clock
+	}
+}
+
+// WithMetricsProvider sets the provider that records the time it takes to evaluate the witness policy and
+// to select witnesses. By default, no metrics are recorded.
+func WithMetricsProvider(mp metricsProvider) Option {
+	return func(wp *WitnessPolicy) {
+		wp.metrics = mp
+	}
 }
 
 const (
@@ -41,88 +184,349 @@ const (
 
 var logger = log.NewStructured("witness-policy")
 
-type gCache interface {
+// ErrPolicyUnsatisfiable indicates that the witness policy can't be satisfied by the available witnesses.
+var ErrPolicyUnsatisfiable = errors.New("witness policy can't be satisfied by the available witnesses")
+
+// ErrCacheMiss is returned by Cache.Get if the cache doesn't contain the given key.
+var ErrCacheMiss = errors.New("key not found in cache")
+
+// Clock returns the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// Cache caches the witness policy. Get must return an error that wraps ErrCacheMiss (or gcache.KeyNotFoundError)
+// if the cache doesn't contain the given key or if the entry has expired.
+type Cache interface {
 	Get(key interface{}) (interface{}, error)
-	SetWithExpire(interface{}, interface{}, time.Duration) error
+	SetWithExpire(key interface{}, value interface{}, expiration time.Duration) error
 }
 
 type selector interface {
 	Select(witnesses []*proof.Witness, n int) ([]*proof.Witness, error)
 }
 
+type metricsProvider interface {
+	WitnessPolicyEvaluateTime(value time.Duration)
+	WitnessPolicySelectTime(value time.Duration)
+}
+
 type policyRetriever interface {
 	GetPolicy() (string, error)
 }
 
+// ProofVerifier verifies a witness proof. An error is returned if the proof is invalid.
+type ProofVerifier interface {
+	Verify(witnessProof *proof.WitnessProof) error
+}
+
+// ShadowPolicyObserver is notified when the result of the shadow policy differs from the result of the
+// enforced policy, e.g. in order to record a metric.
+type ShadowPolicyObserver interface {
+	ShadowPolicyDiverged(enforced, shadow bool)
+}
+
+// CacheRefreshHandler is invoked when the witness policy is reloaded from the policy store, e.g. in order to
+// log or record a metric when the policy changes or can't be loaded.
+type CacheRefreshHandler func(oldPolicy, newPolicy string, err error)
+
 // New will create new witness policy evaluator.
-func New(retriever policyRetriever, policyCacheExpiry time.Duration) (*WitnessPolicy, error) {
+func New(retriever policyRetriever, policyCacheExpiry time.Duration, opts ...Option) (*WitnessPolicy, error) {
 	wp := &WitnessPolicy{
-		retriever:   retriever,
-		cacheExpiry: policyCacheExpiry,
-		selector:    random.New(),
+		retriever:     retriever,
+		cacheKey:      WitnessPolicyKey,
+		cacheExpiry:   policyCacheExpiry,
+		selector:      random.New(),
+		proofVerifier: &noopProofVerifier{},
+		clock:         gcache.NewRealClock(),
+	}
+
+	for _, opt := range opts {
+		opt(wp)
+	}
+
+	if wp.shadowPolicy != "" {
+		shadowConfig, err := config.Parse(wp.shadowPolicy)
+		if err != nil {
+			return nil, fmt.Errorf("parse shadow witness policy: %w", err)
+		}
+
+		wp.shadowConfig = shadowConfig
 	}
 
-	wp.cache = gcache.New(defaultCacheSize).ARC().LoaderExpireFunc(wp.loadWitnessPolicy).Build()
+	if wp.cache == nil {
+		wp.cache = gcache.New(defaultCacheSize).ARC().Clock(wp.clock).
+			LoaderExpireFunc(wp.reloadWitnessPolicy).Build()
+	}
 
 	policy, _, err := wp.loadWitnessPolicy("")
 	if err != nil {
 		return nil, err
 	}
 
-	err = wp.cache.SetWithExpire(WitnessPolicyKey, policy, policyCacheExpiry)
+	err = wp.cache.SetWithExpire(wp.cacheKey, policy, wp.nextCacheExpiry())
 	if err != nil {
 		return nil, fmt.Errorf("failed to set expiry entry in policy cache: %w", err)
 	}
 
 	logger.Debug("Created new witness policy evaluator with cache",
-		log.WithWitnessPolicy(policy.(string)), log.WithCacheExpiration(policyCacheExpiry))
+		log.WithWitnessPolicy(policy.(string)), log.WithKey(wp.cacheKey), log.WithCacheExpiration(policyCacheExpiry))
 
 	return wp, nil
 }
 
 // Evaluate evaluates if witness policy has been satisfied for provided witnesses.
+// If no witness proofs are provided (nil or empty slice) then the policy is satisfied
+// only if it has no requirements, i.e. zero batch and/or system witnesses are required
+// (depending on the policy operator).
 func (wp *WitnessPolicy) Evaluate(witnesses []*proof.WitnessProof) (bool, error) {
+	start := time.Now()
+
 	cfg, err := wp.getWitnessPolicyConfig()
 	if err != nil {
 		return false, err
 	}
 
-	totalSystemWitnesses := 0
-	collectedSystemWitnesses := 0
+	witnesses = validWitnessProofs(witnesses)
 
-	totalBatchWitnesses := 0
-	collectedBatchWitnesses := 0
+	evaluated := wp.evaluateEnforced(cfg, witnesses)
+
+	duration := time.Since(start)
+
+	if wp.metrics != nil {
+		wp.metrics.WitnessPolicyEvaluateTime(duration)
+	}
+
+	if logger.IsEnabled(log.DEBUG) {
+		logger.Debug("Evaluated witness policy", log.WithWitnessPolicy(cfg.Policy()), withEvaluatedField(evaluated),
+			withWitnessProofsField(witnesses), log.WithEvaluationDuration(duration))
+	}
+
+	if wp.shadowConfig != nil {
+		wp.evaluateShadow(witnesses, evaluated)
+	}
+
+	return evaluated, nil
+}
+
+func (wp *WitnessPolicy) evaluateEnforced(cfg *config.WitnessPolicyConfig, witnesses []*proof.WitnessProof) bool {
+	if wp.evaluationCache == nil || !wp.cacheable(cfg) {
+		return wp.evaluate(cfg, witnesses)
+	}
+
+	key := evaluationKey(cfg, witnesses)
+
+	if evaluated, ok := wp.evaluationCache.get(key); ok {
+		logger.Debug("Witness policy evaluation result was retrieved from cache.",
+			withPolicyConfigField(cfg), withEvaluatedField(evaluated))
+
+		return evaluated
+	}
+
+	evaluated := wp.evaluate(cfg, witnesses)
+
+	wp.evaluationCache.put(key, evaluated)
+
+	return evaluated
+}
+
+// evaluateShadow evaluates the shadow policy and reports if its result differs from the enforced result.
+// The shadow result is never returned to the caller.
+func (wp *WitnessPolicy) evaluateShadow(witnesses []*proof.WitnessProof, enforced bool) {
+	shadow := wp.evaluate(wp.shadowConfig, witnesses)
+
+	if shadow == enforced {
+		return
+	}
+
+	logger.Warn("Shadow witness policy evaluation differs from the enforced witness policy evaluation",
+		withShadowPolicyConfigField(wp.shadowConfig), withEvaluatedField(enforced), withShadowEvaluatedField(shadow),
+		withWitnessProofsField(witnesses))
+
+	if wp.shadowObserver != nil {
+		wp.shadowObserver.ShadowPolicyDiverged(enforced, shadow)
+	}
+}
+
+func (wp *WitnessPolicy) evaluate(cfg *config.WitnessPolicyConfig, witnesses []*proof.WitnessProof) bool {
+	e := wp.evaluateDetailed(cfg, witnesses)
+
+	if len(witnesses) == 0 {
+		logger.Debug("No witness proofs provided. Witness policy is satisfied only if it has no requirements.",
+			withPolicyConfigField(cfg), withEvaluatedField(e.evaluated))
+
+		return e.evaluated
+	}
+
+	logger.Debug("Witness policy was evaluated.",
+		withPolicyConfigField(cfg), withEvaluatedField(e.evaluated), withBatchConditionField(e.batchCondition),
+		withSystemConditionField(e.systemCondition), withRequiredConditionField(e.requiredCondition),
+		withSpreadConditionField(e.spreadCondition), withBatchPercentField(e.collectedBatch, e.totalBatch),
+		withSystemPercentField(e.collectedSystem, e.totalSystem),
+		withWitnessProofsField(witnesses))
+
+	return e.evaluated
+}
+
+// evaluation contains the details of a witness policy evaluation.
+type evaluation struct {
+	totalBatch      int
+	collectedBatch  int
+	totalSystem     int
+	collectedSystem int
+
+	batchCondition    bool
+	systemCondition   bool
+	requiredCondition bool
+	spreadCondition   bool
+	evaluated         bool
+}
+
+// collectedTypes returns the number of distinct witness types from which proofs were collected.
+func (e *evaluation) collectedTypes() int {
+	return numTypes(e.collectedBatch, e.collectedSystem)
+}
+
+func (wp *WitnessPolicy) evaluateDetailed(cfg *config.WitnessPolicyConfig,
+	witnesses []*proof.WitnessProof) *evaluation {
+	// If the policy has a RequireFrom rule then a proof must be collected from at least one of the required witnesses.
+	e := &evaluation{requiredCondition: len(cfg.RequiredWitnesses) == 0}
+
+	if len(witnesses) == 0 {
+		e.batchCondition = noRequirements(cfg.MinNumberBatch, cfg.MinPercentBatch)
+		e.systemCondition = noRequirements(cfg.MinNumberSystem, cfg.MinPercentSystem)
+		e.spreadCondition = cfg.MinTypes == 0
+		e.evaluated = cfg.OperatorFnc(e.batchCondition, e.systemCondition) && e.requiredCondition && e.spreadCondition
+
+		return e
+	}
+
+	now := wp.clock.Now()
 
 	for _, w := range witnesses {
 		logOK := checkLog(cfg.LogRequired, w.HasLog)
 
 		switch w.Type {
 		case proof.WitnessTypeBatch:
-			totalBatchWitnesses++
+			e.totalBatch++
 
-			if logOK && w.Proof != nil {
-				collectedBatchWitnesses++
+			if logOK && wp.isCollected(w, cfg.WithinBatch, now) {
+				e.collectedBatch++
+
+				e.requiredCondition = e.requiredCondition || cfg.IsRequiredWitness(w.URI.String())
 			}
 
 		case proof.WitnessTypeSystem:
-			totalSystemWitnesses++
+			e.totalSystem++
 
-			if logOK && w.Proof != nil {
-				collectedSystemWitnesses++
+			if logOK && wp.isCollected(w, cfg.WithinSystem, now) {
+				e.collectedSystem++
+
+				e.requiredCondition = e.requiredCondition || cfg.IsRequiredWitness(w.URI.String())
 			}
 		}
 	}
 
-	batchCondition := evaluate(collectedBatchWitnesses, totalBatchWitnesses, cfg.MinNumberBatch, cfg.MinPercentBatch)
-	systemCondition := evaluate(collectedSystemWitnesses, totalSystemWitnesses, cfg.MinNumberSystem, cfg.MinPercentSystem)
+	e.batchCondition = evaluate(e.collectedBatch, e.totalBatch, cfg.MinNumberBatch, cfg.MinPercentBatch)
+	e.systemCondition = evaluate(e.collectedSystem, e.totalSystem, cfg.MinNumberSystem, cfg.MinPercentSystem)
 
-	evaluated := cfg.OperatorFnc(batchCondition, systemCondition)
+	// If the policy has a Spread rule then proofs must be collected from the minimum number of distinct types.
+	e.spreadCondition = e.collectedTypes() >= cfg.MinTypes
 
-	logger.Debug("Witness policy was evaluated.",
-		withPolicyConfigField(cfg), withEvaluatedField(evaluated), withBatchConditionField(batchCondition),
-		withSystemConditionField(systemCondition), withWitnessProofsField(witnesses))
+	e.evaluated = cfg.OperatorFnc(e.batchCondition, e.systemCondition) && e.requiredCondition && e.spreadCondition
 
-	return evaluated, nil
+	return e
+}
+
+// Shortfall contains the number of additional witness proofs of each type that are needed in order to
+// satisfy the witness policy.
+type Shortfall struct {
+	// Batch is the number of additional batch witness proofs that are needed.
+	Batch int
+	// System is the number of additional system witness proofs that are needed.
+	System int
+	// Operator is the operator (AND or OR) that combines the batch and system rules. If the operator
+	// is OR then making up either the batch or the system shortfall is sufficient.
+	Operator string
+	// RequiredWitness is true if a proof from one of the witnesses in the RequireFrom rule is still needed.
+	RequiredWitness bool
+	// Types is the number of additional distinct witness types from which proofs are needed (see the Spread rule).
+	Types int
+}
+
+// Satisfied returns true if no additional witness proofs are needed.
+func (s *Shortfall) Satisfied() bool {
+	return s.Batch == 0 && s.System == 0 && !s.RequiredWitness && s.Types == 0
+}
+
+// Shortfall returns the number of additional witness proofs of each type that are needed in order to satisfy
+// the witness policy given the provided witness proofs (e.g. one more system proof is needed). An empty shortfall
+// is returned if the policy is satisfied. This allows a caller to decide, when Evaluate returns false, whether
+// to wait for more proofs or to select additional witnesses.
+func (wp *WitnessPolicy) Shortfall(witnesses []*proof.WitnessProof) (*Shortfall, error) {
+	cfg, err := wp.getWitnessPolicyConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	e := wp.evaluateDetailed(cfg, validWitnessProofs(witnesses))
+
+	shortfall := &Shortfall{
+		Operator:        cfg.Operator,
+		RequiredWitness: !e.requiredCondition,
+	}
+
+	if !e.spreadCondition {
+		shortfall.Types = cfg.MinTypes - e.collectedTypes()
+	}
+
+	if cfg.OperatorFnc(e.batchCondition, e.systemCondition) {
+		return shortfall, nil
+	}
+
+	if !e.batchCondition {
+		shortfall.Batch = needed(e.collectedBatch, e.totalBatch, cfg.MinNumberBatch, cfg.MinPercentBatch)
+	}
+
+	if !e.systemCondition {
+		shortfall.System = needed(e.collectedSystem, e.totalSystem, cfg.MinNumberSystem, cfg.MinPercentSystem)
+	}
+
+	return shortfall, nil
+}
+
+// Refresh reloads the witness policy from the policy store and replaces the cached policy.
+func (wp *WitnessPolicy) Refresh() error {
+	oldPolicy := wp.loadedPolicy()
+
+	policy, _, err := wp.loadWitnessPolicy("")
+
+	wp.notifyCacheRefresh(oldPolicy, policy, err)
+
+	if err != nil {
+		wp.setDegraded(err)
+
+		return fmt.Errorf("failed to load witness policy: %w", err)
+	}
+
+	err = wp.cache.SetWithExpire(wp.cacheKey, policy, wp.nextCacheExpiry())
+	if err != nil {
+		return fmt.Errorf("failed to set expiry entry in policy cache: %w", err)
+	}
+
+	logger.Debug("Refreshed witness policy cache", log.WithWitnessPolicy(policy.(string)))
+
+	return nil
+}
+
+// Degraded returns true if the last attempt to reload the witness policy from the policy store failed,
+// in which case the last successfully loaded policy is still being used. The time at which the policy
+// was last successfully loaded is also returned.
+func (wp *WitnessPolicy) Degraded() (bool, time.Time) {
+	wp.mutex.RLock()
+	defer wp.mutex.RUnlock()
+
+	return wp.degraded, wp.lastLoadedTime
 }
 
 func (wp *WitnessPolicy) loadWitnessPolicy(interface{}) (interface{}, *time.Duration, error) {
@@ -133,13 +537,111 @@ func (wp *WitnessPolicy) loadWitnessPolicy(interface{}) (interface{}, *time.Dura
 
 	logger.Debug("Loaded witness policy from store", log.WithWitnessPolicy(policy))
 
-	return policy, &wp.cacheExpiry, nil
+	wp.setLoaded(policy)
+
+	expiry := wp.nextCacheExpiry()
+
+	return policy, &expiry, nil
+}
+
+// reloadWitnessPolicy is invoked by the cache when the policy has expired. If the policy can't be
+// loaded from the store then the last successfully loaded policy is returned and the evaluator
+// enters degraded mode.
+func (wp *WitnessPolicy) reloadWitnessPolicy(key interface{}) (interface{}, *time.Duration, error) {
+	oldPolicy := wp.loadedPolicy()
+
+	policy, expiry, err := wp.loadWitnessPolicy(key)
+
+	wp.notifyCacheRefresh(oldPolicy, policy, err)
+
+	if err == nil {
+		return policy, expiry, nil
+	}
+
+	wp.setDegraded(err)
+
+	wp.mutex.RLock()
+	defer wp.mutex.RUnlock()
+
+	if wp.lastLoadedTime.IsZero() {
+		return nil, nil, err
+	}
+
+	lastPolicyExpiry := wp.nextCacheExpiry()
+
+	return wp.lastPolicy, &lastPolicyExpiry, nil
+}
+
+// nextCacheExpiry returns the cache expiry plus a random jitter (if configured).
+func (wp *WitnessPolicy) nextCacheExpiry() time.Duration {
+	if wp.cacheJitter <= 0 {
+		return wp.cacheExpiry
+	}
+
+	return wp.cacheExpiry + time.Duration(rand.Int63n(int64(wp.cacheJitter)+1)) //nolint:gosec
+}
+
+func (wp *WitnessPolicy) setLoaded(policy string) {
+	wp.mutex.Lock()
+	defer wp.mutex.Unlock()
+
+	if wp.degraded {
+		logger.Info("Witness policy was successfully reloaded. Exiting degraded mode.")
+	}
+
+	wp.degraded = false
+	wp.lastLoadedTime = wp.clock.Now()
+	wp.lastPolicy = policy
+
+	if wp.evaluationCache != nil {
+		wp.evaluationCache.purge()
+	}
+}
+
+func (wp *WitnessPolicy) loadedPolicy() string {
+	wp.mutex.RLock()
+	defer wp.mutex.RUnlock()
+
+	return wp.lastPolicy
+}
+
+func (wp *WitnessPolicy) notifyCacheRefresh(oldPolicy string, newPolicy interface{}, err error) {
+	if wp.cacheRefreshHandler == nil {
+		return
+	}
+
+	if err != nil {
+		wp.cacheRefreshHandler(oldPolicy, "", err)
+
+		return
+	}
+
+	wp.cacheRefreshHandler(oldPolicy, newPolicy.(string), nil)
+}
+
+func (wp *WitnessPolicy) setDegraded(err error) {
+	wp.mutex.Lock()
+	defer wp.mutex.Unlock()
+
+	if !wp.degraded {
+		logger.Warn("Error reloading witness policy. Entering degraded mode in which the last successfully "+
+			"loaded policy is used.", log.WithWitnessPolicy(wp.lastPolicy), log.WithError(err))
+	}
+
+	wp.degraded = true
 }
 
 func (wp *WitnessPolicy) getWitnessPolicyConfig() (*config.WitnessPolicyConfig, error) {
-	value, err := wp.cache.Get(WitnessPolicyKey)
+	value, err := wp.cache.Get(wp.cacheKey)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve policy from policy cache: %w", err)
+		if !isCacheMiss(err) {
+			return nil, fmt.Errorf("failed to retrieve policy from policy cache: %w", err)
+		}
+
+		value, err = wp.loadIntoCache()
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	if value == nil {
@@ -159,6 +661,52 @@ func (wp *WitnessPolicy) getWitnessPolicyConfig() (*config.WitnessPolicyConfig,
 	return policyCfg, nil
 }
 
+// loadIntoCache loads the witness policy from the policy store and adds it to the cache. This is only
+// necessary for a custom cache since the default cache invokes the loader function on a cache miss.
+func (wp *WitnessPolicy) loadIntoCache() (interface{}, error) {
+	logger.Debug("Witness policy not found in cache. Loading policy from store.")
+
+	policy, expiry, err := wp.reloadWitnessPolicy(wp.cacheKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load witness policy: %w", err)
+	}
+
+	err = wp.cache.SetWithExpire(wp.cacheKey, policy, *expiry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set expiry entry in policy cache: %w", err)
+	}
+
+	return policy, nil
+}
+
+func isCacheMiss(err error) bool {
+	return errors.Is(err, ErrCacheMiss) || errors.Is(err, gcache.KeyNotFoundError)
+}
+
+// needed returns the number of additional proofs needed for an unsatisfied rule, i.e. the lesser of the number
+// needed to reach the minimum number and the number needed to reach the minimum percentage of the total.
+func needed(collected, total, minNumber, minPercent int) int {
+	if total == 0 {
+		if minNumber > 0 {
+			return minNumber
+		}
+
+		return 1
+	}
+
+	n := int(math.Ceil(float64(minPercent*total)/maxPercent)) - collected
+
+	if minNumber != 0 && minNumber-collected < n {
+		n = minNumber - collected
+	}
+
+	if n < 1 {
+		return 1
+	}
+
+	return n
+}
+
 func evaluate(collected, total, minNumber, minPercent int) bool {
 	percentCollected := float64(maxPercent)
 	if total != 0 {
@@ -169,6 +717,61 @@ func evaluate(collected, total, minNumber, minPercent int) bool {
 		percentCollected >= float64(minPercent)/maxPercent
 }
 
+// isCollected returns true if a valid proof was collected from the given witness. A proof that
+// can't be decoded or fails verification is treated as not collected. If a trailing time window is specified (by the
+// OutOfWithin rule) then the proof must also have been created within the window, so a proof
+// that doesn't contain a created time is not counted.
+func (wp *WitnessPolicy) isCollected(w *proof.WitnessProof, window time.Duration, now time.Time) bool {
+	if w.Proof == nil {
+		return false
+	}
+
+	decoded, err := w.Decode()
+	if err != nil {
+		logger.Debug("Witness proof is not counted since it could not be decoded",
+			log.WithWitnessURI(w.URI), log.WithProof(w.Proof), log.WithError(err))
+
+		return false
+	}
+
+	if err := wp.proofVerifier.Verify(w); err != nil {
+		logger.Warn("Witness proof is not counted since it failed verification",
+			log.WithWitnessURI(w.URI), log.WithProof(w.Proof), log.WithError(err))
+
+		return false
+	}
+
+	if window == 0 {
+		return true
+	}
+
+	if decoded.Created.IsZero() {
+		logger.Debug("Witness proof is not counted since it doesn't contain a created time",
+			log.WithWitnessURI(w.URI), log.WithProof(w.Proof))
+
+		return false
+	}
+
+	if decoded.Created.Before(now.Add(-window)) {
+		logger.Debug("Witness proof is not counted since it was created outside of the time window",
+			log.WithWitnessURI(w.URI), log.WithCreatedTime(decoded.Created))
+
+		return false
+	}
+
+	return true
+}
+
+type noopProofVerifier struct{}
+
+func (v *noopProofVerifier) Verify(*proof.WitnessProof) error {
+	return nil
+}
+
+func noRequirements(minNumber, minPercent int) bool {
+	return minNumber == 0 && minPercent == 0
+}
+
 func checkLog(logRequired, hasLog bool) bool {
 	if logRequired {
 		return hasLog
@@ -178,62 +781,277 @@ func checkLog(logRequired, hasLog bool) bool {
 	return true
 }
 
+// Validate checks whether the given set of available witnesses could ever satisfy the witness policy, i.e.
+// whether the minimum number of witnesses required by the policy may be selected from the eligible witnesses.
+// This allows a misconfiguration (e.g. the policy requires system witnesses but none are configured) to be
+// detected early. An error that wraps ErrPolicyUnsatisfiable is returned if the policy can't be satisfied.
+func (wp *WitnessPolicy) Validate(available []*proof.Witness) error {
+	cfg, err := wp.getWitnessPolicyConfig()
+	if err != nil {
+		return err
+	}
+
+	available = validWitnesses(available)
+
+	var totalBatch, eligibleBatch, totalSystem, eligibleSystem int
+
+	requiredOK := len(cfg.RequiredWitnesses) == 0
+
+	for _, w := range available {
+		logOK := checkLog(cfg.LogRequired, w.HasLog)
+
+		if logOK && cfg.IsRequiredWitness(w.URI.String()) {
+			requiredOK = true
+		}
+
+		switch w.Type {
+		case proof.WitnessTypeBatch:
+			totalBatch++
+
+			if logOK {
+				eligibleBatch++
+			}
+
+		case proof.WitnessTypeSystem:
+			totalSystem++
+
+			if logOK {
+				eligibleSystem++
+			}
+		}
+	}
+
+	batchOK := satisfiable(eligibleBatch, totalBatch, cfg.MinNumberBatch, cfg.MinPercentBatch)
+	systemOK := satisfiable(eligibleSystem, totalSystem, cfg.MinNumberSystem, cfg.MinPercentSystem)
+
+	if !requiredOK {
+		return fmt.Errorf("%w: policy[%s], none of the required witnesses is available",
+			ErrPolicyUnsatisfiable, cfg)
+	}
+
+	if eligibleTypes := numTypes(eligibleBatch, eligibleSystem); eligibleTypes < cfg.MinTypes {
+		return fmt.Errorf("%w: policy[%s], eligible witnesses of only %d type(s) are available",
+			ErrPolicyUnsatisfiable, cfg, eligibleTypes)
+	}
+
+	if cfg.OperatorFnc(batchOK, systemOK) {
+		return nil
+	}
+
+	return fmt.Errorf("%w: policy[%s], batch witnesses (eligible/total)[%d/%d], system witnesses (eligible/total)[%d/%d]",
+		ErrPolicyUnsatisfiable, cfg, eligibleBatch, totalBatch, eligibleSystem, totalSystem)
+}
+
+// Selection contains the witnesses selected by SelectDetailed along with the policy rules that each witness
+// was selected to satisfy.
+type Selection struct {
+	// Witnesses are the selected witnesses (the same witnesses that are returned by Select).
+	Witnesses []*proof.Witness
+	// Rules maps the URI of each selected witness to the rule(s) that the witness was selected to satisfy,
+	// e.g. "OutOf(1,batch)". A witness that is both a batch and a system witness may satisfy both rules of
+	// an AND policy.
+	Rules map[string][]string
+}
+
 // Select selects min number of witnesses required based on witness policy.
 func (wp *WitnessPolicy) Select(witnesses []*proof.Witness, exclude ...*proof.Witness) ([]*proof.Witness, error) {
+	selection, err := wp.SelectDetailed(witnesses, exclude...)
+	if err != nil {
+		return nil, err
+	}
+
+	return selection.Witnesses, nil
+}
+
+// SelectDetailed selects min number of witnesses required based on witness policy (in the same way as Select)
+// and also reports the policy rule(s) that each witness was selected to satisfy.
+func (wp *WitnessPolicy) SelectDetailed(witnesses []*proof.Witness, exclude ...*proof.Witness) (*Selection, error) {
+	start := time.Now()
+
 	cfg, err := wp.getWitnessPolicyConfig()
 	if err != nil {
 		return nil, err
 	}
 
-	selectedBatchWitnesses, selectedSystemWitnesses, err := wp.selectBatchAndSystemWitnesses(witnesses, cfg, exclude...)
+	witnesses = validWitnesses(witnesses)
+	exclude = validWitnesses(exclude)
+
+	selectBatchAndSystemWitnesses := wp.selectBatchAndSystemWitnesses
+	if wp.fewestOR && cfg.Operator == config.OR {
+		selectBatchAndSystemWitnesses = wp.selectFewestWitnesses
+	}
+
+	selectedBatchWitnesses, selectedSystemWitnesses, err := selectBatchAndSystemWitnesses(witnesses, cfg, exclude...)
 	if err != nil {
 		return nil, err
 	}
 
-	if cfg.Operator == config.AND {
-		return append(selectedBatchWitnesses, selectedSystemWitnesses...), nil
+	batchRule := selectionRule(config.RoleBatch, cfg.MinNumberBatch, cfg.MinPercentBatch, cfg.WithinBatch)
+	systemRule := selectionRule(config.RoleSystem, cfg.MinNumberSystem, cfg.MinPercentSystem, cfg.WithinSystem)
+
+	selection := &Selection{Rules: make(map[string][]string)}
+
+	switch {
+	// With the AND operator both branches are selected. With WithFewestWitnessesForOR only the chosen branch
+	// was selected (the other one is empty).
+	case cfg.Operator == config.AND || wp.fewestOR:
+		selection.Witnesses = append(selectedBatchWitnesses, selectedSystemWitnesses...)
+
+		selection.addRule(batchRule, selectedBatchWitnesses)
+		selection.addRule(systemRule, selectedSystemWitnesses)
+
+	case len(selectedBatchWitnesses) == 0 || len(selectedSystemWitnesses) < len(selectedBatchWitnesses):
+		selection.Witnesses = selectedSystemWitnesses
+
+		selection.addRule(systemRule, selectedSystemWitnesses)
+
+	default:
+		selection.Witnesses = selectedBatchWitnesses
+
+		selection.addRule(batchRule, selectedBatchWitnesses)
 	}
 
-	if len(selectedBatchWitnesses) == 0 || len(selectedSystemWitnesses) < len(selectedBatchWitnesses) {
-		return selectedSystemWitnesses, nil
+	if err := selection.addRequiredWitness(witnesses, cfg, exclude...); err != nil {
+		return nil, err
 	}
 
-	return selectedBatchWitnesses, nil
+	selection.addSpreadWitnesses(witnesses, cfg, exclude...)
+
+	duration := time.Since(start)
+
+	if wp.metrics != nil {
+		wp.metrics.WitnessPolicySelectTime(duration)
+	}
+
+	if logger.IsEnabled(log.DEBUG) {
+		logger.Debug("Selected witnesses", log.WithWitnessPolicy(cfg.Policy()), withPolicyConfigField(cfg),
+			withWitnessesField(selection.Witnesses), withSelectionRulesField(selection.Rules),
+			log.WithEvaluationDuration(duration))
+	}
+
+	return selection, nil
 }
 
-// selects min number of batch and system witnesses that are required to fulfill witness policy.
-func (wp *WitnessPolicy) selectBatchAndSystemWitnesses(witnesses []*proof.Witness, // nolint:funlen,gocyclo,cyclop
-	cfg *config.WitnessPolicyConfig, exclude ...*proof.Witness) ([]*proof.Witness, []*proof.Witness, error) {
-	logger.Debug("Selecting minimum number of batch and system witnesses based on policy",
-		withPolicyConfigField(cfg), withWitnessesField(witnesses))
+// addRequiredWitness ensures that, if the policy has a RequireFrom rule, the selection includes one of the
+// required witnesses. An error that wraps ErrPolicyUnsatisfiable is returned if none of the required
+// witnesses is eligible.
+func (s *Selection) addRequiredWitness(witnesses []*proof.Witness, cfg *config.WitnessPolicyConfig,
+	exclude ...*proof.Witness) error {
+	if len(cfg.RequiredWitnesses) == 0 {
+		return nil
+	}
 
-	var eligibleBatchWitnesses []*proof.Witness
+	rule := fmt.Sprintf("%s(%s)", config.RequireFrom, strings.Join(cfg.RequiredWitnesses, ","))
 
-	var eligibleSystemWitnesses []*proof.Witness
+	for _, w := range s.Witnesses {
+		if cfg.IsRequiredWitness(w.URI.String()) {
+			s.addRule(rule, []*proof.Witness{w})
 
-	totalSystemWitnesses := 0
-	totalBatchWitnesses := 0
+			return nil
+		}
+	}
 
 	for _, w := range witnesses {
-		logOK := checkLog(cfg.LogRequired, w.HasLog)
+		if cfg.IsRequiredWitness(w.URI.String()) && checkLog(cfg.LogRequired, w.HasLog) && !isExcluded(w, exclude...) {
+			s.Witnesses = append(s.Witnesses, w)
 
-		switch w.Type {
-		case proof.WitnessTypeBatch:
-			totalBatchWitnesses++
+			s.addRule(rule, []*proof.Witness{w})
 
-			if logOK && !isExcluded(w, exclude...) {
-				eligibleBatchWitnesses = append(eligibleBatchWitnesses, w)
-			}
+			return nil
+		}
+	}
 
-		case proof.WitnessTypeSystem:
-			totalSystemWitnesses++
+	return fmt.Errorf("%w: none of the required witnesses %s is eligible for selection",
+		ErrPolicyUnsatisfiable, cfg.RequiredWitnesses)
+}
 
-			if logOK && !isExcluded(w, exclude...) {
-				eligibleSystemWitnesses = append(eligibleSystemWitnesses, w)
+// addSpreadWitnesses ensures that, if the policy has a Spread rule, the selection includes witnesses of at least
+// the minimum number of distinct types. For each type that isn't represented in the selection, an eligible witness
+// of that type is added. The Spread rule is only enforced on a best-effort basis since the witnesses of a type may
+// not be eligible (see Validate).
+func (s *Selection) addSpreadWitnesses(witnesses []*proof.Witness, cfg *config.WitnessPolicyConfig,
+	exclude ...*proof.Witness) {
+	if cfg.MinTypes == 0 {
+		return
+	}
+
+	rule := fmt.Sprintf("%s(%d)", config.Spread, cfg.MinTypes)
+
+	selectedTypes := make(map[proof.WitnessType]bool)
+
+	for _, w := range s.Witnesses {
+		selectedTypes[w.Type] = true
+	}
+
+	for _, witnessType := range []proof.WitnessType{proof.WitnessTypeBatch, proof.WitnessTypeSystem} {
+		if len(selectedTypes) >= cfg.MinTypes {
+			return
+		}
+
+		if selectedTypes[witnessType] {
+			continue
+		}
+
+		for _, w := range witnesses {
+			if w.Type == witnessType && checkLog(cfg.LogRequired, w.HasLog) && !isExcluded(w, exclude...) {
+				s.Witnesses = append(s.Witnesses, w)
+
+				s.addRule(rule, []*proof.Witness{w})
+
+				selectedTypes[witnessType] = true
+
+				break
 			}
 		}
 	}
 
+	if len(selectedTypes) < cfg.MinTypes {
+		logger.Debug("Unable to select witnesses of the minimum number of distinct types",
+			withPolicyConfigField(cfg), log.WithTotal(len(selectedTypes)))
+	}
+}
+
+func (s *Selection) addRule(rule string, witnesses []*proof.Witness) {
+	for _, w := range witnesses {
+		uri := w.URI.String()
+
+		if !contains(s.Rules[uri], rule) {
+			s.Rules[uri] = append(s.Rules[uri], rule)
+		}
+	}
+}
+
+// selectionRule returns the policy rule for the given role, e.g. "OutOf(2,system)".
+func selectionRule(role string, minNumber, minPercent int, within time.Duration) string {
+	switch {
+	case minNumber > 0 && within > 0:
+		return fmt.Sprintf("%s(%d,%s,%s)", config.OutOfWithin, minNumber, role, within)
+	case minNumber > 0:
+		return fmt.Sprintf("%s(%d,%s)", config.OutOf, minNumber, role)
+	default:
+		return fmt.Sprintf("%s(%d,%s)", config.MinPercent, minPercent, role)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// selects min number of batch and system witnesses that are required to fulfill witness policy.
+func (wp *WitnessPolicy) selectBatchAndSystemWitnesses(witnesses []*proof.Witness, // nolint:funlen,gocyclo,cyclop
+	cfg *config.WitnessPolicyConfig, exclude ...*proof.Witness) ([]*proof.Witness, []*proof.Witness, error) {
+	logger.Debug("Selecting minimum number of batch and system witnesses based on policy",
+		withPolicyConfigField(cfg), withWitnessesField(witnesses))
+
+	eligibleBatchWitnesses, totalBatchWitnesses, eligibleSystemWitnesses, totalSystemWitnesses :=
+		eligibleWitnesses(witnesses, cfg, exclude...)
+
 	logger.Debug("Selecting minimum number of witnesses based on policy and eligible batch and system witnesses",
 		withPolicyConfigField(cfg), withBatchWitnessesField(eligibleBatchWitnesses),
 		withSystemWitnessesField(eligibleSystemWitnesses))
@@ -243,7 +1061,7 @@ func (wp *WitnessPolicy) selectBatchAndSystemWitnesses(witnesses []*proof.Witnes
 	var commonWitnesses []*proof.Witness
 
 	if cfg.Operator == config.AND {
-		commonWitnesses = intersection(eligibleBatchWitnesses, eligibleSystemWitnesses)
+		commonWitnesses = witnessset.Intersection(eligibleBatchWitnesses, eligibleSystemWitnesses)
 	}
 
 	// it is possible to have 0 zero eligible batch witnesses
@@ -274,6 +1092,99 @@ func (wp *WitnessPolicy) selectBatchAndSystemWitnesses(witnesses []*proof.Witnes
 	return selectedBatchWitnesses, selectedSystemWitnesses, nil
 }
 
+// numTypes returns the number of distinct witness types given the number of batch and system witnesses.
+func numTypes(numBatch, numSystem int) int {
+	types := 0
+
+	if numBatch > 0 {
+		types++
+	}
+
+	if numSystem > 0 {
+		types++
+	}
+
+	return types
+}
+
+// satisfiable returns true if the minimum number of witnesses (or, if no minimum number is specified, the
+// minimum percentage of witnesses) may be selected from the eligible witnesses.
+func satisfiable(eligible, total, minNumber, minPercent int) bool {
+	if minNumber > 0 {
+		return eligible >= minNumber
+	}
+
+	return eligible >= int(math.Ceil(float64(minPercent)/maxPercent*float64(total)))
+}
+
+// validWitnesses returns the given witnesses without the witnesses that have no URI. Such witnesses are
+// malformed and are ignored so that they don't affect the policy calculations (or collide when witnesses
+// are de-duplicated by URI).
+func validWitnesses(witnesses []*proof.Witness) []*proof.Witness {
+	var valid []*proof.Witness
+
+	for i, w := range witnesses {
+		if hasURI(w) {
+			if valid != nil {
+				valid = append(valid, w)
+			}
+
+			continue
+		}
+
+		logger.Warn("Ignoring witness since it has no URI", withWitnessField(w))
+
+		if valid == nil {
+			valid = make([]*proof.Witness, i, len(witnesses))
+			copy(valid, witnesses[:i])
+		}
+	}
+
+	if valid == nil {
+		return witnesses
+	}
+
+	return valid
+}
+
+// validWitnessProofs returns the given witness proofs without the proofs from witnesses that have no URI.
+// Such proofs are malformed and are ignored so that they aren't counted toward the policy.
+func validWitnessProofs(witnesses []*proof.WitnessProof) []*proof.WitnessProof {
+	var valid []*proof.WitnessProof
+
+	for i, w := range witnesses {
+		if w != nil && hasURI(w.Witness) {
+			if valid != nil {
+				valid = append(valid, w)
+			}
+
+			continue
+		}
+
+		var witness *proof.Witness
+		if w != nil {
+			witness = w.Witness
+		}
+
+		logger.Warn("Ignoring witness proof since the witness has no URI", withWitnessField(witness))
+
+		if valid == nil {
+			valid = make([]*proof.WitnessProof, i, len(witnesses))
+			copy(valid, witnesses[:i])
+		}
+	}
+
+	if valid == nil {
+		return witnesses
+	}
+
+	return valid
+}
+
+func hasURI(w *proof.Witness) bool {
+	return w != nil && w.URI.String() != ""
+}
+
 func isExcluded(witness *proof.Witness, excluded ...*proof.Witness) bool {
 	for _, e := range excluded {
 		if witness.URI.String() == e.URI.String() {
@@ -284,6 +1195,80 @@ func isExcluded(witness *proof.Witness, excluded ...*proof.Witness) bool {
 	return false
 }
 
+// selectFewestWitnesses selects witnesses for an OR policy (see WithFewestWitnessesForOR). Only one of the returned
+// batch and system selections is non-empty. An error is returned only if neither branch can be satisfied.
+func (wp *WitnessPolicy) selectFewestWitnesses(witnesses []*proof.Witness, cfg *config.WitnessPolicyConfig,
+	exclude ...*proof.Witness) ([]*proof.Witness, []*proof.Witness, error) {
+	eligibleBatchWitnesses, totalBatchWitnesses, eligibleSystemWitnesses, totalSystemWitnesses :=
+		eligibleWitnesses(witnesses, cfg, exclude...)
+
+	selectedBatchWitnesses, errBatch := wp.selectMinWitnesses(eligibleBatchWitnesses, cfg.MinNumberBatch,
+		cfg.MinPercentBatch, totalBatchWitnesses)
+
+	selectedSystemWitnesses, errSystem := wp.selectMinWitnesses(eligibleSystemWitnesses, cfg.MinNumberSystem,
+		cfg.MinPercentSystem, totalSystemWitnesses)
+
+	switch {
+	case errBatch != nil && errSystem != nil:
+		return nil, nil, fmt.Errorf("select witnesses based on witnesses%s, exclude%s, policy[%s]: batch: %s, system: %w",
+			witnesses, exclude, cfg, errBatch, errSystem)
+	case errBatch != nil:
+		logger.Debug("Batch witnesses can't be selected. Choosing system witnesses.", withPolicyConfigField(cfg),
+			withSystemWitnessesField(selectedSystemWitnesses), log.WithError(errBatch))
+
+		return nil, selectedSystemWitnesses, nil
+	case errSystem != nil:
+		logger.Debug("System witnesses can't be selected. Choosing batch witnesses.", withPolicyConfigField(cfg),
+			withBatchWitnessesField(selectedBatchWitnesses), log.WithError(errSystem))
+
+		return selectedBatchWitnesses, nil, nil
+	case len(selectedSystemWitnesses) < len(selectedBatchWitnesses):
+		logger.Debug("Choosing system witnesses since fewer witnesses are required", withPolicyConfigField(cfg),
+			withBatchWitnessesField(selectedBatchWitnesses), withSystemWitnessesField(selectedSystemWitnesses))
+
+		return nil, selectedSystemWitnesses, nil
+	default:
+		logger.Debug("Choosing batch witnesses since no more witnesses are required", withPolicyConfigField(cfg),
+			withBatchWitnessesField(selectedBatchWitnesses), withSystemWitnessesField(selectedSystemWitnesses))
+
+		return selectedBatchWitnesses, nil, nil
+	}
+}
+
+// eligibleWitnesses returns the batch and system witnesses that are eligible for selection along with the total
+// number of batch and system witnesses.
+func eligibleWitnesses(witnesses []*proof.Witness, cfg *config.WitnessPolicyConfig,
+	exclude ...*proof.Witness) ([]*proof.Witness, int, []*proof.Witness, int) {
+	var eligibleBatchWitnesses []*proof.Witness
+
+	var eligibleSystemWitnesses []*proof.Witness
+
+	totalSystemWitnesses := 0
+	totalBatchWitnesses := 0
+
+	for _, w := range witnesses {
+		logOK := checkLog(cfg.LogRequired, w.HasLog)
+
+		switch w.Type {
+		case proof.WitnessTypeBatch:
+			totalBatchWitnesses++
+
+			if logOK && !isExcluded(w, exclude...) {
+				eligibleBatchWitnesses = append(eligibleBatchWitnesses, w)
+			}
+
+		case proof.WitnessTypeSystem:
+			totalSystemWitnesses++
+
+			if logOK && !isExcluded(w, exclude...) {
+				eligibleSystemWitnesses = append(eligibleSystemWitnesses, w)
+			}
+		}
+	}
+
+	return eligibleBatchWitnesses, totalBatchWitnesses, eligibleSystemWitnesses, totalSystemWitnesses
+}
+
 func (wp *WitnessPolicy) selectMinWitnesses(eligible []*proof.Witness,
 	minNumber, minPercent, totalWitnesses int, preferred ...*proof.Witness) ([]*proof.Witness, error) {
 	var selected []*proof.Witness
@@ -297,10 +1282,17 @@ func (wp *WitnessPolicy) selectMinWitnesses(eligible []*proof.Witness,
 		minSelection = int(math.Ceil(float64(minPercent)/maxPercent*float64(totalWitnesses))) - len(preferred)
 	}
 
+	remaining := witnessset.Difference(eligible, preferred)
+
+	operatorPreferred := wp.preferredWitnesses(remaining, minSelection)
+
+	selected = append(selected, operatorPreferred...)
+	minSelection -= len(operatorPreferred)
+
 	logger.Debug("Selecting witnesses from eligible and preferred", log.WithMinimum(minSelection),
-		withEligibleWitnessesField(eligible), withPreferredWitnessesField(preferred))
+		withEligibleWitnessesField(eligible), withPreferredWitnessesField(selected))
 
-	selection, err := wp.selector.Select(difference(eligible, preferred), minSelection)
+	selection, err := wp.selector.Select(witnessset.Difference(remaining, operatorPreferred), minSelection)
 	if err != nil {
 		return nil, err
 	}
@@ -310,37 +1302,24 @@ func (wp *WitnessPolicy) selectMinWitnesses(eligible []*proof.Witness,
 	return selected, nil
 }
 
-func intersection(a, b []*proof.Witness) []*proof.Witness {
-	var result []*proof.Witness
-
-	hash := make(map[string]bool)
-	for _, e := range a {
-		hash[e.URI.String()] = false
-	}
+// preferredWitnesses returns up to max of the given eligible witnesses that are also in the operator's
+// preferred list. The witnesses are returned in the order of the preferred list.
+func (wp *WitnessPolicy) preferredWitnesses(eligible []*proof.Witness, max int) []*proof.Witness {
+	var selected []*proof.Witness
 
-	for _, e := range b {
-		if v, ok := hash[e.URI.String()]; ok && !v {
-			result = append(result, e)
-			hash[e.URI.String()] = true
+	for _, uri := range wp.preferred {
+		if len(selected) >= max {
+			break
 		}
-	}
-
-	return result
-}
 
-func difference(a, b []*proof.Witness) []*proof.Witness {
-	var result []*proof.Witness
+		for _, w := range eligible {
+			if w.URI.String() == uri.String() {
+				selected = append(selected, w)
 
-	hash := make(map[string]bool)
-	for _, e := range b {
-		hash[e.URI.String()] = true
-	}
-
-	for _, e := range a {
-		if _, ok := hash[e.URI.String()]; !ok {
-			result = append(result, e)
+				break
+			}
 		}
 	}
 
-	return result
+	return selected
 }
@@ -7,20 +7,29 @@ SPDX-License-Identifier: Apache-2.0
 package policy
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"net/url"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/bluele/gcache"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
 
+	"github.com/trustbloc/orb/internal/pkg/log"
 	"github.com/trustbloc/orb/pkg/activitypub/vocab"
 	"github.com/trustbloc/orb/pkg/anchor/witness/policy/mocks"
 	"github.com/trustbloc/orb/pkg/anchor/witness/proof"
+	"github.com/trustbloc/orb/pkg/internal/testutil"
 )
 
 const (
 	defaultPolicyCacheExpiry = 5 * time.Second
+
+	testProof = `{"proof":{"type":"JsonWebSignature2020","proofPurpose":"assertionMethod","jws":"eyJhbGciOiJFZERTQSJ9..abc"}}`
 )
 
 func TestNew(t *testing.T) {
@@ -36,11 +45,17 @@ func TestNew(t *testing.T) {
 		policyStore := &mocks.PolicyStore{}
 		policyStore.GetPolicyReturns("MinPercent(30,system) AND MinPercent(70,batch)", nil)
 
-		wp, err := New(policyStore, 1*time.Second)
+		clock := gcache.NewFakeClock()
+
+		wp, err := New(policyStore, 1*time.Second, WithClock(clock))
 		require.NoError(t, err)
 		require.NotNil(t, wp)
 
-		time.Sleep(2 * time.Second)
+		clock.Advance(2 * time.Second)
+
+		_, err = wp.getWitnessPolicyConfig()
+		require.NoError(t, err)
+		require.Equal(t, 2, policyStore.GetPolicyCallCount())
 	})
 
 	t.Run("error - config store error", func(t *testing.T) {
@@ -83,14 +98,14 @@ func TestEvaluate(t *testing.T) {
 					Type: proof.WitnessTypeBatch,
 					URI:  vocab.NewURLProperty(witnessURL),
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 			{
 				Witness: &proof.Witness{
 					Type: proof.WitnessTypeSystem,
 					URI:  vocab.NewURLProperty(witnessURL),
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 		}
 
@@ -114,7 +129,7 @@ func TestEvaluate(t *testing.T) {
 					URI:    vocab.NewURLProperty(batchWitnessURL),
 					HasLog: true,
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 			{
 				Witness: &proof.Witness{
@@ -122,7 +137,7 @@ func TestEvaluate(t *testing.T) {
 					URI:    vocab.NewURLProperty(systemWitnessURL),
 					HasLog: true,
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 		}
 
@@ -146,7 +161,7 @@ func TestEvaluate(t *testing.T) {
 					URI:    vocab.NewURLProperty(batchWitnessURL),
 					HasLog: false,
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 			{
 				Witness: &proof.Witness{
@@ -154,7 +169,7 @@ func TestEvaluate(t *testing.T) {
 					URI:    vocab.NewURLProperty(systemWitnessURL),
 					HasLog: true,
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 		}
 
@@ -178,7 +193,7 @@ func TestEvaluate(t *testing.T) {
 					URI:    vocab.NewURLProperty(batchWitnessURL),
 					HasLog: true,
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 			{
 				Witness: &proof.Witness{
@@ -186,7 +201,7 @@ func TestEvaluate(t *testing.T) {
 					URI:    vocab.NewURLProperty(batchWitness2URL),
 					HasLog: false,
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 			{
 				Witness: &proof.Witness{
@@ -194,7 +209,7 @@ func TestEvaluate(t *testing.T) {
 					URI:    vocab.NewURLProperty(systemWitnessURL),
 					HasLog: true,
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 			{
 				Witness: &proof.Witness{
@@ -202,7 +217,7 @@ func TestEvaluate(t *testing.T) {
 					URI:    vocab.NewURLProperty(systemWitnessURL),
 					HasLog: false,
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 		}
 
@@ -226,7 +241,7 @@ func TestEvaluate(t *testing.T) {
 					URI:    vocab.NewURLProperty(systemWitnessURL),
 					HasLog: false,
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 		}
 
@@ -250,7 +265,7 @@ func TestEvaluate(t *testing.T) {
 					URI:    vocab.NewURLProperty(systemWitnessURL),
 					HasLog: false,
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 			{
 				Witness: &proof.Witness{
@@ -258,7 +273,7 @@ func TestEvaluate(t *testing.T) {
 					URI:    vocab.NewURLProperty(batchWitnessURL),
 					HasLog: true,
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 		}
 
@@ -282,7 +297,7 @@ func TestEvaluate(t *testing.T) {
 					URI:    vocab.NewURLProperty(systemWitnessURL),
 					HasLog: true,
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 			{
 				Witness: &proof.Witness{
@@ -290,7 +305,7 @@ func TestEvaluate(t *testing.T) {
 					URI:    vocab.NewURLProperty(batchWitnessURL),
 					HasLog: true,
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 		}
 
@@ -314,7 +329,7 @@ func TestEvaluate(t *testing.T) {
 					URI:    vocab.NewURLProperty(systemWitnessURL),
 					HasLog: false,
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 			{
 				Witness: &proof.Witness{
@@ -322,7 +337,7 @@ func TestEvaluate(t *testing.T) {
 					URI:    vocab.NewURLProperty(batchWitnessURL),
 					HasLog: true,
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 		}
 
@@ -384,14 +399,14 @@ func TestEvaluate(t *testing.T) {
 					Type: proof.WitnessTypeBatch,
 					URI:  vocab.NewURLProperty(batchWitnessURL),
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 			{
 				Witness: &proof.Witness{
 					Type: proof.WitnessTypeBatch,
 					URI:  vocab.NewURLProperty(batchWitness2URL),
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 			{
 				Witness: &proof.Witness{
@@ -426,21 +441,21 @@ func TestEvaluate(t *testing.T) {
 					Type: proof.WitnessTypeBatch,
 					URI:  vocab.NewURLProperty(batchWitnessURL),
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 			{
 				Witness: &proof.Witness{
 					Type: proof.WitnessTypeBatch,
 					URI:  vocab.NewURLProperty(batchWitness2URL),
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 			{
 				Witness: &proof.Witness{
 					Type: proof.WitnessTypeSystem,
 					URI:  vocab.NewURLProperty(systemWitnessURL),
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 			{
 				Witness: &proof.Witness{
@@ -469,7 +484,7 @@ func TestEvaluate(t *testing.T) {
 					Type: proof.WitnessTypeBatch,
 					URI:  vocab.NewURLProperty(batchWitnessURL),
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 			{
 				Witness: &proof.Witness{
@@ -482,7 +497,7 @@ func TestEvaluate(t *testing.T) {
 					Type: proof.WitnessTypeSystem,
 					URI:  vocab.NewURLProperty(systemWitnessURL),
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 			{
 				Witness: &proof.Witness{
@@ -511,7 +526,7 @@ func TestEvaluate(t *testing.T) {
 					Type: proof.WitnessTypeBatch,
 					URI:  vocab.NewURLProperty(batchWitnessURL),
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 			{
 				Witness: &proof.Witness{
@@ -552,7 +567,7 @@ func TestEvaluate(t *testing.T) {
 					Type: proof.WitnessTypeBatch,
 					URI:  vocab.NewURLProperty(batchWitnessURL),
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 			{
 				Witness: &proof.Witness{
@@ -587,21 +602,21 @@ func TestEvaluate(t *testing.T) {
 					Type: proof.WitnessTypeBatch,
 					URI:  vocab.NewURLProperty(batchWitnessURL),
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 			{
 				Witness: &proof.Witness{
 					Type: proof.WitnessTypeBatch,
 					URI:  vocab.NewURLProperty(batchWitness2URL),
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 			{
 				Witness: &proof.Witness{
 					Type: proof.WitnessTypeSystem,
 					URI:  vocab.NewURLProperty(systemWitnessURL),
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 			{
 				Witness: &proof.Witness{
@@ -616,6 +631,100 @@ func TestEvaluate(t *testing.T) {
 		require.Equal(t, true, ok)
 	})
 
+	t.Run("success - nil and empty proofs", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			policy    string
+			satisfied bool
+		}{
+			{name: "default policy", policy: "", satisfied: false},
+			{name: "zero requirement policy", policy: "OutOf(0,batch) AND OutOf(0,system)", satisfied: true},
+			{name: "zero batch requirement (OR)", policy: "MinPercent(0,batch) OR OutOf(1,system)", satisfied: true},
+			{name: "zero batch requirement (AND)", policy: "MinPercent(0,batch) AND OutOf(1,system)", satisfied: false},
+		}
+
+		for _, tc := range tests {
+			policyStore := &mocks.PolicyStore{}
+			policyStore.GetPolicyReturns(tc.policy, nil)
+
+			wp, err := New(policyStore, defaultPolicyCacheExpiry)
+			require.NoError(t, err)
+
+			ok, err := wp.Evaluate(nil)
+			require.NoError(t, err, tc.name)
+			require.Equal(t, tc.satisfied, ok, tc.name)
+
+			ok, err = wp.Evaluate([]*proof.WitnessProof{})
+			require.NoError(t, err, tc.name)
+			require.Equal(t, tc.satisfied, ok, tc.name)
+		}
+	})
+
+	t.Run("success - OutOfWithin (proofs straddling the window)", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("OutOfWithin(2,system,1h) AND MinPercent(0,batch)", nil)
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry)
+		require.NoError(t, err)
+
+		newProof := func(u *url.URL, created time.Time) *proof.WitnessProof {
+			return &proof.WitnessProof{
+				Witness: &proof.Witness{
+					Type: proof.WitnessTypeSystem,
+					URI:  vocab.NewURLProperty(u),
+				},
+				Proof: []byte(fmt.Sprintf(`{"proof":{"created":"%s"}}`, created.Format(time.RFC3339))),
+			}
+		}
+
+		recentProof := newProof(systemWitnessURL, time.Now().Add(-5*time.Minute))
+		oldProof := newProof(systemWitness2URL, time.Now().Add(-2*time.Hour))
+		noTimestampProof := &proof.WitnessProof{
+			Witness: &proof.Witness{
+				Type: proof.WitnessTypeSystem,
+				URI:  vocab.NewURLProperty(witnessURL),
+			},
+			Proof: []byte(testProof),
+		}
+
+		// Only one proof within the window.
+		ok, err := wp.Evaluate([]*proof.WitnessProof{recentProof, oldProof})
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		// A proof without a created time isn't counted.
+		ok, err = wp.Evaluate([]*proof.WitnessProof{recentProof, noTimestampProof})
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		// Two proofs within the window.
+		ok, err = wp.Evaluate([]*proof.WitnessProof{
+			recentProof, oldProof, newProof(witnessURL, time.Now().Add(-59*time.Minute)),
+		})
+		require.NoError(t, err)
+		require.True(t, ok)
+	})
+
+	t.Run("success - malformed proof is not counted", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("OutOf(1,system) AND MinPercent(0,batch)", nil)
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry)
+		require.NoError(t, err)
+
+		ok, err := wp.Evaluate([]*proof.WitnessProof{
+			{
+				Witness: &proof.Witness{
+					Type: proof.WitnessTypeSystem,
+					URI:  vocab.NewURLProperty(systemWitnessURL),
+				},
+				Proof: []byte("malformed"),
+			},
+		})
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
 	t.Run("success - no system witnesses provided", func(t *testing.T) {
 		policyStore := &mocks.PolicyStore{}
 		policyStore.GetPolicyReturns("MinPercent(50,system) AND MinPercent(50,batch)", nil)
@@ -630,7 +739,7 @@ func TestEvaluate(t *testing.T) {
 					Type: proof.WitnessTypeBatch,
 					URI:  vocab.NewURLProperty(batchWitnessURL),
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 		}
 
@@ -653,7 +762,7 @@ func TestEvaluate(t *testing.T) {
 					Type: proof.WitnessTypeSystem,
 					URI:  vocab.NewURLProperty(systemWitnessURL),
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 		}
 
@@ -666,7 +775,9 @@ func TestEvaluate(t *testing.T) {
 		policyStore := &mocks.PolicyStore{}
 		policyStore.GetPolicyReturns("OutOf(0,batch) AND OutOf(1,system)", nil)
 
-		wp, err := New(policyStore, 1*time.Second)
+		clock := gcache.NewFakeClock()
+
+		wp, err := New(policyStore, 1*time.Second, WithClock(clock))
 		require.NoError(t, err)
 		require.NotNil(t, wp)
 
@@ -682,7 +793,7 @@ func TestEvaluate(t *testing.T) {
 					Type: proof.WitnessTypeSystem,
 					URI:  vocab.NewURLProperty(systemWitnessURL),
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 		}
 
@@ -693,15 +804,15 @@ func TestEvaluate(t *testing.T) {
 		// change policy to 1 system witness and 1 batch witness
 		policyStore.GetPolicyReturns("OutOf(1,batch) AND OutOf(1,system)", nil)
 
-		// wait for cache to refresh entry
-		time.Sleep(2 * time.Second)
+		// expire the cached entry
+		clock.Advance(2 * time.Second)
 
 		// policy should return false since there is not enough system proofs
 		ok, err = wp.Evaluate(witnessProofs)
 		require.NoError(t, err)
 		require.Equal(t, false, ok)
 
-		witnessProofs[0].Proof = []byte("added proof")
+		witnessProofs[0].Proof = []byte(testProof)
 		ok, err = wp.Evaluate(witnessProofs)
 		require.NoError(t, err)
 		require.Equal(t, true, ok)
@@ -722,14 +833,14 @@ func TestEvaluate(t *testing.T) {
 					Type: proof.WitnessTypeBatch,
 					URI:  vocab.NewURLProperty(witnessURL),
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 			{
 				Witness: &proof.Witness{
 					Type: proof.WitnessTypeSystem,
 					URI:  vocab.NewURLProperty(witnessURL),
 				},
-				Proof: []byte("proof"),
+				Proof: []byte(testProof),
 			},
 		}
 
@@ -740,9 +851,10 @@ func TestEvaluate(t *testing.T) {
 	})
 }
 
-func TestGetWitnessPolicyConfig(t *testing.T) {
-	t.Run("success - policy config retrieved from the cache", func(t *testing.T) {
+func TestRefresh(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
 		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("OutOf(1,system)", nil)
 
 		wp, err := New(policyStore, defaultPolicyCacheExpiry)
 		require.NoError(t, err)
@@ -750,209 +862,1105 @@ func TestGetWitnessPolicyConfig(t *testing.T) {
 
 		cfg, err := wp.getWitnessPolicyConfig()
 		require.NoError(t, err)
-		require.NotNil(t, cfg)
-	})
-
-	t.Run("error - failed to retrieve policy from policy cache (nil value)", func(t *testing.T) {
-		policyStore := &mocks.PolicyStore{}
+		require.Equal(t, 1, cfg.MinNumberSystem)
 
-		wp, err := New(policyStore, defaultPolicyCacheExpiry)
-		require.NoError(t, err)
-		require.NotNil(t, wp)
+		policyStore.GetPolicyReturns("OutOf(2,system)", nil)
 
-		wp.cache = &mockCache{}
+		require.NoError(t, wp.Refresh())
 
-		cfg, err := wp.getWitnessPolicyConfig()
-		require.Error(t, err)
-		require.Nil(t, cfg)
-		require.Contains(t, err.Error(), "failed to retrieve policy from policy cache (nil value)")
+		cfg, err = wp.getWitnessPolicyConfig()
+		require.NoError(t, err)
+		require.Equal(t, 2, cfg.MinNumberSystem)
 	})
 
-	t.Run("error - get witness policy from cache error", func(t *testing.T) {
+	t.Run("error - config store error", func(t *testing.T) {
 		policyStore := &mocks.PolicyStore{}
 
 		wp, err := New(policyStore, defaultPolicyCacheExpiry)
 		require.NoError(t, err)
 		require.NotNil(t, wp)
 
-		wp.cache = &mockCache{GetErr: fmt.Errorf("get error")}
+		policyStore.GetPolicyReturns("", fmt.Errorf("get error"))
 
-		cfg, err := wp.getWitnessPolicyConfig()
+		err = wp.Refresh()
 		require.Error(t, err)
-		require.Nil(t, cfg)
 		require.Contains(t, err.Error(), "get error")
 	})
 
-	t.Run("error - unexpected interface for witness policy value", func(t *testing.T) {
+	t.Run("error - cache error", func(t *testing.T) {
 		policyStore := &mocks.PolicyStore{}
 
 		wp, err := New(policyStore, defaultPolicyCacheExpiry)
 		require.NoError(t, err)
 		require.NotNil(t, wp)
 
-		wp.cache = &mockCache{GetValue: []byte("not string")}
+		wp.cache = &mockCache{SetErr: fmt.Errorf("set error")}
 
-		cfg, err := wp.getWitnessPolicyConfig()
+		err = wp.Refresh()
 		require.Error(t, err)
-		require.Nil(t, cfg)
-		require.Contains(t, err.Error(),
-			"unexpected interface '[]uint8' for witness policy value in policy cache")
+		require.Contains(t, err.Error(), "set error")
 	})
+}
 
-	t.Run("error - parse witness policy error (rule not supported)", func(t *testing.T) {
-		policyStore := &mocks.PolicyStore{}
+func TestCacheExpiryJitter(t *testing.T) {
+	const (
+		expiry = time.Minute
+		jitter = 10 * time.Second
+	)
 
-		wp, err := New(policyStore, defaultPolicyCacheExpiry)
-		require.NoError(t, err)
-		require.NotNil(t, wp)
+	policyStore := &mocks.PolicyStore{}
 
-		// rule not supported - parse fails
-		wp.cache = &mockCache{GetValue: "Test(a,b)"}
+	t.Run("no jitter", func(t *testing.T) {
+		wp, err := New(policyStore, expiry)
+		require.NoError(t, err)
 
-		cfg, err := wp.getWitnessPolicyConfig()
-		require.Error(t, err)
-		require.Nil(t, cfg)
-		require.Contains(t, err.Error(), "rule not supported: Test(a,b)")
+		for i := 0; i < 100; i++ {
+			require.Equal(t, expiry, wp.nextCacheExpiry())
+		}
 	})
-}
-
-func TestSelect(t *testing.T) {
-	batchWitnessURL, err := url.Parse("https://batch.com/service")
-	require.NoError(t, err)
 
-	systemWitnessURL, err := url.Parse("https://system.com/service")
-	require.NoError(t, err)
+	t.Run("with jitter", func(t *testing.T) {
+		wp, err := New(policyStore, expiry, WithCacheExpiryJitter(jitter))
+		require.NoError(t, err)
 
-	batchWitness2URL, err := url.Parse("https://second.batch.com/service")
-	require.NoError(t, err)
+		distinct := make(map[time.Duration]struct{})
 
-	systemWitness2URL, err := url.Parse("https://second.system.com/service")
-	require.NoError(t, err)
+		for i := 0; i < 100; i++ {
+			e := wp.nextCacheExpiry()
+			require.GreaterOrEqual(t, e, expiry)
+			require.LessOrEqual(t, e, expiry+jitter)
 
-	systemWitness3URL, err := url.Parse("https://third.system.com/service")
-	require.NoError(t, err)
+			distinct[e] = struct{}{}
+		}
 
-	t.Run("success - default policy (AND)", func(t *testing.T) {
-		policyStore := &mocks.PolicyStore{}
+		require.Greater(t, len(distinct), 1)
 
-		wp, err := New(policyStore, defaultPolicyCacheExpiry)
+		_, expiryPtr, err := wp.loadWitnessPolicy(nil)
 		require.NoError(t, err)
-		require.NotNil(t, wp)
+		require.GreaterOrEqual(t, *expiryPtr, expiry)
+		require.LessOrEqual(t, *expiryPtr, expiry+jitter)
+	})
+}
 
-		witnesses := []*proof.Witness{
-			{
-				Type: proof.WitnessTypeBatch,
-				URI:  vocab.NewURLProperty(batchWitnessURL),
+func TestCustomCache(t *testing.T) {
+	systemWitnessProofs := []*proof.WitnessProof{
+		{
+			Witness: &proof.Witness{
+				Type: proof.WitnessTypeSystem,
+				URI:  vocab.NewURLProperty(testutil.MustParseURL("https://system.com/service")),
 			},
-			{
+			Proof: []byte(testProof),
+		},
+		{
+			Witness: &proof.Witness{
 				Type: proof.WitnessTypeSystem,
-				URI:  vocab.NewURLProperty(systemWitnessURL),
+				URI:  vocab.NewURLProperty(testutil.MustParseURL("https://other.system.com/service")),
 			},
-		}
+		},
+	}
 
-		selected, err := wp.Select(witnesses)
+	t.Run("success", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("OutOf(0,batch) AND OutOf(1,system)", nil)
+
+		cache := newMapCache()
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry, WithCache(cache))
 		require.NoError(t, err)
-		require.Equal(t, 2, len(selected))
-		require.Equal(t, "https://batch.com/service", selected[0].URI.String())
-		require.Equal(t, "https://system.com/service", selected[1].URI.String())
+
+		require.Equal(t, 1, cache.sets)
+		require.Equal(t, defaultPolicyCacheExpiry, cache.expiries[WitnessPolicyKey])
+		require.Equal(t, "OutOf(0,batch) AND OutOf(1,system)", cache.values[WitnessPolicyKey])
+
+		ok, err := wp.Evaluate(systemWitnessProofs)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, 1, cache.gets)
+		require.Equal(t, 1, policyStore.GetPolicyCallCount())
+
+		// Another instance updates the policy in the shared cache.
+		require.NoError(t, cache.SetWithExpire(WitnessPolicyKey, "OutOf(0,batch) AND OutOf(2,system)", time.Minute))
+
+		ok, err = wp.Evaluate(systemWitnessProofs)
+		require.NoError(t, err)
+		require.False(t, ok)
+		require.Equal(t, 2, cache.gets)
+		require.Equal(t, 1, policyStore.GetPolicyCallCount())
 	})
 
-	t.Run("success - default policy (AND) plus common witnesses", func(t *testing.T) {
+	t.Run("cache miss -> policy loaded from store", func(t *testing.T) {
 		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("OutOf(0,batch) AND OutOf(1,system)", nil)
 
-		wp, err := New(policyStore, defaultPolicyCacheExpiry)
+		cache := newMapCache()
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry, WithCache(cache))
 		require.NoError(t, err)
-		require.NotNil(t, wp)
 
-		witnesses := []*proof.Witness{
-			{
-				Type: proof.WitnessTypeBatch,
-				URI:  vocab.NewURLProperty(batchWitnessURL),
-			},
-			{
-				Type: proof.WitnessTypeSystem,
-				URI:  vocab.NewURLProperty(systemWitnessURL),
-			},
-			{
-				Type: proof.WitnessTypeSystem,
-				URI:  vocab.NewURLProperty(batchWitnessURL),
-			},
-		}
+		cache.expire(WitnessPolicyKey)
 
-		selected, err := wp.Select(witnesses)
+		policyStore.GetPolicyReturns("OutOf(0,batch) AND OutOf(2,system)", nil)
+
+		ok, err := wp.Evaluate(systemWitnessProofs)
 		require.NoError(t, err)
-		require.Equal(t, 3, len(selected))
+		require.False(t, ok)
+		require.Equal(t, 2, policyStore.GetPolicyCallCount())
+		require.Equal(t, 2, cache.sets)
+		require.Equal(t, "OutOf(0,batch) AND OutOf(2,system)", cache.values[WitnessPolicyKey])
+
+		// The policy is now served from the cache.
+		_, err = wp.Evaluate(systemWitnessProofs)
+		require.NoError(t, err)
+		require.Equal(t, 2, policyStore.GetPolicyCallCount())
 	})
 
-	t.Run("success - zero eligible batch witnesses", func(t *testing.T) {
+	t.Run("cache miss and store error -> last loaded policy is used", func(t *testing.T) {
 		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("OutOf(0,batch) AND OutOf(1,system)", nil)
 
-		wp, err := New(policyStore, defaultPolicyCacheExpiry)
+		cache := newMapCache()
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry, WithCache(cache))
 		require.NoError(t, err)
-		require.NotNil(t, wp)
 
-		witnesses := []*proof.Witness{
-			{
-				Type: proof.WitnessTypeSystem,
-				URI:  vocab.NewURLProperty(systemWitnessURL),
-			},
-		}
+		cache.expire(WitnessPolicyKey)
 
-		selected, err := wp.Select(witnesses)
+		policyStore.GetPolicyReturns("", fmt.Errorf("injected store error"))
+
+		ok, err := wp.Evaluate(systemWitnessProofs)
 		require.NoError(t, err)
-		require.Equal(t, 1, len(selected))
-		require.Equal(t, "https://system.com/service", selected[0].URI.String())
+		require.True(t, ok)
+
+		degraded, _ := wp.Degraded()
+		require.True(t, degraded)
 	})
 
-	t.Run("success - policy with AND and minimum percent", func(t *testing.T) {
+	t.Run("cache miss and set error", func(t *testing.T) {
 		policyStore := &mocks.PolicyStore{}
-		policyStore.GetPolicyReturns("MinPercent(50,system) AND MinPercent(50,batch) LogRequired", nil)
 
-		wp, err := New(policyStore, defaultPolicyCacheExpiry)
+		cache := newMapCache()
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry, WithCache(cache))
 		require.NoError(t, err)
-		require.NotNil(t, wp)
 
-		witnesses := []*proof.Witness{
-			{
-				Type:   proof.WitnessTypeSystem,
-				URI:    vocab.NewURLProperty(systemWitnessURL),
-				HasLog: true,
-			},
-			{
-				Type:   proof.WitnessTypeSystem,
-				URI:    vocab.NewURLProperty(systemWitness2URL),
-				HasLog: true,
-			},
-			{
-				Type:   proof.WitnessTypeSystem,
-				URI:    vocab.NewURLProperty(systemWitness3URL),
-				HasLog: true,
-			},
-			{
-				Type:   proof.WitnessTypeBatch,
-				URI:    vocab.NewURLProperty(batchWitnessURL),
-				HasLog: true,
-			},
-			{
-				Type:   proof.WitnessTypeBatch,
-				URI:    vocab.NewURLProperty(batchWitness2URL),
-				HasLog: false,
-			},
-		}
+		cache.expire(WitnessPolicyKey)
+		cache.setErr = fmt.Errorf("injected set error")
 
-		selected, err := wp.Select(witnesses)
-		require.NoError(t, err)
-		require.Equal(t, 3, len(selected))
-		require.Equal(t, "https://batch.com/service", selected[0].URI.String())
+		_, err = wp.Evaluate(systemWitnessProofs)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected set error")
 	})
 
-	t.Run("success - policy with AND and OutOf", func(t *testing.T) {
+	t.Run("get error", func(t *testing.T) {
 		policyStore := &mocks.PolicyStore{}
-		policyStore.GetPolicyReturns("OutOf(2,system) AND OutOf(1,batch) LogRequired", nil)
 
-		wp, err := New(policyStore, defaultPolicyCacheExpiry)
+		cache := newMapCache()
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry, WithCache(cache))
 		require.NoError(t, err)
-		require.NotNil(t, wp)
+
+		cache.getErr = fmt.Errorf("injected get error")
+
+		_, err = wp.Evaluate(systemWitnessProofs)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected get error")
+		require.Equal(t, 1, policyStore.GetPolicyCallCount())
+	})
+
+	t.Run("distinct cache keys -> independent cache entries", func(t *testing.T) {
+		const policy1 = "OutOf(0,batch) AND OutOf(1,system)"
+		const policy2 = "OutOf(0,batch) AND OutOf(2,system)"
+
+		policyStore1 := &mocks.PolicyStore{}
+		policyStore1.GetPolicyReturns(policy1, nil)
+
+		policyStore2 := &mocks.PolicyStore{}
+		policyStore2.GetPolicyReturns(policy2, nil)
+
+		key1 := NamespacedPolicyKey("ns1")
+		key2 := NamespacedPolicyKey("ns2")
+
+		cache := newMapCache()
+
+		wp1, err := New(policyStore1, defaultPolicyCacheExpiry, WithCache(cache), WithCacheKey(key1))
+		require.NoError(t, err)
+
+		wp2, err := New(policyStore2, defaultPolicyCacheExpiry, WithCache(cache), WithCacheKey(key2))
+		require.NoError(t, err)
+
+		require.Len(t, cache.values, 2)
+		require.Equal(t, policy1, cache.values[key1])
+		require.Equal(t, policy2, cache.values[key2])
+
+		ok, err := wp1.Evaluate(systemWitnessProofs)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		ok, err = wp2.Evaluate(systemWitnessProofs)
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		// Expiring one entry only reloads the corresponding policy.
+		cache.expire(key2)
+
+		ok, err = wp2.Evaluate(systemWitnessProofs)
+		require.NoError(t, err)
+		require.False(t, ok)
+		require.Equal(t, 1, policyStore1.GetPolicyCallCount())
+		require.Equal(t, 2, policyStore2.GetPolicyCallCount())
+		require.Equal(t, policy1, cache.values[key1])
+	})
+
+	t.Run("default cache key", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("OutOf(0,batch) AND OutOf(1,system)", nil)
+
+		cache := newMapCache()
+
+		_, err := New(policyStore, defaultPolicyCacheExpiry, WithCache(cache))
+		require.NoError(t, err)
+
+		require.Len(t, cache.values, 1)
+		require.Contains(t, cache.values, WitnessPolicyKey)
+	})
+}
+
+func TestClock(t *testing.T) {
+	policyStore := &mocks.PolicyStore{}
+	policyStore.GetPolicyReturns("OutOf(1,system)", nil)
+
+	clock := gcache.NewFakeClock()
+
+	wp, err := New(policyStore, time.Minute, WithClock(clock))
+	require.NoError(t, err)
+	require.Equal(t, 1, policyStore.GetPolicyCallCount())
+
+	_, lastLoaded := wp.Degraded()
+	require.Equal(t, clock.Now(), lastLoaded)
+
+	policyStore.GetPolicyReturns("OutOf(2,system)", nil)
+
+	clock.Advance(30 * time.Second)
+
+	// The policy hasn't expired yet so it's served from the cache.
+	cfg, err := wp.getWitnessPolicyConfig()
+	require.NoError(t, err)
+	require.Equal(t, 1, cfg.MinNumberSystem)
+	require.Equal(t, 1, policyStore.GetPolicyCallCount())
+
+	clock.Advance(31 * time.Second)
+
+	// The policy has expired so it's reloaded from the policy store.
+	cfg, err = wp.getWitnessPolicyConfig()
+	require.NoError(t, err)
+	require.Equal(t, 2, cfg.MinNumberSystem)
+	require.Equal(t, 2, policyStore.GetPolicyCallCount())
+
+	_, lastLoaded = wp.Degraded()
+	require.Equal(t, clock.Now(), lastLoaded)
+}
+
+func TestDegraded(t *testing.T) {
+	policyStore := &mocks.PolicyStore{}
+	policyStore.GetPolicyReturns("OutOf(1,system)", nil)
+
+	clock := gcache.NewFakeClock()
+
+	wp, err := New(policyStore, 50*time.Millisecond, WithClock(clock))
+	require.NoError(t, err)
+
+	degraded, lastLoaded := wp.Degraded()
+	require.False(t, degraded)
+	require.False(t, lastLoaded.IsZero())
+
+	policyStore.GetPolicyReturns("", fmt.Errorf("injected store error"))
+
+	clock.Advance(100 * time.Millisecond)
+
+	// The last successfully loaded policy is still used.
+	cfg, err := wp.getWitnessPolicyConfig()
+	require.NoError(t, err)
+	require.Equal(t, 1, cfg.MinNumberSystem)
+
+	degradedNow, lastLoadedNow := wp.Degraded()
+	require.True(t, degradedNow)
+	require.Equal(t, lastLoaded, lastLoadedNow)
+
+	require.Error(t, wp.Refresh())
+
+	degradedNow, _ = wp.Degraded()
+	require.True(t, degradedNow)
+
+	policyStore.GetPolicyReturns("OutOf(2,system)", nil)
+
+	require.NoError(t, wp.Refresh())
+
+	degradedNow, lastLoadedNow = wp.Degraded()
+	require.False(t, degradedNow)
+	require.True(t, lastLoadedNow.After(lastLoaded))
+}
+
+func TestCacheRefreshHandler(t *testing.T) {
+	type refresh struct {
+		oldPolicy string
+		newPolicy string
+		err       error
+	}
+
+	policyStore := &mocks.PolicyStore{}
+	policyStore.GetPolicyReturns("OutOf(1,system)", nil)
+
+	clock := gcache.NewFakeClock()
+
+	var refreshes []refresh
+
+	wp, err := New(policyStore, time.Minute, WithClock(clock),
+		WithCacheRefreshHandler(func(oldPolicy, newPolicy string, err error) {
+			refreshes = append(refreshes, refresh{oldPolicy: oldPolicy, newPolicy: newPolicy, err: err})
+		}),
+	)
+	require.NoError(t, err)
+	require.Empty(t, refreshes, "the handler shouldn't be invoked for the initial load")
+
+	// The policy changes in the store and the cached policy expires.
+	policyStore.GetPolicyReturns("OutOf(2,system)", nil)
+
+	clock.Advance(2 * time.Minute)
+
+	_, err = wp.getWitnessPolicyConfig()
+	require.NoError(t, err)
+
+	require.Len(t, refreshes, 1)
+	require.Equal(t, refresh{oldPolicy: "OutOf(1,system)", newPolicy: "OutOf(2,system)"}, refreshes[0])
+
+	// The policy can't be loaded.
+	errExpected := errors.New("injected store error")
+
+	policyStore.GetPolicyReturns("", errExpected)
+
+	require.Error(t, wp.Refresh())
+
+	require.Len(t, refreshes, 2)
+	require.Equal(t, "OutOf(2,system)", refreshes[1].oldPolicy)
+	require.Empty(t, refreshes[1].newPolicy)
+	require.ErrorIs(t, refreshes[1].err, errExpected)
+
+	// The policy is loaded successfully again.
+	policyStore.GetPolicyReturns("OutOf(3,system)", nil)
+
+	require.NoError(t, wp.Refresh())
+
+	require.Len(t, refreshes, 3)
+	require.Equal(t, refresh{oldPolicy: "OutOf(2,system)", newPolicy: "OutOf(3,system)"}, refreshes[2])
+}
+
+func TestEvaluationCache(t *testing.T) {
+	batchWitnessURL := testutil.MustParseURL("https://batch.com/service")
+	systemWitnessURL := testutil.MustParseURL("https://system.com/service")
+
+	newProofs := func() []*proof.WitnessProof {
+		return []*proof.WitnessProof{
+			{
+				Witness: &proof.Witness{
+					Type: proof.WitnessTypeBatch,
+					URI:  vocab.NewURLProperty(batchWitnessURL),
+				},
+			},
+			{
+				Witness: &proof.Witness{
+					Type: proof.WitnessTypeSystem,
+					URI:  vocab.NewURLProperty(systemWitnessURL),
+				},
+				Proof: []byte(testProof),
+			},
+		}
+	}
+
+	t.Run("cached result", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("OutOf(0,batch) AND OutOf(1,system)", nil)
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry, WithEvaluationCache(time.Minute))
+		require.NoError(t, err)
+
+		ok, err := wp.Evaluate(newProofs())
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, 1, wp.evaluationCache.cache.Len(false))
+
+		// The same proofs in a different order result in a cache hit.
+		witnessProofs := newProofs()
+		witnessProofs[0], witnessProofs[1] = witnessProofs[1], witnessProofs[0]
+
+		ok, err = wp.Evaluate(witnessProofs)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, 1, wp.evaluationCache.cache.Len(false))
+	})
+
+	t.Run("policy change", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("OutOf(0,batch) AND OutOf(1,system)", nil)
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry, WithEvaluationCache(time.Minute))
+		require.NoError(t, err)
+
+		ok, err := wp.Evaluate(newProofs())
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		policyStore.GetPolicyReturns("OutOf(1,batch) AND OutOf(1,system)", nil)
+
+		require.NoError(t, wp.Refresh())
+		require.Zero(t, wp.evaluationCache.cache.Len(false))
+
+		ok, err = wp.Evaluate(newProofs())
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		witnessProofs := newProofs()
+		witnessProofs[0].Proof = []byte(testProof)
+
+		ok, err = wp.Evaluate(witnessProofs)
+		require.NoError(t, err)
+		require.True(t, ok)
+	})
+
+	t.Run("time-window policy is not cached", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("OutOfWithin(1,system,1m)", nil)
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry, WithEvaluationCache(time.Minute))
+		require.NoError(t, err)
+
+		_, err = wp.Evaluate(newProofs())
+		require.NoError(t, err)
+		require.Zero(t, wp.evaluationCache.cache.Len(false))
+	})
+
+	t.Run("proof verifier -> not cached", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("OutOf(0,batch) AND OutOf(1,system)", nil)
+
+		verifier := newMockProofVerifier()
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry, WithEvaluationCache(time.Minute),
+			WithProofVerifier(verifier))
+		require.NoError(t, err)
+
+		ok, err := wp.Evaluate(newProofs())
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Zero(t, wp.evaluationCache.cache.Len(false))
+
+		// The verifier now rejects the proof that it previously accepted.
+		verifier.withRejected(systemWitnessURL.String())
+
+		ok, err = wp.Evaluate(newProofs())
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+}
+
+func TestProofVerifier(t *testing.T) {
+	batchWitnessURL := testutil.MustParseURL("https://batch.com/service")
+	systemWitnessURL := testutil.MustParseURL("https://system.com/service")
+	systemWitness2URL := testutil.MustParseURL("https://other.system.com/service")
+
+	witnessProofs := []*proof.WitnessProof{
+		{
+			Witness: &proof.Witness{
+				Type: proof.WitnessTypeBatch,
+				URI:  vocab.NewURLProperty(batchWitnessURL),
+			},
+			Proof: []byte(testProof),
+		},
+		{
+			Witness: &proof.Witness{
+				Type: proof.WitnessTypeSystem,
+				URI:  vocab.NewURLProperty(systemWitnessURL),
+			},
+			Proof: []byte(testProof),
+		},
+		{
+			Witness: &proof.Witness{
+				Type: proof.WitnessTypeSystem,
+				URI:  vocab.NewURLProperty(systemWitness2URL),
+			},
+			Proof: []byte(testProof),
+		},
+	}
+
+	t.Run("all proofs verified", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("OutOf(1,batch) AND OutOf(2,system)", nil)
+
+		verifier := newMockProofVerifier()
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry, WithProofVerifier(verifier))
+		require.NoError(t, err)
+
+		ok, err := wp.Evaluate(witnessProofs)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, 3, verifier.calls)
+	})
+
+	t.Run("rejected proof is not counted", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("OutOf(1,batch) AND OutOf(2,system)", nil)
+
+		verifier := newMockProofVerifier().withRejected(systemWitness2URL.String())
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry, WithProofVerifier(verifier))
+		require.NoError(t, err)
+
+		ok, err := wp.Evaluate(witnessProofs)
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		// The policy is still satisfied if the rejected proof isn't required.
+		policyStore.GetPolicyReturns("OutOf(1,batch) AND OutOf(1,system)", nil)
+
+		require.NoError(t, wp.Refresh())
+
+		ok, err = wp.Evaluate(witnessProofs)
+		require.NoError(t, err)
+		require.True(t, ok)
+	})
+
+	t.Run("rejected batch proof", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("OutOf(1,batch) AND OutOf(1,system)", nil)
+
+		verifier := newMockProofVerifier().withRejected(batchWitnessURL.String())
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry, WithProofVerifier(verifier))
+		require.NoError(t, err)
+
+		ok, err := wp.Evaluate(witnessProofs)
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("proof that can't be decoded isn't verified", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("OutOf(0,batch) AND OutOf(1,system)", nil)
+
+		verifier := newMockProofVerifier()
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry, WithProofVerifier(verifier))
+		require.NoError(t, err)
+
+		ok, err := wp.Evaluate([]*proof.WitnessProof{
+			{
+				Witness: &proof.Witness{
+					Type: proof.WitnessTypeSystem,
+					URI:  vocab.NewURLProperty(systemWitnessURL),
+				},
+				Proof: []byte("proof"),
+			},
+		})
+		require.NoError(t, err)
+		require.False(t, ok)
+		require.Zero(t, verifier.calls)
+	})
+}
+
+func TestShadowPolicy(t *testing.T) {
+	batchWitness := &proof.Witness{
+		Type: proof.WitnessTypeBatch,
+		URI:  vocab.NewURLProperty(testutil.MustParseURL("https://batch.com/service")),
+	}
+
+	systemWitness := &proof.Witness{
+		Type: proof.WitnessTypeSystem,
+		URI:  vocab.NewURLProperty(testutil.MustParseURL("https://system.com/service")),
+	}
+
+	systemWitness2 := &proof.Witness{
+		Type: proof.WitnessTypeSystem,
+		URI:  vocab.NewURLProperty(testutil.MustParseURL("https://other.system.com/service")),
+	}
+
+	witnessProofs := []*proof.WitnessProof{
+		{Witness: batchWitness, Proof: []byte(testProof)},
+		{Witness: systemWitness, Proof: []byte(testProof)},
+		{Witness: systemWitness2},
+	}
+
+	// Replace the package logger so that the log output may be inspected.
+	stdOut := &bytes.Buffer{}
+
+	defaultLogger := logger
+	logger = log.NewStructured("witness-policy", log.WithStdOut(zapcore.AddSync(stdOut)), log.WithEncoding(log.JSON))
+
+	defer func() { logger = defaultLogger }()
+
+	t.Run("Shadow policy diverges", func(t *testing.T) {
+		stdOut.Reset()
+
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("OutOf(1,batch) AND OutOf(1,system)", nil)
+
+		observer := &mockShadowPolicyObserver{}
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry,
+			WithShadowPolicy("OutOf(1,batch) AND OutOf(2,system)"),
+			WithShadowPolicyObserver(observer),
+		)
+		require.NoError(t, err)
+
+		ok, err := wp.Evaluate(witnessProofs)
+		require.NoError(t, err)
+		require.True(t, ok, "the enforced policy should govern the result")
+
+		require.Equal(t, []bool{true, false}, observer.diverged)
+		require.Contains(t, stdOut.String(), "Shadow witness policy evaluation differs")
+		require.Contains(t, stdOut.String(), `"shadow-evaluated-to":false`)
+	})
+
+	t.Run("Stricter enforced policy", func(t *testing.T) {
+		stdOut.Reset()
+
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("OutOf(1,batch) AND OutOf(2,system)", nil)
+
+		observer := &mockShadowPolicyObserver{}
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry,
+			WithShadowPolicy("OutOf(1,batch) AND OutOf(1,system)"),
+			WithShadowPolicyObserver(observer),
+		)
+		require.NoError(t, err)
+
+		ok, err := wp.Evaluate(witnessProofs)
+		require.NoError(t, err)
+		require.False(t, ok, "the enforced policy should govern the result")
+
+		require.Equal(t, []bool{false, true}, observer.diverged)
+	})
+
+	t.Run("Shadow policy agrees", func(t *testing.T) {
+		stdOut.Reset()
+
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("OutOf(1,batch) AND OutOf(1,system)", nil)
+
+		observer := &mockShadowPolicyObserver{}
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry,
+			WithShadowPolicy("OutOf(1,system)"),
+			WithShadowPolicyObserver(observer),
+		)
+		require.NoError(t, err)
+
+		ok, err := wp.Evaluate(witnessProofs)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		require.Empty(t, observer.diverged)
+		require.NotContains(t, stdOut.String(), "Shadow witness policy evaluation differs")
+	})
+
+	t.Run("Invalid shadow policy", func(t *testing.T) {
+		wp, err := New(&mocks.PolicyStore{}, defaultPolicyCacheExpiry, WithShadowPolicy("InvalidRule"))
+		require.Error(t, err)
+		require.Nil(t, wp)
+		require.Contains(t, err.Error(), "parse shadow witness policy")
+	})
+}
+
+func TestWitnessWithoutURI(t *testing.T) {
+	const policy = "MinPercent(100,batch) AND MinPercent(100,system)"
+
+	batchWitness := &proof.Witness{
+		Type: proof.WitnessTypeBatch,
+		URI:  vocab.NewURLProperty(testutil.MustParseURL("https://batch.com/service")),
+	}
+
+	systemWitness := &proof.Witness{
+		Type: proof.WitnessTypeSystem,
+		URI:  vocab.NewURLProperty(testutil.MustParseURL("https://system.com/service")),
+	}
+
+	nilURIWitness := &proof.Witness{Type: proof.WitnessTypeBatch}
+
+	emptyURIWitness := &proof.Witness{Type: proof.WitnessTypeSystem, URI: vocab.NewURLProperty(nil)}
+
+	policyStore := &mocks.PolicyStore{}
+	policyStore.GetPolicyReturns(policy, nil)
+
+	wp, err := New(policyStore, defaultPolicyCacheExpiry)
+	require.NoError(t, err)
+
+	t.Run("Evaluate", func(t *testing.T) {
+		// If the proofs without a URI were counted then only 50% of the batch and system witnesses
+		// would have provided a proof.
+		ok, err := wp.Evaluate([]*proof.WitnessProof{
+			{Witness: batchWitness, Proof: []byte(testProof)},
+			{Witness: nilURIWitness},
+			{Witness: systemWitness, Proof: []byte(testProof)},
+			{Witness: emptyURIWitness},
+			{},
+			nil,
+		})
+		require.NoError(t, err)
+		require.True(t, ok)
+	})
+
+	t.Run("Evaluate - only proofs without URI", func(t *testing.T) {
+		ok, err := wp.Evaluate([]*proof.WitnessProof{
+			{Witness: nilURIWitness, Proof: []byte(testProof)},
+			{Witness: emptyURIWitness, Proof: []byte(testProof)},
+		})
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("Select", func(t *testing.T) {
+		selected, err := wp.Select(
+			[]*proof.Witness{nilURIWitness, batchWitness, emptyURIWitness, systemWitness, nil},
+			nil, nilURIWitness,
+		)
+		require.NoError(t, err)
+		require.Len(t, selected, 2)
+		require.Equal(t, batchWitness, selected[0])
+		require.Equal(t, systemWitness, selected[1])
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		require.NoError(t, wp.Validate([]*proof.Witness{nilURIWitness, batchWitness, emptyURIWitness, systemWitness, nil}))
+	})
+}
+
+func TestPolicyLogging(t *testing.T) {
+	const policy = "OutOf(1,batch) AND MinPercent(50,system)"
+
+	batchWitness := &proof.Witness{
+		Type: proof.WitnessTypeBatch,
+		URI:  vocab.NewURLProperty(testutil.MustParseURL("https://batch.com/service")),
+	}
+
+	systemWitness := &proof.Witness{
+		Type: proof.WitnessTypeSystem,
+		URI:  vocab.NewURLProperty(testutil.MustParseURL("https://system.com/service")),
+	}
+
+	// Replace the package logger so that the log output may be inspected.
+	stdOut := &bytes.Buffer{}
+
+	defaultLogger := logger
+	logger = log.NewStructured("witness-policy", log.WithStdOut(zapcore.AddSync(stdOut)), log.WithEncoding(log.JSON))
+
+	defaultLevel := log.GetLevel("witness-policy")
+
+	defer func() {
+		logger = defaultLogger
+		log.SetLevel("witness-policy", defaultLevel)
+	}()
+
+	policyStore := &mocks.PolicyStore{}
+	policyStore.GetPolicyReturns(policy, nil)
+
+	metrics := &mockMetricsProvider{}
+
+	wp, err := New(policyStore, defaultPolicyCacheExpiry, WithMetricsProvider(metrics))
+	require.NoError(t, err)
+
+	t.Run("Evaluate", func(t *testing.T) {
+		log.SetLevel("witness-policy", log.DEBUG)
+		stdOut.Reset()
+
+		ok, err := wp.Evaluate([]*proof.WitnessProof{
+			{Witness: batchWitness, Proof: []byte(testProof)},
+			{Witness: systemWitness, Proof: []byte(testProof)},
+		})
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		require.Contains(t, stdOut.String(), "Evaluated witness policy")
+		require.Contains(t, stdOut.String(), `"witness-policy":"`+policy+`"`)
+		require.Contains(t, stdOut.String(), `"evaluated-to":true`)
+		require.Contains(t, stdOut.String(), `"evaluation-duration":`)
+		require.Equal(t, 1, metrics.evaluateCount())
+	})
+
+	t.Run("Select", func(t *testing.T) {
+		log.SetLevel("witness-policy", log.DEBUG)
+		stdOut.Reset()
+
+		selected, err := wp.Select([]*proof.Witness{batchWitness, systemWitness})
+		require.NoError(t, err)
+		require.Len(t, selected, 2)
+
+		require.Contains(t, stdOut.String(), "Selected witnesses")
+		require.Contains(t, stdOut.String(), `"witness-policy":"`+policy+`"`)
+		require.Contains(t, stdOut.String(), `"evaluation-duration":`)
+		require.Equal(t, 1, metrics.selectCount())
+	})
+
+	t.Run("Debug disabled", func(t *testing.T) {
+		log.SetLevel("witness-policy", log.INFO)
+		stdOut.Reset()
+
+		_, err := wp.Evaluate(nil)
+		require.NoError(t, err)
+
+		require.NotContains(t, stdOut.String(), "Evaluated witness policy")
+
+		// Metrics are recorded regardless of the log level.
+		require.Equal(t, 2, metrics.evaluateCount())
+	})
+}
+
+type mockMetricsProvider struct {
+	mutex      sync.Mutex
+	evaluateDs []time.Duration
+	selectDs   []time.Duration
+}
+
+func (m *mockMetricsProvider) WitnessPolicyEvaluateTime(value time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.evaluateDs = append(m.evaluateDs, value)
+}
+
+func (m *mockMetricsProvider) WitnessPolicySelectTime(value time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.selectDs = append(m.selectDs, value)
+}
+
+func (m *mockMetricsProvider) evaluateCount() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return len(m.evaluateDs)
+}
+
+func (m *mockMetricsProvider) selectCount() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return len(m.selectDs)
+}
+
+func TestGetWitnessPolicyConfig(t *testing.T) {
+	t.Run("success - policy config retrieved from the cache", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry)
+		require.NoError(t, err)
+		require.NotNil(t, wp)
+
+		cfg, err := wp.getWitnessPolicyConfig()
+		require.NoError(t, err)
+		require.NotNil(t, cfg)
+	})
+
+	t.Run("error - failed to retrieve policy from policy cache (nil value)", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry)
+		require.NoError(t, err)
+		require.NotNil(t, wp)
+
+		wp.cache = &mockCache{}
+
+		cfg, err := wp.getWitnessPolicyConfig()
+		require.Error(t, err)
+		require.Nil(t, cfg)
+		require.Contains(t, err.Error(), "failed to retrieve policy from policy cache (nil value)")
+	})
+
+	t.Run("error - get witness policy from cache error", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry)
+		require.NoError(t, err)
+		require.NotNil(t, wp)
+
+		wp.cache = &mockCache{GetErr: fmt.Errorf("get error")}
+
+		cfg, err := wp.getWitnessPolicyConfig()
+		require.Error(t, err)
+		require.Nil(t, cfg)
+		require.Contains(t, err.Error(), "get error")
+	})
+
+	t.Run("error - unexpected interface for witness policy value", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry)
+		require.NoError(t, err)
+		require.NotNil(t, wp)
+
+		wp.cache = &mockCache{GetValue: []byte("not string")}
+
+		cfg, err := wp.getWitnessPolicyConfig()
+		require.Error(t, err)
+		require.Nil(t, cfg)
+		require.Contains(t, err.Error(),
+			"unexpected interface '[]uint8' for witness policy value in policy cache")
+	})
+
+	t.Run("error - parse witness policy error (rule not supported)", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry)
+		require.NoError(t, err)
+		require.NotNil(t, wp)
+
+		// rule not supported - parse fails
+		wp.cache = &mockCache{GetValue: "Test(a,b)"}
+
+		cfg, err := wp.getWitnessPolicyConfig()
+		require.Error(t, err)
+		require.Nil(t, cfg)
+		require.Contains(t, err.Error(), "rule not supported: Test(a,b)")
+	})
+}
+
+func TestSelect(t *testing.T) {
+	batchWitnessURL, err := url.Parse("https://batch.com/service")
+	require.NoError(t, err)
+
+	systemWitnessURL, err := url.Parse("https://system.com/service")
+	require.NoError(t, err)
+
+	batchWitness2URL, err := url.Parse("https://second.batch.com/service")
+	require.NoError(t, err)
+
+	systemWitness2URL, err := url.Parse("https://second.system.com/service")
+	require.NoError(t, err)
+
+	systemWitness3URL, err := url.Parse("https://third.system.com/service")
+	require.NoError(t, err)
+
+	t.Run("success - default policy (AND)", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry)
+		require.NoError(t, err)
+		require.NotNil(t, wp)
+
+		witnesses := []*proof.Witness{
+			{
+				Type: proof.WitnessTypeBatch,
+				URI:  vocab.NewURLProperty(batchWitnessURL),
+			},
+			{
+				Type: proof.WitnessTypeSystem,
+				URI:  vocab.NewURLProperty(systemWitnessURL),
+			},
+		}
+
+		selected, err := wp.Select(witnesses)
+		require.NoError(t, err)
+		require.Equal(t, 2, len(selected))
+		require.Equal(t, "https://batch.com/service", selected[0].URI.String())
+		require.Equal(t, "https://system.com/service", selected[1].URI.String())
+	})
+
+	t.Run("success - default policy (AND) plus common witnesses", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry)
+		require.NoError(t, err)
+		require.NotNil(t, wp)
+
+		witnesses := []*proof.Witness{
+			{
+				Type: proof.WitnessTypeBatch,
+				URI:  vocab.NewURLProperty(batchWitnessURL),
+			},
+			{
+				Type: proof.WitnessTypeSystem,
+				URI:  vocab.NewURLProperty(systemWitnessURL),
+			},
+			{
+				Type: proof.WitnessTypeSystem,
+				URI:  vocab.NewURLProperty(batchWitnessURL),
+			},
+		}
+
+		selected, err := wp.Select(witnesses)
+		require.NoError(t, err)
+		require.Equal(t, 3, len(selected))
+	})
+
+	t.Run("success - zero eligible batch witnesses", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry)
+		require.NoError(t, err)
+		require.NotNil(t, wp)
+
+		witnesses := []*proof.Witness{
+			{
+				Type: proof.WitnessTypeSystem,
+				URI:  vocab.NewURLProperty(systemWitnessURL),
+			},
+		}
+
+		selected, err := wp.Select(witnesses)
+		require.NoError(t, err)
+		require.Equal(t, 1, len(selected))
+		require.Equal(t, "https://system.com/service", selected[0].URI.String())
+	})
+
+	t.Run("success - policy with AND and minimum percent", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("MinPercent(50,system) AND MinPercent(50,batch) LogRequired", nil)
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry)
+		require.NoError(t, err)
+		require.NotNil(t, wp)
+
+		witnesses := []*proof.Witness{
+			{
+				Type:   proof.WitnessTypeSystem,
+				URI:    vocab.NewURLProperty(systemWitnessURL),
+				HasLog: true,
+			},
+			{
+				Type:   proof.WitnessTypeSystem,
+				URI:    vocab.NewURLProperty(systemWitness2URL),
+				HasLog: true,
+			},
+			{
+				Type:   proof.WitnessTypeSystem,
+				URI:    vocab.NewURLProperty(systemWitness3URL),
+				HasLog: true,
+			},
+			{
+				Type:   proof.WitnessTypeBatch,
+				URI:    vocab.NewURLProperty(batchWitnessURL),
+				HasLog: true,
+			},
+			{
+				Type:   proof.WitnessTypeBatch,
+				URI:    vocab.NewURLProperty(batchWitness2URL),
+				HasLog: false,
+			},
+		}
+
+		selected, err := wp.Select(witnesses)
+		require.NoError(t, err)
+		require.Equal(t, 3, len(selected))
+		require.Equal(t, "https://batch.com/service", selected[0].URI.String())
+	})
+
+	t.Run("success - policy with AND and OutOf", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("OutOf(2,system) AND OutOf(1,batch) LogRequired", nil)
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry)
+		require.NoError(t, err)
+		require.NotNil(t, wp)
 
 		witnesses := []*proof.Witness{
 			{
@@ -1080,234 +2088,867 @@ func TestSelect(t *testing.T) {
 		require.Contains(t, err.Error(), "unable to select 1 witnesses from witness array of length 0")
 	})
 
-	t.Run("error - default policy (AND) plus excluded batch witness", func(t *testing.T) {
+	t.Run("error - default policy (AND) plus excluded batch witness", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry)
+		require.NoError(t, err)
+		require.NotNil(t, wp)
+
+		batchWitness := &proof.Witness{
+			Type: proof.WitnessTypeBatch,
+			URI:  vocab.NewURLProperty(batchWitnessURL),
+		}
+
+		batchWitness2 := &proof.Witness{
+			Type: proof.WitnessTypeBatch,
+			URI:  vocab.NewURLProperty(batchWitness2URL),
+		}
+
+		systemWitness := &proof.Witness{
+			Type: proof.WitnessTypeSystem,
+			URI:  vocab.NewURLProperty(systemWitnessURL),
+		}
+
+		witnesses := []*proof.Witness{
+			batchWitness,
+			batchWitness2,
+			systemWitness,
+		}
+
+		selected, err := wp.Select(witnesses, batchWitness)
+		require.Error(t, err)
+		require.Nil(t, selected)
+		require.Contains(t, err.Error(), "unable to select 2 witnesses from witness array of length 1")
+	})
+
+	t.Run("error - number of eligible system witnesses doesn't meet policy requirements", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("OutOf(2,system) AND OutOf(1,batch) LogRequired", nil)
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry)
+		require.NoError(t, err)
+		require.NotNil(t, wp)
+
+		witnesses := []*proof.Witness{
+			{
+				Type:   proof.WitnessTypeSystem,
+				URI:    vocab.NewURLProperty(systemWitnessURL),
+				HasLog: true,
+			},
+			{
+				Type:   proof.WitnessTypeBatch,
+				URI:    vocab.NewURLProperty(batchWitnessURL),
+				HasLog: true,
+			},
+		}
+
+		selected, err := wp.Select(witnesses)
+		require.Error(t, err)
+		require.Nil(t, selected)
+		require.Contains(t, err.Error(), "unable to select 2 witnesses from witness array of length 1")
+	})
+
+	t.Run("error - number of eligible batch witnesses doesn't meet policy requirements", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("OutOf(1,system) AND OutOf(2,batch) LogRequired", nil)
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry)
+		require.NoError(t, err)
+		require.NotNil(t, wp)
+
+		witnesses := []*proof.Witness{
+			{
+				Type:   proof.WitnessTypeSystem,
+				URI:    vocab.NewURLProperty(systemWitnessURL),
+				HasLog: true,
+			},
+			{
+				Type:   proof.WitnessTypeBatch,
+				URI:    vocab.NewURLProperty(batchWitnessURL),
+				HasLog: true,
+			},
+		}
+
+		selected, err := wp.Select(witnesses)
+		require.Error(t, err)
+		require.Nil(t, selected)
+		require.Contains(t, err.Error(), "unable to select 2 witnesses from witness array of length 1")
+	})
+
+	t.Run("error - get policy from cache error", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry)
+		require.NoError(t, err)
+		require.NotNil(t, wp)
+
+		wp.cache = &mockCache{GetErr: fmt.Errorf("get policy from cache error")}
+
+		witnesses := []*proof.Witness{
+			{
+				Type: proof.WitnessTypeSystem,
+				URI:  vocab.NewURLProperty(systemWitnessURL),
+			},
+		}
+
+		selected, err := wp.Select(witnesses)
+		require.Error(t, err)
+		require.Nil(t, selected)
+		require.Contains(t, err.Error(), "failed to retrieve policy from policy cache: get policy from cache error")
+	})
+}
+
+func TestSelectPreferred(t *testing.T) {
+	newWitness := func(witnessType proof.WitnessType, uri string) *proof.Witness {
+		return &proof.Witness{
+			Type: witnessType,
+			URI:  vocab.NewURLProperty(testutil.MustParseURL(uri)),
+		}
+	}
+
+	batch1 := newWitness(proof.WitnessTypeBatch, "https://batch1.com/service")
+	batch2 := newWitness(proof.WitnessTypeBatch, "https://batch2.com/service")
+	batch3 := newWitness(proof.WitnessTypeBatch, "https://batch3.com/service")
+	system1 := newWitness(proof.WitnessTypeSystem, "https://system1.com/service")
+	system2 := newWitness(proof.WitnessTypeSystem, "https://system2.com/service")
+	system3 := newWitness(proof.WitnessTypeSystem, "https://system3.com/service")
+
+	witnesses := []*proof.Witness{batch1, batch2, batch3, system1, system2, system3}
+
+	t.Run("Preferred witnesses chosen first", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("OutOf(1,batch) AND OutOf(1,system)", nil)
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry,
+			WithPreferredWitnesses(batch3.URI.URL(), system2.URI.URL()),
+		)
+		require.NoError(t, err)
+
+		// Selection is random so run it several times.
+		for i := 0; i < 10; i++ {
+			selected, err := wp.Select(witnesses)
+			require.NoError(t, err)
+			require.Equal(t, []*proof.Witness{batch3, system2}, selected)
+		}
+	})
+
+	t.Run("Preferred witnesses in preferred order, up to the minimum", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("OutOf(1,batch) AND OutOf(2,system)", nil)
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry,
+			WithPreferredWitnesses(system3.URI.URL(), system1.URI.URL(), system2.URI.URL()),
+		)
+		require.NoError(t, err)
+
+		selected, err := wp.Select(witnesses)
+		require.NoError(t, err)
+		require.Len(t, selected, 3)
+		require.Equal(t, proof.WitnessTypeBatch, selected[0].Type)
+		require.Equal(t, system3, selected[1])
+		require.Equal(t, system1, selected[2])
+	})
+
+	t.Run("Fewer preferred witnesses than required -> remaining selected", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("OutOf(2,batch) AND MinPercent(100,system)", nil)
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry,
+			WithPreferredWitnesses(batch2.URI.URL()),
+		)
+		require.NoError(t, err)
+
+		selected, err := wp.Select(witnesses)
+		require.NoError(t, err)
+		require.Len(t, selected, 5)
+		require.Equal(t, batch2, selected[0])
+		require.Equal(t, proof.WitnessTypeBatch, selected[1].Type)
+		require.NotEqual(t, batch2, selected[1])
+		require.ElementsMatch(t, []*proof.Witness{system1, system2, system3}, selected[2:])
+	})
+
+	t.Run("Excluded preferred witness is ignored", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("OutOf(1,batch) AND OutOf(1,system)", nil)
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry,
+			WithPreferredWitnesses(batch1.URI.URL(), batch2.URI.URL(), system1.URI.URL()),
+		)
+		require.NoError(t, err)
+
+		selected, err := wp.Select(witnesses, batch1, system1)
+		require.NoError(t, err)
+		require.Len(t, selected, 2)
+		require.Equal(t, batch2, selected[0])
+		require.Equal(t, proof.WitnessTypeSystem, selected[1].Type)
+		require.NotEqual(t, system1, selected[1])
+	})
+
+	t.Run("Preferred witness not in witness list is ignored", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("OutOf(1,batch) AND OutOf(1,system)", nil)
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry,
+			WithPreferredWitnesses(testutil.MustParseURL("https://other.com/service")),
+		)
+		require.NoError(t, err)
+
+		selected, err := wp.Select(witnesses)
+		require.NoError(t, err)
+		require.Len(t, selected, 2)
+	})
+}
+
+func TestSelectFewestWitnessesForOR(t *testing.T) {
+	newWitness := func(witnessType proof.WitnessType, uri string) *proof.Witness {
+		return &proof.Witness{
+			Type: witnessType,
+			URI:  vocab.NewURLProperty(testutil.MustParseURL(uri)),
+		}
+	}
+
+	batch1 := newWitness(proof.WitnessTypeBatch, "https://batch1.com/service")
+	batch2 := newWitness(proof.WitnessTypeBatch, "https://batch2.com/service")
+	batch3 := newWitness(proof.WitnessTypeBatch, "https://batch3.com/service")
+	batch4 := newWitness(proof.WitnessTypeBatch, "https://batch4.com/service")
+	system1 := newWitness(proof.WitnessTypeSystem, "https://system1.com/service")
+	system2 := newWitness(proof.WitnessTypeSystem, "https://system2.com/service")
+	system3 := newWitness(proof.WitnessTypeSystem, "https://system3.com/service")
+
+	newPolicy := func(t *testing.T, policy string, opts ...Option) *WitnessPolicy {
+		t.Helper()
+
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns(policy, nil)
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry, opts...)
+		require.NoError(t, err)
+
+		return wp
+	}
+
+	t.Run("System branch requires fewer witnesses", func(t *testing.T) {
+		wp := newPolicy(t, "MinPercent(50,system) OR MinPercent(50,batch)", WithFewestWitnessesForOR())
+
+		selection, err := wp.SelectDetailed([]*proof.Witness{batch1, batch2, batch3, batch4, system1, system2})
+		require.NoError(t, err)
+		require.Len(t, selection.Witnesses, 1)
+		require.Equal(t, proof.WitnessTypeSystem, selection.Witnesses[0].Type)
+		require.Equal(t, []string{"MinPercent(50,system)"}, selection.Rules[selection.Witnesses[0].URI.String()])
+	})
+
+	t.Run("Batch branch requires fewer witnesses", func(t *testing.T) {
+		wp := newPolicy(t, "MinPercent(50,system) OR MinPercent(50,batch)", WithFewestWitnessesForOR())
+
+		selection, err := wp.SelectDetailed([]*proof.Witness{batch1, batch2, system1, system2, system3})
+		require.NoError(t, err)
+		require.Len(t, selection.Witnesses, 1)
+		require.Equal(t, proof.WitnessTypeBatch, selection.Witnesses[0].Type)
+		require.Equal(t, []string{"MinPercent(50,batch)"}, selection.Rules[selection.Witnesses[0].URI.String()])
+	})
+
+	t.Run("Same number of witnesses -> batch branch", func(t *testing.T) {
+		wp := newPolicy(t, "OutOf(2,system) OR OutOf(2,batch)", WithFewestWitnessesForOR())
+
+		selected, err := wp.Select([]*proof.Witness{batch1, batch2, batch3, system1, system2, system3})
+		require.NoError(t, err)
+		require.Len(t, selected, 2)
+		require.Equal(t, proof.WitnessTypeBatch, selected[0].Type)
+		require.Equal(t, proof.WitnessTypeBatch, selected[1].Type)
+	})
+
+	t.Run("Unsatisfiable branch is ignored", func(t *testing.T) {
+		witnesses := []*proof.Witness{batch1, batch2, batch3, batch4, system1, system2}
+
+		// By default, selection fails if either branch can't be satisfied.
+		_, err := newPolicy(t, "OutOf(2,system) OR OutOf(3,batch)").Select(witnesses, system1)
+		require.Error(t, err)
+
+		selected, err := newPolicy(t, "OutOf(2,system) OR OutOf(3,batch)", WithFewestWitnessesForOR()).
+			Select(witnesses, system1)
+		require.NoError(t, err)
+		require.Len(t, selected, 3)
+
+		for _, w := range selected {
+			require.Equal(t, proof.WitnessTypeBatch, w.Type)
+		}
+	})
+
+	t.Run("Neither branch satisfiable", func(t *testing.T) {
+		wp := newPolicy(t, "OutOf(2,system) OR OutOf(3,batch)", WithFewestWitnessesForOR())
+
+		_, err := wp.Select([]*proof.Witness{batch1, batch2, system1})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unable to select")
+	})
+
+	t.Run("AND operator not affected", func(t *testing.T) {
+		wp := newPolicy(t, "OutOf(1,system) AND OutOf(1,batch)", WithFewestWitnessesForOR())
+
+		selected, err := wp.Select([]*proof.Witness{batch1, batch2, system1, system2})
+		require.NoError(t, err)
+		require.Len(t, selected, 2)
+	})
+}
+
+func TestSelectDetailed(t *testing.T) {
+	batchWitness := &proof.Witness{
+		Type: proof.WitnessTypeBatch,
+		URI:  vocab.NewURLProperty(testutil.MustParseURL("https://batch.com/service")),
+	}
+
+	systemWitness := &proof.Witness{
+		Type: proof.WitnessTypeSystem,
+		URI:  vocab.NewURLProperty(testutil.MustParseURL("https://system.com/service")),
+	}
+
+	commonURL := testutil.MustParseURL("https://common.com/service")
+
+	commonBatchWitness := &proof.Witness{
+		Type: proof.WitnessTypeBatch,
+		URI:  vocab.NewURLProperty(commonURL),
+	}
+
+	commonSystemWitness := &proof.Witness{
+		Type: proof.WitnessTypeSystem,
+		URI:  vocab.NewURLProperty(commonURL),
+	}
+
+	t.Run("AND policy", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("OutOf(1,batch) AND OutOf(1,system)", nil)
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry)
+		require.NoError(t, err)
+
+		selection, err := wp.SelectDetailed([]*proof.Witness{batchWitness, systemWitness})
+		require.NoError(t, err)
+		require.Len(t, selection.Witnesses, 2)
+		require.Equal(t, map[string][]string{
+			batchWitness.URI.String():  {"OutOf(1,batch)"},
+			systemWitness.URI.String(): {"OutOf(1,system)"},
+		}, selection.Rules)
+
+		selected, err := wp.Select([]*proof.Witness{batchWitness, systemWitness})
+		require.NoError(t, err)
+		require.Equal(t, selection.Witnesses, selected)
+	})
+
+	t.Run("AND policy - common witness satisfies both rules", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("OutOf(1,batch) AND MinPercent(50,system)", nil)
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry)
+		require.NoError(t, err)
+
+		selection, err := wp.SelectDetailed([]*proof.Witness{commonBatchWitness, commonSystemWitness, systemWitness})
+		require.NoError(t, err)
+		require.Equal(t, map[string][]string{
+			commonURL.String(): {"OutOf(1,batch)", "MinPercent(50,system)"},
+		}, selection.Rules)
+	})
+
+	t.Run("OR policy", func(t *testing.T) {
 		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("OutOfWithin(1,batch,1h) OR OutOf(2,system)", nil)
 
 		wp, err := New(policyStore, defaultPolicyCacheExpiry)
 		require.NoError(t, err)
-		require.NotNil(t, wp)
 
-		batchWitness := &proof.Witness{
-			Type: proof.WitnessTypeBatch,
-			URI:  vocab.NewURLProperty(batchWitnessURL),
-		}
+		selection, err := wp.SelectDetailed([]*proof.Witness{batchWitness, systemWitness, commonSystemWitness})
+		require.NoError(t, err)
+		require.Equal(t, []*proof.Witness{batchWitness}, selection.Witnesses)
+		require.Equal(t, map[string][]string{
+			batchWitness.URI.String(): {"OutOfWithin(1,batch,1h0m0s)"},
+		}, selection.Rules)
+	})
 
-		batchWitness2 := &proof.Witness{
-			Type: proof.WitnessTypeBatch,
-			URI:  vocab.NewURLProperty(batchWitness2URL),
-		}
+	t.Run("error", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
 
-		systemWitness := &proof.Witness{
-			Type: proof.WitnessTypeSystem,
-			URI:  vocab.NewURLProperty(systemWitnessURL),
-		}
+		wp, err := New(policyStore, defaultPolicyCacheExpiry)
+		require.NoError(t, err)
 
-		witnesses := []*proof.Witness{
-			batchWitness,
-			batchWitness2,
-			systemWitness,
-		}
+		wp.cache = &mockCache{GetErr: fmt.Errorf("get error")}
 
-		selected, err := wp.Select(witnesses, batchWitness)
+		selection, err := wp.SelectDetailed([]*proof.Witness{batchWitness})
 		require.Error(t, err)
-		require.Nil(t, selected)
-		require.Contains(t, err.Error(), "unable to select 2 witnesses from witness array of length 1")
+		require.Nil(t, selection)
+		require.Contains(t, err.Error(), "get error")
 	})
+}
+
+func TestShortfall(t *testing.T) {
+	newWitness := func(witnessType proof.WitnessType, uri string) *proof.Witness {
+		return &proof.Witness{
+			Type: witnessType,
+			URI:  vocab.NewURLProperty(testutil.MustParseURL(uri)),
+		}
+	}
+
+	system1 := newWitness(proof.WitnessTypeSystem, "https://system1.com/service")
+	system2 := newWitness(proof.WitnessTypeSystem, "https://system2.com/service")
+	system3 := newWitness(proof.WitnessTypeSystem, "https://system3.com/service")
+	batch1 := newWitness(proof.WitnessTypeBatch, "https://batch1.com/service")
+	batch2 := newWitness(proof.WitnessTypeBatch, "https://batch2.com/service")
+
+	newPolicy := func(t *testing.T, policy string) *WitnessPolicy {
+		t.Helper()
 
-	t.Run("error - number of eligible system witnesses doesn't meet policy requirements", func(t *testing.T) {
 		policyStore := &mocks.PolicyStore{}
-		policyStore.GetPolicyReturns("OutOf(2,system) AND OutOf(1,batch) LogRequired", nil)
+		policyStore.GetPolicyReturns(policy, nil)
 
 		wp, err := New(policyStore, defaultPolicyCacheExpiry)
 		require.NoError(t, err)
-		require.NotNil(t, wp)
 
-		witnesses := []*proof.Witness{
-			{
-				Type:   proof.WitnessTypeSystem,
-				URI:    vocab.NewURLProperty(systemWitnessURL),
-				HasLog: true,
-			},
-			{
-				Type:   proof.WitnessTypeBatch,
-				URI:    vocab.NewURLProperty(batchWitnessURL),
-				HasLog: true,
-			},
-		}
+		return wp
+	}
 
-		selected, err := wp.Select(witnesses)
-		require.Error(t, err)
-		require.Nil(t, selected)
-		require.Contains(t, err.Error(), "unable to select 2 witnesses from witness array of length 1")
+	t.Run("OutOf(2,system)", func(t *testing.T) {
+		wp := newPolicy(t, "OutOf(2,system)")
+
+		t.Run("Need 1 more system proof", func(t *testing.T) {
+			witnessProofs := []*proof.WitnessProof{
+				{Witness: system1, Proof: []byte(testProof)},
+				{Witness: system2},
+				{Witness: system3},
+			}
+
+			ok, err := wp.Evaluate(witnessProofs)
+			require.NoError(t, err)
+			require.False(t, ok)
+
+			shortfall, err := wp.Shortfall(witnessProofs)
+			require.NoError(t, err)
+			require.False(t, shortfall.Satisfied())
+			require.Equal(t, 1, shortfall.System)
+			require.Zero(t, shortfall.Batch)
+			require.False(t, shortfall.RequiredWitness)
+		})
+
+		t.Run("Need 2 more system proofs", func(t *testing.T) {
+			shortfall, err := wp.Shortfall([]*proof.WitnessProof{
+				{Witness: system1},
+				{Witness: system2},
+				{Witness: system3},
+			})
+			require.NoError(t, err)
+			require.Equal(t, 2, shortfall.System)
+			require.Zero(t, shortfall.Batch)
+		})
+
+		t.Run("No witness proofs", func(t *testing.T) {
+			shortfall, err := wp.Shortfall(nil)
+			require.NoError(t, err)
+			require.Equal(t, 2, shortfall.System)
+		})
+
+		t.Run("Satisfied", func(t *testing.T) {
+			witnessProofs := []*proof.WitnessProof{
+				{Witness: system1, Proof: []byte(testProof)},
+				{Witness: system2, Proof: []byte(testProof)},
+				{Witness: system3},
+			}
+
+			ok, err := wp.Evaluate(witnessProofs)
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			shortfall, err := wp.Shortfall(witnessProofs)
+			require.NoError(t, err)
+			require.True(t, shortfall.Satisfied())
+		})
 	})
 
-	t.Run("error - number of eligible batch witnesses doesn't meet policy requirements", func(t *testing.T) {
-		policyStore := &mocks.PolicyStore{}
-		policyStore.GetPolicyReturns("OutOf(1,system) AND OutOf(2,batch) LogRequired", nil)
+	t.Run("MinPercent(50,batch) AND OutOf(3,system)", func(t *testing.T) {
+		wp := newPolicy(t, "MinPercent(50,batch) AND OutOf(3,system)")
 
-		wp, err := New(policyStore, defaultPolicyCacheExpiry)
+		shortfall, err := wp.Shortfall([]*proof.WitnessProof{
+			{Witness: batch1},
+			{Witness: batch2},
+			{Witness: system1, Proof: []byte(testProof)},
+			{Witness: system2},
+			{Witness: system3},
+		})
 		require.NoError(t, err)
-		require.NotNil(t, wp)
+		require.Equal(t, 1, shortfall.Batch)
+		require.Equal(t, 2, shortfall.System)
+		require.Equal(t, "AND", shortfall.Operator)
+	})
 
-		witnesses := []*proof.Witness{
-			{
-				Type:   proof.WitnessTypeSystem,
-				URI:    vocab.NewURLProperty(systemWitnessURL),
-				HasLog: true,
-			},
-			{
-				Type:   proof.WitnessTypeBatch,
-				URI:    vocab.NewURLProperty(batchWitnessURL),
-				HasLog: true,
-			},
-		}
+	t.Run("OR -> no shortfall if either rule is satisfied", func(t *testing.T) {
+		wp := newPolicy(t, "OutOf(1,batch) OR OutOf(2,system)")
 
-		selected, err := wp.Select(witnesses)
-		require.Error(t, err)
-		require.Nil(t, selected)
-		require.Contains(t, err.Error(), "unable to select 2 witnesses from witness array of length 1")
+		shortfall, err := wp.Shortfall([]*proof.WitnessProof{
+			{Witness: batch1, Proof: []byte(testProof)},
+			{Witness: system1},
+			{Witness: system2},
+		})
+		require.NoError(t, err)
+		require.True(t, shortfall.Satisfied())
+
+		shortfall, err = wp.Shortfall([]*proof.WitnessProof{
+			{Witness: batch1},
+			{Witness: system1, Proof: []byte(testProof)},
+			{Witness: system2},
+		})
+		require.NoError(t, err)
+		require.Equal(t, 1, shortfall.Batch)
+		require.Equal(t, 1, shortfall.System)
+		require.Equal(t, "OR", shortfall.Operator)
 	})
 
-	t.Run("error - get policy from cache error", func(t *testing.T) {
-		policyStore := &mocks.PolicyStore{}
+	t.Run("Required witness", func(t *testing.T) {
+		wp := newPolicy(t, "OutOf(1,system) RequireFrom(https://batch1.com/service)")
 
-		wp, err := New(policyStore, defaultPolicyCacheExpiry)
+		shortfall, err := wp.Shortfall([]*proof.WitnessProof{
+			{Witness: batch1},
+			{Witness: system1, Proof: []byte(testProof)},
+		})
 		require.NoError(t, err)
-		require.NotNil(t, wp)
+		require.False(t, shortfall.Satisfied())
+		require.True(t, shortfall.RequiredWitness)
+		require.Zero(t, shortfall.System)
+	})
 
-		wp.cache = &mockCache{GetErr: fmt.Errorf("get policy from cache error")}
+	t.Run("Policy retrieval error", func(t *testing.T) {
+		cache := newMapCache()
 
-		witnesses := []*proof.Witness{
-			{
-				Type: proof.WitnessTypeSystem,
-				URI:  vocab.NewURLProperty(systemWitnessURL),
-			},
-		}
+		wp, err := New(&mocks.PolicyStore{}, defaultPolicyCacheExpiry, WithCache(cache))
+		require.NoError(t, err)
 
-		selected, err := wp.Select(witnesses)
+		cache.getErr = fmt.Errorf("injected get error")
+
+		_, err = wp.Shortfall(nil)
 		require.Error(t, err)
-		require.Nil(t, selected)
-		require.Contains(t, err.Error(), "failed to retrieve policy from policy cache: get policy from cache error")
+		require.Contains(t, err.Error(), "injected get error")
 	})
 }
 
-func TestIntersection(t *testing.T) {
-	witnessURL, err := url.Parse("https://witness.com/service")
-	require.NoError(t, err)
-
-	otherWitnessURL, err := url.Parse("https://other.witness.com/service")
-	require.NoError(t, err)
+func TestSpread(t *testing.T) {
+	const policy = "OutOf(1,batch) OR OutOf(1,system) Spread(2)"
 
-	t.Run("success - no common elements", func(t *testing.T) {
-		batchWitnesses := []*proof.Witness{
-			{
-				Type: proof.WitnessTypeBatch,
-				URI:  vocab.NewURLProperty(witnessURL),
-			},
-		}
-		systemWitnesses := []*proof.Witness{
-			{
-				Type: proof.WitnessTypeSystem,
-				URI:  vocab.NewURLProperty(otherWitnessURL),
-			},
+	newWitness := func(witnessType proof.WitnessType, uri string) *proof.Witness {
+		return &proof.Witness{
+			Type: witnessType,
+			URI:  vocab.NewURLProperty(testutil.MustParseURL(uri)),
 		}
+	}
 
-		intersect := intersection(batchWitnesses, systemWitnesses)
-		require.Equal(t, 0, len(intersect))
-	})
+	batch1 := newWitness(proof.WitnessTypeBatch, "https://batch1.com/service")
+	system1 := newWitness(proof.WitnessTypeSystem, "https://system1.com/service")
+	system2 := newWitness(proof.WitnessTypeSystem, "https://system2.com/service")
 
-	t.Run("success - common elements", func(t *testing.T) {
-		batchWitnesses := []*proof.Witness{
-			{
-				Type: proof.WitnessTypeBatch,
-				URI:  vocab.NewURLProperty(witnessURL),
-			},
-		}
-		systemWitnesses := []*proof.Witness{
-			{
-				Type: proof.WitnessTypeSystem,
-				URI:  vocab.NewURLProperty(witnessURL),
-			},
-		}
+	newPolicy := func(t *testing.T) *WitnessPolicy {
+		t.Helper()
+
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns(policy, nil)
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry)
+		require.NoError(t, err)
+
+		return wp
+	}
 
-		intersect := intersection(batchWitnesses, systemWitnesses)
-		require.Equal(t, 1, len(intersect))
+	t.Run("Evaluate", func(t *testing.T) {
+		wp := newPolicy(t)
+
+		t.Run("Proofs from only one type -> not satisfied", func(t *testing.T) {
+			witnessProofs := []*proof.WitnessProof{
+				{Witness: batch1},
+				{Witness: system1, Proof: []byte(testProof)},
+				{Witness: system2, Proof: []byte(testProof)},
+			}
+
+			ok, err := wp.Evaluate(witnessProofs)
+			require.NoError(t, err)
+			require.False(t, ok)
+
+			shortfall, err := wp.Shortfall(witnessProofs)
+			require.NoError(t, err)
+			require.False(t, shortfall.Satisfied())
+			require.Equal(t, 1, shortfall.Types)
+			require.Zero(t, shortfall.Batch)
+			require.Zero(t, shortfall.System)
+		})
+
+		t.Run("Proofs from two types -> satisfied", func(t *testing.T) {
+			witnessProofs := []*proof.WitnessProof{
+				{Witness: batch1, Proof: []byte(testProof)},
+				{Witness: system1, Proof: []byte(testProof)},
+			}
+
+			ok, err := wp.Evaluate(witnessProofs)
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			shortfall, err := wp.Shortfall(witnessProofs)
+			require.NoError(t, err)
+			require.True(t, shortfall.Satisfied())
+		})
+
+		t.Run("No proofs", func(t *testing.T) {
+			ok, err := wp.Evaluate(nil)
+			require.NoError(t, err)
+			require.False(t, ok)
+
+			shortfall, err := wp.Shortfall(nil)
+			require.NoError(t, err)
+			require.Equal(t, 2, shortfall.Types)
+		})
 	})
 
-	t.Run("success - common elements (no duplicates)", func(t *testing.T) {
-		batchWitnesses := []*proof.Witness{
-			{
-				Type: proof.WitnessTypeBatch,
-				URI:  vocab.NewURLProperty(witnessURL),
-			},
-		}
-		systemWitnesses := []*proof.Witness{
-			{
-				Type: proof.WitnessTypeSystem,
-				URI:  vocab.NewURLProperty(witnessURL),
-			},
-			{
-				Type: proof.WitnessTypeSystem,
-				URI:  vocab.NewURLProperty(witnessURL),
-			},
-		}
+	t.Run("Select", func(t *testing.T) {
+		wp := newPolicy(t)
+
+		t.Run("Witnesses of both types selected", func(t *testing.T) {
+			selection, err := wp.SelectDetailed([]*proof.Witness{batch1, system1, system2})
+			require.NoError(t, err)
+			require.Len(t, selection.Witnesses, 2)
+			require.Equal(t, map[string][]string{
+				batch1.URI.String():  {"OutOf(1,batch)"},
+				system1.URI.String(): {"Spread(2)"},
+			}, selection.Rules)
+		})
+
+		t.Run("Excluded witness", func(t *testing.T) {
+			selection, err := wp.SelectDetailed([]*proof.Witness{batch1, system1, system2}, system1)
+			require.NoError(t, err)
+			require.Equal(t, map[string][]string{
+				batch1.URI.String():  {"OutOf(1,batch)"},
+				system2.URI.String(): {"Spread(2)"},
+			}, selection.Rules)
+		})
+
+		t.Run("Witnesses of only one type eligible -> best effort", func(t *testing.T) {
+			selected, err := wp.Select([]*proof.Witness{system1, system2})
+			require.NoError(t, err)
+			require.Len(t, selected, 1)
+			require.Equal(t, proof.WitnessTypeSystem, selected[0].Type)
+		})
+	})
 
-		intersect := intersection(batchWitnesses, systemWitnesses)
-		require.Equal(t, 1, len(intersect))
+	t.Run("Validate", func(t *testing.T) {
+		wp := newPolicy(t)
+
+		require.NoError(t, wp.Validate([]*proof.Witness{batch1, system1}))
+
+		err := wp.Validate([]*proof.Witness{system1, system2})
+		require.ErrorIs(t, err, ErrPolicyUnsatisfiable)
+		require.Contains(t, err.Error(), "eligible witnesses of only 1 type(s) are available")
 	})
 }
 
-func TestDifference(t *testing.T) {
-	witnessURL, err := url.Parse("https://witness.com/service")
-	require.NoError(t, err)
-
-	otherWitnessURL, err := url.Parse("https://other.witness.com/service")
-	require.NoError(t, err)
+func TestRequireFrom(t *testing.T) {
+	const policy = "OutOf(0,batch) AND OutOf(1,system) RequireFrom(https://trusted1.com/service,https://trusted2.com/service)"
 
-	t.Run("success - additional element in eligible", func(t *testing.T) {
-		eligible := []*proof.Witness{
-			{
-				Type: proof.WitnessTypeBatch,
-				URI:  vocab.NewURLProperty(witnessURL),
-			},
-			{
-				Type: proof.WitnessTypeSystem,
-				URI:  vocab.NewURLProperty(otherWitnessURL),
-			},
+	newWitness := func(witnessType proof.WitnessType, uri string) *proof.Witness {
+		return &proof.Witness{
+			Type: witnessType,
+			URI:  vocab.NewURLProperty(testutil.MustParseURL(uri)),
 		}
+	}
 
-		preferred := []*proof.Witness{
-			{
-				Type: proof.WitnessTypeSystem,
-				URI:  vocab.NewURLProperty(otherWitnessURL),
-			},
-		}
+	system1 := newWitness(proof.WitnessTypeSystem, "https://system1.com/service")
+	system2 := newWitness(proof.WitnessTypeSystem, "https://system2.com/service")
+	trusted := newWitness(proof.WitnessTypeBatch, "https://trusted2.com/service")
+
+	newPolicy := func(t *testing.T) *WitnessPolicy {
+		t.Helper()
+
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns(policy, nil)
+
+		wp, err := New(policyStore, defaultPolicyCacheExpiry)
+		require.NoError(t, err)
+
+		return wp
+	}
 
-		diff := difference(eligible, preferred)
-		require.Equal(t, 1, len(diff))
-		require.Equal(t, "https://witness.com/service", diff[0].URI.String())
+	t.Run("Evaluate", func(t *testing.T) {
+		wp := newPolicy(t)
+
+		t.Run("Required witness present", func(t *testing.T) {
+			ok, err := wp.Evaluate([]*proof.WitnessProof{
+				{Witness: system1, Proof: []byte(testProof)},
+				{Witness: trusted, Proof: []byte(testProof)},
+			})
+			require.NoError(t, err)
+			require.True(t, ok)
+		})
+
+		t.Run("Required witness absent", func(t *testing.T) {
+			ok, err := wp.Evaluate([]*proof.WitnessProof{
+				{Witness: system1, Proof: []byte(testProof)},
+				{Witness: system2, Proof: []byte(testProof)},
+			})
+			require.NoError(t, err)
+			require.False(t, ok)
+		})
+
+		t.Run("Required witness has no proof", func(t *testing.T) {
+			ok, err := wp.Evaluate([]*proof.WitnessProof{
+				{Witness: system1, Proof: []byte(testProof)},
+				{Witness: trusted},
+			})
+			require.NoError(t, err)
+			require.False(t, ok)
+		})
+
+		t.Run("No proofs", func(t *testing.T) {
+			ok, err := wp.Evaluate(nil)
+			require.NoError(t, err)
+			require.False(t, ok)
+		})
 	})
 
-	t.Run("success - preferred not provided", func(t *testing.T) {
-		eligible := []*proof.Witness{
-			{
-				Type: proof.WitnessTypeBatch,
-				URI:  vocab.NewURLProperty(witnessURL),
-			},
-			{
-				Type: proof.WitnessTypeSystem,
-				URI:  vocab.NewURLProperty(otherWitnessURL),
-			},
-		}
+	t.Run("Select", func(t *testing.T) {
+		wp := newPolicy(t)
+
+		t.Run("Required witness present", func(t *testing.T) {
+			selection, err := wp.SelectDetailed([]*proof.Witness{system1, system2, trusted})
+			require.NoError(t, err)
+			require.Len(t, selection.Witnesses, 2)
+			require.Equal(t, proof.WitnessTypeSystem, selection.Witnesses[0].Type)
+			require.Equal(t, trusted, selection.Witnesses[1])
+			require.Equal(t,
+				[]string{"RequireFrom(https://trusted1.com/service,https://trusted2.com/service)"},
+				selection.Rules[trusted.URI.String()],
+			)
+		})
+
+		t.Run("Required witness already selected", func(t *testing.T) {
+			trustedSystem := newWitness(proof.WitnessTypeSystem, "https://trusted1.com/service")
+
+			selected, err := wp.Select([]*proof.Witness{trustedSystem})
+			require.NoError(t, err)
+			require.Equal(t, []*proof.Witness{trustedSystem}, selected)
+		})
+
+		t.Run("Required witness absent", func(t *testing.T) {
+			selected, err := wp.Select([]*proof.Witness{system1, system2})
+			require.ErrorIs(t, err, ErrPolicyUnsatisfiable)
+			require.Nil(t, selected)
+		})
+
+		t.Run("Required witness excluded", func(t *testing.T) {
+			selected, err := wp.Select([]*proof.Witness{system1, system2, trusted}, trusted)
+			require.ErrorIs(t, err, ErrPolicyUnsatisfiable)
+			require.Nil(t, selected)
+		})
+	})
 
-		diff := difference(eligible, nil)
-		require.Equal(t, len(eligible), len(diff))
-		require.Equal(t, diff, eligible)
+	t.Run("Validate", func(t *testing.T) {
+		wp := newPolicy(t)
+
+		require.NoError(t, wp.Validate([]*proof.Witness{system1, trusted}))
+		require.ErrorIs(t, wp.Validate([]*proof.Witness{system1, system2}), ErrPolicyUnsatisfiable)
 	})
+}
+
+func TestValidate(t *testing.T) {
+	batchWitness := &proof.Witness{
+		Type: proof.WitnessTypeBatch,
+		URI:  vocab.NewURLProperty(testutil.MustParseURL("https://batch.com/service")),
+	}
+
+	systemWitness := &proof.Witness{
+		Type: proof.WitnessTypeSystem,
+		URI:  vocab.NewURLProperty(testutil.MustParseURL("https://system.com/service")),
+	}
+
+	systemWitnessWithLog := &proof.Witness{
+		Type:   proof.WitnessTypeSystem,
+		URI:    vocab.NewURLProperty(testutil.MustParseURL("https://system2.com/service")),
+		HasLog: true,
+	}
+
+	tests := []struct {
+		name          string
+		policy        string
+		available     []*proof.Witness
+		unsatisfiable bool
+	}{
+		{
+			name:      "default policy",
+			available: []*proof.Witness{batchWitness, systemWitness},
+		},
+		{
+			name:      "default policy - no witnesses",
+			available: nil,
+		},
+		{
+			name:      "OutOf system",
+			policy:    "OutOf(0,batch) AND OutOf(2,system)",
+			available: []*proof.Witness{systemWitness, systemWitnessWithLog},
+		},
+		{
+			name:          "OutOf system - no system witnesses",
+			policy:        "OutOf(1,system)",
+			available:     []*proof.Witness{batchWitness},
+			unsatisfiable: true,
+		},
+		{
+			name:          "OutOf system - not enough system witnesses",
+			policy:        "OutOf(0,batch) AND OutOf(2,system)",
+			available:     []*proof.Witness{batchWitness, systemWitness},
+			unsatisfiable: true,
+		},
+		{
+			name:          "OutOf system with log required - no eligible system witnesses",
+			policy:        "OutOf(0,batch) AND OutOf(1,system) LogRequired",
+			available:     []*proof.Witness{systemWitness},
+			unsatisfiable: true,
+		},
+		{
+			name:      "OutOf system with log required",
+			policy:    "OutOf(0,batch) AND OutOf(1,system) LogRequired",
+			available: []*proof.Witness{systemWitness, systemWitnessWithLog},
+		},
+		{
+			name:      "OR - one branch satisfiable",
+			policy:    "OutOf(1,batch) OR OutOf(1,system)",
+			available: []*proof.Witness{systemWitness},
+		},
+		{
+			name:          "OR - no branch satisfiable",
+			policy:        "OutOf(1,batch) OR OutOf(2,system)",
+			available:     []*proof.Witness{systemWitness},
+			unsatisfiable: true,
+		},
+	}
+
+	for _, tc := range tests {
+		test := tc
+
+		t.Run(test.name, func(t *testing.T) {
+			policyStore := &mocks.PolicyStore{}
+			policyStore.GetPolicyReturns(test.policy, nil)
+
+			wp, err := New(policyStore, defaultPolicyCacheExpiry)
+			require.NoError(t, err)
+
+			err = wp.Validate(test.available)
+			if test.unsatisfiable {
+				require.Error(t, err)
+				require.ErrorIs(t, err, ErrPolicyUnsatisfiable)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+
+	t.Run("error - get policy from cache error", func(t *testing.T) {
+		wp, err := New(&mocks.PolicyStore{}, defaultPolicyCacheExpiry)
+		require.NoError(t, err)
 
-	t.Run("success - eligible not provided either", func(t *testing.T) {
-		diff := difference(nil, nil)
-		require.Equal(t, 0, len(diff))
+		wp.cache = &mockCache{GetErr: fmt.Errorf("get policy from cache error")}
+
+		err = wp.Validate(nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "get policy from cache error")
 	})
 }
 
@@ -1332,3 +2973,134 @@ func (mc *mockCache) SetWithExpire(interface{}, interface{}, time.Duration) erro
 
 	return nil
 }
+
+// mapCache is a custom (non-loading) cache that simulates a cache shared across the cluster.
+type mapCache struct {
+	values   map[interface{}]interface{}
+	expiries map[interface{}]time.Duration
+	getErr   error
+	setErr   error
+	gets     int
+	sets     int
+}
+
+func newMapCache() *mapCache {
+	return &mapCache{
+		values:   make(map[interface{}]interface{}),
+		expiries: make(map[interface{}]time.Duration),
+	}
+}
+
+func (c *mapCache) Get(key interface{}) (interface{}, error) {
+	c.gets++
+
+	if c.getErr != nil {
+		return nil, c.getErr
+	}
+
+	value, ok := c.values[key]
+	if !ok {
+		return nil, fmt.Errorf("get [%s]: %w", key, ErrCacheMiss)
+	}
+
+	return value, nil
+}
+
+func (c *mapCache) SetWithExpire(key, value interface{}, expiration time.Duration) error {
+	c.sets++
+
+	if c.setErr != nil {
+		return c.setErr
+	}
+
+	c.values[key] = value
+	c.expiries[key] = expiration
+
+	return nil
+}
+
+func (c *mapCache) expire(key interface{}) {
+	delete(c.values, key)
+	delete(c.expiries, key)
+}
+
+func BenchmarkEvaluate(b *testing.B) {
+	policyStore := &mocks.PolicyStore{}
+	policyStore.GetPolicyReturns("OutOf(2,batch) AND OutOf(2,system)", nil)
+
+	var witnessProofs []*proof.WitnessProof
+
+	for i := 0; i < 10; i++ {
+		witnessType := proof.WitnessTypeBatch
+		if i%2 == 0 {
+			witnessType = proof.WitnessTypeSystem
+		}
+
+		witnessProofs = append(witnessProofs, &proof.WitnessProof{
+			Witness: &proof.Witness{
+				Type: witnessType,
+				URI:  vocab.NewURLProperty(testutil.MustParseURL(fmt.Sprintf("https://domain%d.com/service", i))),
+			},
+			Proof: []byte(testProof),
+		})
+	}
+
+	b.Run("without evaluation cache", func(b *testing.B) {
+		wp, err := New(policyStore, time.Minute)
+		require.NoError(b, err)
+
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			_, err = wp.Evaluate(witnessProofs)
+			require.NoError(b, err)
+		}
+	})
+
+	b.Run("with evaluation cache", func(b *testing.B) {
+		wp, err := New(policyStore, time.Minute, WithEvaluationCache(time.Minute))
+		require.NoError(b, err)
+
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			_, err = wp.Evaluate(witnessProofs)
+			require.NoError(b, err)
+		}
+	})
+}
+
+type mockShadowPolicyObserver struct {
+	diverged []bool
+}
+
+func (m *mockShadowPolicyObserver) ShadowPolicyDiverged(enforced, shadow bool) {
+	m.diverged = append(m.diverged, enforced, shadow)
+}
+
+type mockProofVerifier struct {
+	rejected map[string]bool
+	calls    int
+}
+
+func newMockProofVerifier() *mockProofVerifier {
+	return &mockProofVerifier{rejected: make(map[string]bool)}
+}
+
+func (m *mockProofVerifier) withRejected(witnessURIs ...string) *mockProofVerifier {
+	for _, uri := range witnessURIs {
+		m.rejected[uri] = true
+	}
+
+	return m
+}
+
+func (m *mockProofVerifier) Verify(witnessProof *proof.WitnessProof) error {
+	m.calls++
+
+	if m.rejected[witnessProof.URI.String()] {
+		return fmt.Errorf("invalid signature from witness [%s]", witnessProof.URI)
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package resthandler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/trustbloc/sidetree-core-go/pkg/restapi/common"
+
+	"github.com/trustbloc/orb/internal/pkg/log"
+)
+
+const flushEndpoint = endpoint + "/flush"
+
+type policyRefresher interface {
+	Refresh() error
+}
+
+// PolicyCacheFlusher drops the cached witness policy and reloads it from the policy store.
+type PolicyCacheFlusher struct {
+	refresher policyRefresher
+	store     policyStore
+}
+
+// NewCacheFlusher returns a new PolicyCacheFlusher.
+func NewCacheFlusher(refresher policyRefresher, store policyStore) *PolicyCacheFlusher {
+	return &PolicyCacheFlusher{
+		refresher: refresher,
+		store:     store,
+	}
+}
+
+// Path returns the HTTP REST endpoint for the policy cache flusher.
+func (pc *PolicyCacheFlusher) Path() string {
+	return flushEndpoint
+}
+
+// Method returns the HTTP REST method for the policy cache flusher.
+func (pc *PolicyCacheFlusher) Method() string {
+	return http.MethodPost
+}
+
+// Handler returns the HTTP REST handle for the PolicyCacheFlusher service.
+func (pc *PolicyCacheFlusher) Handler() common.HTTPRequestHandler {
+	return pc.handle
+}
+
+func (pc *PolicyCacheFlusher) handle(w http.ResponseWriter, _ *http.Request) {
+	if err := pc.refresher.Refresh(); err != nil {
+		logger.Error("Error refreshing witness policy cache", log.WithError(err))
+
+		writeResponse(w, http.StatusInternalServerError, []byte(internalServerErrorResponse))
+
+		return
+	}
+
+	policyStr, err := pc.store.GetPolicy()
+	if err != nil && !errors.Is(err, storage.ErrDataNotFound) {
+		logger.Error("Error retrieving witness policy", log.WithError(err))
+
+		writeResponse(w, http.StatusInternalServerError, []byte(internalServerErrorResponse))
+
+		return
+	}
+
+	logger.Info("Flushed witness policy cache", log.WithWitnessPolicy(policyStr))
+
+	writeResponse(w, http.StatusOK, []byte(policyStr))
+}
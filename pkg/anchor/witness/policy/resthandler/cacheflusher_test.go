@@ -0,0 +1,116 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package resthandler
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/anchor/witness/policy"
+	"github.com/trustbloc/orb/pkg/anchor/witness/policy/mocks"
+)
+
+func TestNewCacheFlusher(t *testing.T) {
+	flusher := NewCacheFlusher(&mockRefresher{}, &mocks.PolicyStore{})
+	require.NotNil(t, flusher)
+	require.Equal(t, flushEndpoint, flusher.Path())
+	require.Equal(t, http.MethodPost, flusher.Method())
+	require.NotNil(t, flusher.Handler())
+}
+
+func TestCacheFlusher_Handler(t *testing.T) {
+	t.Run("success - cache reloaded", func(t *testing.T) {
+		const noRequirementsPolicy = "OutOf(0,batch) AND OutOf(0,system)"
+
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns(noRequirementsPolicy, nil)
+
+		wp, err := policy.New(policyStore, time.Hour)
+		require.NoError(t, err)
+
+		ok, err := wp.Evaluate(nil)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		// Update the stored policy. The cached policy is still used until the cache is flushed.
+		policyStore.GetPolicyReturns(testPolicy, nil)
+
+		ok, err = wp.Evaluate(nil)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		rw := httptest.NewRecorder()
+
+		NewCacheFlusher(wp, policyStore).handle(rw, httptest.NewRequest(http.MethodPost, flushEndpoint, nil))
+
+		result := rw.Result()
+		require.Equal(t, http.StatusOK, result.StatusCode)
+
+		respBytes, err := ioutil.ReadAll(result.Body)
+		require.NoError(t, err)
+		require.Equal(t, testPolicy, string(respBytes))
+		require.NoError(t, result.Body.Close())
+
+		ok, err = wp.Evaluate(nil)
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("success - policy not found", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("", storage.ErrDataNotFound)
+
+		rw := httptest.NewRecorder()
+
+		NewCacheFlusher(&mockRefresher{}, policyStore).handle(rw,
+			httptest.NewRequest(http.MethodPost, flushEndpoint, nil))
+
+		result := rw.Result()
+		require.Equal(t, http.StatusOK, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+	})
+
+	t.Run("error - refresh error", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+
+		NewCacheFlusher(&mockRefresher{err: errors.New("injected refresh error")}, &mocks.PolicyStore{}).handle(rw,
+			httptest.NewRequest(http.MethodPost, flushEndpoint, nil))
+
+		result := rw.Result()
+		require.Equal(t, http.StatusInternalServerError, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+	})
+
+	t.Run("error - policy store error", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("", errors.New("injected store error"))
+
+		rw := httptest.NewRecorder()
+
+		NewCacheFlusher(&mockRefresher{}, policyStore).handle(rw,
+			httptest.NewRequest(http.MethodPost, flushEndpoint, nil))
+
+		result := rw.Result()
+		require.Equal(t, http.StatusInternalServerError, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+	})
+}
+
+type mockRefresher struct {
+	err error
+}
+
+func (m *mockRefresher) Refresh() error {
+	return m.err
+}
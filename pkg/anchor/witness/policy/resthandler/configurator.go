@@ -7,9 +7,12 @@ SPDX-License-Identifier: Apache-2.0
 package resthandler
 
 import (
+	"crypto/sha256"
+	"errors"
 	"io/ioutil"
 	"net/http"
 
+	"github.com/hyperledger/aries-framework-go/spi/storage"
 	"github.com/trustbloc/sidetree-core-go/pkg/restapi/common"
 
 	"github.com/trustbloc/orb/internal/pkg/log"
@@ -20,9 +23,16 @@ const endpoint = "/policy"
 
 const (
 	badRequestResponse          = "Bad Request."
+	methodNotAllowedResponse    = "Method Not Allowed."
 	internalServerErrorResponse = "Internal Server Error."
 )
 
+// Error codes used for log-based alerting.
+const (
+	errorCodePolicyParse = "policy-parse"
+	errorCodeStoreWrite  = "store-write"
+)
+
 var logger = log.NewStructured("policy-rest-handler", log.WithFields(log.WithServiceEndpoint(endpoint)))
 
 type policyStore interface {
@@ -32,7 +42,29 @@ type policyStore interface {
 
 // PolicyConfigurator updates witness policy in config store.
 type PolicyConfigurator struct {
-	store policyStore
+	store           policyStore
+	onPolicyChanged func(newPolicy string)
+	readOnly        bool
+}
+
+// Option is an option for the policy configurator.
+type Option func(opts *PolicyConfigurator)
+
+// WithOnPolicyChanged sets an optional callback that is invoked after a new witness policy
+// has been successfully stored (e.g. to refresh the policy cache).
+func WithOnPolicyChanged(handler func(newPolicy string)) Option {
+	return func(opts *PolicyConfigurator) {
+		opts.onPolicyChanged = handler
+	}
+}
+
+// WithReadOnly puts the policy configurator into read-only mode, in which requests to update the witness
+// policy are rejected with 405 (Method Not Allowed). This is useful if the witness policy is managed
+// out-of-band and mustn't be changed at runtime. The policy retriever is not affected.
+func WithReadOnly(readOnly bool) Option {
+	return func(opts *PolicyConfigurator) {
+		opts.readOnly = readOnly
+	}
 }
 
 // Path returns the HTTP REST endpoint for the PolicyConfigurator service.
@@ -51,18 +83,31 @@ func (pc *PolicyConfigurator) Handler() common.HTTPRequestHandler {
 }
 
 // New returns a new PolicyConfigurator.
-func New(store policyStore) *PolicyConfigurator {
+func New(store policyStore, opts ...Option) *PolicyConfigurator {
 	h := &PolicyConfigurator{
 		store: store,
 	}
 
+	for _, opt := range opts {
+		opt(h)
+	}
+
 	return h
 }
 
 func (pc *PolicyConfigurator) handle(w http.ResponseWriter, req *http.Request) {
+	if pc.readOnly {
+		logger.Warn("Rejected request to update the witness policy since the policy configurator is in read-only mode",
+			log.WithResult(log.ResultFailure))
+
+		writeResponse(w, http.StatusMethodNotAllowed, []byte(methodNotAllowedResponse))
+
+		return
+	}
+
 	policyBytes, err := ioutil.ReadAll(req.Body)
 	if err != nil {
-		logger.Error("Error reading request body", log.WithError(err))
+		logger.Error("Error reading request body", log.WithError(err), log.WithResult(log.ResultFailure))
 
 		writeResponse(w, http.StatusBadRequest, []byte(badRequestResponse))
 
@@ -73,27 +118,58 @@ func (pc *PolicyConfigurator) handle(w http.ResponseWriter, req *http.Request) {
 
 	_, err = config.Parse(policyStr)
 	if err != nil {
-		logger.Error("Invalid witness policy", log.WithError(err), log.WithWitnessPolicy(policyStr))
+		logger.Error("Invalid witness policy", log.WithError(err), log.WithErrorCode(errorCodePolicyParse),
+			log.WithWitnessPolicy(policyStr), log.WithResult(log.ResultFailure))
 
 		writeResponse(w, http.StatusBadRequest, []byte(badRequestResponse))
 
 		return
 	}
 
+	if pc.isUnchanged(policyStr) {
+		logger.Info("Witness policy is unchanged. The policy won't be stored.", log.WithWitnessPolicy(policyStr),
+			log.WithResult(log.ResultSuccess))
+
+		writeResponse(w, http.StatusOK, nil)
+
+		return
+	}
+
 	err = pc.store.PutPolicy(policyStr)
 	if err != nil {
-		logger.Error("Error storing witness policy", log.WithError(err))
+		logger.Error("Error storing witness policy", log.WithError(err), log.WithErrorCode(errorCodeStoreWrite),
+			log.WithResult(log.ResultFailure))
 
 		writeResponse(w, http.StatusInternalServerError, []byte(internalServerErrorResponse))
 
 		return
 	}
 
-	logger.Debug("Stored witness policy", log.WithWitnessPolicy(policyStr))
+	logger.Info("Stored witness policy", log.WithWitnessPolicy(policyStr), log.WithResult(log.ResultSuccess))
+
+	if pc.onPolicyChanged != nil {
+		pc.onPolicyChanged(policyStr)
+	}
 
 	writeResponse(w, http.StatusOK, nil)
 }
 
+// isUnchanged returns true if the hash of the given policy matches the hash of the currently stored policy.
+// If the current policy can't be retrieved then the policy is assumed to have changed.
+func (pc *PolicyConfigurator) isUnchanged(policyStr string) bool {
+	currentPolicy, err := pc.store.GetPolicy()
+	if err != nil {
+		if !errors.Is(err, storage.ErrDataNotFound) {
+			logger.Warn("Error retrieving current witness policy. The policy will be stored regardless.",
+				log.WithError(err))
+		}
+
+		return false
+	}
+
+	return sha256.Sum256([]byte(policyStr)) == sha256.Sum256([]byte(currentPolicy))
+}
+
 func writeResponse(w http.ResponseWriter, status int, body []byte) {
 	if len(body) > 0 {
 		w.Header().Set("Content-Type", "text/plain")
@@ -15,6 +15,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/hyperledger/aries-framework-go/spi/storage"
 	"github.com/stretchr/testify/require"
 
 	"github.com/trustbloc/orb/pkg/anchor/witness/policy/mocks"
@@ -122,6 +123,193 @@ func TestHandler(t *testing.T) {
 	})
 }
 
+func TestHandler_ReadOnly(t *testing.T) {
+	t.Run("read-only - update rejected", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+
+		invoked := false
+
+		policyConfigurator := New(policyStore, WithReadOnly(true), WithOnPolicyChanged(func(string) {
+			invoked = true
+		}))
+
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, endpoint, bytes.NewBuffer([]byte(testPolicy)))
+
+		policyConfigurator.handle(rw, req)
+
+		result := rw.Result()
+		require.Equal(t, http.StatusMethodNotAllowed, result.StatusCode)
+
+		respBytes, err := ioutil.ReadAll(result.Body)
+		require.NoError(t, err)
+		require.Equal(t, []byte(methodNotAllowedResponse), respBytes)
+		require.NoError(t, result.Body.Close())
+
+		require.Zero(t, policyStore.PutPolicyCallCount())
+		require.False(t, invoked)
+
+		// The current policy may still be retrieved.
+		policyStore.GetPolicyReturns(testPolicy, nil)
+
+		rw = httptest.NewRecorder()
+
+		NewRetriever(policyStore).handle(rw, httptest.NewRequest(http.MethodGet, endpoint, nil))
+
+		result = rw.Result()
+		require.Equal(t, http.StatusOK, result.StatusCode)
+
+		respBytes, err = ioutil.ReadAll(result.Body)
+		require.NoError(t, err)
+		require.Equal(t, testPolicy, string(respBytes))
+		require.NoError(t, result.Body.Close())
+	})
+
+	t.Run("not read-only - policy updated", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+
+		policyConfigurator := New(policyStore, WithReadOnly(false))
+
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, endpoint, bytes.NewBuffer([]byte(testPolicy)))
+
+		policyConfigurator.handle(rw, req)
+
+		result := rw.Result()
+		require.Equal(t, http.StatusOK, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+
+		require.Equal(t, 1, policyStore.PutPolicyCallCount())
+		require.Equal(t, testPolicy, policyStore.PutPolicyArgsForCall(0))
+	})
+}
+
+func TestHandler_OnPolicyChanged(t *testing.T) {
+	t.Run("success - callback invoked with new policy", func(t *testing.T) {
+		var changedPolicies []string
+
+		policyConfigurator := New(&mocks.PolicyStore{}, WithOnPolicyChanged(func(newPolicy string) {
+			changedPolicies = append(changedPolicies, newPolicy)
+		}))
+
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, endpoint, bytes.NewBuffer([]byte(testPolicy)))
+
+		policyConfigurator.handle(rw, req)
+
+		result := rw.Result()
+		require.Equal(t, http.StatusOK, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+		require.Equal(t, []string{testPolicy}, changedPolicies)
+	})
+
+	t.Run("parse policy error - callback not invoked", func(t *testing.T) {
+		invoked := false
+
+		policyConfigurator := New(&mocks.PolicyStore{}, WithOnPolicyChanged(func(string) {
+			invoked = true
+		}))
+
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, endpoint, bytes.NewBuffer([]byte("InvalidPolicy")))
+
+		policyConfigurator.handle(rw, req)
+
+		result := rw.Result()
+		require.Equal(t, http.StatusBadRequest, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+		require.False(t, invoked)
+	})
+
+	t.Run("config store error - callback not invoked", func(t *testing.T) {
+		invoked := false
+
+		policyStore := &mocks.PolicyStore{}
+		policyStore.PutPolicyReturns(fmt.Errorf("put error"))
+
+		policyConfigurator := New(policyStore, WithOnPolicyChanged(func(string) {
+			invoked = true
+		}))
+
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, endpoint, bytes.NewBuffer([]byte(testPolicy)))
+
+		policyConfigurator.handle(rw, req)
+
+		result := rw.Result()
+		require.Equal(t, http.StatusInternalServerError, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+		require.False(t, invoked)
+	})
+}
+
+func TestHandler_Unchanged(t *testing.T) {
+	post := func(t *testing.T, pc *PolicyConfigurator, policy string) {
+		t.Helper()
+
+		rw := httptest.NewRecorder()
+
+		pc.handle(rw, httptest.NewRequest(http.MethodPost, endpoint, bytes.NewBuffer([]byte(policy))))
+
+		result := rw.Result()
+		require.Equal(t, http.StatusOK, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+	}
+
+	t.Run("unchanged policy -> not stored", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns(testPolicy, nil)
+
+		var changedPolicies []string
+
+		policyConfigurator := New(policyStore, WithOnPolicyChanged(func(newPolicy string) {
+			changedPolicies = append(changedPolicies, newPolicy)
+		}))
+
+		post(t, policyConfigurator, testPolicy)
+
+		require.Zero(t, policyStore.PutPolicyCallCount())
+		require.Empty(t, changedPolicies)
+	})
+
+	t.Run("changed policy -> stored", func(t *testing.T) {
+		const newPolicy = "MinPercent(100,system) AND MinPercent(50,batch)"
+
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns(testPolicy, nil)
+
+		var changedPolicies []string
+
+		policyConfigurator := New(policyStore, WithOnPolicyChanged(func(newPolicy string) {
+			changedPolicies = append(changedPolicies, newPolicy)
+		}))
+
+		post(t, policyConfigurator, newPolicy)
+
+		require.Equal(t, 1, policyStore.PutPolicyCallCount())
+		require.Equal(t, newPolicy, policyStore.PutPolicyArgsForCall(0))
+		require.Equal(t, []string{newPolicy}, changedPolicies)
+	})
+
+	t.Run("policy not found -> stored", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("", storage.ErrDataNotFound)
+
+		post(t, New(policyStore), testPolicy)
+
+		require.Equal(t, 1, policyStore.PutPolicyCallCount())
+	})
+
+	t.Run("error retrieving current policy -> stored", func(t *testing.T) {
+		policyStore := &mocks.PolicyStore{}
+		policyStore.GetPolicyReturns("", errors.New("injected GetPolicy error"))
+
+		post(t, New(policyStore), testPolicy)
+
+		require.Equal(t, 1, policyStore.PutPolicyCallCount())
+	})
+}
+
 type errReader int
 
 func (errReader) Read(p []byte) (n int, err error) {
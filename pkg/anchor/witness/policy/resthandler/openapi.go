@@ -43,3 +43,21 @@ type policyPostResp struct { // nolint: unused,deadcode
 //        200: policyPostResp
 func postPolicy() { // nolint: unused,deadcode
 }
+
+// swagger:parameters policyFlushReq
+type policyFlushReq struct { // nolint: unused,deadcode
+}
+
+// swagger:response policyFlushResp
+type policyFlushResp struct { // nolint: unused,deadcode
+	Body string
+}
+
+// flushPolicy swagger:route POST /policy/flush policy policyFlushReq
+//
+// Flushes the witness policy cache and returns the policy reloaded from the policy store.
+//
+// Responses:
+//        200: policyFlushResp
+func flushPolicy() { // nolint: unused,deadcode
+}
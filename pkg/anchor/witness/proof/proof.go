@@ -7,7 +7,10 @@ SPDX-License-Identifier: Apache-2.0
 package proof
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/trustbloc/orb/pkg/activitypub/vocab"
 )
@@ -34,6 +37,87 @@ func (wf *WitnessProof) String() string {
 	return fmt.Sprintf("{type:%s, witness:%s, log:%t, proof:%s}", wf.Type, wf.URI, wf.HasLog, string(wf.Proof))
 }
 
+// ErrMalformedProof indicates that the witness proof bytes could not be decoded.
+var ErrMalformedProof = errors.New("malformed witness proof")
+
+// DecodedProof contains the decoded contents of a witness proof.
+type DecodedProof struct {
+	Type               string
+	Created            time.Time
+	VerificationMethod string
+	ProofPurpose       string
+	Domain             string
+	// Signature is the signature of the proof, i.e. either the "proofValue" or "jws" field.
+	Signature string
+}
+
+// Decode decodes the witness proof bytes. An error (that wraps ErrMalformedProof) is returned
+// if the proof bytes are empty, are not valid JSON, or do not contain a proof. The created time
+// is optional and is zero if not specified.
+func (wf *WitnessProof) Decode() (*DecodedProof, error) {
+	if len(wf.Proof) == 0 {
+		return nil, fmt.Errorf("%w: proof is empty", ErrMalformedProof)
+	}
+
+	p := &struct {
+		Proof *struct {
+			Type               string `json:"type"`
+			Created            string `json:"created"`
+			VerificationMethod string `json:"verificationMethod"`
+			ProofPurpose       string `json:"proofPurpose"`
+			Domain             string `json:"domain"`
+			ProofValue         string `json:"proofValue"`
+			JWS                string `json:"jws"`
+		} `json:"proof"`
+	}{}
+
+	if err := json.Unmarshal(wf.Proof, p); err != nil {
+		return nil, fmt.Errorf("%w: unmarshal proof: %s", ErrMalformedProof, err)
+	}
+
+	if p.Proof == nil {
+		return nil, fmt.Errorf("%w: proof field is missing", ErrMalformedProof)
+	}
+
+	decoded := &DecodedProof{
+		Type:               p.Proof.Type,
+		VerificationMethod: p.Proof.VerificationMethod,
+		ProofPurpose:       p.Proof.ProofPurpose,
+		Domain:             p.Proof.Domain,
+		Signature:          p.Proof.ProofValue,
+	}
+
+	if decoded.Signature == "" {
+		decoded.Signature = p.Proof.JWS
+	}
+
+	if p.Proof.Created != "" {
+		created, err := time.Parse(time.RFC3339, p.Proof.Created)
+		if err != nil {
+			return nil, fmt.Errorf("%w: parse created time: %s", ErrMalformedProof, err)
+		}
+
+		decoded.Created = created
+	}
+
+	return decoded, nil
+}
+
+// CreatedTime returns the time at which the witness proof was created (as specified by the "created"
+// field of the proof). An error is returned if the proof doesn't contain a valid created time.
+func (wf *WitnessProof) CreatedTime() (time.Time, error) {
+	decoded, err := wf.Decode()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if decoded.Created.IsZero() {
+		return time.Time{}, fmt.Errorf("proof does not contain a created time")
+	}
+
+	return decoded.Created, nil
+}
+
 // WitnessType defines valid values for witness type.
 type WitnessType string
 
@@ -7,8 +7,10 @@ SPDX-License-Identifier: Apache-2.0
 package proof
 
 import (
+	"errors"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -39,3 +41,70 @@ func Test(t *testing.T) {
 		require.Equal(t, wp.String(), "{type:batch, witness:http://domain.com/service, log:true, proof:proof}")
 	})
 }
+
+func TestWitnessProof_Decode(t *testing.T) {
+	t.Run("success - JWS", func(t *testing.T) {
+		wp := &WitnessProof{Proof: []byte(`{
+  "@context": ["https://w3id.org/security/v1", "https://w3id.org/security/suites/jws-2020/v1"],
+  "proof": {
+    "created": "2021-04-20T20:05:35.055Z",
+    "domain": "http://orb.vct:8077/maple2020",
+    "jws": "eyJhbGciOiJFZERTQSIsImI2NCI6ZmFsc2UsImNyaXQiOlsiYjY0Il19..PahivkKT6iKdnZDpkLu6uwDWYSdP7frt4l66AXI8mTsB",
+    "proofPurpose": "assertionMethod",
+    "type": "JsonWebSignature2020",
+    "verificationMethod": "did:web:abc.com#2130bhDAK-2jKsOXJiEDG909Jux4rcYEpFsYzVlqdAY"
+  }
+}`)}
+
+		decoded, err := wp.Decode()
+		require.NoError(t, err)
+		require.Equal(t, "JsonWebSignature2020", decoded.Type)
+		require.Equal(t, time.Date(2021, 4, 20, 20, 5, 35, 55000000, time.UTC), decoded.Created.UTC())
+		require.Equal(t, "did:web:abc.com#2130bhDAK-2jKsOXJiEDG909Jux4rcYEpFsYzVlqdAY", decoded.VerificationMethod)
+		require.Equal(t, "assertionMethod", decoded.ProofPurpose)
+		require.Equal(t, "http://orb.vct:8077/maple2020", decoded.Domain)
+		require.Equal(t, "eyJhbGciOiJFZERTQSIsImI2NCI6ZmFsc2UsImNyaXQiOlsiYjY0Il19..PahivkKT6iKdnZDpkLu6uwDWYSdP7frt4l66AXI8mTsB",
+			decoded.Signature)
+
+		created, err := wp.CreatedTime()
+		require.NoError(t, err)
+		require.Equal(t, decoded.Created, created)
+	})
+
+	t.Run("success - proof value and no created time", func(t *testing.T) {
+		wp := &WitnessProof{Proof: []byte(`{"proof":{"type":"Ed25519Signature2020","proofValue":"z5Mh8n"}}`)}
+
+		decoded, err := wp.Decode()
+		require.NoError(t, err)
+		require.Equal(t, "Ed25519Signature2020", decoded.Type)
+		require.Equal(t, "z5Mh8n", decoded.Signature)
+		require.True(t, decoded.Created.IsZero())
+
+		_, err = wp.CreatedTime()
+		require.EqualError(t, err, "proof does not contain a created time")
+	})
+
+	t.Run("error - empty proof", func(t *testing.T) {
+		_, err := (&WitnessProof{}).Decode()
+		require.True(t, errors.Is(err, ErrMalformedProof))
+		require.Contains(t, err.Error(), "proof is empty")
+	})
+
+	t.Run("error - malformed proof", func(t *testing.T) {
+		_, err := (&WitnessProof{Proof: []byte("proof")}).Decode()
+		require.True(t, errors.Is(err, ErrMalformedProof))
+		require.Contains(t, err.Error(), "unmarshal proof")
+	})
+
+	t.Run("error - missing proof field", func(t *testing.T) {
+		_, err := (&WitnessProof{Proof: []byte(`{"@context":"https://w3id.org/security/v1"}`)}).Decode()
+		require.True(t, errors.Is(err, ErrMalformedProof))
+		require.Contains(t, err.Error(), "proof field is missing")
+	})
+
+	t.Run("error - invalid created time", func(t *testing.T) {
+		_, err := (&WitnessProof{Proof: []byte(`{"proof":{"created":"yesterday"}}`)}).CreatedTime()
+		require.True(t, errors.Is(err, ErrMalformedProof))
+		require.Contains(t, err.Error(), "parse created time")
+	})
+}
@@ -0,0 +1,71 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package witnessset provides set operations on witnesses. Witnesses are considered to be equal if they have
+// the same URI, and the result of each operation contains at most one witness per URI.
+package witnessset
+
+import (
+	"github.com/trustbloc/orb/pkg/anchor/witness/proof"
+)
+
+// Union returns the witnesses that are in either a or b. The witnesses in a are returned first followed by the
+// witnesses in b that aren't in a.
+func Union(a, b []*proof.Witness) []*proof.Witness {
+	var result []*proof.Witness
+
+	hash := make(map[string]bool)
+
+	for _, witnesses := range [][]*proof.Witness{a, b} {
+		for _, e := range witnesses {
+			if !hash[e.URI.String()] {
+				result = append(result, e)
+				hash[e.URI.String()] = true
+			}
+		}
+	}
+
+	return result
+}
+
+// Intersection returns the witnesses in b that are also in a.
+func Intersection(a, b []*proof.Witness) []*proof.Witness {
+	var result []*proof.Witness
+
+	hash := make(map[string]bool)
+	for _, e := range a {
+		hash[e.URI.String()] = false
+	}
+
+	for _, e := range b {
+		if v, ok := hash[e.URI.String()]; ok && !v {
+			result = append(result, e)
+			hash[e.URI.String()] = true
+		}
+	}
+
+	return result
+}
+
+// Difference returns the witnesses in a that aren't in b, e.g. Difference(current, previous) returns
+// the newly added witnesses.
+func Difference(a, b []*proof.Witness) []*proof.Witness {
+	var result []*proof.Witness
+
+	hash := make(map[string]bool)
+	for _, e := range b {
+		hash[e.URI.String()] = true
+	}
+
+	for _, e := range a {
+		if _, ok := hash[e.URI.String()]; !ok {
+			result = append(result, e)
+			hash[e.URI.String()] = true
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,222 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package witnessset
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/activitypub/vocab"
+	"github.com/trustbloc/orb/pkg/anchor/witness/proof"
+)
+
+func TestUnion(t *testing.T) {
+	witnessURL, err := url.Parse("https://witness.com/service")
+	require.NoError(t, err)
+
+	otherWitnessURL, err := url.Parse("https://other.witness.com/service")
+	require.NoError(t, err)
+
+	thirdWitnessURL, err := url.Parse("https://third.witness.com/service")
+	require.NoError(t, err)
+
+	t.Run("success - no common elements", func(t *testing.T) {
+		a := []*proof.Witness{
+			{
+				Type: proof.WitnessTypeBatch,
+				URI:  vocab.NewURLProperty(witnessURL),
+			},
+		}
+		b := []*proof.Witness{
+			{
+				Type: proof.WitnessTypeSystem,
+				URI:  vocab.NewURLProperty(otherWitnessURL),
+			},
+		}
+
+		union := Union(a, b)
+		require.Equal(t, 2, len(union))
+		require.Equal(t, witnessURL.String(), union[0].URI.String())
+		require.Equal(t, otherWitnessURL.String(), union[1].URI.String())
+	})
+
+	t.Run("success - common elements (no duplicates)", func(t *testing.T) {
+		a := []*proof.Witness{
+			{
+				Type: proof.WitnessTypeBatch,
+				URI:  vocab.NewURLProperty(witnessURL),
+			},
+			{
+				Type: proof.WitnessTypeBatch,
+				URI:  vocab.NewURLProperty(witnessURL),
+			},
+		}
+		b := []*proof.Witness{
+			{
+				Type: proof.WitnessTypeSystem,
+				URI:  vocab.NewURLProperty(thirdWitnessURL),
+			},
+			{
+				Type: proof.WitnessTypeSystem,
+				URI:  vocab.NewURLProperty(witnessURL),
+			},
+		}
+
+		union := Union(a, b)
+		require.Equal(t, 2, len(union))
+		require.Equal(t, witnessURL.String(), union[0].URI.String())
+		require.Equal(t, proof.WitnessTypeBatch, union[0].Type)
+		require.Equal(t, thirdWitnessURL.String(), union[1].URI.String())
+
+		// The input slices aren't modified.
+		require.Len(t, a, 2)
+		require.Len(t, b, 2)
+	})
+
+	t.Run("success - empty", func(t *testing.T) {
+		require.Empty(t, Union(nil, nil))
+	})
+}
+
+func TestIntersection(t *testing.T) {
+	witnessURL, err := url.Parse("https://witness.com/service")
+	require.NoError(t, err)
+
+	otherWitnessURL, err := url.Parse("https://other.witness.com/service")
+	require.NoError(t, err)
+
+	t.Run("success - no common elements", func(t *testing.T) {
+		batchWitnesses := []*proof.Witness{
+			{
+				Type: proof.WitnessTypeBatch,
+				URI:  vocab.NewURLProperty(witnessURL),
+			},
+		}
+		systemWitnesses := []*proof.Witness{
+			{
+				Type: proof.WitnessTypeSystem,
+				URI:  vocab.NewURLProperty(otherWitnessURL),
+			},
+		}
+
+		intersect := Intersection(batchWitnesses, systemWitnesses)
+		require.Equal(t, 0, len(intersect))
+	})
+
+	t.Run("success - common elements", func(t *testing.T) {
+		batchWitnesses := []*proof.Witness{
+			{
+				Type: proof.WitnessTypeBatch,
+				URI:  vocab.NewURLProperty(witnessURL),
+			},
+		}
+		systemWitnesses := []*proof.Witness{
+			{
+				Type: proof.WitnessTypeSystem,
+				URI:  vocab.NewURLProperty(witnessURL),
+			},
+		}
+
+		intersect := Intersection(batchWitnesses, systemWitnesses)
+		require.Equal(t, 1, len(intersect))
+	})
+
+	t.Run("success - common elements (no duplicates)", func(t *testing.T) {
+		batchWitnesses := []*proof.Witness{
+			{
+				Type: proof.WitnessTypeBatch,
+				URI:  vocab.NewURLProperty(witnessURL),
+			},
+		}
+		systemWitnesses := []*proof.Witness{
+			{
+				Type: proof.WitnessTypeSystem,
+				URI:  vocab.NewURLProperty(witnessURL),
+			},
+			{
+				Type: proof.WitnessTypeSystem,
+				URI:  vocab.NewURLProperty(witnessURL),
+			},
+		}
+
+		intersect := Intersection(batchWitnesses, systemWitnesses)
+		require.Equal(t, 1, len(intersect))
+	})
+}
+
+func TestDifference(t *testing.T) {
+	witnessURL, err := url.Parse("https://witness.com/service")
+	require.NoError(t, err)
+
+	otherWitnessURL, err := url.Parse("https://other.witness.com/service")
+	require.NoError(t, err)
+
+	t.Run("success - additional element in eligible", func(t *testing.T) {
+		eligible := []*proof.Witness{
+			{
+				Type: proof.WitnessTypeBatch,
+				URI:  vocab.NewURLProperty(witnessURL),
+			},
+			{
+				Type: proof.WitnessTypeSystem,
+				URI:  vocab.NewURLProperty(otherWitnessURL),
+			},
+		}
+
+		preferred := []*proof.Witness{
+			{
+				Type: proof.WitnessTypeSystem,
+				URI:  vocab.NewURLProperty(otherWitnessURL),
+			},
+		}
+
+		diff := Difference(eligible, preferred)
+		require.Equal(t, 1, len(diff))
+		require.Equal(t, "https://witness.com/service", diff[0].URI.String())
+	})
+
+	t.Run("success - preferred not provided", func(t *testing.T) {
+		eligible := []*proof.Witness{
+			{
+				Type: proof.WitnessTypeBatch,
+				URI:  vocab.NewURLProperty(witnessURL),
+			},
+			{
+				Type: proof.WitnessTypeSystem,
+				URI:  vocab.NewURLProperty(otherWitnessURL),
+			},
+		}
+
+		diff := Difference(eligible, nil)
+		require.Equal(t, len(eligible), len(diff))
+		require.Equal(t, diff, eligible)
+	})
+
+	t.Run("success - no duplicates", func(t *testing.T) {
+		eligible := []*proof.Witness{
+			{
+				Type: proof.WitnessTypeBatch,
+				URI:  vocab.NewURLProperty(witnessURL),
+			},
+			{
+				Type: proof.WitnessTypeSystem,
+				URI:  vocab.NewURLProperty(witnessURL),
+			},
+		}
+
+		diff := Difference(eligible, nil)
+		require.Equal(t, 1, len(diff))
+		require.Equal(t, proof.WitnessTypeBatch, diff[0].Type)
+	})
+
+	t.Run("success - eligible not provided either", func(t *testing.T) {
+		diff := Difference(nil, nil)
+		require.Equal(t, 0, len(diff))
+	})
+}
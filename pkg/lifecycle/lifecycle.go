@@ -16,9 +16,20 @@ import (
 
 const loggerModule = "lifecycle"
 
-// ErrNotStarted indicates that an attempt was made to invoke a service that has not been started
-// or is still in the process of starting.
-var ErrNotStarted = orberrors.NewTransient(errors.New("service has not started"))
+var (
+	// ErrNeverStarted indicates that an attempt was made to invoke a service that has not been started
+	// or is still in the process of starting. This error is transient, i.e. the request may be retried.
+	ErrNeverStarted = orberrors.NewTransient(errors.New("service has not started"))
+
+	// ErrNotStarted is an alias of ErrNeverStarted which is retained for compatibility.
+	ErrNotStarted = ErrNeverStarted
+
+	// ErrStopping indicates that an attempt was made to invoke a service that is in the process of stopping.
+	ErrStopping = errors.New("service is stopping")
+
+	// ErrStopped indicates that an attempt was made to invoke a service that has been stopped.
+	ErrStopped = errors.New("service has been stopped")
+)
 
 // State is the state of the service.
 type State = uint32
@@ -32,6 +43,8 @@ const (
 	StateStarted State = 2
 	// StateStopped indicates that the service has been stopped.
 	StateStopped State = 3
+	// StateStopping indicates that the service is in the process of stopping.
+	StateStopping State = 4
 )
 
 type options struct {
@@ -101,7 +114,7 @@ func (h *Lifecycle) Start() {
 
 // Stop stops the service.
 func (h *Lifecycle) Stop() {
-	if !atomic.CompareAndSwapUint32(&h.state, StateStarted, StateStopped) {
+	if !atomic.CompareAndSwapUint32(&h.state, StateStarted, StateStopping) {
 		h.logger.Debug("Service already stopped")
 
 		return
@@ -112,9 +125,33 @@ func (h *Lifecycle) Stop() {
 	h.stop()
 
 	h.logger.Debug("... service stopped")
+
+	atomic.StoreUint32(&h.state, StateStopped)
 }
 
 // State returns the state of the service.
 func (h *Lifecycle) State() State {
 	return atomic.LoadUint32(&h.state)
 }
+
+// StateError returns nil if the service has been started, otherwise an error corresponding to the
+// current state is returned, i.e. ErrNeverStarted, ErrStopping or ErrStopped.
+func (h *Lifecycle) StateError() error {
+	switch h.State() {
+	case StateStarted:
+		return nil
+	case StateStopping:
+		return ErrStopping
+	case StateStopped:
+		return ErrStopped
+	default:
+		return ErrNeverStarted
+	}
+}
+
+// IsRetryable returns true if the given lifecycle error indicates that the request may succeed
+// if retried (i.e. the service has not yet started). False is returned if the service is stopping
+// or has been stopped.
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrNeverStarted)
+}
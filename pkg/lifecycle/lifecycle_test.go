@@ -7,9 +7,13 @@ SPDX-License-Identifier: Apache-2.0
 package lifecycle
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	orberrors "github.com/trustbloc/orb/pkg/errors"
 )
 
 func TestLifecycle(t *testing.T) {
@@ -41,3 +45,35 @@ func TestLifecycle(t *testing.T) {
 
 	require.NotPanics(t, lc.Stop)
 }
+
+func TestLifecycle_StateError(t *testing.T) {
+	var stateDuringStop error
+
+	var lc *Lifecycle
+
+	lc = New("service1",
+		WithStop(func() {
+			stateDuringStop = lc.StateError()
+		}),
+	)
+
+	err := lc.StateError()
+	require.True(t, errors.Is(err, ErrNeverStarted))
+	require.True(t, errors.Is(err, ErrNotStarted))
+	require.True(t, orberrors.IsTransient(err))
+	require.True(t, IsRetryable(fmt.Errorf("wrapped: %w", err)))
+
+	lc.Start()
+	require.NoError(t, lc.StateError())
+
+	lc.Stop()
+
+	require.True(t, errors.Is(stateDuringStop, ErrStopping))
+	require.False(t, orberrors.IsTransient(stateDuringStop))
+	require.False(t, IsRetryable(stateDuringStop))
+
+	err = lc.StateError()
+	require.True(t, errors.Is(err, ErrStopped))
+	require.False(t, orberrors.IsTransient(err))
+	require.False(t, IsRetryable(err))
+}
@@ -67,6 +67,11 @@ const (
 	docCreateUpdateTimeMetric = "create_update_seconds"
 	docResolveTimeMetric      = "resolve_seconds"
 
+	// Witness policy.
+	witnessPolicy                   = "witness_policy"
+	witnessPolicyEvaluateTimeMetric = "evaluate_seconds"
+	witnessPolicySelectTimeMetric   = "select_seconds"
+
 	// DB.
 	db                  = "db"
 	dbPutTimeMetric     = "put_seconds"
@@ -197,6 +202,9 @@ type Metrics struct {
 	docCreateUpdateTime prometheus.Histogram
 	docResolveTime      prometheus.Histogram
 
+	witnessPolicyEvaluateTime prometheus.Histogram
+	witnessPolicySelectTime   prometheus.Histogram
+
 	dbPutTimes     map[string]prometheus.Histogram
 	dbGetTimes     map[string]prometheus.Histogram
 	dbGetTagsTimes map[string]prometheus.Histogram
@@ -300,6 +308,8 @@ func newMetrics() *Metrics { //nolint:funlen,gocyclo,cyclop
 		casCacheHitCount:                             newCASCacheHitCount(),
 		docCreateUpdateTime:                          newDocCreateUpdateTime(),
 		docResolveTime:                               newDocResolveTime(),
+		witnessPolicyEvaluateTime:                    newWitnessPolicyEvaluateTime(),
+		witnessPolicySelectTime:                      newWitnessPolicySelectTime(),
 		apInboxHandlerTimes:                          newInboxHandlerTimes(activityTypes),
 		apOutboxActivityCounts:                       newOutboxActivityCounts(activityTypes),
 		dbPutTimes:                                   newDBPutTime(dbTypes),
@@ -365,6 +375,7 @@ func newMetrics() *Metrics { //nolint:funlen,gocyclo,cyclop
 		m.opqueueBatchSize, m.observerProcessAnchorTime, m.observerProcessDIDTime,
 		m.casWriteTime, m.casResolveTime, m.casCacheHitCount,
 		m.docCreateUpdateTime, m.docResolveTime,
+		m.witnessPolicyEvaluateTime, m.witnessPolicySelectTime,
 		m.vctWitnessAddProofVCTNilTimes, m.vctWitnessAddVCTimes, m.vctWitnessAddProofTimes,
 		m.vctWitnessAddWebFingerTimes, m.vctWitnessVerifyVCTimes, m.vctAddProofParseCredentialTimes,
 		m.vctAddProofSignTimes, m.signerSignTimes, m.signerGetKeyTimes, m.signerAddLinkedDataProofTimes,
@@ -655,6 +666,20 @@ func (m *Metrics) DocumentResolveTime(value time.Duration) {
 	logger.Debugf("DocumentResolve time: %s", value)
 }
 
+// WitnessPolicyEvaluateTime records the time it takes to evaluate the witness policy.
+func (m *Metrics) WitnessPolicyEvaluateTime(value time.Duration) {
+	m.witnessPolicyEvaluateTime.Observe(value.Seconds())
+
+	logger.Debugf("WitnessPolicyEvaluate time: %s", value)
+}
+
+// WitnessPolicySelectTime records the time it takes to select witnesses according to the witness policy.
+func (m *Metrics) WitnessPolicySelectTime(value time.Duration) {
+	m.witnessPolicySelectTime.Observe(value.Seconds())
+
+	logger.Debugf("WitnessPolicySelect time: %s", value)
+}
+
 // DBPutTime records the time it takes to store data in db.
 func (m *Metrics) DBPutTime(dbType string, value time.Duration) {
 	if c, ok := m.dbPutTimes[dbType]; ok {
@@ -1306,6 +1331,22 @@ func newDocResolveTime() prometheus.Histogram {
 	)
 }
 
+func newWitnessPolicyEvaluateTime() prometheus.Histogram {
+	return newHistogram(
+		witnessPolicy, witnessPolicyEvaluateTimeMetric,
+		"The time (in seconds) it takes to evaluate the witness policy.",
+		nil,
+	)
+}
+
+func newWitnessPolicySelectTime() prometheus.Histogram {
+	return newHistogram(
+		witnessPolicy, witnessPolicySelectTimeMetric,
+		"The time (in seconds) it takes to select witnesses according to the witness policy.",
+		nil,
+	)
+}
+
 func newDBPutTime(dbTypes []string) map[string]prometheus.Histogram {
 	counters := make(map[string]prometheus.Histogram)
 
@@ -50,6 +50,8 @@ func TestMetrics(t *testing.T) {
 		require.NotPanics(t, func() { m.CASReadTime("local", time.Second) })
 		require.NotPanics(t, func() { m.DocumentCreateUpdateTime(time.Second) })
 		require.NotPanics(t, func() { m.DocumentResolveTime(time.Second) })
+		require.NotPanics(t, func() { m.WitnessPolicyEvaluateTime(time.Second) })
+		require.NotPanics(t, func() { m.WitnessPolicySelectTime(time.Second) })
 		require.NotPanics(t, func() { m.OutboxIncrementActivityCount("Create") })
 		require.NotPanics(t, func() { m.DBPutTime("CouchDB", time.Second) })
 		require.NotPanics(t, func() { m.DBGetTime("CouchDB", time.Second) })
@@ -122,6 +122,14 @@ func (m *MetricsProvider) DocumentCreateUpdateTime(value time.Duration) {
 func (m *MetricsProvider) DocumentResolveTime(value time.Duration) {
 }
 
+// WitnessPolicyEvaluateTime records the time it takes to evaluate the witness policy.
+func (m *MetricsProvider) WitnessPolicyEvaluateTime(value time.Duration) {
+}
+
+// WitnessPolicySelectTime records the time it takes to select witnesses according to the witness policy.
+func (m *MetricsProvider) WitnessPolicySelectTime(value time.Duration) {
+}
+
 // WebDocumentResolveTime records the time it takes the REST handler to resolve a web document.
 func (m *MetricsProvider) WebDocumentResolveTime(value time.Duration) {
 }
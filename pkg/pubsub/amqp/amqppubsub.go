@@ -10,6 +10,7 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"net/url"
 	"strconv"
 	"strings"
 	"sync"
@@ -18,6 +19,7 @@ import (
 
 	"github.com/ThreeDotsLabs/watermill-amqp/v2/pkg/amqp"
 	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/bluele/gcache"
 	"github.com/cenkalti/backoff"
 	ramqp "github.com/rabbitmq/amqp091-go"
 
@@ -37,12 +39,19 @@ const (
 	defaultMaxConnectInterval                = 5 * time.Second
 	defaultMaxConnectElapsedTime             = 3 * time.Minute
 	defaultMaxConnectionSubscriptions        = 1000
+	defaultChannelLimitTimeout               = 30 * time.Second
 	defaultWaitQueuePublisherChannelPoolSize = 5
 
 	defaultMaxRedeliveryAttempts     = 10
 	defaultRedeliveryMultiplier      = 1.5
 	defaultRedeliveryInitialInterval = 2 * time.Second
 	defaultMaxRedeliveryInterval     = 30 * time.Second
+	defaultRedeliveryPoolSize        = 1
+
+	defaultIdempotencyCacheSize = 10000
+
+	// defaultLocale is the locale used by the AMQP client library when dialing with its default configuration.
+	defaultLocale = "en_US"
 
 	exchange           = "orb"
 	redeliveryQueue    = "orb.redelivery"
@@ -53,6 +62,10 @@ const (
 
 	expiredReason = "expired"
 
+	errorCodeBrokerUnreachable = "broker-unreachable"
+
+	redactedPassword = "*****"
+
 	metadataDeadLetterExchange   = "x-dead-letter-exchange"
 	metadataDeadLetterRoutingKey = "x-dead-letter-routing-key"
 	metadataDeath                = "x-death"
@@ -61,21 +74,152 @@ const (
 	metadataRedeliveryCount      = "orb-redelivery-count"
 	metadataQueue                = "orb-queue"
 	metadataExpiration           = "expiration"
+
+	// MetadataIdempotencyKey is the message metadata key that holds an optional idempotency key.
+	// If Config.IdempotencyWindow is set then a message whose idempotency key was already
+	// published within the window is not published again.
+	MetadataIdempotencyKey = "orb-idempotency-key"
 )
 
 // Config holds the configuration for the publisher/subscriber.
 type Config struct {
-	URI                       string
-	MaxConnectRetries         int
-	MaxConnectionChannels     int
-	MaxRedeliveryAttempts     int
-	RedeliveryMultiplier      float64
+	// URI is the URI of the AMQP server.
+	URI string
+
+	// MaxConnectRetries is the maximum number of times to retry connecting to the AMQP server on startup. It's
+	// also the maximum number of times to retry re-establishing a subscription that was closed by the broker.
+	// Default is 25.
+	MaxConnectRetries int
+
+	// MaxConnectionChannels is the maximum number of channels (subscriptions) per connection (see ChannelLimitAction).
+	// Default is 1000.
+	MaxConnectionChannels int
+
+	// MaxRedeliveryAttempts is the maximum number of times that a nacked message is redelivered before it's
+	// dead-lettered (see DeadLetterTopic). Default is 10.
+	MaxRedeliveryAttempts int
+
+	// RedeliveryMultiplier is the factor by which the redelivery interval is increased for each redelivery attempt.
+	// The multiplier must be greater than 1. Default is 1.5.
+	RedeliveryMultiplier float64
+
+	// RedeliveryInitialInterval is the interval before the first redelivery attempt. Default is 2s.
 	RedeliveryInitialInterval time.Duration
-	MaxRedeliveryInterval     time.Duration
-	PublisherChannelPoolSize  int
-	PublisherConfirmDelivery  bool
+
+	// MaxRedeliveryInterval is the maximum interval between redelivery attempts. The maximum interval must not be
+	// less than RedeliveryInitialInterval. Default is 30s.
+	MaxRedeliveryInterval time.Duration
+
+	// RedeliveryPoolSize is the number of subscribers that concurrently consume messages from the redelivery
+	// queue. A larger pool may be required under high redelivery load since each subscriber processes one
+	// message at a time. Default is 1.
+	RedeliveryPoolSize int
+
+	// PublisherChannelPoolSize is the number of channels used by the publisher. If zero then the AMQP
+	// library's default is used.
+	PublisherChannelPoolSize int
+
+	// PublisherConfirmDelivery indicates whether the publisher waits for the AMQP server to confirm delivery.
+	PublisherConfirmDelivery bool
+
+	// IdempotencyWindow is the period of time in which published idempotency keys (see MetadataIdempotencyKey)
+	// are remembered. A message with an idempotency key that was already successfully published (or is currently
+	// being published) within this window is skipped and treated as a success. This also applies to messages that
+	// are published with a delivery delay. If zero then deduplication is disabled.
+	IdempotencyWindow time.Duration
+
+	// UsernameFile and PasswordFile are optional paths to files containing the username and password used
+	// to connect to the AMQP server. If not specified then the credentials are read from the environment
+	// variables specified by UsernameEnvKey and PasswordEnvKey (if any). Credentials loaded from a file or
+	// environment variable override any credentials in the URI.
+	UsernameFile   string
+	PasswordFile   string
+	UsernameEnvKey string
+	PasswordEnvKey string
+
+	// Marshaler is an optional marshaler that converts messages to/from AMQP messages, for example to publish
+	// messages using a specific envelope. The marshaler must preserve message metadata (including the headers
+	// that the AMQP server adds to dead-lettered messages) since metadata is used for redelivery.
+	// If not set then DefaultMarshaler is used.
+	Marshaler Marshaler
+
+	// DeadLetterTopic is the optional topic to which messages are posted after the maximum number of redelivery
+	// attempts has been reached. Dead-lettered messages may be consumed using SubscribeDeadLetters and republished
+	// to their original topic using Requeue. If not set then such messages are discarded.
+	DeadLetterTopic string
+
+	// HandlerPanicAction specifies what to do with a message whose handler (see SubscribeWithHandler) panicked.
+	// If not set then PanicActionNack is used.
+	HandlerPanicAction PanicAction
+
+	// MaxMessageSize is the maximum size (in bytes) of a message's payload plus metadata. A message that exceeds
+	// this size is rejected by Publish with an error that wraps ErrMessageTooLarge rather than being sent to the
+	// AMQP server (which would reject it). If zero then the message size isn't checked.
+	MaxMessageSize int
+
+	// ChannelLimitAction specifies what to do when a new subscription requires a channel and the shared
+	// connection already has MaxConnectionChannels channels, i.e. either open a new connection (ChannelLimitActionGrow)
+	// or wait for a channel to be released (ChannelLimitActionBlock). If not set then ChannelLimitActionGrow is used.
+	ChannelLimitAction ChannelLimitAction
+
+	// ChannelLimitTimeout is the maximum time to wait for a channel to be released when ChannelLimitAction is
+	// ChannelLimitActionBlock. Default is 30s.
+	ChannelLimitTimeout time.Duration
+
+	// Heartbeat is the interval at which heartbeats are exchanged with the AMQP server in order to keep the
+	// connection alive (network middleboxes may drop idle connections) and to detect a dead connection.
+	// A low value detects a dead connection sooner but wastes bandwidth on idle connections, whereas a high
+	// value risks late detection (or the connection being dropped by a middlebox during quiet periods).
+	// If zero then the AMQP client library's default (10s) is used.
+	Heartbeat time.Duration
+
+	// SharedTopicSubscriptions indicates that repeated calls to Subscribe (or SubscribeWithOpts) for the same topic
+	// return the Go channel of the existing subscription rather than creating a new subscription (which would
+	// consume messages independently). The subscription is removed from the registry when the context of the
+	// subscribe call that created it is done. Options passed to subsequent calls are ignored. A new subscription
+	// may be forced with the spi.WithNewSubscription option. By default, each call creates a new subscription.
+	SharedTopicSubscriptions bool
+
+	// NameStrategy maps a logical topic name (and the names of the internal exchanges and queues) to the name of
+	// the exchange, queue and routing key on the AMQP server. For example, a strategy that adds a tenant or
+	// environment prefix (see PrefixNameStrategy) allows multiple deployments to share a broker without
+	// consuming each other's messages. The same strategy is used for publishing and subscribing. By default,
+	// names are used as is.
+	NameStrategy NameStrategy
+
+	// MaxInFlightPublishes is the maximum number of publishes (Publish and PublishWithOpts) that may be in
+	// flight concurrently, which provides client-side flow control during bursts of publishes. If the limit is
+	// reached then the publish either waits or fails (see PublishLimitAction). Messages that are republished
+	// internally (e.g. for redelivery) are not limited. If zero then the number of publishes is unlimited.
+	MaxInFlightPublishes int
+
+	// PublishLimitAction specifies what to do when a message is published and MaxInFlightPublishes publishes are
+	// already in flight. If not set then PublishLimitActionBlock is used.
+	PublishLimitAction PublishLimitAction
+}
+
+// NameStrategy maps a logical name to the name that's used on the AMQP server.
+type NameStrategy func(name string) string
+
+// PrefixNameStrategy returns a NameStrategy that adds the given prefix to each name.
+func PrefixNameStrategy(prefix string) NameStrategy {
+	return func(name string) string {
+		return prefix + name
+	}
+}
+
+// brokerName returns the name on the AMQP server for the given logical name.
+func (cfg *Config) brokerName(name string) string {
+	if cfg.NameStrategy == nil {
+		return name
+	}
+
+	return cfg.NameStrategy(name)
 }
 
+// Marshaler marshals messages to AMQP messages and unmarshals AMQP messages to messages.
+type Marshaler = amqp.Marshaler
+
 type closeable interface {
 	Close() error
 }
@@ -98,6 +242,7 @@ type publisher interface {
 type connMgr interface {
 	close() error
 	getConnection(shared bool) (connection, error)
+	releaseChannel(conn connection)
 	isConnected() bool
 }
 
@@ -125,21 +270,46 @@ type PubSub struct {
 	redeliverySubscriberFactory subscriberFactory
 	waitSubscriberFactory       subscriberFactory
 	createWaitPublisher         publisherFactory
-	redeliveryChan              <-chan *message.Message
+	redeliveryChans             []<-chan *message.Message
 	connMgr                     connMgr
+	idempotencyCache            gcache.Cache
+	idempotencyMutex            sync.Mutex
+	subscriptions               map[string]<-chan *message.Message
+	subscriptionsMutex          sync.Mutex
+	subscriptionErrors          *subscriptionErrorNotifier
+	publishLimiter              *publishLimiter
+	stopped                     chan struct{}
 }
 
 // New returns a new AMQP publisher/subscriber.
 func New(cfg Config) *PubSub {
-	cfg = initConfig(cfg)
+	if err := cfg.validateAndSetDefaults(); err != nil {
+		panic(fmt.Sprintf("Invalid AMQP configuration: %s", err))
+	}
+
+	uri, err := resolveURI(cfg)
+	if err != nil {
+		panic(fmt.Sprintf("Unable to resolve AMQP credentials: %s", err))
+	}
+
+	cfg.URI = uri
+
+	connMgr := newConnectionMgr(newConnectionConfig(cfg), cfg.MaxConnectionChannels,
+		cfg.ChannelLimitAction, cfg.ChannelLimitTimeout)
 
 	p := &PubSub{
 		Config:               cfg,
-		connMgr:              newConnectionMgr(amqp.ConnectionConfig{AmqpURI: cfg.URI}, cfg.MaxConnectionChannels),
+		connMgr:              connMgr,
 		amqpConfig:           newQueueConfig(cfg),
 		amqpRedeliveryConfig: newRedeliveryQueueConfig(cfg),
 		amqpWaitConfig:       newWaitQueueConfig(cfg),
 		createPublisher:      createPublisher,
+		subscriptionErrors:   newSubscriptionErrorNotifier(),
+		publishLimiter:       newPublishLimiter(cfg.MaxInFlightPublishes, cfg.PublishLimitAction),
+	}
+
+	if cfg.IdempotencyWindow > 0 {
+		p.idempotencyCache = newIdempotencyCache(cfg.IdempotencyWindow)
 	}
 
 	p.Lifecycle = lifecycle.New("amqp",
@@ -147,7 +317,8 @@ func New(cfg Config) *PubSub {
 		lifecycle.WithStop(p.stop))
 
 	p.subscriberFactory = func(conn connection) (initializingSubscriber, error) {
-		return amqp.NewSubscriberWithConnection(p.amqpConfig, wmlogger.New(), conn.amqpConnection())
+		return amqp.NewSubscriberWithConnection(p.amqpConfig,
+			newErrorNotifyingLogger(wmlogger.New(), p.subscriptionErrors), conn.amqpConnection())
 	}
 
 	p.redeliverySubscriberFactory = func(conn connection) (initializingSubscriber, error) {
@@ -180,15 +351,41 @@ func (p *PubSub) IsConnected() bool {
 }
 
 // SubscribeWithOpts subscribes to a topic using the given options, and returns the Go channel over which messages
-// are sent. The returned channel will be closed when Close() is called on this struct.
+// are sent. The returned channel will be closed when Close() is called on this struct. If SharedTopicSubscriptions
+// is set then the channel of an existing subscription to the topic may be returned (see Config). If an error
+// handler is provided (see spi.WithErrorHandler) then it's notified, on a best-effort basis, of errors that occur
+// on any subscription to the topic until the given context is done.
 func (p *PubSub) SubscribeWithOpts(ctx context.Context, topic string,
 	opts ...spi.Option) (<-chan *message.Message, error) {
-	if p.State() != lifecycle.StateStarted {
-		return nil, lifecycle.ErrNotStarted
+	if err := p.StateError(); err != nil {
+		return nil, err
 	}
 
 	options := getOptions(opts)
 
+	var msgChan <-chan *message.Message
+
+	var err error
+
+	if p.SharedTopicSubscriptions && !options.NewSubscription {
+		msgChan, err = p.subscribeShared(ctx, topic, options)
+	} else {
+		msgChan, err = p.subscribe(ctx, topic, options)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if options.ErrorHandler != nil {
+		p.subscriptionErrors.register(ctx, topic, options.ErrorHandler)
+	}
+
+	return msgChan, nil
+}
+
+func (p *PubSub) subscribe(ctx context.Context, topic string,
+	options *spi.Options) (<-chan *message.Message, error) {
 	if options.PoolSize <= 1 {
 		logger.Debug("Subscribing to topic", log.WithTopic(topic))
 
@@ -197,7 +394,8 @@ func (p *PubSub) SubscribeWithOpts(ctx context.Context, topic string,
 
 	logger.Debug("Creating subscriber pool", log.WithTopic(topic), log.WithSubscriberPoolSize(options.PoolSize))
 
-	pool, err := newPooledSubscriber(ctx, options.PoolSize, p.subscriber, topic)
+	pool, err := newPooledSubscriber(ctx, options.PoolSize, options.MaxConcurrency, options.ProcessingTimeout,
+		p.subscriber, topic)
 	if err != nil {
 		return nil, fmt.Errorf("subscriber pool: %w", err)
 	}
@@ -213,37 +411,145 @@ func (p *PubSub) SubscribeWithOpts(ctx context.Context, topic string,
 
 // Publish publishes the given messages to the given topic.
 func (p *PubSub) Publish(topic string, messages ...*message.Message) error {
-	if p.State() != lifecycle.StateStarted {
-		return lifecycle.ErrNotStarted
+	if err := p.StateError(); err != nil {
+		return err
+	}
+
+	if err := p.checkMessageSize(topic, messages...); err != nil {
+		return err
+	}
+
+	var reservedKeys []string
+
+	if len(messages) > 0 {
+		messages, reservedKeys = p.reserveIdempotencyKeys(topic, messages)
+		if len(messages) == 0 {
+			return nil
+		}
 	}
 
+	if err := p.publishLimiter.acquire(); err != nil {
+		p.releaseIdempotencyKeys(reservedKeys)
+
+		return errors.NewTransientf("publish messages to topic [%s]: %w", topic, err)
+	}
+
+	defer p.publishLimiter.release()
+
 	logger.Debug("Publishing messages", log.WithTopic(topic))
 
+	messages = withQueueMetadata(topic, messages)
+
 	if err := p.publisher.Publish(topic, messages...); err != nil {
 		for _, msg := range messages {
 			logger.Error("Error publishing message", log.WithMessageID(msg.UUID), log.WithTopic(topic))
 		}
 
+		p.releaseIdempotencyKeys(reservedKeys)
+
 		return errors.NewTransientf("publish messages to topic [%s]: %w", topic, err)
 	}
 
 	return nil
 }
 
+// reserveIdempotencyKeys returns the given messages minus the messages whose idempotency key has already been
+// published (or is being published) to the given topic. The idempotency keys of the returned messages are reserved
+// (the check and the reservation are atomic) so that a concurrent publish of a message with the same key is skipped.
+// The returned cache keys must be released (see releaseIdempotencyKeys) if the messages can't be published.
+func (p *PubSub) reserveIdempotencyKeys(topic string, messages []*message.Message) ([]*message.Message, []string) {
+	if p.idempotencyCache == nil {
+		return messages, nil
+	}
+
+	p.idempotencyMutex.Lock()
+	defer p.idempotencyMutex.Unlock()
+
+	var (
+		result   []*message.Message
+		reserved []string
+	)
+
+	for _, msg := range messages {
+		key := msg.Metadata.Get(MetadataIdempotencyKey)
+		if key == "" {
+			result = append(result, msg)
+
+			continue
+		}
+
+		cacheKey := idempotencyCacheKey(topic, key)
+
+		if p.idempotencyCache.Has(cacheKey) {
+			logger.Info("Message with the same idempotency key was already published. Skipping message.",
+				log.WithMessageID(msg.UUID), log.WithTopic(topic), log.WithKey(key))
+
+			continue
+		}
+
+		if err := p.idempotencyCache.Set(cacheKey, struct{}{}); err != nil {
+			logger.Warn("Error recording idempotency key", log.WithMessageID(msg.UUID),
+				log.WithKey(key), log.WithError(err))
+		} else {
+			reserved = append(reserved, cacheKey)
+		}
+
+		result = append(result, msg)
+	}
+
+	return result, reserved
+}
+
+// releaseIdempotencyKeys releases the given reserved idempotency cache keys so that the corresponding messages
+// may be published again.
+func (p *PubSub) releaseIdempotencyKeys(cacheKeys []string) {
+	if p.idempotencyCache == nil {
+		return
+	}
+
+	p.idempotencyMutex.Lock()
+	defer p.idempotencyMutex.Unlock()
+
+	for _, cacheKey := range cacheKeys {
+		p.idempotencyCache.Remove(cacheKey)
+	}
+}
+
 // PublishWithOpts publishes a message to a topic using the supplied options.
 func (p *PubSub) PublishWithOpts(topic string, msg *message.Message, opts ...spi.Option) error {
-	if p.State() != lifecycle.StateStarted {
-		return lifecycle.ErrNotStarted
+	if err := p.StateError(); err != nil {
+		return err
 	}
 
 	if options := getOptions(opts); options.DeliveryDelay > 0 {
-		return p.publishWithDelay(topic, msg, options.DeliveryDelay)
+		if err := p.checkMessageSize(topic, msg); err != nil {
+			return err
+		}
+
+		messages, reservedKeys := p.reserveIdempotencyKeys(topic, []*message.Message{msg})
+		if len(messages) == 0 {
+			return nil
+		}
+
+		if err := p.publishWithDelay(topic, msg, options.DeliveryDelay); err != nil {
+			p.releaseIdempotencyKeys(reservedKeys)
+
+			return err
+		}
+
+		return nil
 	}
 
 	return p.Publish(topic, msg)
 }
 
 func (p *PubSub) publishWithDelay(topic string, msg *message.Message, delay time.Duration) error {
+	if err := p.publishLimiter.acquire(); err != nil {
+		return errors.NewTransientf("publish message to wait queue: %w", err)
+	}
+
+	defer p.publishLimiter.release()
+
 	logger.Debug("Publishing message", log.WithMessageID(msg.UUID),
 		log.WithTopic(topic), log.WithDeliveryDelay(delay))
 
@@ -274,6 +580,11 @@ func (p *PubSub) Close() error {
 }
 
 func (p *PubSub) stop() {
+	// Signal the subscriptions that the subscriber is closing so that they aren't re-established.
+	if p.stopped != nil {
+		close(p.stopped)
+	}
+
 	logger.Debug("Closing publisher...")
 
 	if err := p.publisher.Close(); err != nil {
@@ -313,34 +624,47 @@ func (p *PubSub) stop() {
 }
 
 func (p *PubSub) start() {
-	logger.Info("Connecting to message queue", log.WithAddress(extractEndpoint(p.amqpConfig.Connection.AmqpURI)))
+	logger.Info("Connecting to message queue", log.WithAddress(extractEndpoint(p.amqpConfig.Connection.AmqpURI)),
+		log.WithHost(parseURL(p.amqpConfig.Connection.AmqpURI)))
 
-	maxRetries := p.MaxConnectRetries
-	if maxRetries == 0 {
-		maxRetries = defaultMaxConnectRetries
-	}
+	p.stopped = make(chan struct{})
 
 	err := backoff.RetryNotify(
 		func() error {
 			return p.connect()
 		},
-		backoff.WithMaxRetries(newConnectBackOff(), uint64(maxRetries)),
+		backoff.WithMaxRetries(newConnectBackOff(), uint64(p.MaxConnectRetries)),
 		func(err error, duration time.Duration) {
 			logger.Debug("Error connecting to AMQP service. Will retry with backoff...",
 				log.WithAddress(extractEndpoint(p.amqpConfig.Connection.AmqpURI)),
-				log.WithBackoff(duration), log.WithError(err))
+				log.WithBackoff(duration), log.WithError(redactCredentials(err, p.amqpConfig.Connection.AmqpURI)),
+				log.WithErrorCode(errorCodeBrokerUnreachable))
 		},
 	)
 	if err != nil {
-		panic(fmt.Sprintf("Unable to connect to message queue after %d attempts: %s", maxRetries, err))
+		err = redactCredentials(err, p.amqpConfig.Connection.AmqpURI)
+
+		logger.Error("Unable to connect to AMQP service",
+			log.WithAddress(extractEndpoint(p.amqpConfig.Connection.AmqpURI)),
+			log.WithMaxRetries(p.MaxConnectRetries), log.WithError(err), log.WithErrorCode(errorCodeBrokerUnreachable))
+
+		panic(fmt.Sprintf("Unable to connect to message queue after %d attempts: %s", p.MaxConnectRetries, err))
 	}
 
-	retryChan, err := p.redeliverySubscriber.Subscribe(context.Background(), redeliveryQueue)
-	if err != nil {
-		panic(fmt.Sprintf("Unable to subscribe to queue [%s]: %s", redeliveryQueue, err))
+	poolSize := p.RedeliveryPoolSize
+	if poolSize < 1 {
+		poolSize = 1
 	}
 
-	p.redeliveryChan = retryChan
+	for i := 0; i < poolSize; i++ {
+		retryChan, err := p.redeliverySubscriber.Subscribe(context.Background(), redeliveryQueue)
+		if err != nil {
+			panic(fmt.Sprintf("Unable to subscribe to queue [%s]: %s", redeliveryQueue,
+				redactCredentials(err, p.amqpConfig.Connection.AmqpURI)))
+		}
+
+		p.redeliveryChans = append(p.redeliveryChans, retryChan)
+	}
 
 	// Initialize the wait queue so that it is created. This queue contains all messages that
 	// need to wait for redelivery. There are actually no subscribers to this queue. Messages in
@@ -348,13 +672,17 @@ func (p *PubSub) start() {
 	// back on the redelivery queue.
 	err = p.waitSubscriber.SubscribeInitialize(waitQueue)
 	if err != nil {
-		panic(fmt.Sprintf("Unable to initialize queue [%s]: %s", waitQueue, err))
+		panic(fmt.Sprintf("Unable to initialize queue [%s]: %s", waitQueue,
+			redactCredentials(err, p.amqpConfig.Connection.AmqpURI)))
 	}
 
-	go p.processRedeliveryQueue()
+	for i, retryChan := range p.redeliveryChans {
+		go p.processRedeliveryQueue(i, retryChan)
+	}
 
 	logger.Info("Successfully connected to AMQP service",
-		log.WithAddress(extractEndpoint(p.amqpConfig.Connection.AmqpURI)))
+		log.WithAddress(extractEndpoint(p.amqpConfig.Connection.AmqpURI)),
+		log.WithHost(parseURL(p.amqpConfig.Connection.AmqpURI)))
 }
 
 func (p *PubSub) connect() error {
@@ -365,9 +693,12 @@ func (p *PubSub) connect() error {
 
 	p.publisher = pubPool
 
-	p.subscriber = newSubscriberMgr(p.connMgr, p.subscriberFactory)
+	// Subscriptions that are closed by the broker (e.g. during a rolling restart) are re-established.
+	p.subscriber = newResubscribingSubscriber(newSubscriberMgr(p.connMgr, p.subscriberFactory),
+		p.MaxConnectRetries, p.stopped, p.subscriptionErrors.notify)
 
-	p.redeliverySubscriber = newSubscriberMgr(p.connMgr, p.redeliverySubscriberFactory)
+	p.redeliverySubscriber = newResubscribingSubscriber(newSubscriberMgr(p.connMgr, p.redeliverySubscriberFactory),
+		p.MaxConnectRetries, p.stopped, nil)
 
 	conn, err := p.connMgr.getConnection(true)
 	if err != nil {
@@ -397,14 +728,14 @@ If the message metadata, 'reason', is set to "expired" then it is posted to the
 (if reason is "rejected") it is posted back to the 'wait' queue with a bigger expiration. This process repeats until the
 maximum number of redelivery attempts has been reached, at which point redelivery for the message is aborted.
 */
-func (p *PubSub) processRedeliveryQueue() {
-	logger.Info("Starting message redelivery listener")
+func (p *PubSub) processRedeliveryQueue(index int, retryChan <-chan *message.Message) {
+	logger.Info("Starting message redelivery listener", log.WithIndex(index))
 
-	for msg := range p.redeliveryChan {
+	for msg := range retryChan {
 		p.handleRedelivery(msg)
 	}
 
-	logger.Info("Message redelivery listener stopped")
+	logger.Info("Message redelivery listener stopped", log.WithIndex(index))
 }
 
 func (p *PubSub) handleRedelivery(msg *message.Message) {
@@ -437,6 +768,18 @@ func (p *PubSub) handleRedelivery(msg *message.Message) {
 	} else {
 		logger.Error("Message will not be redelivered since the maximum delivery attempts has been reached",
 			log.WithMessageID(msg.UUID), log.WithTopic(queue), log.WithDeliveryAttempts(redeliveryAttempts+1))
+
+		if p.DeadLetterTopic != "" {
+			err = p.deadLetter(msg, queue)
+			if err != nil {
+				logger.Error("Error posting message to dead-letter topic. The message will be nacked and retried.",
+					log.WithMessageID(msg.UUID), log.WithError(err))
+
+				msg.Nack()
+
+				return
+			}
+		}
 	}
 
 	msg.Ack()
@@ -501,6 +844,14 @@ func (p *PubSub) getRedeliveryInterval(attempts int) time.Duration {
 	return interval
 }
 
+func newIdempotencyCache(window time.Duration) gcache.Cache {
+	return gcache.New(defaultIdempotencyCacheSize).LRU().Expiration(window).Build()
+}
+
+func idempotencyCacheKey(topic, key string) string {
+	return topic + ":" + key
+}
+
 func createPublisher(cfg *amqp.Config, conn connection) (publisher, error) {
 	pub, err := amqp.NewPublisherWithConnection(*cfg, wmlogger.New(), conn.amqpConnection())
 	if err != nil {
@@ -528,32 +879,58 @@ func newConnectBackOff() backoff.BackOff {
 type connection interface {
 	amqpConnection() *amqp.ConnectionWrapper
 	incrementChannelCount() uint32
+	decrementChannelCount() uint32
 	numChannels() uint32
 }
 
+type connectionFactory = func(cfg amqp.ConnectionConfig) (*amqp.ConnectionWrapper, error)
+
 type connectionMgr struct {
-	channelLimit uint32
-	current      *connectionWrapper
-	connections  []*connectionWrapper
-	mutex        sync.RWMutex
-	config       amqp.ConnectionConfig
+	channelLimit     uint32
+	limitAction      ChannelLimitAction
+	limitTimeout     time.Duration
+	current          *connectionWrapper
+	connections      []*connectionWrapper
+	mutex            sync.RWMutex
+	config           amqp.ConnectionConfig
+	released         chan struct{}
+	createConnection connectionFactory
+	isHealthy        func(c *connectionWrapper) bool
 }
 
-func newConnectionMgr(cfg amqp.ConnectionConfig, limit int) *connectionMgr {
+func newConnectionMgr(cfg amqp.ConnectionConfig, limit int, action ChannelLimitAction,
+	timeout time.Duration) *connectionMgr {
+	if action == "" {
+		action = ChannelLimitActionGrow
+	}
+
+	if timeout == 0 {
+		timeout = defaultChannelLimitTimeout
+	}
+
 	return &connectionMgr{
 		config:       cfg,
 		channelLimit: uint32(limit),
+		limitAction:  action,
+		limitTimeout: timeout,
+		released:     make(chan struct{}),
+		createConnection: func(cfg amqp.ConnectionConfig) (*amqp.ConnectionWrapper, error) {
+			return amqp.NewConnection(cfg, wmlogger.New())
+		},
+		isHealthy: func(c *connectionWrapper) bool {
+			return c.amqpConnection().IsConnected()
+		},
 	}
 }
 
 func (m *connectionMgr) getConnection(shared bool) (connection, error) {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
-
 	if !shared {
-		conn, err := amqp.NewConnection(m.config, wmlogger.New())
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+
+		conn, err := m.createConnection(m.config)
 		if err != nil {
-			return nil, fmt.Errorf("create connection: %w", err)
+			return nil, fmt.Errorf("create connection: %w", redactCredentials(err, m.config.AmqpURI))
 		}
 
 		c := &connectionWrapper{conn: conn}
@@ -565,25 +942,142 @@ func (m *connectionMgr) getConnection(shared bool) (connection, error) {
 		return c, nil
 	}
 
-	if m.current == nil || m.current.numChannels() >= m.channelLimit {
-		conn, err := amqp.NewConnection(m.config, wmlogger.New())
+	return m.getSharedConnection()
+}
+
+// getSharedConnection returns the current shared connection. If the channel limit of the current connection has
+// been reached then, depending on the channel limit action, either a new connection is opened or the caller
+// waits for a channel on the current connection to be released.
+func (m *connectionMgr) getSharedConnection() (connection, error) {
+	var timeout <-chan time.Time
+
+	for {
+		m.mutex.Lock()
+
+		if m.current != nil && !m.hasSharedCapacity() && m.limitAction == ChannelLimitActionBlock {
+			released := m.released
+
+			m.mutex.Unlock()
+
+			if timeout == nil {
+				logger.Debug("Channel limit of the shared connection was reached. Waiting for a channel to be released.",
+					log.WithMaxSize(int(m.channelLimit)), log.WithTimeout(m.limitTimeout))
+
+				timer := time.NewTimer(m.limitTimeout)
+				defer timer.Stop()
+
+				timeout = timer.C
+			}
+
+			select {
+			case <-released:
+				continue
+			case <-timeout:
+				return nil, fmt.Errorf("%w: timed out after %s waiting for one of the %d channels to be released",
+					ErrChannelLimitReached, m.limitTimeout, m.channelLimit)
+			}
+		}
+
+		conn, err := m.currentConnection()
+
+		m.mutex.Unlock()
+
+		return conn, err
+	}
+}
+
+// currentConnection returns a shared connection for a new channel. Channels are distributed across the shared
+// connections using weighted round-robin, where the weight of a connection is its number of free channels, so
+// that less loaded connections are chosen more often. Healthy (connected) connections are preferred. A new
+// connection is opened if there is no shared connection or if the channel limit of every shared connection has
+// been reached. The caller must hold the lock.
+func (m *connectionMgr) currentConnection() (connection, error) {
+	conn := m.nextSharedConnection(true)
+	if conn == nil {
+		conn = m.nextSharedConnection(false)
+	}
+
+	if conn == nil {
+		c, err := m.createConnection(m.config)
 		if err != nil {
-			return nil, fmt.Errorf("create connection: %w", err)
+			return nil, fmt.Errorf("create connection: %w", redactCredentials(err, m.config.AmqpURI))
 		}
 
-		newCurrent := &connectionWrapper{conn: conn}
+		conn = &connectionWrapper{conn: c, shared: true}
 
-		m.connections = append(m.connections, newCurrent)
-		m.current = newCurrent
+		m.connections = append(m.connections, conn)
 
 		logger.Info("Created new shared connection.", log.WithTotal(len(m.connections)))
 	}
 
-	numChannels := m.current.incrementChannelCount()
+	// The current connection is the most recently selected shared connection.
+	if m.current != conn {
+		m.current = conn
+	}
+
+	numChannels := conn.incrementChannelCount()
+
+	logger.Debug("Incremented channel count for shared connection.", log.WithTotal(int(numChannels)))
+
+	return conn, nil
+}
+
+// nextSharedConnection selects the next shared connection with free channels using smooth weighted round-robin,
+// where the weight of each connection is its number of free channels. If healthyOnly is true then connections
+// that aren't connected are skipped. Nil is returned if no connection is eligible. The caller must hold the lock.
+func (m *connectionMgr) nextSharedConnection(healthyOnly bool) *connectionWrapper {
+	var selected *connectionWrapper
+
+	totalWeight := 0
 
-	logger.Debug("Incremented channel count for current connection.", log.WithTotal(int(numChannels)))
+	for _, c := range m.connections {
+		if !c.shared || (healthyOnly && !m.isHealthy(c)) {
+			continue
+		}
 
-	return m.current, nil
+		weight := int(m.channelLimit) - int(c.numChannels())
+		if weight <= 0 {
+			continue
+		}
+
+		c.currentWeight += weight
+		totalWeight += weight
+
+		if selected == nil || c.currentWeight > selected.currentWeight {
+			selected = c
+		}
+	}
+
+	if selected != nil {
+		selected.currentWeight -= totalWeight
+	}
+
+	return selected
+}
+
+// hasSharedCapacity returns true if at least one shared connection has a free channel. The caller must hold the lock.
+func (m *connectionMgr) hasSharedCapacity() bool {
+	for _, c := range m.connections {
+		if c.shared && c.numChannels() < m.channelLimit {
+			return true
+		}
+	}
+
+	return false
+}
+
+// releaseChannel decrements the channel count of the given connection and wakes up any callers that are
+// waiting for a channel to be released.
+func (m *connectionMgr) releaseChannel(conn connection) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	numChannels := conn.decrementChannelCount()
+
+	logger.Debug("Decremented channel count for connection.", log.WithTotal(int(numChannels)))
+
+	close(m.released)
+	m.released = make(chan struct{})
 }
 
 func (m *connectionMgr) isConnected() bool {
@@ -611,6 +1105,11 @@ func (m *connectionMgr) close() error {
 type connectionWrapper struct {
 	conn     *amqp.ConnectionWrapper
 	channels uint32
+	shared   bool
+
+	// currentWeight is used by the connection manager for weighted round-robin selection and is guarded
+	// by the connection manager's lock.
+	currentWeight int
 }
 
 func (c *connectionWrapper) amqpConnection() *amqp.ConnectionWrapper {
@@ -621,27 +1120,28 @@ func (c *connectionWrapper) incrementChannelCount() uint32 {
 	return atomic.AddUint32(&c.channels, 1)
 }
 
-func (c *connectionWrapper) numChannels() uint32 {
-	return atomic.LoadUint32(&c.channels)
+func (c *connectionWrapper) decrementChannelCount() uint32 {
+	return atomic.AddUint32(&c.channels, ^uint32(0))
 }
 
-type subscriberInfo struct {
-	conn       connection
-	subscriber initializingSubscriber
+func (c *connectionWrapper) numChannels() uint32 {
+	return atomic.LoadUint32(&c.channels)
 }
 
 type subscriberMgr struct {
 	connMgr          connMgr
 	createSubscriber subscriberFactory
 	mutex            sync.RWMutex
-	current          *subscriberInfo
-	subscribers      []*subscriberInfo
+	// subscribers holds one subscriber per connection. Since channels are spread across connections,
+	// a subscriber is reused whenever its connection is chosen again.
+	subscribers map[connection]initializingSubscriber
 }
 
 func newSubscriberMgr(connMgr connMgr, factory subscriberFactory) *subscriberMgr {
 	return &subscriberMgr{
 		connMgr:          connMgr,
 		createSubscriber: factory,
+		subscribers:      make(map[connection]initializingSubscriber),
 	}
 }
 
@@ -652,7 +1152,7 @@ func (m *subscriberMgr) Close() error {
 	logger.Info("Closing subscribers", log.WithTotal(len(m.subscribers)))
 
 	for _, s := range m.subscribers {
-		if err := s.subscriber.Close(); err != nil {
+		if err := s.Close(); err != nil {
 			logger.Warn("Error closing subscriber", log.WithError(err))
 		}
 	}
@@ -661,51 +1161,69 @@ func (m *subscriberMgr) Close() error {
 }
 
 func (m *subscriberMgr) Subscribe(ctx context.Context, topic string) (<-chan *message.Message, error) {
-	s, err := m.get()
+	s, conn, err := m.get()
 	if err != nil {
 		return nil, err
 	}
 
-	return s.Subscribe(ctx, topic)
+	msgChan, err := s.Subscribe(ctx, topic)
+	if err != nil {
+		m.connMgr.releaseChannel(conn)
+
+		return nil, err
+	}
+
+	// The subscription's channel is closed when the context is done. (A context that can never be
+	// done holds on to the channel for the lifetime of the subscriber.)
+	if done := ctx.Done(); done != nil {
+		go func() {
+			<-done
+
+			m.connMgr.releaseChannel(conn)
+		}()
+	}
+
+	return msgChan, nil
 }
 
 func (m *subscriberMgr) SubscribeInitialize(topic string) error {
-	s, err := m.get()
+	s, conn, err := m.get()
 	if err != nil {
 		return err
 	}
 
+	// The channel that's used to initialize the topic is closed once initialization completes.
+	defer m.connMgr.releaseChannel(conn)
+
 	return s.SubscribeInitialize(topic)
 }
 
-func (m *subscriberMgr) get() (initializingSubscriber, error) {
+func (m *subscriberMgr) get() (initializingSubscriber, connection, error) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
 	conn, err := m.connMgr.getConnection(true)
 	if err != nil {
-		return nil, fmt.Errorf("get connection for subscriber: %w", err)
+		return nil, nil, fmt.Errorf("get connection for subscriber: %w", err)
 	}
 
-	if m.current == nil || conn != m.current.conn {
-		s, err := m.createSubscriber(conn)
+	s, ok := m.subscribers[conn]
+	if !ok {
+		s, err = m.createSubscriber(conn)
 		if err != nil {
-			return nil, err
-		}
+			m.connMgr.releaseChannel(conn)
 
-		m.current = &subscriberInfo{
-			subscriber: s,
-			conn:       conn,
+			return nil, nil, err
 		}
 
-		m.subscribers = append(m.subscribers, m.current)
+		m.subscribers[conn] = s
 
 		logger.Debug("Created a subscriber.", log.WithTotal(len(m.subscribers)))
 	}
 
 	logger.Debug("Incremented channel count for current connection.", log.WithTotal(int(conn.numChannels())))
 
-	return m.current.subscriber, nil
+	return s, conn, nil
 }
 
 // extractEndpoint returns the endpoint of the AMQP URL, i.e. everything after @.
@@ -725,6 +1243,86 @@ func extractEndpoint(amqpURL string) string {
 	return path[j+1:]
 }
 
+// redactURL returns the given AMQP URL without the user info, e.g. "amqps://example.com:5671/mq".
+func redactURL(amqpURL string) string {
+	i := strings.Index(amqpURL, "://")
+	if i < 0 {
+		return ""
+	}
+
+	return amqpURL[:i+3] + extractEndpoint(amqpURL)
+}
+
+// redactCredentials returns an error whose message doesn't contain the credentials in the given AMQP URL.
+// The returned error wraps the given error so that errors.Is and errors.As still work. Any occurrence of the
+// URL in the message is replaced with the redacted URL and any other occurrence of the password is masked.
+func redactCredentials(err error, amqpURL string) error {
+	if err == nil {
+		return nil
+	}
+
+	msg := err.Error()
+
+	if amqpURL != "" {
+		msg = strings.ReplaceAll(msg, amqpURL, redactURL(amqpURL))
+	}
+
+	if u := parseURL(amqpURL); u != nil {
+		if password, ok := u.User.Password(); ok && password != "" {
+			msg = strings.ReplaceAll(msg, password, redactedPassword)
+		}
+	} else if userInfo := extractUserInfo(amqpURL); userInfo != "" {
+		// The URL can't be parsed, so mask the entire user info.
+		msg = strings.ReplaceAll(msg, userInfo, redactedPassword)
+	}
+
+	if msg == err.Error() {
+		return err
+	}
+
+	return &redactedError{msg: msg, err: err}
+}
+
+// extractUserInfo returns the user info of the AMQP URL, i.e. everything between :// and @.
+func extractUserInfo(amqpURL string) string {
+	i := strings.Index(amqpURL, "://")
+	if i < 0 {
+		return ""
+	}
+
+	path := amqpURL[i+3:]
+
+	j := strings.Index(path, "@")
+	if j < 0 {
+		return ""
+	}
+
+	return path[:j]
+}
+
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// parseURL parses the given AMQP URL. Nil is returned if the URL is invalid.
+func parseURL(amqpURL string) *url.URL {
+	u, err := url.Parse(amqpURL)
+	if err != nil {
+		return nil
+	}
+
+	return u
+}
+
 func getRedeliveryAttempts(msg *message.Message) int {
 	var count int
 
@@ -742,6 +1340,27 @@ func getRedeliveryAttempts(msg *message.Message) int {
 	return count
 }
 
+// withQueueMetadata returns copies of the given messages with the logical topic stamped into the queue metadata
+// property. The topic is used when a rejected message is redelivered or dead-lettered, since the queue name in the
+// x-death metadata is the name on the AMQP server which, with a NameStrategy, differs from the logical topic.
+func withQueueMetadata(topic string, messages []*message.Message) []*message.Message {
+	result := make([]*message.Message, len(messages))
+
+	for i, msg := range messages {
+		newMsg := msg.Copy()
+		newMsg.SetContext(msg.Context())
+		newMsg.Metadata.Set(metadataQueue, topic)
+
+		result[i] = newMsg
+	}
+
+	return result
+}
+
+// getQueue returns the logical topic to which the given rejected message was published. The queue metadata
+// property (stamped on publish) is preferred. The x-first-death-queue property is only used as a fallback
+// (for messages published without the queue property) since it holds the name on the AMQP server, which is
+// only the same as the logical topic if no NameStrategy is configured.
 func getQueue(msg *message.Message) (string, error) {
 	queue, ok := msg.Metadata[metadataQueue]
 	if ok {
@@ -814,10 +1433,10 @@ func newMessage(msg *message.Message, opts ...messageOpt) *message.Message {
 
 func newQueueConfig(cfg Config) amqp.Config {
 	queueConfig := newDefaultQueueConfig(cfg)
-	queueConfig.Exchange = newAMQPExchangeConfig(exchange)
-	queueConfig.Queue = newAMQPQueueConfig(ramqp.Table{
-		metadataDeadLetterRoutingKey: redeliveryQueue,
-		metadataDeadLetterExchange:   redeliveryExchange,
+	queueConfig.Exchange = newAMQPExchangeConfig(cfg, exchange)
+	queueConfig.Queue = newAMQPQueueConfig(cfg, ramqp.Table{
+		metadataDeadLetterRoutingKey: cfg.brokerName(redeliveryQueue),
+		metadataDeadLetterExchange:   cfg.brokerName(redeliveryExchange),
 	})
 
 	return queueConfig
@@ -825,7 +1444,7 @@ func newQueueConfig(cfg Config) amqp.Config {
 
 func newRedeliveryQueueConfig(cfg Config) amqp.Config {
 	queueConfig := newDefaultQueueConfig(cfg)
-	queueConfig.Exchange = newAMQPExchangeConfig(redeliveryExchange)
+	queueConfig.Exchange = newAMQPExchangeConfig(cfg, redeliveryExchange)
 	queueConfig.Consume = amqp.ConsumeConfig{
 		Qos:             amqp.QosConfig{PrefetchCount: 1},
 		NoRequeueOnNack: false, // Ensure that the message is re-queued if the server goes down before it is Acked.
@@ -836,26 +1455,56 @@ func newRedeliveryQueueConfig(cfg Config) amqp.Config {
 
 func newWaitQueueConfig(cfg Config) amqp.Config {
 	queueConfig := newDefaultQueueConfig(cfg)
-	queueConfig.Exchange = newAMQPExchangeConfig(waitExchange)
-	queueConfig.Queue = newAMQPQueueConfig(ramqp.Table{
-		metadataDeadLetterRoutingKey: redeliveryQueue,
-		metadataDeadLetterExchange:   redeliveryExchange,
+	queueConfig.Exchange = newAMQPExchangeConfig(cfg, waitExchange)
+	queueConfig.Queue = newAMQPQueueConfig(cfg, ramqp.Table{
+		metadataDeadLetterRoutingKey: cfg.brokerName(redeliveryQueue),
+		metadataDeadLetterExchange:   cfg.brokerName(redeliveryExchange),
 	})
 	queueConfig.Publish.ChannelPoolSize = defaultWaitQueuePublisherChannelPoolSize
 
 	return queueConfig
 }
 
+// newConnectionConfig returns the connection configuration. If a heartbeat is configured then the AMQP client
+// configuration is set explicitly, otherwise the client library's defaults are used.
+func newConnectionConfig(cfg Config) amqp.ConnectionConfig {
+	connCfg := amqp.ConnectionConfig{
+		AmqpURI: cfg.URI,
+		// The connection is automatically re-established if it's closed by the broker. Use the same backoff
+		// as the initial connection.
+		Reconnect: &amqp.ReconnectConfig{
+			BackoffInitialInterval:     backoff.DefaultInitialInterval,
+			BackoffRandomizationFactor: backoff.DefaultRandomizationFactor,
+			BackoffMultiplier:          backoff.DefaultMultiplier,
+			BackoffMaxInterval:         defaultMaxConnectInterval,
+		},
+	}
+
+	if cfg.Heartbeat > 0 {
+		connCfg.AmqpConfig = &ramqp.Config{
+			Heartbeat: cfg.Heartbeat,
+			Locale:    defaultLocale,
+		}
+	}
+
+	return connCfg
+}
+
 func newDefaultQueueConfig(cfg Config) amqp.Config {
+	marshaler := cfg.Marshaler
+	if marshaler == nil {
+		marshaler = &DefaultMarshaler{}
+	}
+
 	return amqp.Config{
-		Connection: amqp.ConnectionConfig{AmqpURI: cfg.URI},
-		Marshaler:  &DefaultMarshaler{},
-		Queue:      newAMQPQueueConfig(nil),
+		Connection: newConnectionConfig(cfg),
+		Marshaler:  marshaler,
+		Queue:      newAMQPQueueConfig(cfg, nil),
 		QueueBind: amqp.QueueBindConfig{
-			GenerateRoutingKey: func(queue string) string { return queue },
+			GenerateRoutingKey: cfg.brokerName,
 		},
 		Publish: amqp.PublishConfig{
-			GenerateRoutingKey: func(queue string) string { return queue },
+			GenerateRoutingKey: cfg.brokerName,
 			ChannelPoolSize:    cfg.PublisherChannelPoolSize,
 			ConfirmDelivery:    cfg.PublisherConfirmDelivery,
 		},
@@ -867,48 +1516,24 @@ func newDefaultQueueConfig(cfg Config) amqp.Config {
 	}
 }
 
-func newAMQPExchangeConfig(exchange string) amqp.ExchangeConfig {
+func newAMQPExchangeConfig(cfg Config, exchange string) amqp.ExchangeConfig {
 	return amqp.ExchangeConfig{
 		GenerateName: func(topic string) string {
-			return exchange
+			return cfg.brokerName(exchange)
 		},
 		Type:    directExchangeType,
 		Durable: true,
 	}
 }
 
-func newAMQPQueueConfig(args ramqp.Table) amqp.QueueConfig {
+func newAMQPQueueConfig(cfg Config, args ramqp.Table) amqp.QueueConfig {
 	return amqp.QueueConfig{
-		GenerateName: amqp.GenerateQueueNameTopicName,
+		GenerateName: cfg.brokerName,
 		Durable:      true,
 		Arguments:    args,
 	}
 }
 
-func initConfig(cfg Config) Config {
-	if cfg.MaxConnectionChannels == 0 {
-		cfg.MaxConnectionChannels = defaultMaxConnectionSubscriptions
-	}
-
-	if cfg.MaxRedeliveryAttempts == 0 {
-		cfg.MaxRedeliveryAttempts = defaultMaxRedeliveryAttempts
-	}
-
-	if cfg.RedeliveryMultiplier == 0 {
-		cfg.RedeliveryMultiplier = defaultRedeliveryMultiplier
-	}
-
-	if cfg.RedeliveryInitialInterval == 0 {
-		cfg.RedeliveryInitialInterval = defaultRedeliveryInitialInterval
-	}
-
-	if cfg.MaxRedeliveryInterval == 0 {
-		cfg.MaxRedeliveryInterval = defaultMaxRedeliveryInterval
-	}
-
-	return cfg
-}
-
 func getOptions(opts []spi.Option) *spi.Options {
 	options := &spi.Options{}
 
@@ -0,0 +1,29 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package amqp
+
+import (
+	"errors"
+)
+
+// ChannelLimitAction specifies what to do when a subscriber requires a channel and the shared connection
+// already has the maximum number of channels (see Config.MaxConnectionChannels).
+type ChannelLimitAction string
+
+const (
+	// ChannelLimitActionGrow opens a new connection when the channel limit of the current connection is reached.
+	// This is the default.
+	ChannelLimitActionGrow ChannelLimitAction = "grow"
+	// ChannelLimitActionBlock waits (up to Config.ChannelLimitTimeout) for a channel on the shared connection
+	// to be released. If no channel is released within the timeout then an error that wraps
+	// ErrChannelLimitReached is returned.
+	ChannelLimitActionBlock ChannelLimitAction = "block"
+)
+
+// ErrChannelLimitReached is returned if the channel limit of the shared connection was reached and no channel
+// was released within the timeout (when Config.ChannelLimitAction is ChannelLimitActionBlock).
+var ErrChannelLimitReached = errors.New("connection channel limit reached")
@@ -0,0 +1,297 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package amqp
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill-amqp/v2/pkg/amqp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectionMgr_ChannelLimit(t *testing.T) {
+	newConnMgr := func(limit int, action ChannelLimitAction, timeout time.Duration) *connectionMgr {
+		m := newConnectionMgr(amqp.ConnectionConfig{AmqpURI: "amqp://localhost:5672"}, limit, action, timeout)
+
+		m.createConnection = func(amqp.ConnectionConfig) (*amqp.ConnectionWrapper, error) {
+			return &amqp.ConnectionWrapper{}, nil
+		}
+
+		return m
+	}
+
+	t.Run("Grow (default)", func(t *testing.T) {
+		const (
+			limit       = 2
+			subscribers = 10
+		)
+
+		m := newConnMgr(limit, "", 0)
+		require.Equal(t, ChannelLimitActionGrow, m.limitAction)
+		require.Equal(t, defaultChannelLimitTimeout, m.limitTimeout)
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < subscribers; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				_, err := m.getConnection(true)
+				require.NoError(t, err)
+			}()
+		}
+
+		wg.Wait()
+
+		require.Len(t, m.connections, subscribers/limit)
+
+		for _, c := range m.connections {
+			require.Equal(t, uint32(limit), c.numChannels())
+		}
+	})
+
+	t.Run("Block", func(t *testing.T) {
+		const (
+			limit       = 2
+			subscribers = 6
+		)
+
+		m := newConnMgr(limit, ChannelLimitActionBlock, 5*time.Second)
+
+		acquired := make(chan connection, subscribers)
+
+		for i := 0; i < subscribers; i++ {
+			go func() {
+				conn, err := m.getConnection(true)
+				require.NoError(t, err)
+
+				acquired <- conn
+			}()
+		}
+
+		var conns []connection
+
+		for i := 0; i < limit; i++ {
+			conns = append(conns, <-acquired)
+		}
+
+		select {
+		case <-acquired:
+			t.Fatal("expecting caller to block until a channel is released")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		// Release the channels one at a time so that each of the remaining callers gets a channel.
+		for i := limit; i < subscribers; i++ {
+			m.releaseChannel(conns[0])
+
+			select {
+			case conn := <-acquired:
+				conns = append(conns[1:], conn)
+			case <-time.After(time.Second):
+				t.Fatal("expecting caller to be unblocked after a channel was released")
+			}
+
+			require.LessOrEqual(t, m.current.numChannels(), uint32(limit))
+		}
+
+		require.Len(t, m.connections, 1)
+		require.Equal(t, uint32(limit), m.current.numChannels())
+	})
+
+	t.Run("Block -> timeout", func(t *testing.T) {
+		m := newConnMgr(1, ChannelLimitActionBlock, 50*time.Millisecond)
+
+		_, err := m.getConnection(true)
+		require.NoError(t, err)
+
+		_, err = m.getConnection(true)
+		require.ErrorIs(t, err, ErrChannelLimitReached)
+
+		// Non-shared connections aren't subject to the channel limit.
+		_, err = m.getConnection(false)
+		require.NoError(t, err)
+	})
+}
+
+func TestSubscriberMgr_ChannelLimit(t *testing.T) {
+	const (
+		limit       = 2
+		subscribers = 8
+	)
+
+	connMgr := newConnectionMgr(amqp.ConnectionConfig{AmqpURI: "amqp://localhost:5672"}, limit,
+		ChannelLimitActionBlock, 5*time.Second)
+
+	connMgr.createConnection = func(amqp.ConnectionConfig) (*amqp.ConnectionWrapper, error) {
+		return &amqp.ConnectionWrapper{}, nil
+	}
+
+	m := newSubscriberMgr(connMgr, func(conn connection) (initializingSubscriber, error) {
+		return &mockSubscriber{mockClosable: &mockClosable{}}, nil
+	})
+
+	require.NoError(t, m.SubscribeInitialize("topic"))
+	require.Zero(t, connMgr.current.numChannels(), "channel should be released after initialization")
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < subscribers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithCancel(context.Background())
+
+			_, err := m.Subscribe(ctx, "topic")
+			require.NoError(t, err)
+
+			require.LessOrEqual(t, connMgr.current.numChannels(), uint32(limit))
+
+			// Closing the subscription releases the channel.
+			time.Sleep(10 * time.Millisecond)
+			cancel()
+		}()
+	}
+
+	wg.Wait()
+
+	require.Len(t, connMgr.connections, 1)
+	require.Len(t, m.subscribers, 1)
+
+	require.Eventually(t, func() bool {
+		return connMgr.current.numChannels() == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestConnectionMgr_WeightedRoundRobin(t *testing.T) {
+	const limit = 10
+
+	newConnMgr := func(channels ...uint32) *connectionMgr {
+		m := newConnectionMgr(amqp.ConnectionConfig{AmqpURI: "amqp://localhost:5672"}, limit, "", 0)
+
+		m.createConnection = func(amqp.ConnectionConfig) (*amqp.ConnectionWrapper, error) {
+			return &amqp.ConnectionWrapper{}, nil
+		}
+
+		m.isHealthy = func(*connectionWrapper) bool { return true }
+
+		for _, n := range channels {
+			m.connections = append(m.connections,
+				&connectionWrapper{conn: &amqp.ConnectionWrapper{}, channels: n, shared: true})
+		}
+
+		return m
+	}
+
+	channelCounts := func(m *connectionMgr) []uint32 {
+		var counts []uint32
+
+		for _, c := range m.connections {
+			counts = append(counts, c.numChannels())
+		}
+
+		return counts
+	}
+
+	t.Run("Channels spread evenly across idle connections", func(t *testing.T) {
+		m := newConnMgr(0, 0, 0)
+
+		for i := 0; i < 6; i++ {
+			_, err := m.getConnection(true)
+			require.NoError(t, err)
+		}
+
+		require.Equal(t, []uint32{2, 2, 2}, channelCounts(m))
+	})
+
+	t.Run("Less loaded connections are chosen more often", func(t *testing.T) {
+		m := newConnMgr(8, 0, 4)
+
+		for i := 0; i < 6; i++ {
+			_, err := m.getConnection(true)
+			require.NoError(t, err)
+		}
+
+		require.Equal(t, []uint32{9, 3, 6}, channelCounts(m))
+	})
+
+	t.Run("Full connections are skipped and a new connection is opened when all are full", func(t *testing.T) {
+		m := newConnMgr(limit, limit-1)
+
+		conn, err := m.getConnection(true)
+		require.NoError(t, err)
+		require.Equal(t, m.connections[1], conn)
+
+		_, err = m.getConnection(true)
+		require.NoError(t, err)
+		require.Len(t, m.connections, 3)
+		require.Equal(t, []uint32{limit, limit, 1}, channelCounts(m))
+	})
+
+	t.Run("Healthy connections are preferred", func(t *testing.T) {
+		m := newConnMgr(0, 0)
+
+		unhealthy := m.connections[0]
+
+		m.isHealthy = func(c *connectionWrapper) bool { return c != unhealthy }
+
+		for i := 0; i < 4; i++ {
+			_, err := m.getConnection(true)
+			require.NoError(t, err)
+		}
+
+		require.Equal(t, []uint32{0, 4}, channelCounts(m))
+
+		// Unhealthy connections are used if no healthy connection has a free channel.
+		m.connections[1].channels = limit
+
+		conn, err := m.getConnection(true)
+		require.NoError(t, err)
+		require.Equal(t, unhealthy, conn)
+	})
+
+	t.Run("Non-shared connections aren't used for shared channels", func(t *testing.T) {
+		m := newConnMgr()
+
+		_, err := m.getConnection(false)
+		require.NoError(t, err)
+
+		_, err = m.getConnection(true)
+		require.NoError(t, err)
+		require.Len(t, m.connections, 2)
+		require.Equal(t, []uint32{0, 1}, channelCounts(m))
+	})
+
+	t.Run("One subscriber per connection", func(t *testing.T) {
+		m := newConnMgr(0, 0)
+
+		var created int
+
+		sm := newSubscriberMgr(m, func(conn connection) (initializingSubscriber, error) {
+			created++
+
+			return &mockSubscriber{mockClosable: &mockClosable{}}, nil
+		})
+
+		// Subscriptions alternate between the connections, so each connection's subscriber is reused.
+		for i := 0; i < 8; i++ {
+			_, err := sm.Subscribe(context.Background(), "topic")
+			require.NoError(t, err)
+		}
+
+		require.Equal(t, []uint32{4, 4}, channelCounts(m))
+		require.Equal(t, len(m.connections), created)
+		require.Len(t, sm.subscribers, len(m.connections))
+	})
+}
@@ -0,0 +1,154 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package amqp
+
+import (
+	"fmt"
+)
+
+// validateAndSetDefaults validates the configuration and sets the default value of each field that isn't set.
+// An error is returned if a field is out of range or if a combination of fields doesn't make sense.
+func (cfg *Config) validateAndSetDefaults() error {
+	if err := cfg.validateConnection(); err != nil {
+		return err
+	}
+
+	if err := cfg.validateRedelivery(); err != nil {
+		return err
+	}
+
+	if err := cfg.validatePublisher(); err != nil {
+		return err
+	}
+
+	switch cfg.HandlerPanicAction {
+	case "":
+		cfg.HandlerPanicAction = PanicActionNack
+	case PanicActionNack, PanicActionAck:
+	default:
+		return fmt.Errorf("unsupported handler panic action: %s", cfg.HandlerPanicAction)
+	}
+
+	return nil
+}
+
+func (cfg *Config) validateConnection() error {
+	if cfg.MaxConnectRetries < 0 {
+		return fmt.Errorf("max connect retries must not be negative: %d", cfg.MaxConnectRetries)
+	}
+
+	if cfg.MaxConnectRetries == 0 {
+		cfg.MaxConnectRetries = defaultMaxConnectRetries
+	}
+
+	if cfg.MaxConnectionChannels < 0 {
+		return fmt.Errorf("max connection channels must not be negative: %d", cfg.MaxConnectionChannels)
+	}
+
+	if cfg.MaxConnectionChannels == 0 {
+		cfg.MaxConnectionChannels = defaultMaxConnectionSubscriptions
+	}
+
+	switch cfg.ChannelLimitAction {
+	case "":
+		cfg.ChannelLimitAction = ChannelLimitActionGrow
+	case ChannelLimitActionGrow, ChannelLimitActionBlock:
+	default:
+		return fmt.Errorf("unsupported channel limit action: %s", cfg.ChannelLimitAction)
+	}
+
+	if cfg.ChannelLimitTimeout < 0 {
+		return fmt.Errorf("channel limit timeout must not be negative: %s", cfg.ChannelLimitTimeout)
+	}
+
+	if cfg.ChannelLimitTimeout == 0 {
+		cfg.ChannelLimitTimeout = defaultChannelLimitTimeout
+	}
+
+	if cfg.Heartbeat < 0 {
+		return fmt.Errorf("heartbeat must not be negative: %s", cfg.Heartbeat)
+	}
+
+	return nil
+}
+
+func (cfg *Config) validateRedelivery() error {
+	if cfg.MaxRedeliveryAttempts < 0 {
+		return fmt.Errorf("max redelivery attempts must not be negative: %d", cfg.MaxRedeliveryAttempts)
+	}
+
+	if cfg.MaxRedeliveryAttempts == 0 {
+		cfg.MaxRedeliveryAttempts = defaultMaxRedeliveryAttempts
+	}
+
+	if cfg.RedeliveryMultiplier == 0 {
+		cfg.RedeliveryMultiplier = defaultRedeliveryMultiplier
+	}
+
+	if cfg.RedeliveryMultiplier <= 1 {
+		return fmt.Errorf("redelivery multiplier must be greater than 1: %g", cfg.RedeliveryMultiplier)
+	}
+
+	if cfg.RedeliveryInitialInterval < 0 {
+		return fmt.Errorf("redelivery initial interval must not be negative: %s", cfg.RedeliveryInitialInterval)
+	}
+
+	if cfg.RedeliveryInitialInterval == 0 {
+		cfg.RedeliveryInitialInterval = defaultRedeliveryInitialInterval
+	}
+
+	if cfg.MaxRedeliveryInterval < 0 {
+		return fmt.Errorf("max redelivery interval must not be negative: %s", cfg.MaxRedeliveryInterval)
+	}
+
+	if cfg.MaxRedeliveryInterval == 0 {
+		cfg.MaxRedeliveryInterval = defaultMaxRedeliveryInterval
+	}
+
+	if cfg.RedeliveryPoolSize < 0 {
+		return fmt.Errorf("redelivery pool size must not be negative: %d", cfg.RedeliveryPoolSize)
+	}
+
+	if cfg.RedeliveryPoolSize == 0 {
+		cfg.RedeliveryPoolSize = defaultRedeliveryPoolSize
+	}
+
+	if cfg.MaxRedeliveryInterval < cfg.RedeliveryInitialInterval {
+		return fmt.Errorf("max redelivery interval [%s] must not be less than the redelivery initial interval [%s]",
+			cfg.MaxRedeliveryInterval, cfg.RedeliveryInitialInterval)
+	}
+
+	return nil
+}
+
+func (cfg *Config) validatePublisher() error {
+	if cfg.PublisherChannelPoolSize < 0 {
+		return fmt.Errorf("publisher channel pool size must not be negative: %d", cfg.PublisherChannelPoolSize)
+	}
+
+	if cfg.IdempotencyWindow < 0 {
+		return fmt.Errorf("idempotency window must not be negative: %s", cfg.IdempotencyWindow)
+	}
+
+	if cfg.MaxMessageSize < 0 {
+		return fmt.Errorf("max message size must not be negative: %d", cfg.MaxMessageSize)
+	}
+
+	if cfg.MaxInFlightPublishes < 0 {
+		return fmt.Errorf("max in-flight publishes must not be negative: %d", cfg.MaxInFlightPublishes)
+	}
+
+	switch cfg.PublishLimitAction {
+	case "":
+		cfg.PublishLimitAction = PublishLimitActionBlock
+	case PublishLimitActionBlock, PublishLimitActionError:
+	default:
+		return fmt.Errorf("unsupported publish limit action: %s", cfg.PublishLimitAction)
+	}
+
+	return nil
+}
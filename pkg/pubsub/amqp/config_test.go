@@ -0,0 +1,200 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package amqp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_ValidateAndSetDefaults(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		cfg := Config{URI: "amqp://localhost:5672"}
+
+		require.NoError(t, cfg.validateAndSetDefaults())
+
+		require.Equal(t, "amqp://localhost:5672", cfg.URI)
+		require.Equal(t, defaultMaxConnectRetries, cfg.MaxConnectRetries)
+		require.Equal(t, defaultMaxConnectionSubscriptions, cfg.MaxConnectionChannels)
+		require.Equal(t, ChannelLimitActionGrow, cfg.ChannelLimitAction)
+		require.Equal(t, defaultChannelLimitTimeout, cfg.ChannelLimitTimeout)
+		require.Equal(t, defaultMaxRedeliveryAttempts, cfg.MaxRedeliveryAttempts)
+		require.Equal(t, defaultRedeliveryMultiplier, cfg.RedeliveryMultiplier)
+		require.Equal(t, defaultRedeliveryInitialInterval, cfg.RedeliveryInitialInterval)
+		require.Equal(t, defaultMaxRedeliveryInterval, cfg.MaxRedeliveryInterval)
+		require.Equal(t, defaultRedeliveryPoolSize, cfg.RedeliveryPoolSize)
+		require.Equal(t, PanicActionNack, cfg.HandlerPanicAction)
+		require.Zero(t, cfg.Heartbeat)
+		require.Zero(t, cfg.IdempotencyWindow)
+		require.Zero(t, cfg.MaxMessageSize)
+		require.Zero(t, cfg.MaxInFlightPublishes)
+		require.Equal(t, PublishLimitActionBlock, cfg.PublishLimitAction)
+	})
+
+	t.Run("Values retained", func(t *testing.T) {
+		cfg := Config{
+			MaxConnectRetries:         3,
+			MaxConnectionChannels:     50,
+			ChannelLimitAction:        ChannelLimitActionBlock,
+			ChannelLimitTimeout:       time.Second,
+			MaxRedeliveryAttempts:     5,
+			RedeliveryMultiplier:      2,
+			RedeliveryInitialInterval: time.Second,
+			MaxRedeliveryInterval:     time.Minute,
+			RedeliveryPoolSize:        4,
+			HandlerPanicAction:        PanicActionAck,
+			MaxInFlightPublishes:      10,
+			PublishLimitAction:        PublishLimitActionError,
+		}
+
+		require.NoError(t, cfg.validateAndSetDefaults())
+
+		require.Equal(t, 3, cfg.MaxConnectRetries)
+		require.Equal(t, 50, cfg.MaxConnectionChannels)
+		require.Equal(t, ChannelLimitActionBlock, cfg.ChannelLimitAction)
+		require.Equal(t, time.Second, cfg.ChannelLimitTimeout)
+		require.Equal(t, 5, cfg.MaxRedeliveryAttempts)
+		require.Equal(t, float64(2), cfg.RedeliveryMultiplier)
+		require.Equal(t, time.Second, cfg.RedeliveryInitialInterval)
+		require.Equal(t, time.Minute, cfg.MaxRedeliveryInterval)
+		require.Equal(t, 4, cfg.RedeliveryPoolSize)
+		require.Equal(t, PanicActionAck, cfg.HandlerPanicAction)
+		require.Equal(t, 10, cfg.MaxInFlightPublishes)
+		require.Equal(t, PublishLimitActionError, cfg.PublishLimitAction)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		tests := []struct {
+			name string
+			cfg  Config
+			err  string
+		}{
+			{
+				name: "negative max connect retries",
+				cfg:  Config{MaxConnectRetries: -1},
+				err:  "max connect retries must not be negative: -1",
+			},
+			{
+				name: "negative max connection channels",
+				cfg:  Config{MaxConnectionChannels: -1},
+				err:  "max connection channels must not be negative: -1",
+			},
+			{
+				name: "unsupported channel limit action",
+				cfg:  Config{ChannelLimitAction: "shrink"},
+				err:  "unsupported channel limit action: shrink",
+			},
+			{
+				name: "negative channel limit timeout",
+				cfg:  Config{ChannelLimitTimeout: -time.Second},
+				err:  "channel limit timeout must not be negative: -1s",
+			},
+			{
+				name: "negative heartbeat",
+				cfg:  Config{Heartbeat: -time.Second},
+				err:  "heartbeat must not be negative: -1s",
+			},
+			{
+				name: "negative max redelivery attempts",
+				cfg:  Config{MaxRedeliveryAttempts: -1},
+				err:  "max redelivery attempts must not be negative: -1",
+			},
+			{
+				name: "redelivery multiplier of 1",
+				cfg:  Config{RedeliveryMultiplier: 1},
+				err:  "redelivery multiplier must be greater than 1: 1",
+			},
+			{
+				name: "negative redelivery multiplier",
+				cfg:  Config{RedeliveryMultiplier: -1.5},
+				err:  "redelivery multiplier must be greater than 1: -1.5",
+			},
+			{
+				name: "negative redelivery initial interval",
+				cfg:  Config{RedeliveryInitialInterval: -time.Second},
+				err:  "redelivery initial interval must not be negative: -1s",
+			},
+			{
+				name: "negative max redelivery interval",
+				cfg:  Config{MaxRedeliveryInterval: -time.Second},
+				err:  "max redelivery interval must not be negative: -1s",
+			},
+			{
+				name: "max redelivery interval less than initial interval",
+				cfg:  Config{RedeliveryInitialInterval: time.Minute, MaxRedeliveryInterval: time.Second},
+				err:  "max redelivery interval [1s] must not be less than the redelivery initial interval [1m0s]",
+			},
+			{
+				name: "initial interval greater than default max redelivery interval",
+				cfg:  Config{RedeliveryInitialInterval: time.Hour},
+				err:  "max redelivery interval [30s] must not be less than the redelivery initial interval [1h0m0s]",
+			},
+			{
+				name: "negative redelivery pool size",
+				cfg:  Config{RedeliveryPoolSize: -1},
+				err:  "redelivery pool size must not be negative: -1",
+			},
+			{
+				name: "negative publisher channel pool size",
+				cfg:  Config{PublisherChannelPoolSize: -1},
+				err:  "publisher channel pool size must not be negative: -1",
+			},
+			{
+				name: "negative idempotency window",
+				cfg:  Config{IdempotencyWindow: -time.Second},
+				err:  "idempotency window must not be negative: -1s",
+			},
+			{
+				name: "negative max message size",
+				cfg:  Config{MaxMessageSize: -1},
+				err:  "max message size must not be negative: -1",
+			},
+			{
+				name: "negative max in-flight publishes",
+				cfg:  Config{MaxInFlightPublishes: -1},
+				err:  "max in-flight publishes must not be negative: -1",
+			},
+			{
+				name: "unsupported publish limit action",
+				cfg:  Config{PublishLimitAction: "drop"},
+				err:  "unsupported publish limit action: drop",
+			},
+			{
+				name: "unsupported handler panic action",
+				cfg:  Config{HandlerPanicAction: "ignore"},
+				err:  "unsupported handler panic action: ignore",
+			},
+		}
+
+		for _, tc := range tests {
+			tc := tc
+
+			t.Run(tc.name, func(t *testing.T) {
+				require.EqualError(t, tc.cfg.validateAndSetDefaults(), tc.err)
+			})
+		}
+	})
+
+	t.Run("New panics on invalid config", func(t *testing.T) {
+		require.PanicsWithValue(t, "Invalid AMQP configuration: redelivery multiplier must be greater than 1: 0.5",
+			func() {
+				New(Config{URI: "amqp://localhost:5672", RedeliveryMultiplier: 0.5})
+			},
+		)
+	})
+}
+
+// newTestConfig returns the given configuration with defaults set.
+func newTestConfig(t *testing.T, cfg Config) Config {
+	t.Helper()
+
+	require.NoError(t, cfg.validateAndSetDefaults())
+
+	return cfg
+}
@@ -0,0 +1,69 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package amqp
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// resolveURI returns the AMQP URI with the credentials from the configured files and/or environment
+// variables applied. Credentials loaded from a file or environment variable override those in the URI.
+func resolveURI(cfg Config) (string, error) {
+	username, err := loadCredential(cfg.UsernameFile, cfg.UsernameEnvKey)
+	if err != nil {
+		return "", fmt.Errorf("load username: %w", err)
+	}
+
+	password, err := loadCredential(cfg.PasswordFile, cfg.PasswordEnvKey)
+	if err != nil {
+		return "", fmt.Errorf("load password: %w", err)
+	}
+
+	if username == "" && password == "" {
+		return cfg.URI, nil
+	}
+
+	u, err := url.Parse(cfg.URI)
+	if err != nil {
+		// Don't include the parse error since it may contain the URI.
+		return "", fmt.Errorf("invalid AMQP URI")
+	}
+
+	if username == "" && u.User != nil {
+		username = u.User.Username()
+	}
+
+	if password == "" && u.User != nil {
+		password, _ = u.User.Password()
+	}
+
+	u.User = url.UserPassword(username, password)
+
+	return u.String(), nil
+}
+
+// loadCredential loads a credential from the given file or, if the file isn't specified, from the given
+// environment variable. An empty string is returned if neither is specified.
+func loadCredential(file, envKey string) (string, error) {
+	if file != "" {
+		contents, err := os.ReadFile(file) //nolint:gosec
+		if err != nil {
+			return "", fmt.Errorf("read file [%s]: %w", file, err)
+		}
+
+		return strings.TrimSpace(string(contents)), nil
+	}
+
+	if envKey != "" {
+		return os.Getenv(envKey), nil
+	}
+
+	return "", nil
+}
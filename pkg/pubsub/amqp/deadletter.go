@@ -0,0 +1,87 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package amqp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+
+	"github.com/trustbloc/orb/internal/pkg/log"
+	orberrors "github.com/trustbloc/orb/pkg/errors"
+)
+
+// MetadataOriginalTopic is the metadata key that holds the topic to which a dead-lettered message
+// was originally published.
+const MetadataOriginalTopic = "orb-original-topic"
+
+// ErrDeadLetterTopicNotConfigured indicates that Config.DeadLetterTopic was not set.
+var ErrDeadLetterTopicNotConfigured = errors.New("dead-letter topic not configured")
+
+// SubscribeDeadLetters subscribes to the dead-letter topic. The returned messages may be inspected
+// and then requeued (using Requeue) or acked in order to discard them.
+func (p *PubSub) SubscribeDeadLetters(ctx context.Context) (<-chan *message.Message, error) {
+	if p.DeadLetterTopic == "" {
+		return nil, ErrDeadLetterTopicNotConfigured
+	}
+
+	return p.Subscribe(ctx, p.DeadLetterTopic)
+}
+
+// Requeue publishes the given dead-lettered message to the topic to which it was originally published.
+// The redelivery count of the message is reset. Note that the caller is responsible for acking the
+// dead-lettered message once it has been successfully requeued.
+func (p *PubSub) Requeue(msg *message.Message) error {
+	if err := p.StateError(); err != nil {
+		return err
+	}
+
+	topic := msg.Metadata[MetadataOriginalTopic]
+	if topic == "" {
+		return fmt.Errorf("message [%s] does not contain metadata property [%s]", msg.UUID, MetadataOriginalTopic)
+	}
+
+	newMsg := msg.Copy()
+
+	for _, key := range []string{
+		MetadataOriginalTopic, metadataDeath, metadataFirstDeathQueue, metadataFirstDeathReason,
+		metadataQueue, metadataRedeliveryCount, metadataExpiration,
+	} {
+		delete(newMsg.Metadata, key)
+	}
+
+	// Stamp the logical topic (as Publish does) so that the message can be redelivered if it's rejected again.
+	newMsg.Metadata.Set(metadataQueue, topic)
+
+	if err := p.publisher.Publish(topic, newMsg); err != nil {
+		return orberrors.NewTransientf("requeue message [%s] to topic [%s]: %w", msg.UUID, topic, err)
+	}
+
+	logger.Info("Requeued dead-lettered message", log.WithMessageID(msg.UUID), log.WithTopic(topic))
+
+	return nil
+}
+
+// deadLetter posts the given message (which could not be delivered to the given queue) to the dead-letter topic.
+func (p *PubSub) deadLetter(msg *message.Message, queue string) error {
+	newMsg := newMessage(msg)
+
+	delete(newMsg.Metadata, metadataQueue)
+
+	newMsg.Metadata.Set(MetadataOriginalTopic, queue)
+
+	if err := p.publisher.Publish(p.DeadLetterTopic, newMsg); err != nil {
+		return fmt.Errorf("publish message to dead-letter topic [%s]: %w", p.DeadLetterTopic, err)
+	}
+
+	logger.Info("Posted message to dead-letter topic", log.WithMessageID(msg.UUID),
+		log.WithTopic(p.DeadLetterTopic), log.WithValue(queue))
+
+	return nil
+}
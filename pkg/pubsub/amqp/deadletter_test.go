@@ -0,0 +1,250 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package amqp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/lifecycle"
+)
+
+func TestPubSub_DeadLetter(t *testing.T) {
+	const (
+		topic           = "some-topic"
+		deadLetterTopic = "orb.dead-letter"
+	)
+
+	newPubSub := func(pub publisher, sub subscriber, deadLetterTopic string) *PubSub {
+		p := &PubSub{
+			Lifecycle: lifecycle.New("ampq"),
+			Config: Config{
+				MaxRedeliveryAttempts: 2,
+				DeadLetterTopic:       deadLetterTopic,
+			},
+			connMgr:              &mockConnectionMgr{},
+			subscriber:           sub,
+			publisher:            pub,
+			waitSubscriber:       &mockSubscriber{mockClosable: &mockClosable{}},
+			waitPublisher:        newMockPublisher(),
+			redeliverySubscriber: &mockSubscriber{mockClosable: &mockClosable{}},
+		}
+
+		p.Start()
+
+		return p
+	}
+
+	newUndeliverableMsg := func() *message.Message {
+		msg := message.NewMessage(watermill.NewUUID(), []byte("some payload"))
+		msg.Metadata.Set(metadataQueue, topic)
+		msg.Metadata.Set(metadataRedeliveryCount, "2")
+		msg.Metadata.Set("foo", "bar")
+
+		return msg
+	}
+
+	t.Run("Dead-letter and requeue", func(t *testing.T) {
+		pub := newMockPublisher()
+		sub := &chanSubscriber{mockClosable: &mockClosable{}, msgChan: make(chan *message.Message, 1)}
+
+		p := newPubSub(pub, sub, deadLetterTopic)
+		defer p.stop()
+
+		msg := newUndeliverableMsg()
+
+		p.handleRedelivery(msg)
+
+		select {
+		case <-msg.Acked():
+		case <-time.After(time.Second):
+			t.Fatal("expecting message to be acked")
+		}
+
+		deadLettered := pub.publishedTo(deadLetterTopic)
+		require.Len(t, deadLettered, 1)
+		require.Equal(t, msg.UUID, deadLettered[0].UUID)
+		require.Equal(t, topic, deadLettered[0].Metadata[MetadataOriginalTopic])
+		require.Empty(t, pub.publishedTo(topic))
+
+		dlqChan, err := p.SubscribeDeadLetters(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, deadLetterTopic, sub.topic)
+
+		sub.msgChan <- deadLettered[0]
+
+		dlqMsg := <-dlqChan
+
+		require.NoError(t, p.Requeue(dlqMsg))
+
+		requeued := pub.publishedTo(topic)
+		require.Len(t, requeued, 1)
+		require.Equal(t, msg.UUID, requeued[0].UUID)
+		require.Equal(t, msg.Payload, requeued[0].Payload)
+		require.Equal(t, "bar", requeued[0].Metadata["foo"])
+		require.Empty(t, requeued[0].Metadata[MetadataOriginalTopic])
+		require.Equal(t, 0, getRedeliveryAttempts(requeued[0]))
+	})
+
+	t.Run("Dead-letter topic not configured", func(t *testing.T) {
+		pub := newMockPublisher()
+
+		p := newPubSub(pub, &mockSubscriber{mockClosable: &mockClosable{}}, "")
+		defer p.stop()
+
+		msg := newUndeliverableMsg()
+
+		p.handleRedelivery(msg)
+
+		require.Zero(t, pub.numPublished())
+
+		_, err := p.SubscribeDeadLetters(context.Background())
+		require.ErrorIs(t, err, ErrDeadLetterTopicNotConfigured)
+	})
+
+	t.Run("Dead-letter publish error", func(t *testing.T) {
+		pub := &mockPublisher{err: errors.New("injected publish error"), mockClosable: &mockClosable{}}
+
+		p := newPubSub(pub, &mockSubscriber{mockClosable: &mockClosable{}}, deadLetterTopic)
+		defer p.stop()
+
+		msg := newUndeliverableMsg()
+
+		p.handleRedelivery(msg)
+
+		select {
+		case <-msg.Nacked():
+		case <-time.After(time.Second):
+			t.Fatal("expecting message to be nacked")
+		}
+	})
+
+	t.Run("Requeue error", func(t *testing.T) {
+		pub := newMockPublisher()
+
+		p := newPubSub(pub, &mockSubscriber{mockClosable: &mockClosable{}}, deadLetterTopic)
+
+		err := p.Requeue(newUndeliverableMsg())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "does not contain metadata property")
+
+		msg := newUndeliverableMsg()
+		msg.Metadata.Set(MetadataOriginalTopic, topic)
+
+		pub.err = errors.New("injected publish error")
+
+		err = p.Requeue(msg)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), pub.err.Error())
+
+		p.Stop()
+
+		require.ErrorIs(t, p.Requeue(msg), lifecycle.ErrStopped)
+	})
+}
+
+func TestPubSub_RedeliveryWithNameStrategy(t *testing.T) {
+	const (
+		topic           = "some-topic"
+		deadLetterTopic = "orb.dead-letter"
+		prefix          = "dev."
+	)
+
+	pub := newMockPublisher()
+	waitPub := newMockPublisher()
+
+	p := &PubSub{
+		Lifecycle: lifecycle.New("ampq"),
+		Config: Config{
+			MaxRedeliveryAttempts:     2,
+			RedeliveryInitialInterval: time.Second,
+			DeadLetterTopic:           deadLetterTopic,
+			NameStrategy:              PrefixNameStrategy(prefix),
+		},
+		connMgr:              &mockConnectionMgr{},
+		subscriber:           &mockSubscriber{mockClosable: &mockClosable{}},
+		publisher:            pub,
+		waitSubscriber:       &mockSubscriber{mockClosable: &mockClosable{}},
+		waitPublisher:        waitPub,
+		redeliverySubscriber: &mockSubscriber{mockClosable: &mockClosable{}},
+	}
+
+	p.Start()
+	defer p.stop()
+
+	// rejected returns the given published message as it would be received from the redelivery queue after
+	// being rejected, i.e. with x-death metadata that contains the (prefixed) name of the queue on the server.
+	rejected := func(msg *message.Message, redeliveryCount string) *message.Message {
+		newMsg := msg.Copy()
+		newMsg.Metadata.Set(metadataFirstDeathQueue, p.brokerName(topic))
+		newMsg.Metadata.Set(metadataRedeliveryCount, redeliveryCount)
+
+		return newMsg
+	}
+
+	msg := message.NewMessage(watermill.NewUUID(), []byte("some payload"))
+
+	require.NoError(t, p.Publish(topic, msg))
+
+	published := pub.publishedTo(topic)
+	require.Len(t, published, 1)
+	require.Equal(t, topic, published[0].Metadata[metadataQueue])
+	require.Empty(t, msg.Metadata[metadataQueue], "the caller's message should not be modified")
+
+	t.Run("Redelivery -> published to logical topic", func(t *testing.T) {
+		p.handleRedelivery(rejected(published[0], "0"))
+
+		redelivered := pub.publishedTo(topic)
+		require.Len(t, redelivered, 2)
+		require.Equal(t, msg.UUID, redelivered[1].UUID)
+		require.Equal(t, topic, redelivered[1].Metadata[metadataQueue])
+		require.Empty(t, pub.publishedTo(p.brokerName(topic)))
+
+		p.handleRedelivery(rejected(published[0], "1"))
+
+		waiting := waitPub.publishedTo(waitQueue)
+		require.Len(t, waiting, 1)
+		require.Equal(t, topic, waiting[0].Metadata[metadataQueue])
+	})
+
+	t.Run("Dead-letter and requeue -> published to logical topic", func(t *testing.T) {
+		p.handleRedelivery(rejected(published[0], "2"))
+
+		deadLettered := pub.publishedTo(deadLetterTopic)
+		require.Len(t, deadLettered, 1)
+		require.Equal(t, topic, deadLettered[0].Metadata[MetadataOriginalTopic])
+
+		numPublished := len(pub.publishedTo(topic))
+
+		require.NoError(t, p.Requeue(deadLettered[0]))
+
+		requeued := pub.publishedTo(topic)
+		require.Len(t, requeued, numPublished+1)
+		require.Equal(t, msg.UUID, requeued[numPublished].UUID)
+		require.Equal(t, topic, requeued[numPublished].Metadata[metadataQueue])
+		require.Empty(t, pub.publishedTo(p.brokerName(topic)))
+	})
+}
+
+type chanSubscriber struct {
+	*mockClosable
+
+	msgChan chan *message.Message
+	topic   string
+}
+
+func (m *chanSubscriber) Subscribe(_ context.Context, topic string) (<-chan *message.Message, error) {
+	m.topic = topic
+
+	return m.msgChan, nil
+}
@@ -0,0 +1,89 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package amqp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+
+	"github.com/trustbloc/orb/internal/pkg/log"
+	"github.com/trustbloc/orb/pkg/pubsub/spi"
+)
+
+// PanicAction specifies what to do with a message whose handler panicked.
+type PanicAction string
+
+const (
+	// PanicActionNack nacks the message so that it is redelivered. This is the default.
+	PanicActionNack PanicAction = "nack"
+	// PanicActionAck acks the message so that it is dropped.
+	PanicActionAck PanicAction = "ack"
+)
+
+// Handler handles a message. The handler is responsible for acking/nacking the message.
+type Handler func(msg *message.Message)
+
+// SubscribeWithHandler subscribes to the given topic and invokes the handler for each message. The handler is
+// invoked concurrently by a number of workers equal to the pool size (see spi.WithPool). If the handler panics
+// then the panic is logged and the message is either nacked or acked, depending on Config.HandlerPanicAction,
+// and the worker continues consuming messages.
+func (p *PubSub) SubscribeWithHandler(ctx context.Context, topic string, handler Handler,
+	opts ...spi.Option) error {
+	msgChan, err := p.SubscribeWithOpts(ctx, topic, opts...)
+	if err != nil {
+		return err
+	}
+
+	numWorkers := getOptions(opts).PoolSize
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+
+	logger.Debug("Starting message handler workers", log.WithTopic(topic), log.WithSize(numWorkers))
+
+	var wg sync.WaitGroup
+
+	wg.Add(numWorkers)
+
+	for i := 0; i < numWorkers; i++ {
+		go func() {
+			defer wg.Done()
+
+			for msg := range msgChan {
+				p.invokeHandler(topic, handler, msg)
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+
+		logger.Debug("Message handler workers stopped", log.WithTopic(topic))
+	}()
+
+	return nil
+}
+
+func (p *PubSub) invokeHandler(topic string, handler Handler, msg *message.Message) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("Message handler panicked", log.WithTopic(topic), log.WithMessageID(msg.UUID),
+				log.WithError(fmt.Errorf("%v", r)), log.WithValue(string(p.HandlerPanicAction)))
+
+			if p.HandlerPanicAction == PanicActionAck {
+				msg.Ack()
+			} else {
+				msg.Nack()
+			}
+		}
+	}()
+
+	handler(msg)
+}
@@ -0,0 +1,119 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package amqp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/lifecycle"
+	"github.com/trustbloc/orb/pkg/pubsub/spi"
+)
+
+func TestPubSub_SubscribeWithHandler(t *testing.T) {
+	const topic = "some-topic"
+
+	newPubSub := func(sub subscriber, panicAction PanicAction) *PubSub {
+		p := &PubSub{
+			Lifecycle:            lifecycle.New("ampq"),
+			Config:               newTestConfig(t, Config{HandlerPanicAction: panicAction}),
+			connMgr:              &mockConnectionMgr{},
+			subscriber:           sub,
+			publisher:            newMockPublisher(),
+			waitSubscriber:       &mockSubscriber{mockClosable: &mockClosable{}},
+			waitPublisher:        newMockPublisher(),
+			redeliverySubscriber: &mockSubscriber{mockClosable: &mockClosable{}},
+		}
+
+		p.Start()
+
+		return p
+	}
+
+	handler := func(msg *message.Message) {
+		if string(msg.Payload) == "panic" {
+			panic("injected panic")
+		}
+
+		msg.Ack()
+	}
+
+	waitFor := func(t *testing.T, c <-chan struct{}) {
+		t.Helper()
+
+		select {
+		case <-c:
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for message to be acked/nacked")
+		}
+	}
+
+	nackOnPanic := func(t *testing.T, opts ...spi.Option) {
+		t.Helper()
+
+		sub := &chanSubscriber{mockClosable: &mockClosable{}, msgChan: make(chan *message.Message, 10)}
+
+		p := newPubSub(sub, "")
+
+		require.NoError(t, p.SubscribeWithHandler(context.Background(), topic, handler, opts...))
+
+		panicMsg := message.NewMessage(watermill.NewUUID(), []byte("panic"))
+		sub.msgChan <- panicMsg
+
+		waitFor(t, panicMsg.Nacked())
+
+		// The subscriber keeps consuming after the panic.
+		for i := 0; i < 5; i++ {
+			msg := message.NewMessage(watermill.NewUUID(), []byte("payload"))
+			sub.msgChan <- msg
+
+			waitFor(t, msg.Acked())
+		}
+
+		close(sub.msgChan)
+		p.stop()
+	}
+
+	t.Run("Nack on panic", func(t *testing.T) {
+		nackOnPanic(t)
+	})
+
+	t.Run("Nack on panic with pool", func(t *testing.T) {
+		nackOnPanic(t, spi.WithPool(3))
+	})
+
+	t.Run("Ack on panic", func(t *testing.T) {
+		sub := &chanSubscriber{mockClosable: &mockClosable{}, msgChan: make(chan *message.Message, 10)}
+
+		p := newPubSub(sub, PanicActionAck)
+		defer p.stop()
+
+		require.NoError(t, p.SubscribeWithHandler(context.Background(), topic, handler))
+
+		panicMsg := message.NewMessage(watermill.NewUUID(), []byte("panic"))
+		sub.msgChan <- panicMsg
+
+		waitFor(t, panicMsg.Acked())
+
+		close(sub.msgChan)
+	})
+
+	t.Run("Subscribe error", func(t *testing.T) {
+		errExpected := errors.New("injected subscribe error")
+
+		p := newPubSub(&mockSubscriber{err: errExpected, mockClosable: &mockClosable{}}, "")
+		defer p.stop()
+
+		require.ErrorIs(t, p.SubscribeWithHandler(context.Background(), topic, handler), errExpected)
+	})
+}
@@ -173,6 +173,102 @@ func TestDefaultMarshaler_metadata(t *testing.T) {
 	assert.Equal(t, marshaled.ContentType, "application/json")
 }
 
+func TestCustomMarshaler(t *testing.T) {
+	t.Run("default marshaler", func(t *testing.T) {
+		cfg := newQueueConfig(Config{})
+		require.IsType(t, &DefaultMarshaler{}, cfg.Marshaler)
+	})
+
+	t.Run("CloudEvents marshaler", func(t *testing.T) {
+		marshaler := &cloudEventsMarshaler{source: "https://orb.domain1.com"}
+
+		require.Equal(t, marshaler, newQueueConfig(Config{Marshaler: marshaler}).Marshaler)
+		require.Equal(t, marshaler, newRedeliveryQueueConfig(Config{Marshaler: marshaler}).Marshaler)
+		require.Equal(t, marshaler, newWaitQueueConfig(Config{Marshaler: marshaler}).Marshaler)
+
+		msg := message.NewMessage(watermill.NewUUID(), []byte("payload"))
+		msg.Metadata.Set("foo", "bar")
+		msg.Metadata.Set(metadataRedeliveryCount, "2")
+		msg.Metadata.Set("x-death",
+			`[{"count":1,"exchange":"some_exchange","queue":"some_queue","reason":"rejected","routing-keys":["some_queue"],"time":"2021-10-25T17:26:24Z"}]`) //nolint:lll
+
+		marshaled, err := marshaler.Marshal(msg)
+		require.NoError(t, err)
+		require.Equal(t, cloudEventsContentType, marshaled.ContentType)
+
+		event := &cloudEvent{}
+		require.NoError(t, json.Unmarshal(marshaled.Body, event))
+		require.Equal(t, msg.UUID, event.ID)
+		require.Equal(t, "https://orb.domain1.com", event.Source)
+		require.Equal(t, []byte("payload"), event.Data)
+
+		unmarshaledMsg, err := marshaler.Unmarshal(publishingToDelivery(&marshaled))
+		require.NoError(t, err)
+		require.True(t, msg.Equals(unmarshaledMsg))
+		require.Equal(t, 2, getRedeliveryAttempts(unmarshaledMsg))
+	})
+
+	t.Run("CloudEvents marshaler - invalid envelope", func(t *testing.T) {
+		marshaler := &cloudEventsMarshaler{}
+
+		_, err := marshaler.Unmarshal(ramqp.Delivery{Body: []byte("{")})
+		require.Error(t, err)
+	})
+}
+
+const cloudEventsContentType = "application/cloudevents+json"
+
+type cloudEvent struct {
+	SpecVersion string `json:"specversion"`
+	ID          string `json:"id"`
+	Source      string `json:"source"`
+	Type        string `json:"type"`
+	Data        []byte `json:"data_base64"`
+}
+
+// cloudEventsMarshaler wraps the message payload in a (structured mode) CloudEvents envelope. Metadata is
+// carried in the AMQP headers by the embedded DefaultMarshaler.
+type cloudEventsMarshaler struct {
+	DefaultMarshaler
+
+	source string
+}
+
+func (m *cloudEventsMarshaler) Marshal(msg *message.Message) (ramqp.Publishing, error) {
+	publishing, err := m.DefaultMarshaler.Marshal(msg)
+	if err != nil {
+		return ramqp.Publishing{}, err
+	}
+
+	publishing.Body, err = json.Marshal(&cloudEvent{
+		SpecVersion: "1.0",
+		ID:          msg.UUID,
+		Source:      m.source,
+		Type:        "orb.message",
+		Data:        msg.Payload,
+	})
+	if err != nil {
+		return ramqp.Publishing{}, err
+	}
+
+	publishing.ContentType = cloudEventsContentType
+
+	return publishing, nil
+}
+
+//nolint:gocritic
+func (m *cloudEventsMarshaler) Unmarshal(amqpMsg ramqp.Delivery) (*message.Message, error) {
+	event := &cloudEvent{}
+
+	if err := json.Unmarshal(amqpMsg.Body, event); err != nil {
+		return nil, err
+	}
+
+	amqpMsg.Body = event.Data
+
+	return m.DefaultMarshaler.Unmarshal(amqpMsg)
+}
+
 func publishingToDelivery(marshaled *ramqp.Publishing) ramqp.Delivery {
 	return ramqp.Delivery{
 		Body:    marshaled.Body,
@@ -0,0 +1,53 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package amqp
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+
+	"github.com/trustbloc/orb/internal/pkg/log"
+)
+
+// ErrMessageTooLarge is returned from Publish if the size of a message exceeds Config.MaxMessageSize.
+var ErrMessageTooLarge = errors.New("message too large")
+
+// checkMessageSize returns an error that wraps ErrMessageTooLarge if the size of any of the given messages
+// exceeds the maximum message size. This error is not transient since retrying won't help.
+func (p *PubSub) checkMessageSize(topic string, messages ...*message.Message) error {
+	if p.MaxMessageSize <= 0 {
+		return nil
+	}
+
+	for _, msg := range messages {
+		size := messageSize(msg)
+
+		if size > p.MaxMessageSize {
+			logger.Error("Message exceeds the maximum message size and won't be published",
+				log.WithMessageID(msg.UUID), log.WithTopic(topic), log.WithSize(size),
+				log.WithMaxSize(p.MaxMessageSize))
+
+			return fmt.Errorf("%w: message [%s] of size %d exceeds the maximum size of %d bytes",
+				ErrMessageTooLarge, msg.UUID, size, p.MaxMessageSize)
+		}
+	}
+
+	return nil
+}
+
+// messageSize returns the size of the message payload plus the size of the metadata keys and values.
+func messageSize(msg *message.Message) int {
+	size := len(msg.Payload)
+
+	for k, v := range msg.Metadata {
+		size += len(k) + len(v)
+	}
+
+	return size
+}
@@ -0,0 +1,108 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package amqp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/errors"
+	"github.com/trustbloc/orb/pkg/lifecycle"
+	"github.com/trustbloc/orb/pkg/pubsub/spi"
+)
+
+func TestPubSub_MaxMessageSize(t *testing.T) {
+	const (
+		topic          = "some-topic"
+		maxMessageSize = 100
+	)
+
+	newPubSub := func(pub, waitPub publisher, maxSize int) *PubSub {
+		p := &PubSub{
+			Lifecycle:            lifecycle.New("ampq"),
+			Config:               newTestConfig(t, Config{MaxMessageSize: maxSize}),
+			connMgr:              &mockConnectionMgr{},
+			subscriber:           &mockSubscriber{mockClosable: &mockClosable{}},
+			publisher:            pub,
+			waitSubscriber:       &mockSubscriber{mockClosable: &mockClosable{}},
+			waitPublisher:        waitPub,
+			redeliverySubscriber: &mockSubscriber{mockClosable: &mockClosable{}},
+		}
+
+		p.Start()
+
+		return p
+	}
+
+	newMsg := func(payloadSize int) *message.Message {
+		return message.NewMessage(watermill.NewUUID(), []byte(strings.Repeat("x", payloadSize)))
+	}
+
+	t.Run("Within limit", func(t *testing.T) {
+		pub := newMockPublisher()
+
+		p := newPubSub(pub, newMockPublisher(), maxMessageSize)
+		defer p.stop()
+
+		require.NoError(t, p.Publish(topic, newMsg(maxMessageSize)))
+		require.Len(t, pub.publishedTo(topic), 1)
+	})
+
+	t.Run("Payload too large", func(t *testing.T) {
+		pub := newMockPublisher()
+
+		p := newPubSub(pub, newMockPublisher(), maxMessageSize)
+		defer p.stop()
+
+		err := p.Publish(topic, newMsg(10), newMsg(maxMessageSize+1))
+		require.ErrorIs(t, err, ErrMessageTooLarge)
+		require.False(t, errors.IsTransient(err))
+		require.Contains(t, err.Error(), "exceeds the maximum size of 100 bytes")
+
+		// No messages in the batch should have been published.
+		require.Zero(t, pub.numPublished())
+	})
+
+	t.Run("Metadata counts toward size", func(t *testing.T) {
+		pub := newMockPublisher()
+
+		p := newPubSub(pub, newMockPublisher(), maxMessageSize)
+		defer p.stop()
+
+		msg := newMsg(maxMessageSize - 10)
+		msg.Metadata.Set("some-key", "some-value")
+
+		require.ErrorIs(t, p.Publish(topic, msg), ErrMessageTooLarge)
+		require.Zero(t, pub.numPublished())
+	})
+
+	t.Run("Publish with delivery delay", func(t *testing.T) {
+		waitPub := newMockPublisher()
+
+		p := newPubSub(newMockPublisher(), waitPub, maxMessageSize)
+		defer p.stop()
+
+		err := p.PublishWithOpts(topic, newMsg(maxMessageSize+1), spi.WithDeliveryDelay(time.Second))
+		require.ErrorIs(t, err, ErrMessageTooLarge)
+		require.Zero(t, waitPub.numPublished())
+	})
+
+	t.Run("Size not checked", func(t *testing.T) {
+		pub := newMockPublisher()
+
+		p := newPubSub(pub, newMockPublisher(), 0)
+		defer p.stop()
+
+		require.NoError(t, p.Publish(topic, newMsg(10*maxMessageSize)))
+		require.Len(t, pub.publishedTo(topic), 1)
+	})
+}
@@ -10,6 +10,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"time"
 
 	"github.com/ThreeDotsLabs/watermill/message"
 
@@ -17,23 +18,35 @@ import (
 )
 
 // pooledSubscriber manages a pool of subscriptions. Each subscription listens on a topic and forwards
-// the message to a Go channel that is consumed by the subscriber.
+// the message to a Go channel that is consumed by the subscriber. If maxConcurrency is set then no more
+// than maxConcurrency messages are forwarded before they are acked/nacked. If processingTimeout is set then
+// a message that isn't acked/nacked within the timeout is nacked (so that it's redelivered) in order to free
+// the subscription.
 type pooledSubscriber struct {
-	topic       string
-	msgChan     chan *message.Message
-	subscribers []reflect.SelectCase
-	logger      *log.StructuredLog
+	topic             string
+	msgChan           chan *message.Message
+	subscribers       []reflect.SelectCase
+	logger            *log.StructuredLog
+	semaphore         chan struct{}
+	processingTimeout time.Duration
+	done              chan struct{}
 }
 
-func newPooledSubscriber(ctx context.Context, size int, subscriber subscriber,
-	topic string) (*pooledSubscriber, error) {
+func newPooledSubscriber(ctx context.Context, size, maxConcurrency int, processingTimeout time.Duration,
+	subscriber subscriber, topic string) (*pooledSubscriber, error) {
 	l := log.NewStructured(loggerModule, log.WithFields(log.WithTopic(topic)))
 
 	p := &pooledSubscriber{
-		topic:       topic,
-		msgChan:     make(chan *message.Message, size),
-		subscribers: make([]reflect.SelectCase, size),
-		logger:      l,
+		topic:             topic,
+		msgChan:           make(chan *message.Message, size),
+		subscribers:       make([]reflect.SelectCase, size),
+		logger:            l,
+		processingTimeout: processingTimeout,
+		done:              make(chan struct{}),
+	}
+
+	if maxConcurrency > 0 {
+		p.semaphore = make(chan struct{}, maxConcurrency)
 	}
 
 	for i := 0; i < size; i++ {
@@ -68,7 +81,13 @@ func (s *pooledSubscriber) start() {
 
 			logger.Debug("Pool subscriber got message", log.WithIndex(i), log.WithMessageID(msg.UUID))
 
+			if !s.acquire() {
+				return
+			}
+
 			s.msgChan <- msg
+
+			s.monitor(msg)
 		}
 	}()
 }
@@ -76,5 +95,60 @@ func (s *pooledSubscriber) start() {
 func (s *pooledSubscriber) stop() {
 	logger.Info("Closing pooled subscriber")
 
+	close(s.done)
 	close(s.msgChan)
 }
+
+// acquire blocks until the message may be processed without exceeding the maximum concurrency. The acquired
+// slot is released (see monitor) when the message is acked or nacked. False is returned if the subscriber was stopped.
+func (s *pooledSubscriber) acquire() bool {
+	if s.semaphore == nil {
+		return true
+	}
+
+	select {
+	case s.semaphore <- struct{}{}:
+		return true
+	case <-s.done:
+		return false
+	}
+}
+
+// monitor waits (in the background) for the given message to be acked or nacked and then releases the slot that
+// was acquired for the message (if any). If the message isn't acked/nacked within the processing timeout then the
+// message is nacked so that it's redelivered.
+func (s *pooledSubscriber) monitor(msg *message.Message) {
+	if s.semaphore == nil && s.processingTimeout <= 0 {
+		return
+	}
+
+	go func() {
+		defer s.release()
+
+		var timeout <-chan time.Time
+
+		if s.processingTimeout > 0 {
+			timer := time.NewTimer(s.processingTimeout)
+			defer timer.Stop()
+
+			timeout = timer.C
+		}
+
+		select {
+		case <-msg.Acked():
+		case <-msg.Nacked():
+		case <-s.done:
+		case <-timeout:
+			s.logger.Warn("Message was not acked or nacked within the processing timeout. The message will be nacked "+
+				"so that it's redelivered.", log.WithMessageID(msg.UUID), log.WithTimeout(s.processingTimeout))
+
+			msg.Nack()
+		}
+	}()
+}
+
+func (s *pooledSubscriber) release() {
+	if s.semaphore != nil {
+		<-s.semaphore
+	}
+}
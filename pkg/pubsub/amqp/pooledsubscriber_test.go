@@ -9,9 +9,12 @@ package amqp
 import (
 	"context"
 	"errors"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/ThreeDotsLabs/watermill"
 	"github.com/ThreeDotsLabs/watermill/message"
 	"github.com/stretchr/testify/require"
 
@@ -28,7 +31,7 @@ func TestPooledSubscriber(t *testing.T) {
 
 		s.SubscribeReturns(nil, errExpected)
 
-		_, err := newPooledSubscriber(context.Background(), 10, s, topic)
+		_, err := newPooledSubscriber(context.Background(), 10, 0, 0, s, topic)
 		require.Error(t, err)
 		require.Contains(t, err.Error(), errExpected.Error())
 	})
@@ -39,7 +42,7 @@ func TestPooledSubscriber(t *testing.T) {
 		pubSub := &mocks.PubSub{}
 		pubSub.SubscribeReturns(msgChan, nil)
 
-		ps, err := newPooledSubscriber(context.Background(), 10, pubSub, topic)
+		ps, err := newPooledSubscriber(context.Background(), 10, 0, 0, pubSub, topic)
 		require.NoError(t, err)
 		require.NotNil(t, ps)
 
@@ -49,4 +52,134 @@ func TestPooledSubscriber(t *testing.T) {
 
 		ps.stop()
 	})
+	t.Run("Max concurrency", func(t *testing.T) {
+		const (
+			numMessages    = 10
+			maxConcurrency = 2
+		)
+
+		msgChan := make(chan *message.Message, numMessages)
+
+		pubSub := &mocks.PubSub{}
+		pubSub.SubscribeReturns(msgChan, nil)
+
+		ps, err := newPooledSubscriber(context.Background(), 10, maxConcurrency, 0, pubSub, topic)
+		require.NoError(t, err)
+		require.NotNil(t, ps)
+
+		ps.start()
+		defer ps.stop()
+
+		for i := 0; i < numMessages; i++ {
+			msgChan <- message.NewMessage(watermill.NewUUID(), nil)
+		}
+
+		var (
+			current   int32
+			maxActive int32
+			wg        sync.WaitGroup
+		)
+
+		wg.Add(numMessages)
+
+		go func() {
+			for msg := range ps.msgChan {
+				go func(msg *message.Message) {
+					defer wg.Done()
+
+					n := atomic.AddInt32(&current, 1)
+
+					for {
+						m := atomic.LoadInt32(&maxActive)
+						if n <= m || atomic.CompareAndSwapInt32(&maxActive, m, n) {
+							break
+						}
+					}
+
+					time.Sleep(20 * time.Millisecond)
+
+					atomic.AddInt32(&current, -1)
+
+					msg.Ack()
+				}(msg)
+			}
+		}()
+
+		wg.Wait()
+
+		require.LessOrEqual(t, atomic.LoadInt32(&maxActive), int32(maxConcurrency))
+		require.Equal(t, int32(maxConcurrency), atomic.LoadInt32(&maxActive))
+	})
+	t.Run("Processing timeout", func(t *testing.T) {
+		const (
+			numMessages       = 3
+			maxConcurrency    = 1
+			processingTimeout = 50 * time.Millisecond
+		)
+
+		msgChan := make(chan *message.Message, numMessages)
+
+		pubSub := &mocks.PubSub{}
+		pubSub.SubscribeReturns(msgChan, nil)
+
+		ps, err := newPooledSubscriber(context.Background(), 1, maxConcurrency, processingTimeout, pubSub, topic)
+		require.NoError(t, err)
+
+		ps.start()
+		defer ps.stop()
+
+		var msgs []*message.Message
+
+		for i := 0; i < numMessages; i++ {
+			msg := message.NewMessage(watermill.NewUUID(), nil)
+
+			msgs = append(msgs, msg)
+			msgChan <- msg
+		}
+
+		// The handler never acks/nacks the messages. Each message should be nacked after the timeout, which frees
+		// up the slot for the next message.
+		for i := 0; i < numMessages; i++ {
+			select {
+			case msg := <-ps.msgChan:
+				require.Equal(t, msgs[i].UUID, msg.UUID)
+			case <-time.After(time.Second):
+				t.Fatalf("expecting message %d to be delivered after the previous message timed out", i)
+			}
+		}
+
+		for _, msg := range msgs {
+			select {
+			case <-msg.Nacked():
+			case <-time.After(time.Second):
+				t.Fatal("expecting message to be nacked after the processing timeout")
+			}
+		}
+	})
+
+	t.Run("Acked within processing timeout", func(t *testing.T) {
+		msgChan := make(chan *message.Message, 1)
+
+		pubSub := &mocks.PubSub{}
+		pubSub.SubscribeReturns(msgChan, nil)
+
+		ps, err := newPooledSubscriber(context.Background(), 1, 0, 50*time.Millisecond, pubSub, topic)
+		require.NoError(t, err)
+
+		ps.start()
+		defer ps.stop()
+
+		msgChan <- message.NewMessage(watermill.NewUUID(), nil)
+
+		msg := <-ps.msgChan
+		require.True(t, msg.Ack())
+
+		time.Sleep(100 * time.Millisecond)
+
+		select {
+		case <-msg.Nacked():
+			t.Fatal("message should not be nacked after it was acked")
+		default:
+		}
+	})
 }
@@ -0,0 +1,71 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package amqp
+
+import (
+	"errors"
+)
+
+// PublishLimitAction specifies what to do when a message is published and the maximum number of publishes
+// are already in flight (see Config.MaxInFlightPublishes).
+type PublishLimitAction string
+
+const (
+	// PublishLimitActionBlock waits for an in-flight publish to complete. This is the default.
+	PublishLimitActionBlock PublishLimitAction = "block"
+	// PublishLimitActionError immediately returns a transient error that wraps ErrPublishLimitReached.
+	PublishLimitActionError PublishLimitAction = "error"
+)
+
+// ErrPublishLimitReached is returned by Publish if the maximum number of publishes are in flight
+// (when Config.PublishLimitAction is PublishLimitActionError).
+var ErrPublishLimitReached = errors.New("in-flight publish limit reached")
+
+// publishLimiter limits the number of concurrent in-flight publishes. A nil limiter doesn't impose a limit.
+type publishLimiter struct {
+	slots  chan struct{}
+	action PublishLimitAction
+}
+
+func newPublishLimiter(limit int, action PublishLimitAction) *publishLimiter {
+	if limit <= 0 {
+		return nil
+	}
+
+	return &publishLimiter{
+		slots:  make(chan struct{}, limit),
+		action: action,
+	}
+}
+
+// acquire acquires a slot for a publish. The slot must be released with release once the publish completes.
+func (l *publishLimiter) acquire() error {
+	if l == nil {
+		return nil
+	}
+
+	if l.action == PublishLimitActionError {
+		select {
+		case l.slots <- struct{}{}:
+			return nil
+		default:
+			return ErrPublishLimitReached
+		}
+	}
+
+	l.slots <- struct{}{}
+
+	return nil
+}
+
+func (l *publishLimiter) release() {
+	if l == nil {
+		return
+	}
+
+	<-l.slots
+}
@@ -0,0 +1,175 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package amqp
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/require"
+
+	orberrors "github.com/trustbloc/orb/pkg/errors"
+	"github.com/trustbloc/orb/pkg/lifecycle"
+	"github.com/trustbloc/orb/pkg/pubsub/spi"
+)
+
+func TestPubSub_MaxInFlightPublishes(t *testing.T) {
+	const topic = "topic1"
+
+	newPubSub := func(cfg Config, pub publisher) *PubSub {
+		cfg = newTestConfig(t, cfg)
+
+		p := &PubSub{
+			Lifecycle:            lifecycle.New("ampq"),
+			Config:               cfg,
+			connMgr:              &mockConnectionMgr{},
+			subscriber:           &mockSubscriber{mockClosable: &mockClosable{}},
+			publisher:            pub,
+			waitSubscriber:       &mockSubscriber{mockClosable: &mockClosable{}},
+			waitPublisher:        pub,
+			redeliverySubscriber: &mockSubscriber{mockClosable: &mockClosable{}},
+			publishLimiter:       newPublishLimiter(cfg.MaxInFlightPublishes, cfg.PublishLimitAction),
+		}
+
+		p.Start()
+
+		return p
+	}
+
+	t.Run("Block", func(t *testing.T) {
+		const (
+			maxInFlight = 3
+			n           = 100
+		)
+
+		pub := newConcurrencyCountingPublisher(5 * time.Millisecond)
+
+		p := newPubSub(Config{MaxInFlightPublishes: maxInFlight}, pub)
+		defer p.stop()
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+
+				msg := message.NewMessage(watermill.NewUUID(), []byte("payload"))
+
+				if i%2 == 0 {
+					require.NoError(t, p.Publish(topic, msg))
+				} else {
+					require.NoError(t, p.PublishWithOpts(topic, msg, spi.WithDeliveryDelay(time.Second)))
+				}
+			}(i)
+		}
+
+		wg.Wait()
+
+		require.Equal(t, int32(n), pub.published())
+		require.LessOrEqual(t, pub.maxInFlight(), int32(maxInFlight))
+		require.Equal(t, int32(maxInFlight), pub.maxInFlight())
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		pub := newConcurrencyCountingPublisher(0)
+		pub.block = make(chan struct{})
+
+		p := newPubSub(Config{MaxInFlightPublishes: 1, PublishLimitAction: PublishLimitActionError}, pub)
+		defer p.stop()
+
+		errChan := make(chan error)
+
+		go func() {
+			errChan <- p.Publish(topic, message.NewMessage(watermill.NewUUID(), []byte("payload")))
+		}()
+
+		require.Eventually(t, func() bool { return pub.inFlightCount() == 1 }, time.Second, time.Millisecond)
+
+		err := p.Publish(topic, message.NewMessage(watermill.NewUUID(), []byte("payload")))
+		require.True(t, errors.Is(err, ErrPublishLimitReached))
+		require.True(t, orberrors.IsTransient(err))
+
+		err = p.PublishWithOpts(topic, message.NewMessage(watermill.NewUUID(), []byte("payload")),
+			spi.WithDeliveryDelay(time.Second))
+		require.True(t, errors.Is(err, ErrPublishLimitReached))
+
+		close(pub.block)
+
+		require.NoError(t, <-errChan)
+
+		// The slot was released.
+		require.NoError(t, p.Publish(topic, message.NewMessage(watermill.NewUUID(), []byte("payload"))))
+		require.Equal(t, int32(2), pub.published())
+	})
+
+	t.Run("Unlimited (default)", func(t *testing.T) {
+		p := newPubSub(Config{}, newMockPublisher())
+		defer p.stop()
+
+		require.Nil(t, p.publishLimiter)
+		require.NoError(t, p.Publish(topic, message.NewMessage(watermill.NewUUID(), []byte("payload"))))
+	})
+}
+
+// concurrencyCountingPublisher records the maximum number of concurrent calls to Publish.
+type concurrencyCountingPublisher struct {
+	*mockClosable
+
+	delay    time.Duration
+	block    chan struct{}
+	inFlight int32
+	max      int32
+	count    int32
+}
+
+func newConcurrencyCountingPublisher(delay time.Duration) *concurrencyCountingPublisher {
+	return &concurrencyCountingPublisher{
+		mockClosable: &mockClosable{},
+		delay:        delay,
+	}
+}
+
+func (m *concurrencyCountingPublisher) Publish(string, ...*message.Message) error {
+	n := atomic.AddInt32(&m.inFlight, 1)
+	defer atomic.AddInt32(&m.inFlight, -1)
+
+	for {
+		current := atomic.LoadInt32(&m.max)
+		if n <= current || atomic.CompareAndSwapInt32(&m.max, current, n) {
+			break
+		}
+	}
+
+	if m.block != nil {
+		<-m.block
+	}
+
+	time.Sleep(m.delay)
+
+	atomic.AddInt32(&m.count, 1)
+
+	return nil
+}
+
+func (m *concurrencyCountingPublisher) inFlightCount() int32 {
+	return atomic.LoadInt32(&m.inFlight)
+}
+
+func (m *concurrencyCountingPublisher) maxInFlight() int32 {
+	return atomic.LoadInt32(&m.max)
+}
+
+func (m *concurrencyCountingPublisher) published() int32 {
+	return atomic.LoadInt32(&m.count)
+}
@@ -0,0 +1,186 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package amqp
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill-amqp/v2/pkg/amqp"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/lifecycle"
+)
+
+func TestPubSub_RedeliveryPoolSize(t *testing.T) {
+	const (
+		topic       = "topic1"
+		numMessages = 20
+	)
+
+	newPubSub := func(poolSize int, redeliverySubscriber initializingSubscriber, pub publisher) *PubSub {
+		p := &PubSub{
+			Config:  newTestConfig(t, Config{RedeliveryPoolSize: poolSize}),
+			connMgr: &mockConnectionMgr{},
+			subscriberFactory: func(connection) (initializingSubscriber, error) {
+				return &mockSubscriber{mockClosable: &mockClosable{}}, nil
+			},
+			redeliverySubscriberFactory: func(connection) (initializingSubscriber, error) {
+				return redeliverySubscriber, nil
+			},
+			waitSubscriberFactory: func(connection) (initializingSubscriber, error) {
+				return &mockSubscriber{mockClosable: &mockClosable{}}, nil
+			},
+			createPublisher: func(*amqp.Config, connection) (publisher, error) {
+				return pub, nil
+			},
+			createWaitPublisher: func(connection) (publisher, error) {
+				return pub, nil
+			},
+		}
+
+		p.Lifecycle = lifecycle.New("amqp", lifecycle.WithStart(p.start), lifecycle.WithStop(p.stop))
+
+		p.Start()
+
+		return p
+	}
+
+	redeliver := func(t *testing.T, sub *channelSubscriber, n int) {
+		t.Helper()
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < n; i++ {
+			msg := message.NewMessage(watermill.NewUUID(), []byte("payload"))
+			msg.Metadata.Set(metadataQueue, topic)
+
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+
+				sub.send(redeliveryQueue, i, msg)
+			}(i)
+		}
+
+		wg.Wait()
+	}
+
+	t.Run("Default pool size", func(t *testing.T) {
+		sub := newChannelSubscriber()
+		pub := newConcurrencyCountingPublisher(5 * time.Millisecond)
+
+		p := newPubSub(0, sub, pub)
+		defer p.Stop()
+
+		require.Equal(t, 1, sub.subscriptions(redeliveryQueue))
+
+		redeliver(t, sub, numMessages)
+
+		require.Eventually(t, func() bool { return pub.published() == numMessages }, 5*time.Second, 10*time.Millisecond)
+		require.Equal(t, int32(1), pub.maxInFlight())
+	})
+
+	t.Run("Pool size honored", func(t *testing.T) {
+		const poolSize = 4
+
+		sub := newChannelSubscriber()
+		pub := newConcurrencyCountingPublisher(20 * time.Millisecond)
+
+		p := newPubSub(poolSize, sub, pub)
+		defer p.Stop()
+
+		require.Equal(t, poolSize, sub.subscriptions(redeliveryQueue))
+
+		redeliver(t, sub, numMessages)
+
+		require.Eventually(t, func() bool { return pub.published() == numMessages }, 5*time.Second, 10*time.Millisecond)
+		require.Greater(t, pub.maxInFlight(), int32(1))
+		require.LessOrEqual(t, pub.maxInFlight(), int32(poolSize))
+	})
+}
+
+// channelSubscriber creates a new Go channel for each subscription. Messages may be sent to any of the
+// subscriptions for a topic.
+type channelSubscriber struct {
+	*mockClosable
+
+	mutex    sync.Mutex
+	channels map[string][]chan *message.Message
+	err      error
+}
+
+func newChannelSubscriber() *channelSubscriber {
+	return &channelSubscriber{
+		mockClosable: &mockClosable{},
+		channels:     make(map[string][]chan *message.Message),
+	}
+}
+
+func (m *channelSubscriber) Subscribe(_ context.Context, topic string) (<-chan *message.Message, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	msgChan := make(chan *message.Message)
+
+	m.channels[topic] = append(m.channels[topic], msgChan)
+
+	return msgChan, nil
+}
+
+func (m *channelSubscriber) SubscribeInitialize(string) error {
+	return nil
+}
+
+func (m *channelSubscriber) subscriptions(topic string) int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return len(m.channels[topic])
+}
+
+// send sends the given message to the i'th subscription (modulo the number of subscriptions) of the given topic.
+func (m *channelSubscriber) send(topic string, i int, msg *message.Message) {
+	m.mutex.Lock()
+	channels := m.channels[topic]
+	m.mutex.Unlock()
+
+	channels[i%len(channels)] <- msg
+}
+
+func (m *channelSubscriber) setError(err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.err = err
+}
+
+// closeSubscriptions closes the channels of all current subscriptions to the given topic (as the AMQP subscriber
+// does when the broker closes the connection) and returns the number of channels that were closed.
+func (m *channelSubscriber) closeSubscriptions(topic string) int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	channels := m.channels[topic]
+
+	for _, msgChan := range channels {
+		close(msgChan)
+	}
+
+	m.channels[topic] = nil
+
+	return len(channels)
+}
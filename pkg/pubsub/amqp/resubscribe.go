@@ -0,0 +1,137 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package amqp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/cenkalti/backoff"
+
+	"github.com/trustbloc/orb/internal/pkg/log"
+)
+
+// resubscribingSubscriber wraps a subscriber and re-establishes a subscription if the underlying subscription is
+// closed while it's still active, for example if the broker closes the channel or connection during a rolling
+// restart. Messages are forwarded to the Go channel that was returned to the caller, so the caller continues
+// to consume from the same channel and doesn't need to re-subscribe. Re-subscribing is retried with backoff
+// up to the maximum number of connect retries, after which the caller's channel is closed.
+type resubscribingSubscriber struct {
+	subscriber
+
+	maxRetries int
+	stopped    <-chan struct{}
+	notifyErr  func(topic string, err error)
+}
+
+func newResubscribingSubscriber(s subscriber, maxRetries int, stopped <-chan struct{},
+	notifyErr func(topic string, err error)) *resubscribingSubscriber {
+	return &resubscribingSubscriber{
+		subscriber: s,
+		maxRetries: maxRetries,
+		stopped:    stopped,
+		notifyErr:  notifyErr,
+	}
+}
+
+func (s *resubscribingSubscriber) Subscribe(ctx context.Context, topic string) (<-chan *message.Message, error) {
+	msgChan, err := s.subscriber.Subscribe(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan *message.Message)
+
+	go s.forward(ctx, topic, msgChan, out)
+
+	return out, nil
+}
+
+func (s *resubscribingSubscriber) forward(ctx context.Context, topic string, msgChan <-chan *message.Message,
+	out chan<- *message.Message) {
+	defer close(out)
+
+	for {
+		for msg := range msgChan {
+			select {
+			case out <- msg:
+			case <-ctx.Done():
+				msg.Nack()
+
+				return
+			case <-s.stopped:
+				msg.Nack()
+
+				return
+			}
+		}
+
+		if !s.isActive(ctx) {
+			return
+		}
+
+		logger.Warn("Subscription to topic was closed unexpectedly. Re-subscribing...", log.WithTopic(topic))
+
+		var err error
+
+		msgChan, err = s.resubscribe(ctx, topic)
+		if err != nil {
+			logger.Error("Unable to re-subscribe to topic. The subscription will be closed.",
+				log.WithTopic(topic), log.WithMaxRetries(s.maxRetries), log.WithError(err))
+
+			if s.notifyErr != nil {
+				s.notifyErr(topic, fmt.Errorf("re-subscribe to topic [%s]: %w", topic, err))
+			}
+
+			return
+		}
+
+		logger.Info("Successfully re-subscribed to topic", log.WithTopic(topic))
+	}
+}
+
+func (s *resubscribingSubscriber) resubscribe(ctx context.Context, topic string) (<-chan *message.Message, error) {
+	var msgChan <-chan *message.Message
+
+	err := backoff.RetryNotify(
+		func() error {
+			if !s.isActive(ctx) {
+				return backoff.Permanent(fmt.Errorf("subscription to topic [%s] is no longer active", topic))
+			}
+
+			var e error
+
+			msgChan, e = s.subscriber.Subscribe(ctx, topic)
+
+			return e
+		},
+		backoff.WithContext(backoff.WithMaxRetries(newConnectBackOff(), uint64(s.maxRetries)), ctx),
+		func(err error, duration time.Duration) {
+			logger.Debug("Error re-subscribing to topic. Will retry with backoff...",
+				log.WithTopic(topic), log.WithBackoff(duration), log.WithError(err))
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return msgChan, nil
+}
+
+// isActive returns false if the subscription's context is done or if the publisher/subscriber was stopped.
+func (s *resubscribingSubscriber) isActive(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-s.stopped:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,188 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package amqp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill-amqp/v2/pkg/amqp"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/lifecycle"
+	"github.com/trustbloc/orb/pkg/pubsub/spi"
+)
+
+func TestPubSub_Resubscribe(t *testing.T) {
+	const topic = "topic1"
+
+	newPubSub := func(sub *channelSubscriber, maxRetries int) *PubSub {
+		p := &PubSub{
+			Config:             newTestConfig(t, Config{MaxConnectRetries: maxRetries}),
+			connMgr:            &mockConnectionMgr{},
+			subscriptionErrors: newSubscriptionErrorNotifier(),
+			subscriberFactory: func(connection) (initializingSubscriber, error) {
+				return sub, nil
+			},
+			redeliverySubscriberFactory: func(connection) (initializingSubscriber, error) {
+				return &mockSubscriber{mockClosable: &mockClosable{}}, nil
+			},
+			waitSubscriberFactory: func(connection) (initializingSubscriber, error) {
+				return &mockSubscriber{mockClosable: &mockClosable{}}, nil
+			},
+			createPublisher: func(*amqp.Config, connection) (publisher, error) {
+				return newMockPublisher(), nil
+			},
+			createWaitPublisher: func(connection) (publisher, error) {
+				return newMockPublisher(), nil
+			},
+		}
+
+		p.Lifecycle = lifecycle.New("amqp", lifecycle.WithStart(p.start), lifecycle.WithStop(p.stop))
+
+		p.Start()
+
+		return p
+	}
+
+	receive := func(t *testing.T, sub *channelSubscriber, msgChan <-chan *message.Message, i int) {
+		t.Helper()
+
+		msg := message.NewMessage(watermill.NewUUID(), []byte("payload"))
+
+		go sub.send(topic, i, msg)
+
+		select {
+		case m, ok := <-msgChan:
+			require.True(t, ok)
+			require.Equal(t, msg.UUID, m.UUID)
+		case <-time.After(time.Second):
+			require.FailNow(t, "timed out waiting for message")
+		}
+	}
+
+	t.Run("Connection closed mid-consumption -> consumption resumes", func(t *testing.T) {
+		sub := newChannelSubscriber()
+
+		p := newPubSub(sub, 3)
+		defer p.Stop()
+
+		msgChan, err := p.Subscribe(context.Background(), topic)
+		require.NoError(t, err)
+
+		receive(t, sub, msgChan, 0)
+
+		require.Equal(t, 1, sub.closeSubscriptions(topic))
+
+		require.Eventually(t, func() bool {
+			return sub.subscriptions(topic) == 1
+		}, time.Second, 10*time.Millisecond)
+
+		// Messages are received on the original channel without re-subscribing manually.
+		receive(t, sub, msgChan, 0)
+	})
+
+	t.Run("Subscriber pool -> all subscriptions re-established", func(t *testing.T) {
+		const poolSize = 3
+
+		sub := newChannelSubscriber()
+
+		p := newPubSub(sub, 3)
+		defer p.Stop()
+
+		msgChan, err := p.SubscribeWithOpts(context.Background(), topic, spi.WithPool(poolSize))
+		require.NoError(t, err)
+
+		require.Equal(t, poolSize, sub.closeSubscriptions(topic))
+
+		require.Eventually(t, func() bool {
+			return sub.subscriptions(topic) == poolSize
+		}, time.Second, 10*time.Millisecond)
+
+		for i := 0; i < poolSize; i++ {
+			receive(t, sub, msgChan, i)
+		}
+	})
+
+	t.Run("Context done -> not re-subscribed", func(t *testing.T) {
+		sub := newChannelSubscriber()
+
+		p := newPubSub(sub, 3)
+		defer p.Stop()
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		msgChan, err := p.Subscribe(ctx, topic)
+		require.NoError(t, err)
+
+		cancel()
+
+		sub.closeSubscriptions(topic)
+
+		requireClosed(t, msgChan)
+		require.Zero(t, sub.subscriptions(topic))
+	})
+
+	t.Run("Stopped -> not re-subscribed", func(t *testing.T) {
+		sub := newChannelSubscriber()
+
+		p := newPubSub(sub, 3)
+
+		msgChan, err := p.Subscribe(context.Background(), topic)
+		require.NoError(t, err)
+
+		p.Stop()
+
+		sub.closeSubscriptions(topic)
+
+		requireClosed(t, msgChan)
+		require.Zero(t, sub.subscriptions(topic))
+	})
+
+	t.Run("Re-subscribe error -> subscription closed and error handler notified", func(t *testing.T) {
+		sub := newChannelSubscriber()
+
+		p := newPubSub(sub, 1)
+		defer p.Stop()
+
+		errChan := make(chan error, 1)
+
+		msgChan, err := p.SubscribeWithOpts(context.Background(), topic,
+			spi.WithErrorHandler(func(err error) {
+				errChan <- err
+			}),
+		)
+		require.NoError(t, err)
+
+		sub.setError(errors.New("injected subscribe error"))
+		sub.closeSubscriptions(topic)
+
+		requireClosed(t, msgChan)
+
+		select {
+		case err := <-errChan:
+			require.Contains(t, err.Error(), "injected subscribe error")
+		case <-time.After(time.Second):
+			require.FailNow(t, "timed out waiting for error notification")
+		}
+	})
+}
+
+func requireClosed(t *testing.T, msgChan <-chan *message.Message) {
+	t.Helper()
+
+	select {
+	case _, ok := <-msgChan:
+		require.False(t, ok)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timed out waiting for channel to be closed")
+	}
+}
@@ -0,0 +1,63 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package amqp
+
+import (
+	"context"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+
+	"github.com/trustbloc/orb/internal/pkg/log"
+	"github.com/trustbloc/orb/pkg/pubsub/spi"
+)
+
+// subscribeShared returns the Go channel of the existing subscription to the given topic or, if there is
+// no subscription, creates a new subscription and adds it to the per-topic registry.
+func (p *PubSub) subscribeShared(ctx context.Context, topic string,
+	options *spi.Options) (<-chan *message.Message, error) {
+	p.subscriptionsMutex.Lock()
+	defer p.subscriptionsMutex.Unlock()
+
+	if msgChan, ok := p.subscriptions[topic]; ok {
+		logger.Debug("Returning existing subscription to topic", log.WithTopic(topic))
+
+		return msgChan, nil
+	}
+
+	msgChan, err := p.subscribe(ctx, topic, options)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.subscriptions == nil {
+		p.subscriptions = make(map[string]<-chan *message.Message)
+	}
+
+	p.subscriptions[topic] = msgChan
+
+	// The subscription is closed when the context is done, so it may no longer be shared.
+	if done := ctx.Done(); done != nil {
+		go func() {
+			<-done
+
+			p.removeSharedSubscription(topic, msgChan)
+		}()
+	}
+
+	return msgChan, nil
+}
+
+func (p *PubSub) removeSharedSubscription(topic string, msgChan <-chan *message.Message) {
+	p.subscriptionsMutex.Lock()
+	defer p.subscriptionsMutex.Unlock()
+
+	if p.subscriptions[topic] == msgChan {
+		delete(p.subscriptions, topic)
+
+		logger.Debug("Removed shared subscription to topic", log.WithTopic(topic))
+	}
+}
@@ -0,0 +1,169 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package amqp
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/lifecycle"
+	"github.com/trustbloc/orb/pkg/pubsub/spi"
+)
+
+func TestPubSub_SharedTopicSubscriptions(t *testing.T) {
+	const (
+		topic1 = "topic1"
+		topic2 = "topic2"
+	)
+
+	newPubSub := func(sub subscriber, shared bool) *PubSub {
+		p := &PubSub{
+			Lifecycle:            lifecycle.New("ampq"),
+			Config:               newTestConfig(t, Config{SharedTopicSubscriptions: shared}),
+			connMgr:              &mockConnectionMgr{},
+			subscriber:           sub,
+			publisher:            newMockPublisher(),
+			waitSubscriber:       &mockSubscriber{mockClosable: &mockClosable{}},
+			waitPublisher:        newMockPublisher(),
+			redeliverySubscriber: &mockSubscriber{mockClosable: &mockClosable{}},
+		}
+
+		p.Start()
+
+		return p
+	}
+
+	t.Run("Shared", func(t *testing.T) {
+		sub := newCountingSubscriber()
+
+		p := newPubSub(sub, true)
+		defer p.stop()
+
+		msgChan1, err := p.Subscribe(context.Background(), topic1)
+		require.NoError(t, err)
+
+		msgChan2, err := p.Subscribe(context.Background(), topic1)
+		require.NoError(t, err)
+		require.Equal(t, msgChan1, msgChan2)
+
+		msgChan3, err := p.SubscribeWithOpts(context.Background(), topic1, spi.WithPool(2))
+		require.NoError(t, err)
+		require.Equal(t, msgChan1, msgChan3)
+
+		require.Equal(t, 1, sub.subscriptions(topic1))
+
+		msgChan4, err := p.Subscribe(context.Background(), topic2)
+		require.NoError(t, err)
+		require.NotEqual(t, msgChan1, msgChan4)
+		require.Equal(t, 1, sub.subscriptions(topic2))
+	})
+
+	t.Run("Force new subscription", func(t *testing.T) {
+		sub := newCountingSubscriber()
+
+		p := newPubSub(sub, true)
+		defer p.stop()
+
+		msgChan1, err := p.Subscribe(context.Background(), topic1)
+		require.NoError(t, err)
+
+		msgChan2, err := p.SubscribeWithOpts(context.Background(), topic1, spi.WithNewSubscription())
+		require.NoError(t, err)
+		require.NotEqual(t, msgChan1, msgChan2)
+		require.Equal(t, 2, sub.subscriptions(topic1))
+
+		// The forced subscription doesn't replace the shared subscription.
+		msgChan3, err := p.Subscribe(context.Background(), topic1)
+		require.NoError(t, err)
+		require.Equal(t, msgChan1, msgChan3)
+	})
+
+	t.Run("Context done -> subscription removed", func(t *testing.T) {
+		sub := newCountingSubscriber()
+
+		p := newPubSub(sub, true)
+		defer p.stop()
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		msgChan1, err := p.Subscribe(ctx, topic1)
+		require.NoError(t, err)
+
+		cancel()
+
+		require.Eventually(t, func() bool {
+			msgChan2, err := p.Subscribe(context.Background(), topic1)
+			require.NoError(t, err)
+
+			return msgChan2 != msgChan1
+		}, time.Second, 10*time.Millisecond)
+
+		require.Equal(t, 2, sub.subscriptions(topic1))
+	})
+
+	t.Run("Not shared (default)", func(t *testing.T) {
+		sub := newCountingSubscriber()
+
+		p := newPubSub(sub, false)
+		defer p.stop()
+
+		msgChan1, err := p.Subscribe(context.Background(), topic1)
+		require.NoError(t, err)
+
+		msgChan2, err := p.Subscribe(context.Background(), topic1)
+		require.NoError(t, err)
+		require.NotEqual(t, msgChan1, msgChan2)
+		require.Equal(t, 2, sub.subscriptions(topic1))
+	})
+}
+
+// countingSubscriber creates a new Go channel for each subscription (which is closed when the context is done)
+// and counts the number of subscriptions per topic.
+type countingSubscriber struct {
+	*mockClosable
+
+	mutex  sync.Mutex
+	counts map[string]int
+}
+
+func newCountingSubscriber() *countingSubscriber {
+	return &countingSubscriber{
+		mockClosable: &mockClosable{},
+		counts:       make(map[string]int),
+	}
+}
+
+func (m *countingSubscriber) Subscribe(ctx context.Context, topic string) (<-chan *message.Message, error) {
+	m.mutex.Lock()
+	m.counts[topic]++
+	m.mutex.Unlock()
+
+	msgChan := make(chan *message.Message)
+
+	go func() {
+		<-ctx.Done()
+		close(msgChan)
+	}()
+
+	return msgChan, nil
+}
+
+func (m *countingSubscriber) SubscribeInitialize(string) error {
+	return nil
+}
+
+func (m *countingSubscriber) subscriptions(topic string) int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.counts[topic]
+}
@@ -0,0 +1,63 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package amqp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+
+	"github.com/trustbloc/orb/internal/pkg/log"
+	"github.com/trustbloc/orb/pkg/pubsub/spi"
+)
+
+// SubscribeOnce subscribes to the given topic, waits for the first message to be delivered, acks the
+// message and then tears down the subscription. This is useful for admin tools that need to pull a single
+// message from a topic. The given context should have a timeout (or deadline) since this function blocks
+// until either a message is received or the context is done. A new subscription is always created, even if
+// SharedTopicSubscriptions is set.
+func (p *PubSub) SubscribeOnce(ctx context.Context, topic string) (*message.Message, error) {
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	msgChan, err := p.SubscribeWithOpts(subCtx, topic, spi.WithNewSubscription())
+	if err != nil {
+		return nil, fmt.Errorf("subscribe to topic [%s]: %w", topic, err)
+	}
+
+	// Any message that's delivered while the subscription is being torn down is nacked so that it's redelivered
+	// to another subscriber.
+	defer func() {
+		go drainAndNack(topic, msgChan)
+	}()
+
+	select {
+	case msg, ok := <-msgChan:
+		if !ok {
+			return nil, fmt.Errorf("subscription to topic [%s] was closed before a message was received", topic)
+		}
+
+		msg.Ack()
+
+		logger.Debug("Received one-shot message", log.WithTopic(topic), log.WithMessageID(msg.UUID))
+
+		return msg, nil
+
+	case <-ctx.Done():
+		return nil, fmt.Errorf("wait for message on topic [%s]: %w", topic, ctx.Err())
+	}
+}
+
+func drainAndNack(topic string, msgChan <-chan *message.Message) {
+	for msg := range msgChan {
+		logger.Debug("Nacking message received after one-shot subscription was closed",
+			log.WithTopic(topic), log.WithMessageID(msg.UUID))
+
+		msg.Nack()
+	}
+}
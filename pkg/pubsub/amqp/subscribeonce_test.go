@@ -0,0 +1,178 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package amqp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/lifecycle"
+)
+
+func TestPubSub_SubscribeOnce(t *testing.T) {
+	const topic = "some-topic"
+
+	newPubSub := func(sub subscriber) *PubSub {
+		p := &PubSub{
+			Lifecycle:            lifecycle.New("ampq"),
+			Config:               newTestConfig(t, Config{}),
+			connMgr:              &mockConnectionMgr{},
+			subscriber:           sub,
+			publisher:            newMockPublisher(),
+			waitSubscriber:       &mockSubscriber{mockClosable: &mockClosable{}},
+			waitPublisher:        newMockPublisher(),
+			redeliverySubscriber: &mockSubscriber{mockClosable: &mockClosable{}},
+		}
+
+		p.Start()
+
+		return p
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		sub := newLoopbackSubscriber()
+
+		p := newPubSub(sub)
+		defer p.stop()
+
+		msg := message.NewMessage(watermill.NewUUID(), []byte("some payload"))
+
+		go sub.publish(msg)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		m, err := p.SubscribeOnce(ctx, topic)
+		require.NoError(t, err)
+		require.Equal(t, msg.UUID, m.UUID)
+		require.Equal(t, topic, sub.topic)
+
+		select {
+		case <-m.Acked():
+		default:
+			t.Fatal("expecting message to be acked")
+		}
+
+		select {
+		case <-sub.closed:
+		case <-time.After(time.Second):
+			t.Fatal("expecting subscription to be closed")
+		}
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		sub := newLoopbackSubscriber()
+
+		p := newPubSub(sub)
+		defer p.stop()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		m, err := p.SubscribeOnce(ctx, topic)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Nil(t, m)
+
+		select {
+		case <-sub.closed:
+		case <-time.After(time.Second):
+			t.Fatal("expecting subscription to be closed")
+		}
+	})
+
+	t.Run("Subscription closed", func(t *testing.T) {
+		msgChan := make(chan *message.Message)
+		close(msgChan)
+
+		p := newPubSub(&chanSubscriber{mockClosable: &mockClosable{}, msgChan: msgChan})
+		defer p.stop()
+
+		_, err := p.SubscribeOnce(context.Background(), topic)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "subscription to topic [some-topic] was closed")
+	})
+
+	t.Run("Subscribe error", func(t *testing.T) {
+		errExpected := errors.New("injected subscribe error")
+
+		p := newPubSub(&mockSubscriber{mockClosable: &mockClosable{}, err: errExpected})
+		defer p.stop()
+
+		_, err := p.SubscribeOnce(context.Background(), topic)
+		require.ErrorIs(t, err, errExpected)
+	})
+
+	t.Run("Stopped", func(t *testing.T) {
+		p := newPubSub(newLoopbackSubscriber())
+		p.Stop()
+
+		_, err := p.SubscribeOnce(context.Background(), topic)
+		require.ErrorIs(t, err, lifecycle.ErrStopped)
+	})
+}
+
+// loopbackSubscriber delivers published messages to the subscriber and closes the subscription
+// when the subscriber's context is done (as the AMQP subscriber does).
+type loopbackSubscriber struct {
+	*mockClosable
+
+	msgChan chan *message.Message
+	closed  chan struct{}
+	topic   string
+}
+
+func newLoopbackSubscriber() *loopbackSubscriber {
+	return &loopbackSubscriber{
+		mockClosable: &mockClosable{},
+		msgChan:      make(chan *message.Message),
+		closed:       make(chan struct{}),
+	}
+}
+
+func (m *loopbackSubscriber) publish(msg *message.Message) {
+	m.msgChan <- msg
+}
+
+func (m *loopbackSubscriber) Subscribe(ctx context.Context, topic string) (<-chan *message.Message, error) {
+	m.topic = topic
+
+	out := make(chan *message.Message)
+
+	go func() {
+		defer func() {
+			close(out)
+			close(m.closed)
+		}()
+
+		for {
+			select {
+			case msg := <-m.msgChan:
+				select {
+				case out <- msg:
+				case <-ctx.Done():
+					msg.Nack()
+
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func (m *loopbackSubscriber) SubscribeInitialize(string) error {
+	return nil
+}
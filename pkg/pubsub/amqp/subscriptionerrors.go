@@ -0,0 +1,133 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package amqp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ThreeDotsLabs/watermill"
+)
+
+// topicLogField is the field that the Watermill AMQP subscriber uses to log the topic of a subscription.
+const topicLogField = "topic"
+
+type errorHandler = func(err error)
+
+// subscriptionErrorNotifier notifies registered handlers (see spi.WithErrorHandler) of errors that occur
+// on the subscriptions to a topic.
+type subscriptionErrorNotifier struct {
+	mutex    sync.RWMutex
+	nextID   uint64
+	handlers map[string]map[uint64]errorHandler
+}
+
+func newSubscriptionErrorNotifier() *subscriptionErrorNotifier {
+	return &subscriptionErrorNotifier{
+		handlers: make(map[string]map[uint64]errorHandler),
+	}
+}
+
+// register registers the given handler for the given topic. The handler is removed when the context is done.
+func (n *subscriptionErrorNotifier) register(ctx context.Context, topic string, handler errorHandler) {
+	n.mutex.Lock()
+
+	n.nextID++
+
+	id := n.nextID
+
+	handlers, ok := n.handlers[topic]
+	if !ok {
+		handlers = make(map[uint64]errorHandler)
+		n.handlers[topic] = handlers
+	}
+
+	handlers[id] = handler
+
+	n.mutex.Unlock()
+
+	done := ctx.Done()
+	if done == nil {
+		return
+	}
+
+	go func() {
+		<-done
+
+		n.mutex.Lock()
+		defer n.mutex.Unlock()
+
+		delete(n.handlers[topic], id)
+
+		if len(n.handlers[topic]) == 0 {
+			delete(n.handlers, topic)
+		}
+	}()
+}
+
+// notify invokes all handlers that are registered for the given topic.
+func (n *subscriptionErrorNotifier) notify(topic string, err error) {
+	n.mutex.RLock()
+
+	handlers := make([]errorHandler, 0, len(n.handlers[topic]))
+
+	for _, handler := range n.handlers[topic] {
+		handlers = append(handlers, handler)
+	}
+
+	n.mutex.RUnlock()
+
+	for _, handler := range handlers {
+		handler(err)
+	}
+}
+
+// errorNotifyingLogger is a Watermill logger that passes all log entries to the given logger and also
+// notifies the error handlers of a topic when the Watermill subscriber logs an error for the topic. The
+// Watermill subscriber doesn't otherwise expose errors that occur on a subscription (e.g. the channel was
+// closed by the broker) since it transparently resubscribes.
+type errorNotifyingLogger struct {
+	watermill.LoggerAdapter
+
+	notifier *subscriptionErrorNotifier
+	fields   watermill.LogFields
+}
+
+func newErrorNotifyingLogger(logger watermill.LoggerAdapter,
+	notifier *subscriptionErrorNotifier) *errorNotifyingLogger {
+	return &errorNotifyingLogger{
+		LoggerAdapter: logger,
+		notifier:      notifier,
+	}
+}
+
+func (l *errorNotifyingLogger) Error(msg string, err error, fields watermill.LogFields) {
+	l.LoggerAdapter.Error(msg, err, fields)
+
+	topic, ok := l.fields.Add(fields)[topicLogField].(string)
+	if !ok {
+		return
+	}
+
+	if err == nil {
+		err = errors.New(msg)
+	} else {
+		err = fmt.Errorf("%s: %w", msg, err)
+	}
+
+	l.notifier.notify(topic, err)
+}
+
+func (l *errorNotifyingLogger) With(fields watermill.LogFields) watermill.LoggerAdapter {
+	return &errorNotifyingLogger{
+		LoggerAdapter: l.LoggerAdapter.With(fields),
+		notifier:      l.notifier,
+		fields:        l.fields.Add(fields),
+	}
+}
@@ -0,0 +1,167 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package amqp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/lifecycle"
+	"github.com/trustbloc/orb/pkg/pubsub/spi"
+)
+
+func TestPubSub_SubscriptionErrors(t *testing.T) {
+	const (
+		topic1 = "topic1"
+		topic2 = "topic2"
+	)
+
+	newPubSub := func(shared bool) *PubSub {
+		p := &PubSub{
+			Lifecycle:            lifecycle.New("ampq"),
+			Config:               newTestConfig(t, Config{SharedTopicSubscriptions: shared}),
+			connMgr:              &mockConnectionMgr{},
+			subscriber:           newCountingSubscriber(),
+			publisher:            newMockPublisher(),
+			waitSubscriber:       &mockSubscriber{mockClosable: &mockClosable{}},
+			waitPublisher:        newMockPublisher(),
+			redeliverySubscriber: &mockSubscriber{mockClosable: &mockClosable{}},
+			subscriptionErrors:   newSubscriptionErrorNotifier(),
+		}
+
+		p.Start()
+
+		return p
+	}
+
+	// closeChannel simulates the broker closing the channel of a subscription, which is logged by the
+	// Watermill subscriber.
+	closeChannel := func(p *PubSub, topic string) {
+		logger := newErrorNotifyingLogger(watermill.NopLogger{}, p.subscriptionErrors).
+			With(watermill.LogFields{"subscriber_uuid": "1234"})
+
+		logger.Error("Channel closed, stopping ProcessMessages", nil,
+			watermill.LogFields{"topic": topic, "amqp_queue_name": topic})
+	}
+
+	t.Run("Handler notified", func(t *testing.T) {
+		p := newPubSub(false)
+		defer p.stop()
+
+		handler1 := &mockErrorHandler{}
+		handler2 := &mockErrorHandler{}
+
+		_, err := p.SubscribeWithOpts(context.Background(), topic1, spi.WithErrorHandler(handler1.handle))
+		require.NoError(t, err)
+
+		_, err = p.SubscribeWithOpts(context.Background(), topic2, spi.WithPool(2),
+			spi.WithErrorHandler(handler2.handle))
+		require.NoError(t, err)
+
+		closeChannel(p, topic1)
+
+		require.Len(t, handler1.errors(), 1)
+		require.EqualError(t, handler1.errors()[0], "Channel closed, stopping ProcessMessages")
+		require.Empty(t, handler2.errors())
+
+		closeChannel(p, topic2)
+
+		require.Len(t, handler1.errors(), 1)
+		require.Len(t, handler2.errors(), 1)
+	})
+
+	t.Run("Shared subscription", func(t *testing.T) {
+		p := newPubSub(true)
+		defer p.stop()
+
+		handler1 := &mockErrorHandler{}
+		handler2 := &mockErrorHandler{}
+
+		_, err := p.SubscribeWithOpts(context.Background(), topic1, spi.WithErrorHandler(handler1.handle))
+		require.NoError(t, err)
+
+		_, err = p.SubscribeWithOpts(context.Background(), topic1, spi.WithErrorHandler(handler2.handle))
+		require.NoError(t, err)
+
+		closeChannel(p, topic1)
+
+		require.Len(t, handler1.errors(), 1)
+		require.Len(t, handler2.errors(), 1)
+	})
+
+	t.Run("Context done -> handler removed", func(t *testing.T) {
+		p := newPubSub(false)
+		defer p.stop()
+
+		handler := &mockErrorHandler{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		_, err := p.SubscribeWithOpts(ctx, topic1, spi.WithErrorHandler(handler.handle))
+		require.NoError(t, err)
+
+		cancel()
+
+		require.Eventually(t, func() bool {
+			p.subscriptionErrors.mutex.RLock()
+			defer p.subscriptionErrors.mutex.RUnlock()
+
+			return len(p.subscriptionErrors.handlers) == 0
+		}, time.Second, 10*time.Millisecond)
+
+		closeChannel(p, topic1)
+
+		require.Empty(t, handler.errors())
+	})
+
+	t.Run("Error without topic -> not notified", func(t *testing.T) {
+		p := newPubSub(false)
+		defer p.stop()
+
+		handler := &mockErrorHandler{}
+
+		_, err := p.SubscribeWithOpts(context.Background(), topic1, spi.WithErrorHandler(handler.handle))
+		require.NoError(t, err)
+
+		newErrorNotifyingLogger(watermill.NopLogger{}, p.subscriptionErrors).
+			Error("Failed to close channel", errors.New("injected error"), nil)
+
+		require.Empty(t, handler.errors())
+
+		newErrorNotifyingLogger(watermill.NopLogger{}, p.subscriptionErrors).
+			Error("Failed to start consuming messages", errors.New("injected error"),
+				watermill.LogFields{"topic": topic1})
+
+		require.Len(t, handler.errors(), 1)
+		require.EqualError(t, handler.errors()[0], "Failed to start consuming messages: injected error")
+	})
+}
+
+type mockErrorHandler struct {
+	mutex sync.Mutex
+	errs  []error
+}
+
+func (m *mockErrorHandler) handle(err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.errs = append(m.errs, err)
+}
+
+func (m *mockErrorHandler) errors() []error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.errs
+}
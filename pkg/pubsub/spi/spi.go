@@ -13,8 +13,12 @@ const UndeliverableTopic = "orb.undeliverable.activities"
 
 // Options contains publisher/subscriber options.
 type Options struct {
-	PoolSize      int
-	DeliveryDelay time.Duration
+	PoolSize          int
+	DeliveryDelay     time.Duration
+	MaxConcurrency    int
+	ProcessingTimeout time.Duration
+	NewSubscription   bool
+	ErrorHandler      func(err error)
 }
 
 // Option specifies a publisher/subscriber option.
@@ -34,3 +38,44 @@ func WithDeliveryDelay(delay time.Duration) Option {
 		option.DeliveryDelay = delay
 	}
 }
+
+// WithMaxConcurrency sets the maximum number of messages that may be processed concurrently, regardless
+// of the pool size. A message is considered to be processing until it is acked or nacked. If zero (default)
+// then concurrency is bounded only by the pool size.
+// Note: Not all message brokers support this option.
+func WithMaxConcurrency(n int) Option {
+	return func(option *Options) {
+		option.MaxConcurrency = n
+	}
+}
+
+// WithProcessingTimeout sets the maximum amount of time that a subscriber may take to process (ack or nack)
+// a message. If a message isn't acked/nacked within the timeout then it's nacked so that it's redelivered
+// (and the subscription is freed up to receive another message). If zero (default) then there is no timeout.
+// Note: Not all message brokers support this option. The AMQP implementation only supports this option for
+// pooled subscriptions (see WithPool).
+func WithProcessingTimeout(timeout time.Duration) Option {
+	return func(option *Options) {
+		option.ProcessingTimeout = timeout
+	}
+}
+
+// WithNewSubscription forces a new subscription to be created even if the subscriber is configured to share
+// a single subscription per topic.
+// Note: Not all message brokers support this option.
+func WithNewSubscription() Option {
+	return func(option *Options) {
+		option.NewSubscription = true
+	}
+}
+
+// WithErrorHandler sets a handler that's notified of subscription-level errors that aren't associated with a
+// particular message, for example if the broker closes the subscription's channel. Notification is best-effort:
+// the handler isn't guaranteed to be notified of every error and may be invoked concurrently. The handler must
+// not block.
+// Note: Not all message brokers support this option.
+func WithErrorHandler(handler func(err error)) Option {
+	return func(option *Options) {
+		option.ErrorHandler = handler
+	}
+}
@@ -16,7 +16,11 @@ import (
 	"github.com/trustbloc/orb/internal/pkg/log"
 )
 
-const taskName = "data-expiry"
+const (
+	taskName = "data-expiry"
+
+	defaultPageSize = 1000
+)
 
 var logger = log.NewStructured("expiry-service")
 
@@ -28,10 +32,32 @@ type registeredStore struct {
 	store storage.Store
 	name  string
 
-	expiryTagName string
-	expiryHandler expiryHandler
+	expiryTagNames []string
+	expiryHandler  expiryHandler
+	buildQuery     QueryBuilder
+	customQuery    bool
+	pageSize       int
+	handlerMode    HandlerMode
 }
 
+// StoreInfo contains diagnostic information about a store that's registered with the expiry service.
+type StoreInfo struct {
+	// Name is the name under which the store was registered.
+	Name string `json:"name"`
+	// ExpiryTagNames contains the expiry tag name followed by any additional expiry tag names.
+	ExpiryTagNames []string `json:"expiryTagNames"`
+	// PageSize is the maximum number of expired keys that are held in memory at once.
+	PageSize int `json:"pageSize"`
+	// ExpiryHandler is true if an expiry handler was registered for the store.
+	ExpiryHandler bool `json:"expiryHandler"`
+	// CustomQuery is true if a custom query builder was registered for the store.
+	CustomQuery bool `json:"customQuery"`
+}
+
+// QueryBuilder returns the query expression used to find data in a store that expired at or before
+// the given time. expiryTagName is the tag name under which the expiry values are stored.
+type QueryBuilder func(expiryTagName string, expiryTime time.Time) string
+
 // Option is an option for registered store.
 type Option func(opts *registeredStore)
 
@@ -42,15 +68,89 @@ func WithExpiryHandler(handler expiryHandler) Option {
 	}
 }
 
+// WithAdditionalExpiryTags registers additional expiry tag names for a store that holds different kinds of
+// records, each with its own expiry tag. Each tag is swept independently within the same pass.
+func WithAdditionalExpiryTags(expiryTagNames ...string) Option {
+	return func(opts *registeredStore) {
+		opts.expiryTagNames = append(opts.expiryTagNames, expiryTagNames...)
+	}
+}
+
+// WithQueryBuilder sets an optional query builder for stores whose query syntax differs from the default,
+// i.e. "<expiryTagName><=<Unix timestamp>".
+func WithQueryBuilder(builder QueryBuilder) Option {
+	return func(opts *registeredStore) {
+		opts.buildQuery = builder
+		opts.customQuery = true
+	}
+}
+
+// WithPageSize sets the maximum number of expired keys that are held in memory at once. Expired keys are read
+// from the store, handed to the expiry handler and deleted one page at a time. Default page size is 1000.
+func WithPageSize(pageSize int) Option {
+	return func(opts *registeredStore) {
+		opts.pageSize = pageSize
+	}
+}
+
+// HandlerMode specifies when the expiry handler is invoked relative to the deletion of the expired keys.
+type HandlerMode int
+
+const (
+	// HandleBeforeDelete invokes the expiry handler before the expired keys are deleted. This is the default.
+	// If the handler fails then the keys are not deleted and both the handler and the delete are retried on
+	// the next sweep. If the delete fails after the handler succeeded then the handler's side effects have
+	// already been applied. In this case, if the handler implements RollbackHandler then RollbackExpiredKeys is
+	// invoked in order to undo the side effects, otherwise the handler is invoked again with the same keys on
+	// the next sweep (so the handler must be idempotent).
+	HandleBeforeDelete HandlerMode = iota
+
+	// HandleAfterDelete invokes the expiry handler only after the expired keys were successfully deleted, so the
+	// handler never has side effects for keys that weren't deleted. If the delete fails then the handler isn't
+	// invoked and the delete is retried on the next sweep. If the handler fails then the keys have already been
+	// deleted and the handler isn't invoked again for those keys, i.e. the handler is invoked at most once per key.
+	HandleAfterDelete
+)
+
+// WithHandlerMode sets when the expiry handler is invoked relative to the deletion of the expired keys.
+// Default is HandleBeforeDelete.
+func WithHandlerMode(mode HandlerMode) Option {
+	return func(opts *registeredStore) {
+		opts.handlerMode = mode
+	}
+}
+
 type expiryHandler interface {
 	HandleExpiredKeys(keys ...string) error
 }
 
+// RollbackHandler may optionally be implemented by an expiry handler in order to undo the side effects of
+// HandleExpiredKeys if the expired keys couldn't be deleted (in HandleBeforeDelete mode). Since the storage
+// provider doesn't support transactions that span the handler and the delete, this compensating action is
+// the only way to keep the handler's side effects consistent with the store.
+type RollbackHandler interface {
+	RollbackExpiredKeys(keys ...string) error
+}
+
 // Service is an expiry service that periodically polls registered stores and removes data past a specified
 // expiration time.
 type Service struct {
 	registeredStores []registeredStore
 	mutex            sync.RWMutex
+	initialDelay     time.Duration
+	firstSweepTime   time.Time
+}
+
+// ServiceOpt is an option for the expiry service.
+type ServiceOpt func(s *Service)
+
+// WithInitialDelay sets a grace period, starting when the service is created, during which no expired data is
+// deleted. This avoids contention with data loading at startup. Subsequent sweeps run at the normal interval.
+// By default there is no delay.
+func WithInitialDelay(delay time.Duration) ServiceOpt {
+	return func(s *Service) {
+		s.initialDelay = delay
+	}
 }
 
 // NewService returns a new expiry Service.
@@ -68,58 +168,171 @@ type Service struct {
 // cleanup. It must be unique for every Orb instance within the cluster in order for this service to work efficiently.
 // You must register each store you want this service to run on using the Register method. Once all your stores are
 // registered, call the Start method to start the service.
-func NewService(scheduler taskManager, interval time.Duration) *Service {
+func NewService(scheduler taskManager, interval time.Duration, opts ...ServiceOpt) *Service {
 	s := &Service{}
 
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.firstSweepTime = time.Now().Add(s.initialDelay)
+
 	scheduler.RegisterTask(taskName, interval, s.deleteExpiredData)
 
 	return s
 }
 
+// StoreRegistration contains the parameters for registering a store with the expiry service.
+// See Register for a description of each field.
+type StoreRegistration struct {
+	Store         storage.Store
+	ExpiryTagName string
+	Name          string
+	Options       []Option
+}
+
 // Register adds a store to this expiry service.
 // store is the store on which to periodically cleanup expired data.
 // name is used to identify the purpose of this expiry service for logging purposes.
 // expiryTagName is the tag name used to store expiry values under. The expiry values must be standard Unix timestamps.
+// Additional expiry tag names for the same store may be provided with the WithAdditionalExpiryTags option.
 func (s *Service) Register(store storage.Store, expiryTagName, storeName string, opts ...Option) {
-	newRegisteredStore := registeredStore{
-		store:         store,
-		name:          storeName,
-		expiryTagName: expiryTagName,
-		expiryHandler: &noopExpiryHandler{},
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.registeredStores = append(s.registeredStores, newRegisteredStore(store, expiryTagName, storeName, opts...))
+}
+
+// RegisterAll adds all of the given stores to this expiry service.
+func (s *Service) RegisterAll(registrations []StoreRegistration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, r := range registrations {
+		s.registeredStores = append(s.registeredStores,
+			newRegisteredStore(r.Store, r.ExpiryTagName, r.Name, r.Options...))
+	}
+}
+
+func newRegisteredStore(store storage.Store, expiryTagName, storeName string, opts ...Option) registeredStore {
+	rs := registeredStore{
+		store:          store,
+		name:           storeName,
+		expiryTagNames: []string{expiryTagName},
+		expiryHandler:  &noopExpiryHandler{},
+		buildQuery:     defaultQuery,
+		pageSize:       defaultPageSize,
 	}
 
 	// apply options
 	for _, opt := range opts {
-		opt(&newRegisteredStore)
+		opt(&rs)
 	}
 
-	s.mutex.Lock()
-
-	s.registeredStores = append(s.registeredStores, newRegisteredStore)
+	if rs.pageSize <= 0 {
+		rs.pageSize = defaultPageSize
+	}
 
-	s.mutex.Unlock()
+	return rs
 }
 
 func (s *Service) deleteExpiredData() {
+	if time.Now().Before(s.firstSweepTime) {
+		logger.Debug("Skipping expired data sweep since the initial delay has not yet elapsed")
+
+		return
+	}
+
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
 	for _, registeredStore := range s.registeredStores {
-		if err := registeredStore.deleteExpiredData(); err != nil {
-			logger.Warn("Error deleting expired data", log.WithError(err), log.WithStoreName(registeredStore.name))
+		for _, expiryTagName := range registeredStore.expiryTagNames {
+			if err := registeredStore.deleteExpiredData(expiryTagName); err != nil {
+				logger.Warn("Error deleting expired data", log.WithError(err), log.WithStoreName(registeredStore.name),
+					log.WithTagName(expiryTagName))
+			}
+		}
+	}
+}
+
+// PendingExpired returns the number of items in the given registered store that have expired but have not
+// yet been deleted. No data is deleted.
+func (s *Service) PendingExpired(storeName string) (int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for i := range s.registeredStores {
+		r := &s.registeredStores[i]
+
+		if r.name != storeName {
+			continue
+		}
+
+		now := time.Now()
+
+		// A record may carry more than one expiry tag, so count each key only once.
+		pending := make(map[string]struct{})
+
+		for _, expiryTagName := range r.expiryTagNames {
+			keys, err := r.expiredKeys(expiryTagName, now)
+			if err != nil {
+				return 0, err
+			}
+
+			for _, key := range keys {
+				pending[key] = struct{}{}
+			}
+		}
+
+		return len(pending), nil
+	}
+
+	return 0, fmt.Errorf("store [%s] is not registered", storeName)
+}
+
+// RegisteredStores returns information about each of the stores that are registered with the expiry service,
+// in the order in which they were registered.
+func (s *Service) RegisteredStores() []StoreInfo {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	stores := make([]StoreInfo, len(s.registeredStores))
+
+	for i, r := range s.registeredStores {
+		_, noopHandler := r.expiryHandler.(*noopExpiryHandler)
+
+		stores[i] = StoreInfo{
+			Name:           r.name,
+			ExpiryTagNames: append([]string(nil), r.expiryTagNames...),
+			PageSize:       r.pageSize,
+			ExpiryHandler:  !noopHandler,
+			CustomQuery:    r.customQuery,
 		}
 	}
+
+	return stores
 }
 
-func (r *registeredStore) deleteExpiredData() error {
-	logger.Debug("Checking for expired data in store", log.WithStoreName(r.name))
+func (r *registeredStore) deleteExpiredData(expiryTagName string) error {
+	logger.Debug("Checking for expired data in store", log.WithStoreName(r.name), log.WithTagName(expiryTagName))
 
-	iterator, err := r.store.Query(fmt.Sprintf("%s<=%d", r.expiryTagName, time.Now().Unix()))
+	iterator, err := r.store.Query(r.buildQuery(expiryTagName, time.Now()), storage.WithPageSize(r.pageSize))
 	if err != nil {
 		return fmt.Errorf("query store for expired data: %w", err)
 	}
 
-	var keysToDelete []string
+	defer func() {
+		if errClose := iterator.Close(); errClose != nil {
+			logger.Warn("Error closing iterator", log.WithStoreName(r.name), log.WithError(errClose))
+		}
+	}()
+
+	// Expired keys are handled and deleted one page at a time so that no more than one page of keys
+	// is held in memory, regardless of the size of the backlog. If deletions cause the iterator to skip
+	// any expired data then that data is deleted on the next sweep.
+	page := make([]string, 0, r.pageSize)
+	total := 0
 
 	more, err := iterator.Next()
 	if err != nil {
@@ -132,7 +345,18 @@ func (r *registeredStore) deleteExpiredData() error {
 			return fmt.Errorf("get key from iterator: %w", errKey)
 		}
 
-		keysToDelete = append(keysToDelete, key)
+		page = append(page, key)
+
+		if len(page) == r.pageSize {
+			if err := r.deleteKeys(page); err != nil {
+				return err
+			}
+
+			total += len(page)
+
+			// Allocate a new page rather than reusing the old one in case the expiry handler holds on to the keys.
+			page = make([]string, 0, r.pageSize)
+		}
 
 		var errNext error
 
@@ -142,34 +366,136 @@ func (r *registeredStore) deleteExpiredData() error {
 		}
 	}
 
-	logger.Debug("Found expired data to delete.", log.WithTotal(len(keysToDelete)), log.WithStoreName(r.name))
+	// The expiry handler is always invoked at least once (even if no data has expired).
+	if len(page) > 0 || total == 0 {
+		if err := r.deleteKeys(page); err != nil {
+			return err
+		}
 
-	err = r.expiryHandler.HandleExpiredKeys(keysToDelete...)
-	if err != nil {
-		return fmt.Errorf("invoke expiry handler: %w", err)
+		total += len(page)
 	}
 
-	if len(keysToDelete) > 0 {
-		operations := make([]storage.Operation, len(keysToDelete))
+	logger.Debug("Finished deleting expired data.", log.WithStoreName(r.name), log.WithTagName(expiryTagName),
+		log.WithTotal(total))
 
-		for i, key := range keysToDelete {
-			logger.Debug("Deleting expired data for key", log.WithStoreName(r.name), log.WithKey(key))
+	return nil
+}
 
-			operations[i] = storage.Operation{Key: key}
+// deleteKeys deletes the given keys from the store and invokes the expiry handler (either before or after
+// the delete, depending on the handler mode).
+func (r *registeredStore) deleteKeys(keys []string) error {
+	logger.Debug("Found expired data to delete.", log.WithTotal(len(keys)), log.WithStoreName(r.name))
+
+	if r.handlerMode == HandleAfterDelete {
+		if err := r.batchDelete(keys); err != nil {
+			return err
 		}
 
-		err = r.store.Batch(operations)
-		if err != nil {
-			return fmt.Errorf("delete expired data: %w", err)
+		if err := r.expiryHandler.HandleExpiredKeys(keys...); err != nil {
+			return fmt.Errorf("invoke expiry handler: %w", err)
 		}
 
-		logger.Debug("Successfully deleted expired data.", log.WithStoreName(r.name),
-			log.WithTotal(len(operations)))
+		return nil
+	}
+
+	if err := r.expiryHandler.HandleExpiredKeys(keys...); err != nil {
+		return fmt.Errorf("invoke expiry handler: %w", err)
+	}
+
+	if err := r.batchDelete(keys); err != nil {
+		r.rollback(keys)
+
+		return err
+	}
+
+	return nil
+}
+
+// rollback invokes the expiry handler's rollback function (if the handler supports it) after the
+// given keys failed to be deleted.
+func (r *registeredStore) rollback(keys []string) {
+	rh, ok := r.expiryHandler.(RollbackHandler)
+	if !ok {
+		return
+	}
+
+	if err := rh.RollbackExpiredKeys(keys...); err != nil {
+		logger.Error("Error rolling back expiry handler after the expired keys failed to be deleted",
+			log.WithStoreName(r.name), log.WithTotal(len(keys)), log.WithError(err))
+
+		return
+	}
+
+	logger.Debug("Rolled back expiry handler after the expired keys failed to be deleted",
+		log.WithStoreName(r.name), log.WithTotal(len(keys)))
+}
+
+func (r *registeredStore) batchDelete(keys []string) error {
+	if len(keys) == 0 {
+		return nil
 	}
 
+	operations := make([]storage.Operation, len(keys))
+
+	for i, key := range keys {
+		logger.Debug("Deleting expired data for key", log.WithStoreName(r.name), log.WithKey(key))
+
+		operations[i] = storage.Operation{Key: key}
+	}
+
+	err := r.store.Batch(operations)
+	if err != nil {
+		return fmt.Errorf("delete expired data: %w", err)
+	}
+
+	logger.Debug("Successfully deleted expired data.", log.WithStoreName(r.name), log.WithTotal(len(operations)))
+
 	return nil
 }
 
+// expiredKeys returns the keys of the data tagged with the given expiry tag that expired at or before the given time.
+func (r *registeredStore) expiredKeys(expiryTagName string, expiryTime time.Time) ([]string, error) {
+	iterator, err := r.store.Query(r.buildQuery(expiryTagName, expiryTime), storage.WithPageSize(r.pageSize))
+	if err != nil {
+		return nil, fmt.Errorf("query store for expired data: %w", err)
+	}
+
+	defer func() {
+		if errClose := iterator.Close(); errClose != nil {
+			logger.Warn("Error closing iterator", log.WithStoreName(r.name), log.WithError(errClose))
+		}
+	}()
+
+	var keys []string
+
+	more, err := iterator.Next()
+	if err != nil {
+		return nil, fmt.Errorf("get next value from iterator: %w", err)
+	}
+
+	for more {
+		key, errKey := iterator.Key()
+		if errKey != nil {
+			return nil, fmt.Errorf("get key from iterator: %w", errKey)
+		}
+
+		keys = append(keys, key)
+
+		var errNext error
+
+		more, errNext = iterator.Next()
+		if errNext != nil {
+			return nil, fmt.Errorf("get next value from iterator: %w", errNext)
+		}
+	}
+
+	return keys, nil
+}
+
+func defaultQuery(expiryTagName string, expiryTime time.Time) string {
+	return fmt.Sprintf("%s<=%d", expiryTagName, expiryTime.Unix())
+}
+
 type noopExpiryHandler struct{}
 
 func (h *noopExpiryHandler) HandleExpiredKeys(_ ...string) error {
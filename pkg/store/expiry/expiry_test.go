@@ -9,6 +9,7 @@ package expiry
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -160,6 +161,345 @@ func TestService(t *testing.T) {
 	})
 }
 
+func TestService_RegisterAll(t *testing.T) {
+	taskMgr := &mockTaskManager{}
+
+	service := NewService(taskMgr, time.Second)
+
+	handler1 := &mockExpiryHandler{}
+	handler2 := &mockExpiryHandler{}
+	handler3 := &mockExpiryHandler{}
+
+	service.RegisterAll([]StoreRegistration{
+		{
+			Store:         &mock.Store{QueryReturn: newKeysIterator("key1", "key2")},
+			ExpiryTagName: "ExpiryTag",
+			Name:          "Store1",
+			Options:       []Option{WithExpiryHandler(handler1)},
+		},
+		{
+			Store:         &mock.Store{QueryReturn: newKeysIterator("key3")},
+			ExpiryTagName: "ExpiryTag",
+			Name:          "Store2",
+			Options:       []Option{WithExpiryHandler(handler2)},
+		},
+		{
+			Store:         &mock.Store{QueryReturn: newKeysIterator()},
+			ExpiryTagName: "OtherExpiryTag",
+			Name:          "Store3",
+			Options:       []Option{WithExpiryHandler(handler3)},
+		},
+	})
+
+	require.Len(t, service.registeredStores, 3)
+	require.Equal(t, "Store1", service.registeredStores[0].name)
+	require.Equal(t, "OtherExpiryTag", service.registeredStores[2].expiryTagNames[0])
+
+	require.NotNil(t, taskMgr.handler)
+
+	taskMgr.handler()
+
+	require.Equal(t, []string{"key1", "key2"}, handler1.keys)
+	require.Equal(t, []string{"key3"}, handler2.keys)
+	require.Empty(t, handler3.keys)
+	require.Equal(t, 1, handler3.calls)
+}
+
+func TestService_QueryBuilder(t *testing.T) {
+	t.Run("Default query", func(t *testing.T) {
+		taskMgr := &mockTaskManager{}
+
+		store := &queryRecordingStore{Store: &mock.Store{QueryReturn: newKeysIterator()}}
+
+		NewService(taskMgr, time.Second).Register(store, "ExpiryTag", "TestStore")
+
+		taskMgr.handler()
+
+		require.Len(t, store.queries, 1)
+		require.Regexp(t, `^ExpiryTag<=\d+$`, store.queries[0])
+	})
+
+	t.Run("Custom query builder", func(t *testing.T) {
+		taskMgr := &mockTaskManager{}
+
+		store := &queryRecordingStore{Store: &mock.Store{QueryReturn: newKeysIterator("key1")}}
+		handler := &mockExpiryHandler{}
+
+		NewService(taskMgr, time.Second).Register(store, "ExpiryTag", "TestStore",
+			WithExpiryHandler(handler),
+			WithQueryBuilder(func(expiryTagName string, expiryTime time.Time) string {
+				return fmt.Sprintf("%s:lte:%s", expiryTagName, expiryTime.UTC().Format(time.RFC3339))
+			}),
+		)
+
+		taskMgr.handler()
+
+		require.Len(t, store.queries, 1)
+		require.Regexp(t, `^ExpiryTag:lte:\d{4}-\d{2}-\d{2}T`, store.queries[0])
+		require.Equal(t, []string{"key1"}, handler.keys)
+	})
+}
+
+func TestService_InitialDelay(t *testing.T) {
+	t.Run("No sweep before initial delay", func(t *testing.T) {
+		taskMgr := &mockTaskManager{}
+
+		store := &queryRecordingStore{Store: &mock.Store{QueryReturn: newKeysIterator("key1")}}
+		handler := &mockExpiryHandler{}
+
+		NewService(taskMgr, time.Second, WithInitialDelay(100*time.Millisecond)).
+			Register(store, "ExpiryTag", "TestStore", WithExpiryHandler(handler))
+
+		taskMgr.handler()
+
+		require.Empty(t, store.queries)
+		require.Empty(t, handler.keys)
+
+		time.Sleep(150 * time.Millisecond)
+
+		taskMgr.handler()
+
+		require.Len(t, store.queries, 1)
+		require.Equal(t, []string{"key1"}, handler.keys)
+	})
+
+	t.Run("No delay by default", func(t *testing.T) {
+		taskMgr := &mockTaskManager{}
+
+		store := &queryRecordingStore{Store: &mock.Store{QueryReturn: newKeysIterator()}}
+
+		NewService(taskMgr, time.Second).Register(store, "ExpiryTag", "TestStore")
+
+		taskMgr.handler()
+
+		require.Len(t, store.queries, 1)
+	})
+}
+
+func TestService_PageSize(t *testing.T) {
+	const (
+		numKeys  = 2550
+		pageSize = 100
+	)
+
+	keys := make([]string, numKeys)
+
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+
+	taskMgr := &mockTaskManager{}
+
+	store := &queryRecordingStore{Store: &mock.Store{QueryReturn: newKeysIterator(keys...)}}
+	handler := &pageRecordingHandler{}
+
+	NewService(taskMgr, time.Second).Register(store, "ExpiryTag", "TestStore",
+		WithExpiryHandler(handler), WithPageSize(pageSize),
+	)
+
+	taskMgr.handler()
+
+	require.Equal(t, keys, handler.keys)
+	require.Equal(t, 26, handler.calls)
+	require.Equal(t, pageSize, handler.maxPageSize)
+	require.Equal(t, 26, store.batchCalls)
+}
+
+func TestService_PendingExpired(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		iterator := newKeysIterator("key1", "key2", "key3")
+		store := &queryRecordingStore{Store: &mock.Store{QueryReturn: iterator}}
+		handler := &mockExpiryHandler{}
+
+		service := NewService(&mockTaskManager{}, time.Second)
+		service.Register(&mock.Store{QueryReturn: newKeysIterator("key4")}, "ExpiryTag", "OtherStore")
+		service.Register(store, "ExpiryTag", "TestStore", WithExpiryHandler(handler), WithPageSize(50))
+
+		count, err := service.PendingExpired("TestStore")
+		require.NoError(t, err)
+		require.Equal(t, 3, count)
+
+		require.Len(t, store.queries, 1)
+		require.Regexp(t, `^ExpiryTag<=\d+$`, store.queries[0])
+		require.Equal(t, []int{50}, store.pageSizes)
+		require.True(t, iterator.closed)
+
+		// Nothing should have been deleted.
+		require.Zero(t, handler.calls)
+		require.Zero(t, store.batchCalls)
+	})
+
+	t.Run("Store not registered", func(t *testing.T) {
+		_, err := NewService(&mockTaskManager{}, time.Second).PendingExpired("TestStore")
+		require.EqualError(t, err, "store [TestStore] is not registered")
+	})
+
+	t.Run("Query error", func(t *testing.T) {
+		service := NewService(&mockTaskManager{}, time.Second)
+		service.Register(&mock.Store{ErrQuery: errors.New("query error")}, "ExpiryTag", "TestStore")
+
+		_, err := service.PendingExpired("TestStore")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "query error")
+	})
+}
+
+func TestService_HandlerMode(t *testing.T) {
+	keys := []string{"key1", "key2", "key3"}
+
+	newStore := func(events *[]string, errBatch error) *eventRecordingStore {
+		return &eventRecordingStore{
+			Store:  &mock.Store{QueryReturn: newKeysIterator(keys...), ErrBatch: errBatch},
+			events: events,
+		}
+	}
+
+	sweep := func(store storage.Store, opts ...Option) {
+		taskMgr := &mockTaskManager{}
+
+		NewService(taskMgr, time.Second).Register(store, "ExpiryTag", "TestStore", opts...)
+
+		taskMgr.handler()
+	}
+
+	t.Run("Before delete (default)", func(t *testing.T) {
+		var events []string
+
+		handler := newRelatedDataHandler(&events, keys...)
+
+		sweep(newStore(&events, nil), WithExpiryHandler(handler))
+
+		require.Equal(t, []string{"handle", "batch"}, events)
+		require.Empty(t, handler.relatedData)
+	})
+
+	t.Run("Before delete, batch error -> handler effects rolled back", func(t *testing.T) {
+		var events []string
+
+		handler := newRelatedDataHandler(&events, keys...)
+
+		sweep(newStore(&events, errors.New("injected batch error")), WithExpiryHandler(handler),
+			WithHandlerMode(HandleBeforeDelete))
+
+		require.Equal(t, []string{"handle", "batch", "rollback"}, events)
+		require.Len(t, handler.relatedData, len(keys))
+	})
+
+	t.Run("Before delete, batch error, no rollback support -> handler effects remain", func(t *testing.T) {
+		var events []string
+
+		handler := &mockExpiryHandler{}
+
+		sweep(newStore(&events, errors.New("injected batch error")), WithExpiryHandler(handler))
+
+		require.Equal(t, []string{"batch"}, events)
+		require.Equal(t, keys, handler.keys)
+	})
+
+	t.Run("Before delete, rollback error", func(t *testing.T) {
+		var events []string
+
+		handler := newRelatedDataHandler(&events, keys...)
+		handler.errRollback = errors.New("injected rollback error")
+
+		sweep(newStore(&events, errors.New("injected batch error")), WithExpiryHandler(handler))
+
+		require.Equal(t, []string{"handle", "batch", "rollback"}, events)
+	})
+
+	t.Run("After delete", func(t *testing.T) {
+		var events []string
+
+		handler := newRelatedDataHandler(&events, keys...)
+
+		sweep(newStore(&events, nil), WithExpiryHandler(handler), WithHandlerMode(HandleAfterDelete))
+
+		require.Equal(t, []string{"batch", "handle"}, events)
+		require.Empty(t, handler.relatedData)
+	})
+
+	t.Run("After delete, batch error -> handler not invoked", func(t *testing.T) {
+		var events []string
+
+		handler := newRelatedDataHandler(&events, keys...)
+
+		sweep(newStore(&events, errors.New("injected batch error")), WithExpiryHandler(handler),
+			WithHandlerMode(HandleAfterDelete))
+
+		require.Equal(t, []string{"batch"}, events)
+		require.Len(t, handler.relatedData, len(keys))
+	})
+
+	t.Run("After delete, handler error -> keys deleted", func(t *testing.T) {
+		var events []string
+
+		handler := newRelatedDataHandler(&events, keys...)
+		handler.errHandle = errors.New("injected handler error")
+
+		sweep(newStore(&events, nil), WithExpiryHandler(handler), WithHandlerMode(HandleAfterDelete))
+
+		require.Equal(t, []string{"batch", "handle"}, events)
+	})
+}
+
+func TestService_MultipleExpiryTags(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		taskMgr := &mockTaskManager{}
+
+		store := newTaggedStore(map[string][]string{
+			"TokenExpiry":  {"token1", "token2", "shared"},
+			"AnchorExpiry": {"anchor1", "shared"},
+		})
+
+		handler := &mockExpiryHandler{}
+
+		service := NewService(taskMgr, time.Second)
+		service.Register(store, "TokenExpiry", "TestStore",
+			WithAdditionalExpiryTags("AnchorExpiry"), WithExpiryHandler(handler))
+
+		require.Len(t, service.registeredStores, 1)
+		require.Equal(t, []string{"TokenExpiry", "AnchorExpiry"}, service.registeredStores[0].expiryTagNames)
+
+		count, err := service.PendingExpired("TestStore")
+		require.NoError(t, err)
+		require.Equal(t, 4, count)
+
+		taskMgr.handler()
+
+		require.Len(t, store.queries, 4)
+		require.Regexp(t, `^AnchorExpiry<=\d+$`, store.queries[3])
+
+		// The shared key was deleted under the first tag so it must not be handled again under the second tag.
+		require.Equal(t, []string{"token1", "token2", "shared", "anchor1"}, handler.keys)
+		require.Equal(t, 2, handler.calls)
+		require.Empty(t, store.keysByTag["TokenExpiry"])
+		require.Empty(t, store.keysByTag["AnchorExpiry"])
+
+		count, err = service.PendingExpired("TestStore")
+		require.NoError(t, err)
+		require.Zero(t, count)
+	})
+
+	t.Run("Query error for one tag", func(t *testing.T) {
+		taskMgr := &mockTaskManager{}
+
+		store := newTaggedStore(map[string][]string{
+			"AnchorExpiry": {"anchor1"},
+		})
+		store.errQuery = map[string]error{"TokenExpiry": errors.New("query error")}
+
+		handler := &mockExpiryHandler{}
+
+		NewService(taskMgr, time.Second).Register(store, "TokenExpiry", "TestStore",
+			WithAdditionalExpiryTags("AnchorExpiry"), WithExpiryHandler(handler))
+
+		taskMgr.handler()
+
+		require.Equal(t, []string{"anchor1"}, handler.keys)
+		require.Empty(t, store.keysByTag["AnchorExpiry"])
+	})
+}
+
 func storeTestData(t *testing.T, expiryTagName string, store storage.Store) {
 	t.Helper()
 
@@ -360,8 +700,244 @@ func doFinalSetOfChecks(t *testing.T, storeToRunExpiryChecksOn storage.Store) {
 
 type mockExpiryHandler struct {
 	Err error
+
+	keys  []string
+	calls int
 }
 
-func (m *mockExpiryHandler) HandleExpiredKeys(_ ...string) error {
+func (m *mockExpiryHandler) HandleExpiredKeys(keys ...string) error {
+	m.keys = append(m.keys, keys...)
+	m.calls++
+
 	return m.Err
 }
+
+// pageRecordingHandler records the largest number of keys that it was given at once.
+type pageRecordingHandler struct {
+	mockExpiryHandler
+
+	maxPageSize int
+}
+
+func (m *pageRecordingHandler) HandleExpiredKeys(keys ...string) error {
+	if len(keys) > m.maxPageSize {
+		m.maxPageSize = len(keys)
+	}
+
+	return m.mockExpiryHandler.HandleExpiredKeys(keys...)
+}
+
+// relatedDataHandler deletes related data for the expired keys and restores the related data on rollback.
+type relatedDataHandler struct {
+	relatedData map[string]bool
+	events      *[]string
+	errHandle   error
+	errRollback error
+}
+
+func newRelatedDataHandler(events *[]string, keys ...string) *relatedDataHandler {
+	relatedData := make(map[string]bool)
+
+	for _, key := range keys {
+		relatedData[key] = true
+	}
+
+	return &relatedDataHandler{relatedData: relatedData, events: events}
+}
+
+func (h *relatedDataHandler) HandleExpiredKeys(keys ...string) error {
+	*h.events = append(*h.events, "handle")
+
+	if h.errHandle != nil {
+		return h.errHandle
+	}
+
+	for _, key := range keys {
+		delete(h.relatedData, key)
+	}
+
+	return nil
+}
+
+func (h *relatedDataHandler) RollbackExpiredKeys(keys ...string) error {
+	*h.events = append(*h.events, "rollback")
+
+	if h.errRollback != nil {
+		return h.errRollback
+	}
+
+	for _, key := range keys {
+		h.relatedData[key] = true
+	}
+
+	return nil
+}
+
+// eventRecordingStore records each batch call in the given events.
+type eventRecordingStore struct {
+	*mock.Store
+
+	events *[]string
+}
+
+func (s *eventRecordingStore) Batch(operations []storage.Operation) error {
+	*s.events = append(*s.events, "batch")
+
+	return s.Store.Batch(operations)
+}
+
+type queryRecordingStore struct {
+	*mock.Store
+
+	queries    []string
+	pageSizes  []int
+	batchCalls int
+}
+
+func (s *queryRecordingStore) Batch(operations []storage.Operation) error {
+	s.batchCalls++
+
+	return s.Store.Batch(operations)
+}
+
+func (s *queryRecordingStore) Query(expression string, opts ...storage.QueryOption) (storage.Iterator, error) {
+	s.queries = append(s.queries, expression)
+
+	queryOpts := &storage.QueryOptions{}
+
+	for _, opt := range opts {
+		opt(queryOpts)
+	}
+
+	s.pageSizes = append(s.pageSizes, queryOpts.PageSize)
+
+	return s.Store.Query(expression, opts...)
+}
+
+// taggedStore is a store that holds expired keys under more than one expiry tag. Deleting a key removes it
+// from every tag.
+type taggedStore struct {
+	*mock.Store
+
+	keysByTag map[string][]string
+	errQuery  map[string]error
+	queries   []string
+}
+
+func newTaggedStore(keysByTag map[string][]string) *taggedStore {
+	return &taggedStore{
+		Store:     &mock.Store{},
+		keysByTag: keysByTag,
+	}
+}
+
+func (s *taggedStore) Query(expression string, _ ...storage.QueryOption) (storage.Iterator, error) {
+	s.queries = append(s.queries, expression)
+
+	tagName := strings.Split(expression, "<=")[0]
+
+	if err := s.errQuery[tagName]; err != nil {
+		return nil, err
+	}
+
+	return newKeysIterator(s.keysByTag[tagName]...), nil
+}
+
+func (s *taggedStore) Batch(operations []storage.Operation) error {
+	deleted := make(map[string]bool)
+
+	for _, op := range operations {
+		deleted[op.Key] = true
+	}
+
+	for tagName, keys := range s.keysByTag {
+		var remaining []string
+
+		for _, key := range keys {
+			if !deleted[key] {
+				remaining = append(remaining, key)
+			}
+		}
+
+		s.keysByTag[tagName] = remaining
+	}
+
+	return nil
+}
+
+type mockTaskManager struct {
+	handler func()
+}
+
+func (m *mockTaskManager) RegisterTask(_ string, _ time.Duration, handler func()) {
+	m.handler = handler
+}
+
+type keysIterator struct {
+	*mock.Iterator
+
+	keys    []string
+	current int
+	closed  bool
+}
+
+func newKeysIterator(keys ...string) *keysIterator {
+	return &keysIterator{
+		Iterator: &mock.Iterator{},
+		keys:     keys,
+		current:  -1,
+	}
+}
+
+func (it *keysIterator) Next() (bool, error) {
+	it.current++
+
+	return it.current < len(it.keys), nil
+}
+
+func (it *keysIterator) Key() (string, error) {
+	return it.keys[it.current], nil
+}
+
+func (it *keysIterator) Close() error {
+	it.closed = true
+
+	return nil
+}
+
+func TestService_RegisteredStores(t *testing.T) {
+	service := NewService(&mockTaskManager{}, time.Second)
+
+	require.Empty(t, service.RegisteredStores())
+
+	service.Register(&mock.Store{}, "ExpiryTag", "Store1")
+	service.Register(&mock.Store{}, "ExpiryTag", "Store2",
+		WithExpiryHandler(&mockExpiryHandler{}),
+		WithAdditionalExpiryTags("OtherExpiryTag"),
+		WithQueryBuilder(func(expiryTagName string, expiryTime time.Time) string {
+			return expiryTagName
+		}),
+		WithPageSize(100),
+	)
+
+	stores := service.RegisteredStores()
+	require.Equal(t, []StoreInfo{
+		{
+			Name:           "Store1",
+			ExpiryTagNames: []string{"ExpiryTag"},
+			PageSize:       defaultPageSize,
+		},
+		{
+			Name:           "Store2",
+			ExpiryTagNames: []string{"ExpiryTag", "OtherExpiryTag"},
+			PageSize:       100,
+			ExpiryHandler:  true,
+			CustomQuery:    true,
+		},
+	}, stores)
+
+	// The returned info is a copy.
+	stores[1].ExpiryTagNames[0] = "ChangedTag"
+
+	require.Equal(t, "ExpiryTag", service.RegisteredStores()[1].ExpiryTagNames[0])
+}
@@ -0,0 +1,73 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package resthandler
+
+import (
+	"sync"
+	"time"
+)
+
+// LogURLCache caches the current log URL in memory for a configurable TTL. Since the log URL rarely changes,
+// the cache may be shared by the LogRetriever (see WithCache) and the LogConfigurator
+// (see WithCacheInvalidation) so that repeated GETs are served from memory and the cached value is
+// invalidated as soon as a new log URL is written.
+type LogURLCache struct {
+	ttl     time.Duration
+	mutex   sync.RWMutex
+	entry   *logConfig
+	cached  bool
+	expires time.Time
+}
+
+// NewLogURLCache returns a new log URL cache with the given TTL.
+func NewLogURLCache(ttl time.Duration) *LogURLCache {
+	return &LogURLCache{ttl: ttl}
+}
+
+// WithCacheInvalidation invalidates the given cache whenever a new log URL is stored.
+func WithCacheInvalidation(cache *LogURLCache) Option {
+	return func(c *LogConfigurator) {
+		c.cache = cache
+	}
+}
+
+// Invalidate removes the cached log URL so that the next GET is served from the config store.
+func (c *LogURLCache) Invalidate() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entry = nil
+	c.cached = false
+	c.expires = time.Time{}
+}
+
+// get returns the cached log configuration (which is nil if the log URL wasn't found in the store)
+// and true if the cache holds an unexpired entry.
+func (c *LogURLCache) get() (*logConfig, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if !c.cached || time.Now().After(c.expires) {
+		return nil, false
+	}
+
+	return c.entry, true
+}
+
+// put caches the given log configuration. A nil value indicates that the log URL isn't configured.
+func (c *LogURLCache) put(cfg *logConfig) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entry = cfg
+	c.cached = true
+	c.expires = time.Now().Add(c.ttl)
+}
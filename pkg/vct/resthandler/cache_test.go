@@ -0,0 +1,150 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package resthandler
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/stretchr/testify/require"
+
+	storemocks "github.com/trustbloc/orb/pkg/store/mocks"
+)
+
+func TestLogURLCache(t *testing.T) {
+	const testLogURL2 = "https://vct2.com/log"
+
+	newStore := func(t *testing.T) *storemocks.Store {
+		t.Helper()
+
+		s, err := mem.NewProvider().OpenStore(configStoreName)
+		require.NoError(t, err)
+
+		store := &storemocks.Store{}
+		store.GetCalls(s.Get)
+		store.PutCalls(s.Put)
+
+		return store
+	}
+
+	getLogURL := func(t *testing.T, r *LogRetriever) (int, string) {
+		t.Helper()
+
+		rw := httptest.NewRecorder()
+
+		r.handle(rw, httptest.NewRequest(http.MethodGet, endpoint, nil))
+
+		result := rw.Result()
+
+		respBytes, err := ioutil.ReadAll(result.Body)
+		require.NoError(t, err)
+		require.NoError(t, result.Body.Close())
+
+		return result.StatusCode, string(respBytes)
+	}
+
+	setLogURL := func(t *testing.T, c *LogConfigurator, logURL string) {
+		t.Helper()
+
+		rw := httptest.NewRecorder()
+
+		c.handle(rw, httptest.NewRequest(http.MethodPost, endpoint, bytes.NewBuffer([]byte(logURL))))
+
+		result := rw.Result()
+		require.Equal(t, http.StatusOK, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+	}
+
+	t.Run("served from cache within TTL", func(t *testing.T) {
+		store := newStore(t)
+
+		cache := NewLogURLCache(time.Minute)
+
+		c := New(store, &mockLogMonitorStore{}, WithCacheInvalidation(cache))
+		r := NewRetriever(store, WithCache(cache))
+
+		setLogURL(t, c, testLogURL)
+
+		status, logURL := getLogURL(t, r)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, testLogURL, logURL)
+		require.Equal(t, 1, store.GetCallCount())
+
+		status, logURL = getLogURL(t, r)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, testLogURL, logURL)
+		require.Equal(t, 1, store.GetCallCount(), "second GET within the TTL should not hit the store")
+
+		// Writing a new log URL invalidates the cache.
+		setLogURL(t, c, testLogURL2)
+
+		status, logURL = getLogURL(t, r)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, testLogURL2, logURL)
+		require.Equal(t, 2, store.GetCallCount())
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		store := newStore(t)
+
+		cache := NewLogURLCache(time.Minute)
+
+		c := New(store, &mockLogMonitorStore{}, WithCacheInvalidation(cache))
+		r := NewRetriever(store, WithCache(cache))
+
+		status, _ := getLogURL(t, r)
+		require.Equal(t, http.StatusNotFound, status)
+
+		status, _ = getLogURL(t, r)
+		require.Equal(t, http.StatusNotFound, status)
+		require.Equal(t, 1, store.GetCallCount())
+
+		setLogURL(t, c, testLogURL)
+
+		status, logURL := getLogURL(t, r)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, testLogURL, logURL)
+		require.Equal(t, 2, store.GetCallCount())
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		store := newStore(t)
+
+		cache := NewLogURLCache(10 * time.Millisecond)
+
+		setLogURL(t, New(store, &mockLogMonitorStore{}), testLogURL)
+
+		r := NewRetriever(store, WithCache(cache))
+
+		getLogURL(t, r)
+		require.Equal(t, 1, store.GetCallCount())
+
+		time.Sleep(20 * time.Millisecond)
+
+		status, logURL := getLogURL(t, r)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, testLogURL, logURL)
+		require.Equal(t, 2, store.GetCallCount())
+	})
+
+	t.Run("zero TTL -> caching disabled", func(t *testing.T) {
+		store := newStore(t)
+
+		setLogURL(t, New(store, &mockLogMonitorStore{}), testLogURL)
+
+		r := NewRetriever(store, WithCache(NewLogURLCache(0)))
+
+		getLogURL(t, r)
+		getLogURL(t, r)
+		require.Equal(t, 2, store.GetCallCount())
+	})
+}
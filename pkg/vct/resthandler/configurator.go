@@ -34,6 +34,8 @@ const (
 type LogConfigurator struct {
 	configStore     storage.Store
 	logMonitorStore logMonitorStore
+	historyStore    storage.Store
+	cache           *LogURLCache
 	logger          *log.StructuredLog
 	marshal         func(interface{}) ([]byte, error)
 }
@@ -58,7 +60,7 @@ type logMonitorStore interface {
 }
 
 // New returns a new LogConfigurator.
-func New(cfgStore storage.Store, lmStore logMonitorStore) *LogConfigurator {
+func New(cfgStore storage.Store, lmStore logMonitorStore, opts ...Option) *LogConfigurator {
 	h := &LogConfigurator{
 		configStore:     cfgStore,
 		logMonitorStore: lmStore,
@@ -66,6 +68,10 @@ func New(cfgStore storage.Store, lmStore logMonitorStore) *LogConfigurator {
 		marshal:         json.Marshal,
 	}
 
+	for _, opt := range opts {
+		opt(h)
+	}
+
 	return h
 }
 
@@ -116,6 +122,17 @@ func (c *LogConfigurator) handle(w http.ResponseWriter, req *http.Request) {
 
 	c.logger.Debug("Stored log URL", log.WithLogURLString(logURLStr))
 
+	if c.cache != nil {
+		c.cache.Invalidate()
+	}
+
+	if c.historyStore != nil {
+		// The log URL has already been updated, so don't fail the request if the history can't be recorded.
+		if err := c.recordHistory(logURLStr); err != nil {
+			c.logger.Warn("Error recording log URL history", log.WithLogURLString(logURLStr), log.WithError(err))
+		}
+	}
+
 	if logURLStr != "" {
 		err = c.logMonitorStore.Activate(logURLStr)
 		if err != nil {
@@ -0,0 +1,153 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package resthandler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/trustbloc/sidetree-core-go/pkg/restapi/common"
+
+	"github.com/trustbloc/orb/internal/pkg/log"
+)
+
+const (
+	logURLHistoryKey = "log-url-history"
+	historyEndpoint  = endpoint + "/history"
+)
+
+// LogHistoryEntry contains a log URL along with the time that the log URL was set.
+type LogHistoryEntry struct {
+	URL     string    `json:"url"`
+	Updated time.Time `json:"updated"`
+}
+
+// Option is an option for the LogConfigurator.
+type Option func(c *LogConfigurator)
+
+// WithHistoryStore sets the store in which the history of log URL changes is recorded. If not set then
+// the history isn't recorded.
+func WithHistoryStore(store storage.Store) Option {
+	return func(c *LogConfigurator) {
+		c.historyStore = store
+	}
+}
+
+// recordHistory adds the given log URL to the history if it differs from the most recent log URL in the history.
+func (c *LogConfigurator) recordHistory(logURL string) error {
+	history, err := getHistory(c.historyStore)
+	if err != nil {
+		return err
+	}
+
+	if len(history) > 0 && history[len(history)-1].URL == logURL {
+		return nil
+	}
+
+	history = append(history, &LogHistoryEntry{URL: logURL, Updated: time.Now().UTC()})
+
+	historyBytes, err := c.marshal(history)
+	if err != nil {
+		return fmt.Errorf("marshal log URL history: %w", err)
+	}
+
+	err = c.historyStore.Put(logURLHistoryKey, historyBytes)
+	if err != nil {
+		return fmt.Errorf("store log URL history: %w", err)
+	}
+
+	return nil
+}
+
+// LogHistoryRetriever retrieves the history of log URL changes.
+type LogHistoryRetriever struct {
+	historyStore storage.Store
+	logger       *log.StructuredLog
+	marshal      func(interface{}) ([]byte, error)
+}
+
+// Path returns the HTTP REST endpoint for the log history retriever.
+func (lr *LogHistoryRetriever) Path() string {
+	return historyEndpoint
+}
+
+// Method returns the HTTP REST method for the log history retriever.
+func (lr *LogHistoryRetriever) Method() string {
+	return http.MethodGet
+}
+
+// Handler returns the HTTP REST handle for the log history retriever service.
+func (lr *LogHistoryRetriever) Handler() common.HTTPRequestHandler {
+	return lr.handle
+}
+
+// NewHistoryRetriever returns a new LogHistoryRetriever. The given store must be the same store that's
+// provided to the LogConfigurator (see WithHistoryStore).
+func NewHistoryRetriever(historyStore storage.Store) *LogHistoryRetriever {
+	return &LogHistoryRetriever{
+		historyStore: historyStore,
+		logger:       log.NewStructured(loggerModule, log.WithFields(log.WithServiceEndpoint(historyEndpoint))),
+		marshal:      json.Marshal,
+	}
+}
+
+func (lr *LogHistoryRetriever) handle(w http.ResponseWriter, _ *http.Request) {
+	history, err := getHistory(lr.historyStore)
+	if err != nil {
+		lr.logger.Error("Error retrieving log URL history", log.WithError(err))
+
+		writeResponse(lr.logger, w, http.StatusInternalServerError, []byte(internalServerErrorResponse))
+
+		return
+	}
+
+	historyBytes, err := lr.marshal(history)
+	if err != nil {
+		lr.logger.Error("Error marshalling log URL history", log.WithError(err))
+
+		writeResponse(lr.logger, w, http.StatusInternalServerError, []byte(internalServerErrorResponse))
+
+		return
+	}
+
+	lr.logger.Debug("Retrieved log URL history", log.WithTotal(len(history)))
+
+	w.Header().Set("Content-Type", jsonContentType)
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(historyBytes); err != nil {
+		log.WriteResponseBodyError(lr.logger, err)
+
+		return
+	}
+
+	log.WroteResponse(lr.logger, historyBytes)
+}
+
+// getHistory returns the log URL history (oldest first). An empty slice is returned if there is no history.
+func getHistory(store storage.Store) ([]*LogHistoryEntry, error) {
+	history := []*LogHistoryEntry{}
+
+	historyBytes, err := store.Get(logURLHistoryKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrDataNotFound) {
+			return history, nil
+		}
+
+		return nil, fmt.Errorf("get log URL history: %w", err)
+	}
+
+	if err := json.Unmarshal(historyBytes, &history); err != nil {
+		return nil, fmt.Errorf("unmarshal log URL history: %w", err)
+	}
+
+	return history, nil
+}
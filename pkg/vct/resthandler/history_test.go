@@ -0,0 +1,148 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package resthandler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/stretchr/testify/require"
+
+	storemocks "github.com/trustbloc/orb/pkg/store/mocks"
+)
+
+func TestNewHistoryRetriever(t *testing.T) {
+	historyStore, err := mem.NewProvider().OpenStore(configStoreName)
+	require.NoError(t, err)
+
+	retriever := NewHistoryRetriever(historyStore)
+	require.NotNil(t, retriever)
+	require.Equal(t, "/log/history", retriever.Path())
+	require.Equal(t, http.MethodGet, retriever.Method())
+	require.NotNil(t, retriever.Handler())
+}
+
+func TestLogHistory(t *testing.T) {
+	const (
+		testLogURL2 = "https://vct2.com/log"
+		testLogURL3 = "https://vct3.com/log"
+	)
+
+	setLogURL := func(t *testing.T, c *LogConfigurator, logURL string) {
+		t.Helper()
+
+		rw := httptest.NewRecorder()
+
+		c.handle(rw, httptest.NewRequest(http.MethodPost, endpoint, bytes.NewBuffer([]byte(logURL))))
+
+		result := rw.Result()
+		require.Equal(t, http.StatusOK, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+	}
+
+	getHistory := func(t *testing.T, r *LogHistoryRetriever) []*LogHistoryEntry {
+		t.Helper()
+
+		rw := httptest.NewRecorder()
+
+		r.handle(rw, httptest.NewRequest(http.MethodGet, historyEndpoint, nil))
+
+		result := rw.Result()
+		require.Equal(t, http.StatusOK, result.StatusCode)
+		require.Equal(t, "application/json", result.Header.Get("Content-Type"))
+
+		respBytes, err := ioutil.ReadAll(result.Body)
+		require.NoError(t, err)
+		require.NoError(t, result.Body.Close())
+
+		var history []*LogHistoryEntry
+		require.NoError(t, json.Unmarshal(respBytes, &history))
+
+		return history
+	}
+
+	t.Run("set-change-change", func(t *testing.T) {
+		configStore, err := mem.NewProvider().OpenStore(configStoreName)
+		require.NoError(t, err)
+
+		c := New(configStore, &mockLogMonitorStore{}, WithHistoryStore(configStore))
+		r := NewHistoryRetriever(configStore)
+
+		history := getHistory(t, r)
+		require.NotNil(t, history)
+		require.Empty(t, history)
+
+		setLogURL(t, c, testLogURL)
+		setLogURL(t, c, testLogURL2)
+		setLogURL(t, c, testLogURL2) // Unchanged
+		setLogURL(t, c, testLogURL3)
+
+		history = getHistory(t, r)
+		require.Len(t, history, 3)
+		require.Equal(t, testLogURL, history[0].URL)
+		require.Equal(t, testLogURL2, history[1].URL)
+		require.Equal(t, testLogURL3, history[2].URL)
+
+		require.False(t, history[0].Updated.IsZero())
+		require.False(t, history[1].Updated.Before(history[0].Updated))
+		require.False(t, history[2].Updated.Before(history[1].Updated))
+	})
+
+	t.Run("No history store -> history not recorded", func(t *testing.T) {
+		configStore, err := mem.NewProvider().OpenStore(configStoreName)
+		require.NoError(t, err)
+
+		setLogURL(t, New(configStore, &mockLogMonitorStore{}), testLogURL)
+
+		require.Empty(t, getHistory(t, NewHistoryRetriever(configStore)))
+	})
+
+	t.Run("History store error -> log URL still updated", func(t *testing.T) {
+		configStore, err := mem.NewProvider().OpenStore(configStoreName)
+		require.NoError(t, err)
+
+		historyStore := &storemocks.Store{}
+		historyStore.GetReturns(nil, errors.New("injected get error"))
+
+		setLogURL(t, New(configStore, &mockLogMonitorStore{}, WithHistoryStore(historyStore)), testLogURL)
+
+		_, err = configStore.Get(logURLKey)
+		require.NoError(t, err)
+	})
+
+	t.Run("Retrieve error", func(t *testing.T) {
+		historyStore := &storemocks.Store{}
+		historyStore.GetReturns(nil, errors.New("injected get error"))
+
+		rw := httptest.NewRecorder()
+
+		NewHistoryRetriever(historyStore).handle(rw, httptest.NewRequest(http.MethodGet, historyEndpoint, nil))
+
+		result := rw.Result()
+		require.Equal(t, http.StatusInternalServerError, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+	})
+
+	t.Run("Unmarshal error", func(t *testing.T) {
+		historyStore := &storemocks.Store{}
+		historyStore.GetReturns([]byte("{"), nil)
+
+		rw := httptest.NewRecorder()
+
+		NewHistoryRetriever(historyStore).handle(rw, httptest.NewRequest(http.MethodGet, historyEndpoint, nil))
+
+		result := rw.Result()
+		require.Equal(t, http.StatusInternalServerError, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+	})
+}
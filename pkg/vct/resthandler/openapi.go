@@ -43,3 +43,22 @@ type logPostResp struct { // nolint: unused,deadcode
 //        200: logPostResp
 func postLog() { // nolint: unused,deadcode
 }
+
+// swagger:parameters logHistoryGetReq
+type logHistoryGetReq struct { // nolint: unused,deadcode
+}
+
+// swagger:response logHistoryGetResp
+type logHistoryGetResp struct { // nolint: unused,deadcode
+	// in: body
+	Body []LogHistoryEntry
+}
+
+// getLogHistory swagger:route GET /log/history Log logHistoryGetReq
+//
+// Retrieves the history of witness log changes (oldest first).
+//
+// Responses:
+//        200: logHistoryGetResp
+func getLogHistory() { // nolint: unused,deadcode
+}
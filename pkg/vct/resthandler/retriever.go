@@ -9,7 +9,10 @@ package resthandler
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"mime"
 	"net/http"
+	"strings"
 
 	"github.com/hyperledger/aries-framework-go/spi/storage"
 	"github.com/trustbloc/sidetree-core-go/pkg/restapi/common"
@@ -17,11 +20,39 @@ import (
 	"github.com/trustbloc/orb/internal/pkg/log"
 )
 
+const (
+	jsonContentType = "application/json"
+
+	logNotConfiguredResponse = `{"configured":false}`
+)
+
 // LogRetriever retrieves the current log URL.
 type LogRetriever struct {
-	configStore storage.Store
-	logger      *log.StructuredLog
-	unmarshal   func([]byte, interface{}) error
+	configStore           storage.Store
+	logger                *log.StructuredLog
+	unmarshal             func([]byte, interface{}) error
+	notConfiguredResponse bool
+	cache                 *LogURLCache
+}
+
+// RetrieverOption is an option for the LogRetriever.
+type RetrieverOption func(lr *LogRetriever)
+
+// WithNotConfiguredResponse indicates that, if no log is configured, a 200 (OK) should be returned with
+// the JSON body {"configured":false} rather than a 404 (Not Found). This response is also returned if the
+// request's Accept header includes application/json.
+func WithNotConfiguredResponse() RetrieverOption {
+	return func(lr *LogRetriever) {
+		lr.notConfiguredResponse = true
+	}
+}
+
+// WithCache serves the log URL from the given in-memory cache (see LogURLCache) rather than querying
+// the config store on every request.
+func WithCache(cache *LogURLCache) RetrieverOption {
+	return func(lr *LogRetriever) {
+		lr.cache = cache
+	}
 }
 
 // Path returns the HTTP REST endpoint for the log retriever.
@@ -40,21 +71,27 @@ func (lr *LogRetriever) Handler() common.HTTPRequestHandler {
 }
 
 // NewRetriever returns a new LogRetriever.
-func NewRetriever(cfgStore storage.Store) *LogRetriever {
-	return &LogRetriever{
+func NewRetriever(cfgStore storage.Store, opts ...RetrieverOption) *LogRetriever {
+	lr := &LogRetriever{
 		configStore: cfgStore,
 		logger:      log.NewStructured(loggerModule, log.WithFields(log.WithServiceEndpoint(endpoint))),
 		unmarshal:   json.Unmarshal,
 	}
+
+	for _, opt := range opts {
+		opt(lr)
+	}
+
+	return lr
 }
 
 func (lr *LogRetriever) handle(w http.ResponseWriter, req *http.Request) {
-	logConfigBytes, err := lr.configStore.Get(logURLKey)
+	logConfig, err := lr.getLogConfig()
 	if err != nil {
 		if errors.Is(err, storage.ErrDataNotFound) {
 			lr.logger.Debug("Log URL not found")
 
-			writeResponse(lr.logger, w, http.StatusNotFound, nil)
+			lr.writeNotConfigured(w, req)
 
 			return
 		}
@@ -66,18 +103,78 @@ func (lr *LogRetriever) handle(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	lr.logger.Debug("Retrieved log URL", log.WithLogURLString(logConfig.URL))
+
+	writeResponse(lr.logger, w, http.StatusOK, []byte(logConfig.URL))
+}
+
+// getLogConfig returns the log configuration from the cache (if configured) or from the config store.
+// storage.ErrDataNotFound is returned if no log URL is configured.
+func (lr *LogRetriever) getLogConfig() (*logConfig, error) {
+	if lr.cache != nil {
+		if cfg, ok := lr.cache.get(); ok {
+			if cfg == nil {
+				return nil, storage.ErrDataNotFound
+			}
+
+			return cfg, nil
+		}
+	}
+
+	logConfigBytes, err := lr.configStore.Get(logURLKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrDataNotFound) && lr.cache != nil {
+			lr.cache.put(nil)
+		}
+
+		return nil, err
+	}
+
 	logConfig := &logConfig{}
 
 	err = lr.unmarshal(logConfigBytes, &logConfig)
 	if err != nil {
-		lr.logger.Error("Error unmarshalling log configuration", log.WithError(err))
+		return nil, fmt.Errorf("unmarshal log configuration: %w", err)
+	}
 
-		writeResponse(lr.logger, w, http.StatusInternalServerError, []byte(internalServerErrorResponse))
+	if lr.cache != nil {
+		lr.cache.put(logConfig)
+	}
+
+	return logConfig, nil
+}
+
+// writeNotConfigured writes a 404 (Not Found) or, if configured (or requested via the Accept header),
+// a 200 (OK) with a JSON body that indicates that no log is configured.
+func (lr *LogRetriever) writeNotConfigured(w http.ResponseWriter, req *http.Request) {
+	if !lr.notConfiguredResponse && !acceptsJSON(req) {
+		writeResponse(lr.logger, w, http.StatusNotFound, nil)
 
 		return
 	}
 
-	lr.logger.Debug("Retrieved log URL", log.WithLogURLString(logConfig.URL))
+	respBytes := []byte(logNotConfiguredResponse)
 
-	writeResponse(lr.logger, w, http.StatusOK, []byte(logConfig.URL))
+	w.Header().Set("Content-Type", jsonContentType)
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(respBytes); err != nil {
+		log.WriteResponseBodyError(lr.logger, err)
+
+		return
+	}
+
+	log.WroteResponse(lr.logger, respBytes)
+}
+
+func acceptsJSON(req *http.Request) bool {
+	for _, accept := range req.Header.Values("Accept") {
+		for _, mediaType := range strings.Split(accept, ",") {
+			if mt, _, err := mime.ParseMediaType(strings.TrimSpace(mediaType)); err == nil && mt == jsonContentType {
+				return true
+			}
+		}
+	}
+
+	return false
 }
@@ -78,6 +78,50 @@ func TestLogRetriever_Handler(t *testing.T) {
 		require.NoError(t, result.Body.Close())
 	})
 
+	t.Run("not configured response - option", func(t *testing.T) {
+		configStore, err := mem.NewProvider().OpenStore(configStoreName)
+		require.NoError(t, err)
+
+		logRetriever := NewRetriever(configStore, WithNotConfiguredResponse())
+		require.NotNil(t, logRetriever)
+
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, endpoint, nil)
+
+		logRetriever.handle(rw, req)
+
+		result := rw.Result()
+		require.Equal(t, http.StatusOK, result.StatusCode)
+		require.Equal(t, "application/json", result.Header.Get("Content-Type"))
+
+		respBytes, err := ioutil.ReadAll(result.Body)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"configured":false}`, string(respBytes))
+		require.NoError(t, result.Body.Close())
+	})
+
+	t.Run("not configured response - Accept header", func(t *testing.T) {
+		configStore, err := mem.NewProvider().OpenStore(configStoreName)
+		require.NoError(t, err)
+
+		logRetriever := NewRetriever(configStore)
+		require.NotNil(t, logRetriever)
+
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, endpoint, nil)
+		req.Header.Set("Accept", "text/plain, application/json; q=0.9")
+
+		logRetriever.handle(rw, req)
+
+		result := rw.Result()
+		require.Equal(t, http.StatusOK, result.StatusCode)
+
+		respBytes, err := ioutil.ReadAll(result.Body)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"configured":false}`, string(respBytes))
+		require.NoError(t, result.Body.Close())
+	})
+
 	t.Run("error - config store error", func(t *testing.T) {
 		configStore := &storemocks.Store{}
 		configStore.GetReturns(nil, errors.New("get error"))